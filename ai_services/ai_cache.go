@@ -0,0 +1,337 @@
+package ai_services
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"projeto-integrador/database"
+	"projeto-integrador/models"
+	"projeto-integrador/scheduler"
+	"projeto-integrador/utilities"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxCachedAIResponseBytes = 1 << 20
+
+// TTL padrão do cache de cada serviço; 0 desliga o cache do serviço. As respostas do
+// assistente de tarefas dependem da conversa e do momento, então não são reaproveitadas.
+var defaultAICacheTTLs = map[string]time.Duration{
+	ServiceCodeReview:    24 * time.Hour,
+	ServiceTextSummary:   24 * time.Hour,
+	ServiceMindMapIdeas:  24 * time.Hour,
+	ServiceTaskBreakdown: time.Hour,
+	ServiceTaskSummary:   time.Hour,
+	ServiceTaskAssistant: 0,
+}
+
+// AIRequestOptions ajusta a execução de uma requisição de IA.
+type AIRequestOptions struct {
+	NoCache bool // Não usa uma resposta em cache (a nova resposta ainda é gravada)
+}
+
+// AIResponseCache guarda respostas do provedor por serviço e requisição normalizada:
+// um LRU em memória e, opcionalmente, uma camada no PostgreSQL compartilhada entre as
+// réplicas. As respostas são guardadas como enviadas pelo provedor, ainda com os
+// placeholders do mascaramento, que são restaurados com os valores de cada requisição.
+type AIResponseCache struct {
+	mu          sync.Mutex
+	capacity    int
+	entries     map[string]*list.Element
+	order       *list.List // Mais recente na frente
+	ttls        map[string]time.Duration
+	usePostgres bool
+}
+
+type aiCacheEntry struct {
+	key       string
+	response  []byte
+	expiresAt time.Time
+}
+
+var (
+	aiCacheOnce    sync.Once
+	defaultAICache *AIResponseCache
+)
+
+// GetAIResponseCache retorna o cache configurado, ou nil se estiver desligado.
+//
+// Variáveis de ambiente:
+//   - AI_CACHE_ENABLED: "false" desliga o cache (padrão: ligado)
+//   - AI_CACHE_MAX_ENTRIES: respostas mantidas em memória (padrão: 500)
+//   - AI_CACHE_POSTGRES: "true" liga a camada no PostgreSQL (padrão: desligada)
+//   - AI_CACHE_TTL_<SERVIÇO>: TTL de cada serviço, ex: AI_CACHE_TTL_CODE_REVIEW=12h; 0 desliga
+func GetAIResponseCache() *AIResponseCache {
+	aiCacheOnce.Do(func() {
+		if !scheduler.EnabledFromEnv("AI_CACHE_ENABLED") {
+			utilities.LogInfo("AICache: cache de respostas de IA desativado por AI_CACHE_ENABLED")
+			return
+		}
+		capacity := 500
+		if n, err := strconv.Atoi(os.Getenv("AI_CACHE_MAX_ENTRIES")); err == nil && n > 0 {
+			capacity = n
+		}
+		ttls := make(map[string]time.Duration, len(defaultAICacheTTLs))
+		for serviceType, def := range defaultAICacheTTLs {
+			ttls[serviceType] = aiCacheTTLFromEnv("AI_CACHE_TTL_"+strings.ToUpper(serviceType), def)
+		}
+		defaultAICache = NewAIResponseCache(capacity, ttls, os.Getenv("AI_CACHE_POSTGRES") == "true")
+	})
+	return defaultAICache
+}
+
+// NewAIResponseCache cria um cache com capacity respostas em memória e o TTL de cada serviço.
+func NewAIResponseCache(capacity int, ttls map[string]time.Duration, usePostgres bool) *AIResponseCache {
+	return &AIResponseCache{
+		capacity:    capacity,
+		entries:     map[string]*list.Element{},
+		order:       list.New(),
+		ttls:        ttls,
+		usePostgres: usePostgres,
+	}
+}
+
+func aiCacheTTLFromEnv(key string, def time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	if value == "0" {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		utilities.LogInfo("Valor inválido para %s (%q), usando padrão %v", key, value, def)
+		return def
+	}
+	return d
+}
+
+// TTL retorna por quanto tempo as respostas do serviço ficam no cache (0 = não usa cache).
+func (c *AIResponseCache) TTL(serviceType string) time.Duration {
+	if c == nil {
+		return 0
+	}
+	return c.ttls[serviceType]
+}
+
+// AICacheKey identifica uma requisição no cache: o serviço e o hash do payload normalizado
+// (espaços e quebras de linha) enviado ao provedor.
+func AICacheKey(providerName string, serviceType string, requestToAI interface{}) (string, error) {
+	normalize := normalizeTextForCache
+	if serviceType == ServiceCodeReview {
+		normalize = normalizeCodeForCache // A indentação faz parte do código
+	}
+	raw, err := json.Marshal(transformStrings(requestToAI, normalize))
+	if err != nil {
+		return "", fmt.Errorf("erro ao serializar requisição para o cache de IA: %w", err)
+	}
+	hash := sha256.New()
+	hash.Write([]byte(providerName + "\n" + serviceType + "\n"))
+	hash.Write(raw)
+	return serviceType + ":" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func normalizeTextForCache(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func normalizeCodeForCache(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// Get busca a resposta em memória e, se não houver, no PostgreSQL. A resposta volta
+// decodificada no tipo do serviço.
+func (c *AIResponseCache) Get(serviceType string, key string) (interface{}, bool) {
+	raw, ok := c.getMemory(key)
+	if !ok && c.usePostgres {
+		raw, ok = c.getPostgres(key)
+	}
+	if !ok {
+		return nil, false
+	}
+	response, err := decodeCachedAIResponse(serviceType, raw)
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("AICache: Resposta inválida no cache (%s)", key))
+		return nil, false
+	}
+	return response, true
+}
+
+// Set grava a resposta do provedor com o TTL do serviço.
+func (c *AIResponseCache) Set(serviceType string, key string, response interface{}) {
+	ttl := c.TTL(serviceType)
+	if ttl <= 0 {
+		return
+	}
+	raw, err := json.Marshal(response)
+	if err != nil || len(raw) > maxCachedAIResponseBytes {
+		return
+	}
+	expiresAt := time.Now().Add(ttl)
+	c.setMemory(key, raw, expiresAt)
+	if c.usePostgres {
+		c.setPostgres(serviceType, key, raw, expiresAt)
+	}
+}
+
+func (c *AIResponseCache) getMemory(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*aiCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.response, true
+}
+
+func (c *AIResponseCache) setMemory(key string, response []byte, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		element.Value = &aiCacheEntry{key: key, response: response, expiresAt: expiresAt}
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&aiCacheEntry{key: key, response: response, expiresAt: expiresAt})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*aiCacheEntry).key)
+	}
+}
+
+// Falhas da camada no PostgreSQL são apenas logadas: o cache nunca impede a chamada à IA.
+func (c *AIResponseCache) getPostgres(key string) ([]byte, bool) {
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, "AICache: Erro ao conectar ao PG")
+		return nil, false
+	}
+	defer db.Close()
+	raw, expiresAt, found, err := models.GetCachedAIResponse(db, key)
+	if err != nil {
+		utilities.LogError(err, "AICache: Erro ao buscar resposta no PG")
+		return nil, false
+	}
+	if found {
+		c.setMemory(key, raw, expiresAt)
+	}
+	return raw, found
+}
+
+func (c *AIResponseCache) setPostgres(serviceType string, key string, response []byte, expiresAt time.Time) {
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, "AICache: Erro ao conectar ao PG")
+		return
+	}
+	defer db.Close()
+	if err := models.SaveCachedAIResponse(db, key, serviceType, response, expiresAt); err != nil {
+		utilities.LogError(err, "AICache: Erro ao salvar resposta no PG")
+	}
+}
+
+// decodeCachedAIResponse decodifica a resposta guardada no tipo do serviço, como
+// devolvido por callProvider.
+func decodeCachedAIResponse(serviceType string, raw []byte) (interface{}, error) {
+	var err error
+	switch serviceType {
+	case ServiceCodeReview:
+		var resp models.CodeReviewAIResponse
+		err = json.Unmarshal(raw, &resp)
+		return resp, err
+	case ServiceTextSummary:
+		var resp models.SummarizeTextAIResponse
+		err = json.Unmarshal(raw, &resp)
+		return resp, err
+	case ServiceMindMapIdeas:
+		var resp models.MindMapIdeasAIResponse
+		err = json.Unmarshal(raw, &resp)
+		return resp, err
+	case ServiceTaskAssistant:
+		var resp models.TaskAssistantAIResponse
+		err = json.Unmarshal(raw, &resp)
+		return resp, err
+	case ServiceTaskBreakdown:
+		var resp models.TaskBreakdownAIResponse
+		err = json.Unmarshal(raw, &resp)
+		return resp, err
+	case ServiceTaskSummary:
+		var resp models.TaskSummaryAIResponse
+		err = json.Unmarshal(raw, &resp)
+		return resp, err
+	}
+	return nil, fmt.Errorf("serviço de IA desconhecido %q", serviceType)
+}
+
+// markCached sinaliza na resposta enviada ao cliente que ela veio do cache.
+func markCached(response interface{}) interface{} {
+	switch resp := response.(type) {
+	case models.CodeReviewAIResponse:
+		resp.Cached = true
+		return resp
+	case models.SummarizeTextAIResponse:
+		resp.Cached = true
+		return resp
+	case models.MindMapIdeasAIResponse:
+		resp.Cached = true
+		return resp
+	case models.TaskAssistantAIResponse:
+		resp.Cached = true
+		return resp
+	case models.TaskBreakdownAIResponse:
+		resp.Cached = true
+		return resp
+	case models.TaskSummaryAIResponse:
+		resp.Cached = true
+		return resp
+	}
+	return response
+}
+
+// StartAICacheCleanup remove periodicamente as respostas expiradas da camada no
+// PostgreSQL, se estiver ligada.
+//
+// Variáveis de ambiente:
+//   - AI_CACHE_CLEANUP_INTERVAL: intervalo entre limpezas (padrão: 1h)
+func StartAICacheCleanup(ctx context.Context) {
+	cache := GetAIResponseCache()
+	if cache == nil || !cache.usePostgres {
+		return
+	}
+	interval := scheduler.DurationFromEnv("AI_CACHE_CLEANUP_INTERVAL", time.Hour)
+	scheduler.Every(ctx, "ai-cache-cleanup", interval, func(ctx context.Context) {
+		db, err := database.ConnectPostgres()
+		if err != nil {
+			utilities.LogError(err, "AICache: Erro ao conectar ao PG")
+			return
+		}
+		defer db.Close()
+		removed, err := models.DeleteExpiredAIResponses(db)
+		if err != nil {
+			utilities.LogError(err, "AICache: Erro ao limpar respostas expiradas")
+			return
+		}
+		if removed > 0 {
+			utilities.LogInfo("AICache: %d respostas expiradas removidas do cache", removed)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package ai_services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"projeto-integrador/models"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"cloud.google.com/go/firestore"
+)
+
+const (
+	MaxAIFeedbackCommentLength = 2000 // Caracteres
+	aiFeedbackRecentComments   = 20
+)
+
+// ErrInvalidAIFeedback indica uma avaliação inválida ou de uma requisição que falhou.
+var ErrInvalidAIFeedback = errors.New("invalid ai feedback")
+
+// AIFeedbackInput é a avaliação enviada pelo usuário.
+type AIFeedbackInput struct {
+	Rating  string `json:"rating"` // "up" ou "down"
+	Comment string `json:"comment"`
+	Applied *bool  `json:"applied"` // A resposta foi usada? Opcional
+}
+
+// ValidateAIFeedback confere a avaliação enviada pelo usuário.
+func ValidateAIFeedback(input AIFeedbackInput) error {
+	if input.Rating != models.AIFeedbackUp && input.Rating != models.AIFeedbackDown {
+		return fmt.Errorf("%w: rating must be up or down", ErrInvalidAIFeedback)
+	}
+	if utf8.RuneCountInString(input.Comment) > MaxAIFeedbackCommentLength {
+		return fmt.Errorf("%w: comment must have at most %d characters", ErrInvalidAIFeedback, MaxAIFeedbackCommentLength)
+	}
+	return nil
+}
+
+// SubmitAIFeedback grava a avaliação do usuário sobre uma resposta de IA que ele
+// recebeu. A entrada do histórico precisa ser do próprio usuário e bem-sucedida; o
+// serviço e a data da requisição são copiados dela, para o relatório continuar válido
+// depois da retenção do histórico.
+func SubmitAIFeedback(ctx context.Context, client *firestore.Client, db *sql.DB, workspaceIDPg int64, historyID string, userID string, input AIFeedbackInput) (*models.AIFeedback, error) {
+	entry, err := GetAIHistoryEntry(ctx, client, workspaceIDPg, historyID, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	if entry.AIError != "" {
+		return nil, fmt.Errorf("%w: failed AI requests cannot be rated", ErrInvalidAIFeedback)
+	}
+	return models.SaveAIFeedback(db, models.AIFeedback{
+		WorkspaceID: workspaceIDPg,
+		HistoryID:   historyID,
+		UserID:      userID,
+		ServiceType: entry.AIServiceType,
+		Rating:      input.Rating,
+		Comment:     strings.TrimSpace(input.Comment),
+		Applied:     input.Applied,
+		RequestedAt: entry.Timestamp,
+	})
+}
+
+// BuildAIFeedbackReport agrega as avaliações das requisições de IA feitas em
+// [since, until), no total, por serviço e por período (interval), com os comentários
+// mais recentes.
+func BuildAIFeedbackReport(db *sql.DB, workspaceIDPg int64, since time.Time, until time.Time, serviceType string, interval string) (*models.AIFeedbackReport, error) {
+	rows, err := models.LoadAIFeedbackRows(db, workspaceIDPg, since, until, serviceType, interval)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := models.ListRecentAIFeedbackComments(db, workspaceIDPg, since, until, serviceType, aiFeedbackRecentComments)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.AIFeedbackReport{
+		WorkspaceID:    workspaceIDPg,
+		Since:          since,
+		Until:          until,
+		Interval:       interval,
+		Services:       []models.AIServiceFeedback{},
+		RecentComments: comments,
+	}
+	// As linhas vêm ordenadas por serviço e período
+	for _, row := range rows {
+		if n := len(report.Services); n == 0 || report.Services[n-1].ServiceType != row.ServiceType {
+			report.Services = append(report.Services, models.AIServiceFeedback{ServiceType: row.ServiceType, Series: []models.AIFeedbackPeriod{}})
+		}
+		service := &report.Services[len(report.Services)-1]
+		addAIFeedbackStats(&service.AIFeedbackStats, row.AIFeedbackStats)
+		addAIFeedbackStats(&report.Overall, row.AIFeedbackStats)
+		period := models.AIFeedbackPeriod{PeriodStart: row.PeriodStart, AIFeedbackStats: row.AIFeedbackStats}
+		withAIFeedbackRates(&period.AIFeedbackStats)
+		service.Series = append(service.Series, period)
+	}
+	for i := range report.Services {
+		withAIFeedbackRates(&report.Services[i].AIFeedbackStats)
+	}
+	withAIFeedbackRates(&report.Overall)
+	return report, nil
+}
+
+func addAIFeedbackStats(total *models.AIFeedbackStats, stats models.AIFeedbackStats) {
+	total.Total += stats.Total
+	total.Up += stats.Up
+	total.Down += stats.Down
+	total.Applied += stats.Applied
+	total.NotApplied += stats.NotApplied
+	total.WithComment += stats.WithComment
+}
+
+func withAIFeedbackRates(stats *models.AIFeedbackStats) {
+	if stats.Total > 0 {
+		stats.SatisfactionRate = math.Round(float64(stats.Up)/float64(stats.Total)*10000) / 10000
+	}
+	if answered := stats.Applied + stats.NotApplied; answered > 0 {
+		stats.AppliedRate = math.Round(float64(stats.Applied)/float64(answered)*10000) / 10000
+	}
+}
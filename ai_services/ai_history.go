@@ -0,0 +1,163 @@
+package ai_services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"projeto-integrador/models"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	historySubCollectionName = "ai_request_history"
+
+	DefaultAIHistoryPageSize = 20
+	MaxAIHistoryPageSize     = 100
+	maxAIHistoryScan         = 1000 // Entradas lidas por página, no máximo, ao aplicar os filtros
+)
+
+// Filtro de status do histórico (AIHistoryFilter.Status).
+const (
+	AIHistoryStatusSuccess = "success"
+	AIHistoryStatusError   = "error"
+)
+
+// ErrInvalidAIHistoryCursor indica um cursor de paginação que não é uma entrada do histórico.
+var ErrInvalidAIHistoryCursor = errors.New("invalid ai history cursor")
+
+// HistoryCollection retorna o histórico de IA do workspace: /workspaces/{workspace_id}/ai_request_history
+func HistoryCollection(client *firestore.Client, workspaceIDPg int64) *firestore.CollectionRef {
+	return client.Collection("workspaces").Doc(strconv.FormatInt(workspaceIDPg, 10)).Collection(historySubCollectionName)
+}
+
+// AIHistoryFilter filtra a listagem do histórico. Campos vazios não filtram.
+type AIHistoryFilter struct {
+	UserID      string
+	ServiceType string
+	Status      string     // "success" ou "error"
+	Since       *time.Time // Inclusive
+	Until       *time.Time // Exclusive
+	Cursor      string     // next_cursor da página anterior
+	Limit       int
+}
+
+// ListAIHistory lista o histórico de IA do workspace, do mais recente para o mais antigo,
+// sem os payloads. Os filtros de usuário, serviço e status são aplicados na leitura (para
+// não exigir índices compostos); se uma página ler maxAIHistoryScan entradas sem
+// completar o limite, ela volta incompleta com o cursor para continuar.
+func ListAIHistory(ctx context.Context, client *firestore.Client, workspaceIDPg int64, filter AIHistoryFilter) (*models.AIHistoryPage, error) {
+	if filter.Limit < 1 || filter.Limit > MaxAIHistoryPageSize {
+		filter.Limit = DefaultAIHistoryPageSize
+	}
+	collection := HistoryCollection(client, workspaceIDPg)
+	query := collection.OrderBy("timestamp", firestore.Desc)
+	if filter.Since != nil {
+		query = query.Where("timestamp", ">=", *filter.Since)
+	}
+	if filter.Until != nil {
+		query = query.Where("timestamp", "<", *filter.Until)
+	}
+	if filter.Cursor != "" {
+		cursorDoc, err := collection.Doc(filter.Cursor).Get(ctx)
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrInvalidAIHistoryCursor
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao buscar cursor do histórico de IA: %w", err)
+		}
+		query = query.StartAfter(cursorDoc)
+	}
+
+	iter := query.Limit(maxAIHistoryScan).Documents(ctx)
+	defer iter.Stop()
+
+	page := &models.AIHistoryPage{Entries: []models.AIRequestHistoryEntry{}}
+	scanned := 0
+	lastID := ""
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao listar histórico de IA: %w", err)
+		}
+		scanned++
+		lastID = doc.Ref.ID
+
+		var entry models.AIRequestHistoryEntry
+		if err := doc.DataTo(&entry); err != nil {
+			continue
+		}
+		if !filter.matches(entry) {
+			continue
+		}
+		entry.ID = doc.Ref.ID
+		entry.FrontendRequestPayload, entry.RequestToAI, entry.ResponseFromAI = nil, nil, nil
+		page.Entries = append(page.Entries, entry)
+		if len(page.Entries) == filter.Limit {
+			page.NextCursor = doc.Ref.ID
+			return page, nil
+		}
+	}
+	if scanned == maxAIHistoryScan {
+		page.NextCursor = lastID // Pode haver mais entradas que passam nos filtros
+	}
+	return page, nil
+}
+
+func (f AIHistoryFilter) matches(entry models.AIRequestHistoryEntry) bool {
+	if f.UserID != "" && entry.UserID != f.UserID {
+		return false
+	}
+	if f.ServiceType != "" && entry.AIServiceType != f.ServiceType {
+		return false
+	}
+	switch f.Status {
+	case AIHistoryStatusSuccess:
+		return entry.AIError == ""
+	case AIHistoryStatusError:
+		return entry.AIError != ""
+	}
+	return true
+}
+
+// GetAIHistoryEntry busca uma entrada do histórico, com os payloads. Sem canViewAll
+// (admins do workspace), apenas as entradas do próprio usuário são visíveis.
+func GetAIHistoryEntry(ctx context.Context, client *firestore.Client, workspaceIDPg int64, historyID string, userID string, canViewAll bool) (*models.AIRequestHistoryEntry, error) {
+	doc, err := HistoryCollection(client, workspaceIDPg).Doc(historyID).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrAIHistoryNotFound
+		}
+		return nil, fmt.Errorf("erro ao buscar histórico de IA %s: %w", historyID, err)
+	}
+	var entry models.AIRequestHistoryEntry
+	if err := doc.DataTo(&entry); err != nil {
+		return nil, fmt.Errorf("erro ao ler histórico de IA %s: %w", historyID, err)
+	}
+	if !canViewAll && entry.UserID != userID {
+		return nil, ErrAIHistoryNotFound
+	}
+	entry.ID = doc.Ref.ID
+	return &entry, nil
+}
+
+// DeleteAIHistoryEntry apaga uma entrada do histórico, com as mesmas regras de
+// visibilidade de GetAIHistoryEntry. Tarefas e revisões criadas a partir da entrada são
+// mantidas, mas ela não pode mais ser aceita ou anexada.
+func DeleteAIHistoryEntry(ctx context.Context, client *firestore.Client, workspaceIDPg int64, historyID string, userID string, canViewAll bool) error {
+	if _, err := GetAIHistoryEntry(ctx, client, workspaceIDPg, historyID, userID, canViewAll); err != nil {
+		return err
+	}
+	if _, err := HistoryCollection(client, workspaceIDPg).Doc(historyID).Delete(ctx); err != nil {
+		return fmt.Errorf("erro ao apagar histórico de IA %s: %w", historyID, err)
+	}
+	return nil
+}
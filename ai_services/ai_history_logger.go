@@ -11,7 +11,20 @@ import (
 	// Para firestore.ServerTimestamp
 )
 
-// LogAIInteraction registra uma interação com a API de IA no Firestore.
+// AICallMetrics são os dados de execução de uma chamada de IA registrados no histórico.
+type AICallMetrics struct {
+	Source     string // "sync", "stream", "job" ou "report"
+	Provider   string
+	Endpoint   string
+	Latency    time.Duration
+	Attempts   int
+	ErrorClass string               // Veja ClassifyAIError
+	Redactions []models.AIRedaction // Valores mascarados antes do envio (veja Redactor)
+	CacheHit   bool                 // Resposta do cache (sem chamada ao provedor)
+}
+
+// LogAIInteraction registra uma interação com a API de IA no Firestore e retorna o ID
+// do registro (vazio se não foi possível salvar).
 func LogAIInteraction(
 	ctx context.Context,
 	userID string, // Firebase UID do usuário requisitante
@@ -22,15 +35,16 @@ func LogAIInteraction(
 	responseFromAI interface{}, // A resposta da API Python (pode ser a struct de sucesso ou de erro)
 	aiStatusCode int, // Status code da resposta da API Python
 	aiCallError error, // Erro ocorrido na chamada à API Python (se houver)
-) {
+	metrics AICallMetrics, // Latência, tentativas, endpoint e classificação do erro
+) string {
 	firestoreClient, err := firebase.GetFirestoreClient()
 	if err != nil {
 		utilities.LogError(err, "LogAIInteraction: Falha ao obter cliente Firestore")
-		return // Não impede o fluxo principal, apenas não loga
+		return "" // Não impede o fluxo principal, apenas não loga
 	}
+	defer firestoreClient.Close()
 
 	workspaceDocIDForFirestore := strconv.FormatInt(workspaceIDPg, 10)
-	historyCollectionPath := fmt.Sprintf("workspaces/%s/ai_request_history", workspaceDocIDForFirestore)
 
 	entry := models.AIRequestHistoryEntry{
 		UserID:        userID,
@@ -44,16 +58,28 @@ func LogAIInteraction(
 		RequestToAI:            requestToAI,
 		ResponseFromAI:         responseFromAI, // Se errAI não for nil, isso pode conter a estrutura de erro da IA
 		AIStatusCode:           aiStatusCode,
+		ErrorClass:             metrics.ErrorClass,
+		Source:                 metrics.Source,
+		Provider:               metrics.Provider,
+		Endpoint:               metrics.Endpoint,
+		LatencyMs:              metrics.Latency.Milliseconds(),
+		Attempts:               metrics.Attempts,
+		Redactions:             metrics.Redactions,
+		CacheHit:               metrics.CacheHit,
 	}
 
 	if aiCallError != nil {
 		entry.AIError = aiCallError.Error()
 	}
+	if assistantInput, ok := frontendPayload.(models.TaskAssistantUserInput); ok {
+		entry.ThreadID = assistantInput.ThreadID
+	}
 
-	docRef, _, err := firestoreClient.Collection(historyCollectionPath).Add(ctx, entry)
+	docRef, _, err := HistoryCollection(firestoreClient, workspaceIDPg).Add(ctx, entry)
 	if err != nil {
 		utilities.LogError(err, fmt.Sprintf("LogAIInteraction: Falha ao salvar histórico de IA para workspace %s, user %s", workspaceDocIDForFirestore, userID))
-	} else {
-		utilities.LogDebug(fmt.Sprintf("LogAIInteraction: Histórico de IA salvo com ID %s para workspace %s", docRef.ID, workspaceDocIDForFirestore))
+		return ""
 	}
+	utilities.LogDebug("LogAIInteraction: Histórico de IA salvo com ID %s para workspace %s", docRef.ID, workspaceDocIDForFirestore)
+	return docRef.ID
 }
@@ -0,0 +1,165 @@
+package ai_services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"projeto-integrador/database"
+	"projeto-integrador/firebase"
+	"projeto-integrador/models"
+	"projeto-integrador/scheduler"
+	"projeto-integrador/utilities"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// Chave do advisory lock usada para que só uma réplica aplique a retenção por vez.
+const aiHistoryRetentionLockKey int64 = 260002
+
+// AIHistoryRetentionDefaults retorna a política de retenção dos workspaces sem
+// configuração própria.
+//
+// Variáveis de ambiente:
+//   - AI_HISTORY_RETENTION_DAYS: dias que as entradas ficam completas (padrão: 90; 0 = para sempre)
+//   - AI_HISTORY_RETENTION_MODE: "truncate" (padrão, remove os payloads) ou "delete"
+func AIHistoryRetentionDefaults() (days int, mode string) {
+	days = 90
+	if raw := os.Getenv("AI_HISTORY_RETENTION_DAYS"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n >= 0 {
+			days = n
+		} else {
+			utilities.LogInfo("Valor inválido para AI_HISTORY_RETENTION_DAYS (%q), usando padrão %d", raw, days)
+		}
+	}
+	mode = models.AIHistoryRetentionTruncate
+	switch raw := os.Getenv("AI_HISTORY_RETENTION_MODE"); raw {
+	case "", models.AIHistoryRetentionTruncate:
+	case models.AIHistoryRetentionDelete:
+		mode = raw
+	default:
+		utilities.LogInfo("Valor inválido para AI_HISTORY_RETENTION_MODE (%q), usando padrão %s", raw, mode)
+	}
+	return days, mode
+}
+
+// StartAIHistoryRetention inicia em background a aplicação periódica da política de
+// retenção do histórico de IA de cada workspace: entradas mais antigas que o prazo têm
+// os payloads removidos ("truncate") ou são apagadas ("delete").
+//
+// Variáveis de ambiente:
+//   - AI_HISTORY_RETENTION_ENABLED: "false" desliga a retenção (padrão: ligada)
+//   - AI_HISTORY_RETENTION_INTERVAL: intervalo entre execuções (padrão: 6h)
+func StartAIHistoryRetention(ctx context.Context) {
+	if !scheduler.EnabledFromEnv("AI_HISTORY_RETENTION_ENABLED") {
+		utilities.LogInfo("Retenção do histórico de IA desativada por AI_HISTORY_RETENTION_ENABLED")
+		return
+	}
+	interval := scheduler.DurationFromEnv("AI_HISTORY_RETENTION_INTERVAL", 6*time.Hour)
+	scheduler.Every(ctx, "ai-history-retention", interval, runAIHistoryRetention)
+}
+
+func runAIHistoryRetention(ctx context.Context) {
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, "AIHistoryRetention: Erro ao conectar ao PG")
+		return
+	}
+	defer db.Close()
+
+	ran, err := scheduler.WithAdvisoryLock(ctx, db, aiHistoryRetentionLockKey, func(ctx context.Context) error {
+		return applyAIHistoryRetention(ctx, db)
+	})
+	if err != nil {
+		utilities.LogError(err, "AIHistoryRetention: Erro ao aplicar a retenção do histórico de IA")
+		return
+	}
+	if !ran {
+		utilities.LogDebug("AIHistoryRetention: Outra instância está aplicando a retenção, pulando este ciclo")
+	}
+}
+
+func applyAIHistoryRetention(ctx context.Context, db *sql.DB) error {
+	defaultDays, defaultMode := AIHistoryRetentionDefaults()
+	policies, err := models.ListAIHistoryRetentions(db, defaultDays, defaultMode)
+	if err != nil {
+		return err
+	}
+	firestoreClient, err := firebase.GetFirestoreClient()
+	if err != nil {
+		return err
+	}
+	defer firestoreClient.Close()
+
+	now := time.Now()
+	for _, policy := range policies {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if policy.RetentionDays == 0 {
+			continue
+		}
+		cutoff := now.AddDate(0, 0, -policy.RetentionDays)
+		processed, err := applyWorkspaceAIHistoryRetention(ctx, firestoreClient, policy, cutoff)
+		if err != nil {
+			// Sem o progresso registrado, o workspace é processado de novo no próximo ciclo.
+			utilities.LogError(err, fmt.Sprintf("AIHistoryRetention: Erro ao processar workspace %d", policy.WorkspaceID))
+			continue
+		}
+		if err := models.MarkAIHistoryPurged(db, policy.WorkspaceID, cutoff); err != nil {
+			utilities.LogError(err, fmt.Sprintf("AIHistoryRetention: Erro ao registrar progresso do workspace %d", policy.WorkspaceID))
+		}
+		if processed > 0 {
+			utilities.LogInfo("AIHistoryRetention: %d entradas do histórico de IA do workspace %d processadas (%s, %d dias)", processed, policy.WorkspaceID, policy.Mode, policy.RetentionDays)
+		}
+	}
+	return nil
+}
+
+// applyWorkspaceAIHistoryRetention aplica a política às entradas anteriores a cutoff que
+// ainda não foram processadas (a partir de policy.PurgedBefore). Retorna quantas
+// entradas foram apagadas ou truncadas.
+func applyWorkspaceAIHistoryRetention(ctx context.Context, client *firestore.Client, policy models.AIHistoryRetention, cutoff time.Time) (int, error) {
+	query := HistoryCollection(client, policy.WorkspaceID).Where("timestamp", "<", cutoff)
+	if policy.PurgedBefore != nil {
+		query = query.Where("timestamp", ">=", *policy.PurgedBefore)
+	}
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	processed := 0
+	truncatedAt := time.Now()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return processed, nil
+		}
+		if err != nil {
+			return processed, fmt.Errorf("erro ao buscar histórico de IA antigo: %w", err)
+		}
+
+		if policy.Mode == models.AIHistoryRetentionDelete {
+			if _, err := doc.Ref.Delete(ctx); err != nil {
+				return processed, fmt.Errorf("erro ao apagar histórico de IA %s: %w", doc.Ref.ID, err)
+			}
+			processed++
+			continue
+		}
+		if _, err := doc.DataAt("truncated_at"); err == nil {
+			continue // Já truncada
+		}
+		_, err = doc.Ref.Update(ctx, []firestore.Update{
+			{Path: "frontend_request_payload", Value: firestore.Delete},
+			{Path: "request_to_ai", Value: firestore.Delete},
+			{Path: "response_from_ai", Value: firestore.Delete},
+			{Path: "truncated_at", Value: truncatedAt},
+		})
+		if err != nil {
+			return processed, fmt.Errorf("erro ao truncar histórico de IA %s: %w", doc.Ref.ID, err)
+		}
+		processed++
+	}
+}
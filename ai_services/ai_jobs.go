@@ -0,0 +1,264 @@
+package ai_services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"projeto-integrador/database"
+	"projeto-integrador/models"
+	"projeto-integrador/scheduler"
+	"projeto-integrador/utilities"
+	"sync"
+	"time"
+)
+
+// Intervalo em que o worker renova o lease do job e verifica se ele foi cancelado.
+const aiJobHeartbeatInterval = 5 * time.Second
+
+// aiJobWake acorda um worker ocioso quando um job é criado nesta instância, sem esperar
+// o próximo ciclo de polling.
+var aiJobWake = make(chan struct{}, 1)
+
+// runningAIJobs guarda o cancelamento dos jobs em execução nesta instância.
+var runningAIJobs = struct {
+	sync.Mutex
+	cancels map[int64]context.CancelFunc
+}{cancels: make(map[int64]context.CancelFunc)}
+
+// NotifyAIJobQueued avisa os workers locais de que há um novo job na fila.
+func NotifyAIJobQueued() {
+	select {
+	case aiJobWake <- struct{}{}:
+	default:
+	}
+}
+
+// CancelRunningAIJob interrompe imediatamente a chamada ao provedor se o job estiver
+// executando nesta instância. Em outras réplicas, o worker percebe o cancelamento no
+// próximo heartbeat.
+func CancelRunningAIJob(jobID int64) {
+	runningAIJobs.Lock()
+	defer runningAIJobs.Unlock()
+	if cancel, ok := runningAIJobs.cancels[jobID]; ok {
+		cancel()
+	}
+}
+
+type aiJobPool struct {
+	workers      int
+	pollInterval time.Duration
+	lease        time.Duration
+	maxAttempts  int
+	retryDelay   time.Duration
+
+	db database.SharedConnection // Conexão compartilhada pelos workers
+}
+
+// StartAIJobWorkers inicia o pool de workers que executa os jobs de IA da tabela ai_jobs.
+// O número de workers limita as chamadas simultâneas ao provedor feitas por jobs.
+//
+// Variáveis de ambiente:
+//   - AI_JOBS_ENABLED: "false" desliga os workers (os jobs continuam na fila)
+//   - AI_JOB_WORKERS: workers por instância (padrão: 2)
+//   - AI_JOB_POLL_INTERVAL: intervalo de consulta à fila (padrão: 2s)
+//   - AI_JOB_LEASE: tempo sem heartbeat após o qual outro worker assume o job (padrão: 2m)
+//   - AI_JOB_MAX_ATTEMPTS: execuções por job antes de falhar (padrão: 3)
+//   - AI_JOB_RETRY_DELAY: espera mínima para nova tentativa com a IA indisponível (padrão: 30s)
+func StartAIJobWorkers(ctx context.Context) {
+	if !scheduler.EnabledFromEnv("AI_JOBS_ENABLED") {
+		utilities.LogInfo("Workers de jobs de IA desativados por AI_JOBS_ENABLED")
+		return
+	}
+
+	pool := &aiJobPool{
+		workers:      scheduler.IntFromEnv("AI_JOB_WORKERS", 2),
+		pollInterval: scheduler.DurationFromEnv("AI_JOB_POLL_INTERVAL", 2*time.Second),
+		lease:        scheduler.DurationFromEnv("AI_JOB_LEASE", 2*time.Minute),
+		maxAttempts:  scheduler.IntFromEnv("AI_JOB_MAX_ATTEMPTS", 3),
+		retryDelay:   scheduler.DurationFromEnv("AI_JOB_RETRY_DELAY", 30*time.Second),
+	}
+	if pool.lease < 2*aiJobHeartbeatInterval {
+		pool.lease = 2 * aiJobHeartbeatInterval
+	}
+	utilities.LogInfo("AIJobs: iniciando %d workers (lease %v)", pool.workers, pool.lease)
+	for i := 1; i <= pool.workers; i++ {
+		go pool.worker(ctx, i)
+	}
+}
+
+func (p *aiJobPool) worker(ctx context.Context, n int) {
+	for {
+		if !p.runOnce(ctx, n) {
+			select {
+			case <-ctx.Done():
+				return
+			case <-aiJobWake:
+			case <-time.After(p.pollInterval):
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// runOnce reserva e executa um job. Retorna false quando não havia job (ou houve erro
+// ao consultar a fila), para o worker esperar antes de tentar de novo.
+func (p *aiJobPool) runOnce(ctx context.Context, n int) (processed bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			// O job fica "running" até o lease expirar e então é tentado de novo
+			utilities.LogError(fmt.Errorf("%v", r), fmt.Sprintf("AIJobs: pânico no worker %d", n))
+			processed = false
+		}
+	}()
+
+	db, err := p.db.Get()
+	if err != nil {
+		utilities.LogError(err, "AIJobs: Erro ao conectar ao PG")
+		return false
+	}
+	job, err := models.ClaimNextAIJob(db, p.lease)
+	if err != nil {
+		utilities.LogError(err, "AIJobs: Erro ao consultar a fila")
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	if job.Attempts > p.maxAttempts {
+		// O job foi reassumido depois que os workers anteriores pararam sem concluí-lo
+		if _, err := models.FailAIJob(db, job.ID, 0, "job exceeded the maximum number of attempts"); err != nil {
+			utilities.LogError(err, fmt.Sprintf("AIJobs: Erro ao encerrar job %d", job.ID))
+		}
+		return true
+	}
+
+	utilities.LogInfo("AIJobs: worker %d executando job %d (%s, workspace %d, tentativa %d)", n, job.ID, job.ServiceType, job.WorkspaceID, job.Attempts)
+	p.execute(ctx, db, job)
+	return true
+}
+
+func (p *aiJobPool) execute(ctx context.Context, db *sql.DB, job *models.AIJob) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	runningAIJobs.Lock()
+	runningAIJobs.cancels[job.ID] = cancel
+	runningAIJobs.Unlock()
+	defer func() {
+		runningAIJobs.Lock()
+		delete(runningAIJobs.cancels, job.ID)
+		runningAIJobs.Unlock()
+	}()
+
+	go p.heartbeat(jobCtx, cancel, db, job.ID)
+
+	input, err := DecodeAIInput(job.ServiceType, job.Payload)
+	if err != nil {
+		p.fail(db, job.ID, 0, err.Error())
+		return
+	}
+
+	exec, errAI := ExecuteAIRequest(jobCtx, GetProvider(), job.WorkspaceID, job.ServiceType, input, AIRequestOptions{NoCache: job.NoCache})
+	exec.Source = AISourceJob
+	if jobCtx.Err() != nil {
+		if errAI != nil {
+			RecordAIInteraction(context.WithoutCancel(ctx), job.CreatedBy, job.WorkspaceID, exec, errAI)
+		}
+		if ctx.Err() == nil {
+			utilities.LogInfo("AIJobs: job %d cancelado durante a execução", job.ID)
+		}
+		return // Cancelado, ou servidor encerrando (o lease expira e outro worker assume)
+	}
+
+	if errAI == nil {
+		result, err := json.Marshal(exec.Response)
+		if err != nil {
+			p.fail(db, job.ID, exec.Result.StatusCode, "failed to encode AI response")
+			return
+		}
+		stored, err := models.CompleteAIJob(db, job.ID, result, exec.Result.StatusCode)
+		if err != nil {
+			utilities.LogError(err, fmt.Sprintf("AIJobs: Erro ao salvar resultado do job %d", job.ID))
+			return
+		}
+		if stored {
+			// O resultado já está gravado: o histórico e o pós-processamento terminam
+			// mesmo se o servidor estiver encerrando
+			saveCtx := context.WithoutCancel(ctx)
+			historyID := RecordAIInteraction(saveCtx, job.CreatedBy, job.WorkspaceID, exec, nil)
+			p.finalizeResult(saveCtx, db, job, exec.Response, historyID)
+			utilities.LogInfo("AIJobs: job %d concluído", job.ID)
+		}
+		return
+	}
+
+	RecordAIInteraction(ctx, job.CreatedBy, job.WorkspaceID, exec, errAI)
+	var unavailable *UnavailableError
+	if errors.As(errAI, &unavailable) && job.Attempts < p.maxAttempts {
+		delay := unavailable.RetryAfter
+		if delay < p.retryDelay {
+			delay = p.retryDelay
+		}
+		if _, err := models.RequeueAIJob(db, job.ID, delay, AIErrorMessage(exec.Result, errAI)); err != nil {
+			utilities.LogError(err, fmt.Sprintf("AIJobs: Erro ao reagendar job %d", job.ID))
+		} else {
+			utilities.LogInfo("AIJobs: IA indisponível, job %d reagendado em %v", job.ID, delay)
+		}
+		return
+	}
+
+	utilities.LogError(errAI, fmt.Sprintf("AIJobs: job %d falhou (status da IA: %d)", job.ID, exec.Result.StatusCode))
+	p.fail(db, job.ID, exec.Result.StatusCode, AIErrorMessage(exec.Result, errAI))
+}
+
+// finalizeResult aplica FinalizeAIResponse ao resultado do job (ID do histórico, mapa
+// mental salvo, resumo gravado na tarefa), o que só é possível depois de o job ser
+// concluído e registrado.
+func (p *aiJobPool) finalizeResult(ctx context.Context, db *sql.DB, job *models.AIJob, response interface{}, historyID string) {
+	switch response.(type) {
+	case models.CodeReviewAIResponse, models.TaskAssistantAIResponse, models.TaskBreakdownAIResponse, models.MindMapIdeasAIResponse, models.TaskSummaryAIResponse:
+	default:
+		return
+	}
+	result, err := json.Marshal(FinalizeAIResponse(ctx, job.CreatedBy, job.WorkspaceID, response, historyID))
+	if err == nil {
+		err = models.UpdateAIJobResult(db, job.ID, result)
+	}
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("AIJobs: Erro ao atualizar resultado do job %d", job.ID))
+	}
+}
+
+func (p *aiJobPool) fail(db *sql.DB, jobID int64, aiStatusCode int, message string) {
+	if _, err := models.FailAIJob(db, jobID, aiStatusCode, message); err != nil {
+		utilities.LogError(err, fmt.Sprintf("AIJobs: Erro ao encerrar job %d", jobID))
+	}
+}
+
+// heartbeat renova o lease enquanto o job executa e interrompe a execução se o job
+// deixou de estar "running" (cancelado pelo usuário).
+func (p *aiJobPool) heartbeat(ctx context.Context, cancel context.CancelFunc, db *sql.DB, jobID int64) {
+	ticker := time.NewTicker(aiJobHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			running, err := models.ExtendAIJobLease(db, jobID, p.lease)
+			if err != nil {
+				utilities.LogError(err, fmt.Sprintf("AIJobs: Erro ao renovar lease do job %d", jobID))
+				continue
+			}
+			if !running {
+				cancel()
+				return
+			}
+		}
+	}
+}
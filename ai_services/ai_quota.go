@@ -0,0 +1,254 @@
+package ai_services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"projeto-integrador/models"
+	"projeto-integrador/utilities"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// ErrAIQuotaExceeded indica que a requisição ultrapassaria uma cota de IA. Os handlers
+	// respondem 429 com Retry-After.
+	ErrAIQuotaExceeded = errors.New("ai quota exceeded")
+	// ErrInvalidAIQuota indica uma configuração de cotas inválida.
+	ErrInvalidAIQuota = errors.New("invalid ai quota")
+)
+
+// QuotaExceededError informa a cota que seria ultrapassada. errors.Is(err, ErrAIQuotaExceeded)
+// é verdadeiro para ele.
+type QuotaExceededError struct {
+	Quota models.AIQuotaStatus
+}
+
+func (e *QuotaExceededError) Error() string {
+	scope := e.Quota.Scope
+	if e.Quota.ServiceType != "" {
+		scope += "/" + e.Quota.ServiceType
+	}
+	return fmt.Sprintf("%v: %s %s (%d/%d)", ErrAIQuotaExceeded, scope, e.Quota.Metric, e.Quota.Used, e.Quota.Limit)
+}
+
+func (e *QuotaExceededError) Is(target error) bool { return target == ErrAIQuotaExceeded }
+
+// RetryAfterSeconds é o valor do cabeçalho Retry-After: até a cota ser renovada.
+func (e *QuotaExceededError) RetryAfterSeconds() int {
+	return retryAfterSeconds(time.Until(e.Quota.ResetsAt))
+}
+
+// AIQuotaDefaults retorna os limites do servidor, aplicados a todos os workspaces além
+// das cotas que cada um configura.
+//
+// Variáveis de ambiente (0 ou ausente = sem limite):
+//   - AI_QUOTA_USER_REQUESTS_PER_DAY: requisições por dia de cada membro, em cada workspace
+//   - AI_QUOTA_USER_INPUT_CHARS_PER_MONTH: caracteres de entrada por mês de cada membro
+//   - AI_QUOTA_WORKSPACE_REQUESTS_PER_DAY: requisições por dia de cada workspace
+//   - AI_QUOTA_WORKSPACE_INPUT_CHARS_PER_MONTH: caracteres de entrada por mês de cada workspace
+func AIQuotaDefaults() []models.AIQuota {
+	var defaults []models.AIQuota
+	for _, scope := range []string{models.AIQuotaScopeUser, models.AIQuotaScopeWorkspace} {
+		prefix := "AI_QUOTA_USER_"
+		if scope == models.AIQuotaScopeWorkspace {
+			prefix = "AI_QUOTA_WORKSPACE_"
+		}
+		quota := models.AIQuota{Scope: scope, IsDefault: true}
+		quota.RequestsPerDay = quotaLimitFromEnv(prefix + "REQUESTS_PER_DAY")
+		quota.InputCharsPerMonth = quotaLimitFromEnv(prefix + "INPUT_CHARS_PER_MONTH")
+		if quota.RequestsPerDay != nil || quota.InputCharsPerMonth != nil {
+			defaults = append(defaults, quota)
+		}
+	}
+	return defaults
+}
+
+func quotaLimitFromEnv(name string) *int64 {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return nil
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || n < 0 {
+		utilities.LogInfo("Valor inválido para %s (%q), sem limite", name, raw)
+		return nil
+	}
+	if n == 0 {
+		return nil
+	}
+	return &n
+}
+
+// ValidateAIQuotas confere as cotas enviadas por um admin: escopo e serviço conhecidos,
+// ao menos um limite não negativo e no máximo uma regra por escopo e serviço.
+func ValidateAIQuotas(quotas []models.AIQuota) error {
+	seen := make(map[string]bool, len(quotas))
+	for _, quota := range quotas {
+		if quota.Scope != models.AIQuotaScopeUser && quota.Scope != models.AIQuotaScopeWorkspace {
+			return fmt.Errorf("%w: scope must be user or workspace", ErrInvalidAIQuota)
+		}
+		if _, ok := aiPathsByService[quota.ServiceType]; quota.ServiceType != "" && !ok {
+			return fmt.Errorf("%w: unknown service_type %q", ErrInvalidAIQuota, quota.ServiceType)
+		}
+		if quota.RequestsPerDay == nil && quota.InputCharsPerMonth == nil {
+			return fmt.Errorf("%w: requests_per_day or input_chars_per_month is required", ErrInvalidAIQuota)
+		}
+		if (quota.RequestsPerDay != nil && *quota.RequestsPerDay < 0) || (quota.InputCharsPerMonth != nil && *quota.InputCharsPerMonth < 0) {
+			return fmt.Errorf("%w: limits cannot be negative", ErrInvalidAIQuota)
+		}
+		key := quota.Scope + "/" + quota.ServiceType
+		if seen[key] {
+			return fmt.Errorf("%w: duplicate quota for scope %s and service_type %q", ErrInvalidAIQuota, quota.Scope, quota.ServiceType)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// EffectiveAIQuotas retorna os limites do servidor seguidos das cotas do workspace.
+// Todas são aplicadas: as do workspace não afrouxam as do servidor.
+func EffectiveAIQuotas(db *sql.DB, workspaceIDPg int64) ([]models.AIQuota, error) {
+	configured, err := models.ListAIQuotas(db, workspaceIDPg)
+	if err != nil {
+		return nil, err
+	}
+	return append(AIQuotaDefaults(), configured...), nil
+}
+
+// AIInputChars conta os caracteres de entrada de uma requisição (o texto enviado pelo
+// usuário), a métrica das cotas mensais. A decomposição e o resumo de tarefas não têm
+// texto do usuário.
+func AIInputChars(input interface{}) int64 {
+	switch in := input.(type) {
+	case models.CodeReviewAIRequest:
+		return int64(utf8.RuneCountInString(in.Code) + utf8.RuneCountInString(in.Diff))
+	case models.SummarizeTextAIRequest:
+		return int64(utf8.RuneCountInString(in.Text))
+	case models.MindMapIdeasAIRequest:
+		return int64(utf8.RuneCountInString(in.Text))
+	case models.TaskAssistantUserInput:
+		return int64(utf8.RuneCountInString(in.UserMessage))
+	}
+	return 0
+}
+
+// ReserveAIQuota confere as cotas do workspace e registra a requisição no consumo, antes
+// da chamada ao provedor (ou da criação do job). Retorna um *QuotaExceededError se alguma
+// cota seria ultrapassada; nesse caso nada é registrado.
+func ReserveAIQuota(db *sql.DB, workspaceIDPg int64, userID string, serviceType string, input interface{}) error {
+	quotas, err := EffectiveAIQuotas(db, workspaceIDPg)
+	if err != nil {
+		return err
+	}
+	inputChars := AIInputChars(input)
+	now := time.Now()
+	return models.ReserveAIUsage(db, workspaceIDPg, userID, serviceType, inputChars, now, func(usage []models.AIUsageRow) error {
+		for _, quota := range quotas {
+			if quota.ServiceType != "" && quota.ServiceType != serviceType {
+				continue
+			}
+			for _, status := range quotaStatuses(quota, usage, now) {
+				increment := int64(1)
+				if status.Metric == models.AIQuotaInputCharsPerMonth {
+					increment = inputChars
+				}
+				if status.Used+increment > status.Limit {
+					return &QuotaExceededError{Quota: status}
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// quotaStatuses calcula o consumo de cada limite da cota. Cotas de usuário consideram
+// apenas o consumo do usuário consultado.
+func quotaStatuses(quota models.AIQuota, usage []models.AIUsageRow, now time.Time) []models.AIQuotaStatus {
+	var requests, chars int64
+	for _, row := range usage {
+		if quota.Scope == models.AIQuotaScopeUser && !row.Mine {
+			continue
+		}
+		if quota.ServiceType != "" && row.ServiceType != quota.ServiceType {
+			continue
+		}
+		requests += row.RequestsToday
+		chars += row.InputCharsMonth
+	}
+
+	today, monthStart := models.AIUsagePeriod(now)
+	var statuses []models.AIQuotaStatus
+	add := func(metric string, limit *int64, used int64, resetsAt time.Time) {
+		if limit == nil {
+			return
+		}
+		status := models.AIQuotaStatus{
+			Scope:       quota.Scope,
+			ServiceType: quota.ServiceType,
+			Metric:      metric,
+			Limit:       *limit,
+			Used:        used,
+			Remaining:   *limit - used,
+			ResetsAt:    resetsAt,
+			IsDefault:   quota.IsDefault,
+		}
+		if status.Remaining < 0 {
+			status.Remaining = 0
+		}
+		statuses = append(statuses, status)
+	}
+	add(models.AIQuotaRequestsPerDay, quota.RequestsPerDay, requests, today.AddDate(0, 0, 1))
+	add(models.AIQuotaInputCharsPerMonth, quota.InputCharsPerMonth, chars, monthStart.AddDate(0, 1, 0))
+	return statuses
+}
+
+// GetAIUsage monta o consumo de IA do membro e do workspace no período corrente,
+// comparado a todas as cotas aplicáveis.
+func GetAIUsage(db *sql.DB, workspaceIDPg int64, userID string) (*models.AIUsageReport, error) {
+	quotas, err := EffectiveAIQuotas(db, workspaceIDPg)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	usage, err := models.LoadAIUsage(db, workspaceIDPg, userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	today, _ := models.AIUsagePeriod(now)
+	report := &models.AIUsageReport{
+		WorkspaceID: workspaceIDPg,
+		UserID:      userID,
+		Day:         today.Format("2006-01-02"),
+		Month:       today.Format("2006-01"),
+		Quotas:      []models.AIQuotaStatus{},
+		Services:    []models.AIServiceUsage{},
+	}
+	for _, quota := range quotas {
+		report.Quotas = append(report.Quotas, quotaStatuses(quota, usage, now)...)
+	}
+
+	byService := map[string]*models.AIServiceUsage{}
+	for _, row := range usage {
+		service, ok := byService[row.ServiceType]
+		if !ok {
+			service = &models.AIServiceUsage{ServiceType: row.ServiceType}
+			byService[row.ServiceType] = service
+		}
+		if row.Mine {
+			service.RequestsToday += row.RequestsToday
+			service.InputCharsThisMonth += row.InputCharsMonth
+		}
+		service.WorkspaceRequestsToday += row.RequestsToday
+		service.WorkspaceInputCharsThisMonth += row.InputCharsMonth
+	}
+	for _, service := range byService {
+		report.Services = append(report.Services, *service)
+	}
+	sort.Slice(report.Services, func(i, j int) bool { return report.Services[i].ServiceType < report.Services[j].ServiceType })
+	return report, nil
+}
@@ -0,0 +1,207 @@
+package ai_services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"projeto-integrador/models"
+	"sort"
+	"time"
+
+	"google.golang.org/api/iterator"
+
+	"cloud.google.com/go/firestore"
+)
+
+// Classificação das falhas de IA (AIRequestHistoryEntry.ErrorClass).
+const (
+	AIErrorTimeout            = "timeout"             // Timeout da chamada (ou 408/504)
+	AIErrorTransport          = "transport"           // Sem resposta: rede, DNS, conexão recusada
+	AIErrorRateLimited        = "rate_limited"        // 429 do provedor
+	AIErrorServer             = "server_error"        // 5xx do provedor
+	AIErrorClient             = "client_error"        // 4xx do provedor (requisição recusada)
+	AIErrorInvalidResponse    = "invalid_response"    // 2xx sem uma resposta utilizável
+	AIErrorCircuitOpen        = "circuit_open"        // Recusada pelo circuit breaker, sem chamar o provedor
+	AIErrorContextUnavailable = "context_unavailable" // Falha ao carregar os dados do workspace
+	AIErrorClientDisconnected = "client_disconnected" // O cliente desconectou durante o streaming
+	AIErrorCanceled           = "canceled"            // Cancelada pelo chamador (ex: job cancelado)
+	AIErrorUnknown            = "unknown"
+)
+
+const (
+	maxAIReliabilityScan       = 20000 // Entradas lidas por relatório, no máximo
+	maxAIHistoryErrorBodyRunes = 2000
+)
+
+// ClassifyAIError classifica a falha de uma execução de IA para o histórico e o
+// relatório de confiabilidade. Retorna "" quando não houve erro.
+func ClassifyAIError(result AICallResult, err error) string {
+	if err == nil {
+		return ""
+	}
+	var unavailable *UnavailableError
+	switch {
+	case errors.Is(err, ErrClientDisconnected):
+		return AIErrorClientDisconnected
+	case errors.Is(err, ErrAIContextUnavailable):
+		return AIErrorContextUnavailable
+	case errors.As(err, &unavailable) && result.Attempts == 0:
+		return AIErrorCircuitOpen
+	case errors.Is(err, context.DeadlineExceeded),
+		result.StatusCode == http.StatusRequestTimeout, result.StatusCode == http.StatusGatewayTimeout:
+		return AIErrorTimeout
+	case errors.Is(err, context.Canceled):
+		return AIErrorCanceled
+	case result.StatusCode == 0:
+		return AIErrorTransport
+	case result.StatusCode == http.StatusTooManyRequests:
+		return AIErrorRateLimited
+	case result.StatusCode >= 500:
+		return AIErrorServer
+	case result.StatusCode >= 400:
+		return AIErrorClient
+	case result.StatusCode >= 200 && result.StatusCode < 300:
+		return AIErrorInvalidResponse
+	}
+	return AIErrorUnknown
+}
+
+// rawAIResponseForHistory converte o corpo de uma resposta de erro do provedor para o
+// histórico: o JSON decodificado ou, se não for JSON, o texto (limitado).
+func rawAIResponseForHistory(raw []byte) interface{} {
+	var decoded interface{}
+	if err := json.Unmarshal(raw, &decoded); err == nil {
+		return decoded
+	}
+	return truncateRunes(string(raw), maxAIHistoryErrorBodyRunes)
+}
+
+// reliabilitySample são os campos do histórico usados no relatório.
+type reliabilitySample struct {
+	AIServiceType string `firestore:"ai_service_type"`
+	AIError       string `firestore:"ai_error"`
+	ErrorClass    string `firestore:"error_class"`
+	LatencyMs     int64  `firestore:"latency_ms"`
+	Attempts      int    `firestore:"attempts"`
+}
+
+type reliabilityAccumulator struct {
+	stats     models.AIReliabilityStats
+	latencies []int64
+	attempts  int
+}
+
+func (a *reliabilityAccumulator) add(sample reliabilitySample) {
+	a.stats.Total++
+	if sample.AIError == "" {
+		a.stats.Succeeded++
+	} else {
+		a.stats.Failed++
+		class := sample.ErrorClass
+		if class == "" {
+			class = AIErrorUnknown // Registros anteriores à classificação
+		}
+		a.stats.ErrorsByClass[class]++
+	}
+	// Sem tentativas não houve chamada ao provedor (circuito aberto, falha ao montar o
+	// contexto, resposta do cache ou registro anterior à medição)
+	if sample.Attempts > 0 {
+		a.latencies = append(a.latencies, sample.LatencyMs)
+		a.attempts += sample.Attempts
+		if sample.Attempts > 1 {
+			a.stats.Retried++
+		}
+	}
+}
+
+func (a *reliabilityAccumulator) finish() models.AIReliabilityStats {
+	stats := a.stats
+	if stats.Total > 0 {
+		stats.SuccessRate = math.Round(float64(stats.Succeeded)/float64(stats.Total)*10000) / 10000
+	}
+	if n := len(a.latencies); n > 0 {
+		sort.Slice(a.latencies, func(i, j int) bool { return a.latencies[i] < a.latencies[j] })
+		var sum int64
+		for _, latency := range a.latencies {
+			sum += latency
+		}
+		stats.AvgAttempts = math.Round(float64(a.attempts)/float64(n)*100) / 100
+		stats.Latency = models.AILatencyStats{
+			Samples: n,
+			AvgMs:   sum / int64(n),
+			P50Ms:   percentile(a.latencies, 0.50),
+			P95Ms:   percentile(a.latencies, 0.95),
+			MaxMs:   a.latencies[n-1],
+		}
+	}
+	return stats
+}
+
+// percentile usa o método nearest-rank sobre valores ordenados.
+func percentile(sorted []int64, p float64) int64 {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+func newReliabilityAccumulator(serviceType string) *reliabilityAccumulator {
+	return &reliabilityAccumulator{stats: models.AIReliabilityStats{ServiceType: serviceType, ErrorsByClass: map[string]int{}}}
+}
+
+// BuildAIReliabilityReport agrega o histórico de IA do workspace no período [since, until):
+// taxa de sucesso, falhas por classificação, latência e novas tentativas, no total e por
+// tipo de serviço (apenas serviceType, se informado).
+func BuildAIReliabilityReport(ctx context.Context, client *firestore.Client, workspaceIDPg int64, since, until time.Time, serviceType string) (*models.AIReliabilityReport, error) {
+	iter := HistoryCollection(client, workspaceIDPg).
+		Where("timestamp", ">=", since).
+		Where("timestamp", "<", until).
+		Select("ai_service_type", "ai_error", "error_class", "latency_ms", "attempts").
+		Limit(maxAIReliabilityScan + 1).
+		Documents(ctx)
+	defer iter.Stop()
+
+	report := &models.AIReliabilityReport{WorkspaceID: workspaceIDPg, Since: since, Until: until}
+	totals := newReliabilityAccumulator("")
+	byService := map[string]*reliabilityAccumulator{}
+	scanned := 0
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler histórico de IA para o relatório: %w", err)
+		}
+		if scanned++; scanned > maxAIReliabilityScan {
+			report.Partial = true
+			break
+		}
+		var sample reliabilitySample
+		if err := doc.DataTo(&sample); err != nil {
+			continue
+		}
+		if serviceType != "" && sample.AIServiceType != serviceType {
+			continue
+		}
+		totals.add(sample)
+		service, ok := byService[sample.AIServiceType]
+		if !ok {
+			service = newReliabilityAccumulator(sample.AIServiceType)
+			byService[sample.AIServiceType] = service
+		}
+		service.add(sample)
+	}
+
+	report.Totals = totals.finish()
+	report.Services = []models.AIReliabilityStats{}
+	for _, service := range byService {
+		report.Services = append(report.Services, service.finish())
+	}
+	sort.Slice(report.Services, func(i, j int) bool { return report.Services[i].ServiceType < report.Services[j].ServiceType })
+	return report, nil
+}
@@ -5,38 +5,160 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil" // ou "io" se estiver usando Go 1.16+
+	"io"
 	"net/http"
-	"projeto-integrador/utilities" // Onde você definiu LogError, LogWarn, etc.
+	"os"
+	"projeto-integrador/models"
+	"projeto-integrador/scheduler"
+	"strings"
 	"time"
 )
 
-// No seu pacote ai_service ou handlers (onde CallAIAPI está)
-// ... (imports) ...
-const aiApiBaseURL = "https://servico-ia.onrender.com"
-const aiApiTimeout = 45 * time.Second
+const defaultAIApiBaseURL = "https://servico-ia.onrender.com"
+const defaultAIApiTimeout = 45 * time.Second
 
-func CallAIAPI(ctx context.Context, aiEndpointPath string, requestPayload interface{}, targetSuccessResponse interface{}) (statusCode int, rawResponseBody []byte, err error) {
+// Caminhos dos endpoints do serviço Python de IA.
+const (
+	aiPathSummarize     = "/summarize-text"
+	aiPathCodeReview    = "/code-review"
+	aiPathMindMap       = "/mindmap-ideas"
+	aiPathTaskAssistant = "/assistente-tarefas"
+	aiPathTaskBreakdown = "/decompor-tarefa"
+)
+
+// aiPathsByService associa cada tipo de serviço ao endpoint do serviço Python.
+var aiPathsByService = map[string]string{
+	ServiceCodeReview:    aiPathCodeReview,
+	ServiceTextSummary:   aiPathSummarize,
+	ServiceMindMapIdeas:  aiPathMindMap,
+	ServiceTaskAssistant: aiPathTaskAssistant,
+	ServiceTaskBreakdown: aiPathTaskBreakdown,
+	ServiceTaskSummary:   aiPathSummarize, // Texto montado pelo backend a partir da tarefa
+}
+
+// HTTPProvider chama o serviço Python de IA via HTTP.
+//
+// Variáveis de ambiente:
+//   - AI_API_BASE_URL: URL base do serviço (padrão: https://servico-ia.onrender.com)
+//   - AI_API_TIMEOUT: timeout padrão das chamadas (padrão: 45s)
+//   - AI_API_TIMEOUT_SUMMARIZE, AI_API_TIMEOUT_CODE_REVIEW, AI_API_TIMEOUT_MINDMAP,
+//     AI_API_TIMEOUT_TASK_ASSISTANT, AI_API_TIMEOUT_TASK_BREAKDOWN: timeout por endpoint (padrão: AI_API_TIMEOUT)
+//   - AI_API_STREAMING, AI_API_STREAM_TIMEOUT: veja Stream
+type HTTPProvider struct {
+	baseURL       string
+	timeouts      map[string]time.Duration // Por caminho do endpoint
+	client        *http.Client
+	streaming     bool
+	streamTimeout time.Duration
+}
+
+// NewHTTPProviderFromEnv cria o provedor HTTP com a configuração do ambiente.
+func NewHTTPProviderFromEnv() *HTTPProvider {
+	baseURL := strings.TrimRight(os.Getenv("AI_API_BASE_URL"), "/")
+	if baseURL == "" {
+		baseURL = defaultAIApiBaseURL
+	}
+	def := scheduler.DurationFromEnv("AI_API_TIMEOUT", defaultAIApiTimeout)
+	streaming, streamTimeout := streamingFromEnv()
+	return &HTTPProvider{
+		baseURL: baseURL,
+		timeouts: map[string]time.Duration{
+			aiPathSummarize:     scheduler.DurationFromEnv("AI_API_TIMEOUT_SUMMARIZE", def),
+			aiPathCodeReview:    scheduler.DurationFromEnv("AI_API_TIMEOUT_CODE_REVIEW", def),
+			aiPathMindMap:       scheduler.DurationFromEnv("AI_API_TIMEOUT_MINDMAP", def),
+			aiPathTaskAssistant: scheduler.DurationFromEnv("AI_API_TIMEOUT_TASK_ASSISTANT", def),
+			aiPathTaskBreakdown: scheduler.DurationFromEnv("AI_API_TIMEOUT_TASK_BREAKDOWN", def),
+			"":                  def,
+		},
+		client:        &http.Client{}, // O timeout é aplicado por chamada, via contexto
+		streaming:     streaming,
+		streamTimeout: streamTimeout,
+	}
+}
+
+func (p *HTTPProvider) Name() string { return ProviderHTTP }
+
+func (p *HTTPProvider) Summarize(ctx context.Context, req models.SummarizeTextAIRequest) (*models.SummarizeTextAIResponse, AICallResult, error) {
+	var resp models.SummarizeTextAIResponse
+	result, err := p.call(ctx, aiPathSummarize, req, &resp)
+	if err != nil {
+		return nil, result, err
+	}
+	return &resp, result, nil
+}
+
+func (p *HTTPProvider) CodeReview(ctx context.Context, req models.CodeReviewAIRequest) (*models.CodeReviewAIResponse, AICallResult, error) {
+	var resp models.CodeReviewAIResponse
+	result, err := p.call(ctx, aiPathCodeReview, req, &resp)
+	if err != nil {
+		return nil, result, err
+	}
+	return &resp, result, nil
+}
+
+func (p *HTTPProvider) MindMapIdeas(ctx context.Context, req models.MindMapIdeasAIRequest) (*models.MindMapIdeasAIResponse, AICallResult, error) {
+	var resp models.MindMapIdeasAIResponse
+	result, err := p.call(ctx, aiPathMindMap, req, &resp)
+	if err != nil {
+		return nil, result, err
+	}
+	return &resp, result, nil
+}
+
+func (p *HTTPProvider) TaskAssistant(ctx context.Context, req models.TaskAssistantAIRequest) (*models.TaskAssistantAIResponse, AICallResult, error) {
+	var resp models.TaskAssistantAIResponse
+	result, err := p.call(ctx, aiPathTaskAssistant, req, &resp)
+	if err != nil {
+		return nil, result, err
+	}
+	return &resp, result, nil
+}
+
+func (p *HTTPProvider) BreakdownTask(ctx context.Context, req models.TaskBreakdownAIRequest) (*models.TaskBreakdownAIResponse, AICallResult, error) {
+	var resp models.TaskBreakdownAIResponse
+	result, err := p.call(ctx, aiPathTaskBreakdown, req, &resp)
+	if err != nil {
+		return nil, result, err
+	}
+	return &resp, result, nil
+}
+
+func (p *HTTPProvider) timeoutFor(aiEndpointPath string) time.Duration {
+	if timeout, ok := p.timeouts[aiEndpointPath]; ok {
+		return timeout
+	}
+	return p.timeouts[""]
+}
+
+// call envia o payload ao endpoint e, em caso de 2xx, decodifica a resposta em target.
+func (p *HTTPProvider) call(ctx context.Context, aiEndpointPath string, requestPayload interface{}, target interface{}) (AICallResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeoutFor(aiEndpointPath))
+	defer cancel()
+
+	statusCode, rawResponseBody, err := p.do(ctx, aiEndpointPath, requestPayload, target)
+	return AICallResult{StatusCode: statusCode, RawResponse: rawResponseBody}, err
+}
+
+func (p *HTTPProvider) do(ctx context.Context, aiEndpointPath string, requestPayload interface{}, targetSuccessResponse interface{}) (statusCode int, rawResponseBody []byte, err error) {
 	jsonData, err := json.Marshal(requestPayload)
 	if err != nil {
 		return 0, nil, fmt.Errorf("erro ao preparar dados para API de IA: %w", err)
 	}
 
-	fullURL := aiApiBaseURL + aiEndpointPath
+	fullURL := p.baseURL + aiEndpointPath
 	req, err := http.NewRequestWithContext(ctx, "POST", fullURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return 0, nil, fmt.Errorf("erro ao criar requisição para API de IA: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: aiApiTimeout}
-	resp, err := client.Do(req)
+	resp, err := p.client.Do(req)
 	if err != nil {
 		return 0, nil, fmt.Errorf("erro ao comunicar com API de IA: %w", err)
 	}
 	defer resp.Body.Close()
 
-	rawResponseBody, err = ioutil.ReadAll(resp.Body) // ou io.ReadAll para Go 1.16+
+	rawResponseBody, err = io.ReadAll(resp.Body)
 	if err != nil {
 		return resp.StatusCode, nil, fmt.Errorf("erro ao ler resposta da API de IA: %w", err)
 	}
@@ -44,11 +166,11 @@ func CallAIAPI(ctx context.Context, aiEndpointPath string, requestPayload interf
 	statusCode = resp.StatusCode
 
 	if statusCode >= 200 && statusCode < 300 {
-		if targetSuccessResponse != nil && rawResponseBody != nil {
+		if targetSuccessResponse != nil {
+			// Uma resposta que não decodifica não pode seguir como sucesso: viraria uma
+			// resposta vazia no cache e no histórico
 			if umErr := json.Unmarshal(rawResponseBody, targetSuccessResponse); umErr != nil {
-				// Logar o erro de unmarshal, mas não necessariamente tratar como falha da chamada à IA
-				utilities.LogInfo(fmt.Sprintf("CallAIAPI: Erro ao fazer unmarshal da resposta de sucesso da API de IA em targetSuccessResponse: %v. Corpo: %s", umErr, string(rawResponseBody)))
-				// Opcional: retornar um erro específico aqui se o unmarshal for crítico
+				return statusCode, rawResponseBody, fmt.Errorf("resposta inválida da API de IA (%s): %w", fullURL, umErr)
 			}
 		}
 		return statusCode, rawResponseBody, nil // Sucesso
@@ -0,0 +1,48 @@
+package ai_services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"projeto-integrador/models"
+	"testing"
+	"time"
+)
+
+func TestHTTPProviderDecodesResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr bool
+	}{
+		{"resposta válida", http.StatusOK, `{"summary": "Resumo"}`, false},
+		{"json inválido com 200", http.StatusOK, `<html>erro no proxy</html>`, true},
+		{"corpo vazio com 200", http.StatusOK, ``, true},
+		{"erro do serviço", http.StatusBadRequest, `{"detail": "texto vazio"}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+			provider := &HTTPProvider{baseURL: server.URL, timeouts: map[string]time.Duration{"": time.Second}, client: server.Client()}
+
+			resp, result, err := provider.Summarize(context.Background(), models.SummarizeTextAIRequest{Text: "texto"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Summarize erro = %v, wantErr %t", err, tt.wantErr)
+			}
+			if result.StatusCode != tt.status || string(result.RawResponse) != tt.body {
+				t.Errorf("AICallResult = %d %q", result.StatusCode, result.RawResponse)
+			}
+			if tt.wantErr && resp != nil {
+				t.Errorf("resposta com erro = %+v, want nil", resp)
+			}
+			if !tt.wantErr && (resp == nil || resp.Summary != "Resumo") {
+				t.Errorf("resposta = %+v", resp)
+			}
+		})
+	}
+}
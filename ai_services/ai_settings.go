@@ -0,0 +1,119 @@
+package ai_services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"projeto-integrador/database"
+	"projeto-integrador/models"
+	"projeto-integrador/task_services"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"cloud.google.com/go/firestore"
+)
+
+const (
+	MaxAICustomInstructionsLength = 2000 // Caracteres
+
+	maxContextCommentsPerTask = 3   // Comentários mais recentes de cada tarefa no contexto
+	maxContextCommentLength   = 200 // Caracteres de cada comentário no contexto
+)
+
+var (
+	// ErrAIFeatureDisabled indica que o workspace desativou a IA ou o serviço pedido.
+	ErrAIFeatureDisabled = errors.New("AI feature disabled for this workspace")
+	// ErrInvalidAISettings indica uma configuração de IA inválida.
+	ErrInvalidAISettings = errors.New("invalid AI settings")
+
+	languageTagPattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+)
+
+// ValidateWorkspaceAISettings confere as configurações enviadas por um admin e
+// normaliza o idioma e as instruções.
+func ValidateWorkspaceAISettings(settings *models.WorkspaceAISettings) error {
+	for serviceType := range settings.Features {
+		if _, ok := aiPathsByService[serviceType]; !ok {
+			return fmt.Errorf("%w: unknown AI feature %q", ErrInvalidAISettings, serviceType)
+		}
+	}
+	if settings.Features == nil {
+		settings.Features = map[string]bool{}
+	}
+	settings.Language = strings.TrimSpace(settings.Language)
+	if settings.Language != "" && (len(settings.Language) > 35 || !languageTagPattern.MatchString(settings.Language)) {
+		return fmt.Errorf("%w: language must be a language tag such as pt-BR or en", ErrInvalidAISettings)
+	}
+	settings.CustomInstructions = strings.TrimSpace(settings.CustomInstructions)
+	if utf8.RuneCountInString(settings.CustomInstructions) > MaxAICustomInstructionsLength {
+		return fmt.Errorf("%w: custom_instructions must have at most %d characters", ErrInvalidAISettings, MaxAICustomInstructionsLength)
+	}
+	return nil
+}
+
+// LoadWorkspaceAISettings carrega as configurações de IA do workspace.
+func LoadWorkspaceAISettings(workspaceIDPg int64) (models.WorkspaceAISettings, error) {
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		return models.WorkspaceAISettings{}, fmt.Errorf("erro ao conectar ao PG para carregar configurações de IA: %w", err)
+	}
+	defer db.Close()
+	return models.GetWorkspaceAISettings(db, workspaceIDPg)
+}
+
+// CheckAIServiceEnabled retorna ErrAIFeatureDisabled se o workspace não permite o serviço.
+func CheckAIServiceEnabled(settings models.WorkspaceAISettings, serviceType string) error {
+	if !settings.Enabled {
+		return fmt.Errorf("%w: AI is disabled", ErrAIFeatureDisabled)
+	}
+	if !settings.ServiceEnabled(serviceType) {
+		return fmt.Errorf("%w: %s is disabled", ErrAIFeatureDisabled, serviceType)
+	}
+	return nil
+}
+
+// withAIPreferences inclui o idioma e as instruções do workspace no payload enviado à IA.
+func withAIPreferences(requestToAI interface{}, settings models.WorkspaceAISettings) interface{} {
+	preferences := models.AIPreferences{ResponseLanguage: settings.Language, CustomInstructions: settings.CustomInstructions}
+	switch req := requestToAI.(type) {
+	case models.CodeReviewAIRequest:
+		req.AIPreferences = preferences
+		return req
+	case models.SummarizeTextAIRequest:
+		req.AIPreferences = preferences
+		return req
+	case models.MindMapIdeasAIRequest:
+		req.AIPreferences = preferences
+		return req
+	case models.TaskAssistantAIRequest:
+		req.AIPreferences = preferences
+		return req
+	case models.TaskBreakdownAIRequest:
+		req.AIPreferences = preferences
+		return req
+	case models.TaskSummaryAIRequest:
+		req.AIPreferences = preferences
+		return req
+	}
+	return requestToAI
+}
+
+// recentCommentsForAI retorna o texto dos comentários mais recentes da tarefa, do mais
+// antigo para o mais novo, sem os autores.
+func recentCommentsForAI(ctx context.Context, client *firestore.Client, workspaceIDPg int64, taskDocID string) ([]string, error) {
+	comments, err := task_services.ListTaskComments(ctx, client, workspaceIDPg, taskDocID)
+	if err != nil {
+		return nil, err
+	}
+	if len(comments) > maxContextCommentsPerTask {
+		comments = comments[len(comments)-maxContextCommentsPerTask:]
+	}
+	var texts []string
+	for _, comment := range comments {
+		if text := truncateRunes(strings.TrimSpace(comment.Text), maxContextCommentLength); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return texts, nil
+}
@@ -0,0 +1,191 @@
+package ai_services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"projeto-integrador/scheduler"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultAttachmentMaxBytes = 5 << 20 // Tamanho máximo do arquivo baixado
+	defaultAttachmentTimeout  = 15 * time.Second
+	defaultAttachmentHosts    = "firebasestorage.googleapis.com,storage.googleapis.com"
+	maxAttachmentRedirects    = 5
+)
+
+var (
+	errAttachmentNotAllowed  = errors.New("anexo não é um link https para um host permitido")
+	errAttachmentUnsupported = errors.New("tipo de anexo não suportado")
+	errAttachmentTooLarge    = errors.New("anexo maior que o limite")
+	errAttachmentNoText      = errors.New("anexo sem texto extraível")
+)
+
+// textAttachmentExtensions são os arquivos de texto lidos como estão, além das
+// extensões de código de languageByExtension.
+var textAttachmentExtensions = map[string]bool{
+	".txt": true, ".text": true, ".md": true, ".markdown": true, ".rst": true, ".csv": true, ".tsv": true,
+	".log": true, ".ini": true, ".toml": true, ".cfg": true, ".conf": true,
+}
+
+// textAttachmentMediaTypes são os tipos de conteúdo de texto fora de "text/*".
+var textAttachmentMediaTypes = map[string]bool{
+	"application/json": true, "application/xml": true, "application/yaml": true, "application/x-yaml": true,
+	"application/javascript": true, "application/x-sh": true, "application/sql": true, "application/toml": true,
+}
+
+// attachmentFetcher baixa o arquivo anexado a uma tarefa para extrair o texto. O anexo é
+// um link enviado pelo cliente depois do upload, então só links https para os hosts de
+// AI_ATTACHMENT_HOSTS são seguidos (inclusive nos redirecionamentos), com tamanho e
+// tempo limitados.
+type attachmentFetcher struct {
+	client   *http.Client
+	hosts    map[string]bool
+	maxBytes int64
+	timeout  time.Duration
+}
+
+var (
+	attachmentFetcherOnce    sync.Once
+	defaultAttachmentFetcher *attachmentFetcher
+)
+
+// getAttachmentFetcher retorna o attachmentFetcher configurado pelas variáveis de
+// ambiente, criado na primeira chamada:
+//   - AI_ATTACHMENT_HOSTS: hosts dos quais anexos podem ser baixados, separados por vírgula
+//   - AI_ATTACHMENT_MAX_BYTES: tamanho máximo do arquivo (padrão: 5 MiB)
+//   - AI_ATTACHMENT_TIMEOUT: tempo máximo do download (padrão: 15s)
+func getAttachmentFetcher() *attachmentFetcher {
+	attachmentFetcherOnce.Do(func() {
+		hosts := os.Getenv("AI_ATTACHMENT_HOSTS")
+		if hosts == "" {
+			hosts = defaultAttachmentHosts
+		}
+		defaultAttachmentFetcher = newAttachmentFetcher(&http.Client{}, strings.Split(hosts, ","),
+			int64(scheduler.IntFromEnv("AI_ATTACHMENT_MAX_BYTES", defaultAttachmentMaxBytes)),
+			scheduler.DurationFromEnv("AI_ATTACHMENT_TIMEOUT", defaultAttachmentTimeout))
+	})
+	return defaultAttachmentFetcher
+}
+
+func newAttachmentFetcher(client *http.Client, hosts []string, maxBytes int64, timeout time.Duration) *attachmentFetcher {
+	f := &attachmentFetcher{client: client, hosts: make(map[string]bool, len(hosts)), maxBytes: maxBytes, timeout: timeout}
+	for _, host := range hosts {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			f.hosts[host] = true
+		}
+	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxAttachmentRedirects {
+			return errors.New("redirecionamentos demais")
+		}
+		if !f.allowed(req.URL) {
+			return errAttachmentNotAllowed
+		}
+		return nil
+	}
+	return f
+}
+
+func (f *attachmentFetcher) allowed(u *url.URL) bool {
+	return u.Scheme == "https" && f.hosts[strings.ToLower(u.Hostname())]
+}
+
+// Text baixa o anexo e extrai o texto: arquivos de texto, markdown e código vêm como
+// estão e PDFs pelo texto das páginas. Anexos que não são links permitidos, de outros
+// tipos ou sem texto retornam erro.
+func (f *attachmentFetcher) Text(ctx context.Context, attachment string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(attachment))
+	if err != nil || !f.allowed(u) {
+		return "", errAttachmentNotAllowed
+	}
+	if !isTextAttachment("", u.Path) && !isPDFAttachment("", u.Path, nil) && path.Ext(u.Path) != "" {
+		// Extensão conhecida de outro tipo (imagem, planilha...): nem baixa
+		return "", errAttachmentUnsupported
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("erro ao criar requisição do anexo: %w", err)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("erro ao baixar anexo: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download do anexo respondeu %d", resp.StatusCode)
+	}
+	if resp.ContentLength > f.maxBytes {
+		return "", errAttachmentTooLarge
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("erro ao ler anexo: %w", err)
+	}
+	if int64(len(data)) > f.maxBytes {
+		return "", errAttachmentTooLarge
+	}
+	return attachmentText(data, resp.Header.Get("Content-Type"), u.Path)
+}
+
+// attachmentText extrai o texto do conteúdo do anexo, pelo tipo de conteúdo ou, se ele
+// não for conclusivo (ex: "application/octet-stream"), pela extensão do arquivo.
+func attachmentText(data []byte, contentType string, filename string) (string, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	var text string
+	switch {
+	case isPDFAttachment(mediaType, filename, data):
+		extracted, err := extractPDFText(data)
+		if err != nil {
+			return "", err
+		}
+		text = extracted
+	case isTextAttachment(mediaType, filename):
+		if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+			return "", errAttachmentUnsupported
+		}
+		text = string(data)
+	default:
+		return "", errAttachmentUnsupported
+	}
+	if text = strings.TrimSpace(text); text == "" {
+		return "", errAttachmentNoText
+	}
+	return text, nil
+}
+
+func isPDFAttachment(mediaType string, filename string, data []byte) bool {
+	return mediaType == "application/pdf" || strings.EqualFold(path.Ext(filename), ".pdf") || bytes.HasPrefix(data, []byte("%PDF-"))
+}
+
+func isTextAttachment(mediaType string, filename string) bool {
+	return strings.HasPrefix(mediaType, "text/") || textAttachmentMediaTypes[mediaType] ||
+		textAttachmentExtensions[strings.ToLower(path.Ext(filename))] || languageFromFilename(filename) != ""
+}
+
+// attachmentName retorna o nome do arquivo anexado: o último trecho do caminho, se o
+// anexo for um link (sem a query, que pode ter o token de acesso), ou o próprio valor.
+func attachmentName(attachment string) string {
+	attachment = strings.TrimSpace(attachment)
+	if u, err := url.Parse(attachment); err == nil && u.Host != "" {
+		if name := path.Base(u.Path); name != "." && name != "/" {
+			return name
+		}
+		return u.Host
+	}
+	return attachment
+}
@@ -0,0 +1,155 @@
+package ai_services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPDF monta um PDF com um objeto por stream; cada stream é dado como (dicionário, conteúdo).
+func testPDF(streams ...[2]string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	for i, stream := range streams {
+		fmt.Fprintf(&b, "%d 0 obj\n<< %s /Length %d >>\nstream\n%s\nendstream\nendobj\n", i+3, stream[0], len(stream[1]), stream[1])
+	}
+	b.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return b.Bytes()
+}
+
+func deflate(s string) string {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	w.Write([]byte(s))
+	w.Close()
+	return b.String()
+}
+
+func TestExtractPDFText(t *testing.T) {
+	tests := []struct {
+		name    string
+		pdf     []byte
+		want    string
+		wantErr error
+	}{
+		{
+			name: "stream sem compressão",
+			pdf:  testPDF([2]string{"", "BT /F1 12 Tf 72 712 Td (Primeira linha) Tj 0 -14 Td (Segunda \\(linha\\)) Tj ET"}),
+			want: "Primeira linha\nSegunda (linha)",
+		},
+		{
+			name: "FlateDecode com TJ, octal e aspas",
+			pdf:  testPDF([2]string{"/Filter /FlateDecode", deflate("BT 1 0 0 1 72 700 Tm [(Relat) -10 (\\363rio) -300 (final)] TJ (Pr\\363xima) ' ET")}),
+			want: "Relatório final\nPróxima",
+		},
+		{
+			name: "imagens e glifos em hexadecimal são ignorados",
+			pdf: testPDF(
+				[2]string{"/Type /XObject /Subtype /Image /Filter /FlateDecode", deflate("BT (não é texto) Tj ET")},
+				[2]string{"/Filter /DCTDecode", "BT (jpeg) Tj ET"},
+				[2]string{"", "BT <00410042> Tj <4F6C61> Tj ET"},
+			),
+			want: "Ola",
+		},
+		{
+			name:    "sem texto",
+			pdf:     testPDF([2]string{"", "0 0 m 10 10 l S"}),
+			wantErr: errAttachmentNoText,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractPDFText(tt.pdf)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("texto = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttachmentFetcherText(t *testing.T) {
+	pdf := testPDF([2]string{"", "BT (Conte\\372do do PDF) Tj ET"})
+	requests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/notas.md":
+			w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+			w.Write([]byte("# Notas\n\nDecidido usar filas."))
+		case "/o/tasks/relatorio.pdf":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(pdf)
+		case "/main.go":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte("package main\n"))
+		case "/binario.txt":
+			w.Write([]byte{0x00, 0x01, 0xff})
+		case "/grande.txt":
+			w.Write(bytes.Repeat([]byte("a"), 2048))
+		case "/redireciona.txt":
+			http.Redirect(w, r, "https://exemplo.com/notas.txt", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	fetcher := newAttachmentFetcher(server.Client(), []string{"127.0.0.1"}, 1024, 5*time.Second)
+
+	tests := []struct {
+		name       string
+		attachment string
+		want       string
+		wantErr    error
+		noRequest  bool
+	}{
+		{name: "markdown", attachment: server.URL + "/notas.md", want: "# Notas\n\nDecidido usar filas."},
+		{name: "pdf pela extensão", attachment: server.URL + "/o/tasks%2Frelatorio.pdf?alt=media&token=abc", want: "Conteúdo do PDF"},
+		{name: "código pela extensão", attachment: server.URL + "/main.go", want: "package main"},
+		{name: "conteúdo binário", attachment: server.URL + "/binario.txt", wantErr: errAttachmentUnsupported},
+		{name: "maior que o limite", attachment: server.URL + "/grande.txt", wantErr: errAttachmentTooLarge},
+		{name: "redirecionamento para outro host", attachment: server.URL + "/redireciona.txt", wantErr: errAttachmentNotAllowed},
+		{name: "imagem não é baixada", attachment: server.URL + "/foto.png", wantErr: errAttachmentUnsupported, noRequest: true},
+		{name: "host não permitido", attachment: "https://exemplo.com/notas.md", wantErr: errAttachmentNotAllowed, noRequest: true},
+		{name: "http sem TLS", attachment: strings.Replace(server.URL, "https://", "http://", 1) + "/notas.md", wantErr: errAttachmentNotAllowed, noRequest: true},
+		{name: "só o nome do arquivo", attachment: "notas.md", wantErr: errAttachmentNotAllowed, noRequest: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := requests
+			got, err := fetcher.Text(context.Background(), tt.attachment)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("texto = %q, want %q", got, tt.want)
+			}
+			if tt.noRequest && requests != before {
+				t.Error("o anexo não deveria ter sido baixado")
+			}
+		})
+	}
+}
+
+func TestAttachmentName(t *testing.T) {
+	tests := map[string]string{
+		"https://firebasestorage.googleapis.com/v0/b/app.appspot.com/o/tasks%2Fabc%2Frelatorio.pdf?alt=media&token=segredo": "relatorio.pdf",
+		"notas.md":                "notas.md",
+		"https://exemplo.com":     "exemplo.com",
+		" https://exemplo.com/a ": "a",
+	}
+	for attachment, want := range tests {
+		if got := attachmentName(attachment); got != want {
+			t.Errorf("attachmentName(%q) = %q, want %q", attachment, got, want)
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package ai_services
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"projeto-integrador/models"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxCodeReviewFindings = 100
+	unknownCodeLanguage   = "plaintext" // Linguagem quando a detecção não é conclusiva
+)
+
+// Severidades dos achados da revisão, da menos para a mais grave.
+const (
+	SeverityInfo     = "info"
+	SeverityMinor    = "minor"
+	SeverityMajor    = "major"
+	SeverityCritical = "critical"
+)
+
+var severityRank = map[string]int{SeverityInfo: 0, SeverityMinor: 1, SeverityMajor: 2, SeverityCritical: 3}
+
+// severityAliases aceita os nomes mais comuns devolvidos pelos modelos.
+var severityAliases = map[string]string{
+	"info": SeverityInfo, "information": SeverityInfo, "low": SeverityInfo, "nit": SeverityInfo, "suggestion": SeverityInfo, "baixa": SeverityInfo,
+	"minor": SeverityMinor, "medium": SeverityMinor, "warning": SeverityMinor, "moderate": SeverityMinor, "media": SeverityMinor, "média": SeverityMinor,
+	"major": SeverityMajor, "high": SeverityMajor, "error": SeverityMajor, "alta": SeverityMajor,
+	"critical": SeverityCritical, "blocker": SeverityCritical, "severe": SeverityCritical, "critica": SeverityCritical, "crítica": SeverityCritical,
+}
+
+// severityPriority define a prioridade da tarefa criada a partir de um achado.
+var severityPriority = map[string]string{SeverityInfo: "low", SeverityMinor: "medium", SeverityMajor: "high", SeverityCritical: "high"}
+
+// languageByExtension mapeia extensões (e nomes de arquivo conhecidos) para a linguagem.
+var languageByExtension = map[string]string{
+	".py": "Python", ".go": "Go", ".js": "JavaScript", ".jsx": "JavaScript", ".mjs": "JavaScript", ".cjs": "JavaScript",
+	".ts": "TypeScript", ".tsx": "TypeScript", ".java": "Java", ".kt": "Kotlin", ".kts": "Kotlin", ".rb": "Ruby",
+	".php": "PHP", ".cs": "C#", ".c": "C", ".h": "C", ".cpp": "C++", ".cc": "C++", ".cxx": "C++", ".hpp": "C++",
+	".rs": "Rust", ".swift": "Swift", ".scala": "Scala", ".dart": "Dart", ".lua": "Lua", ".r": "R",
+	".sql": "SQL", ".sh": "Shell", ".bash": "Shell", ".html": "HTML", ".htm": "HTML", ".css": "CSS", ".scss": "SCSS",
+	".vue": "Vue", ".json": "JSON", ".yaml": "YAML", ".yml": "YAML", ".xml": "XML", ".md": "Markdown",
+	"dockerfile": "Dockerfile", "makefile": "Makefile",
+}
+
+// languageHints são indícios no conteúdo; vence a linguagem com mais indícios.
+var languageHints = []struct {
+	Language string
+	Pattern  *regexp.Regexp
+}{
+	{"Go", regexp.MustCompile(`(?m)^package \w+$|^func (\(\w+ \*?\w+\) )?\w+\(|:= |\berr != nil\b`)},
+	{"Python", regexp.MustCompile(`(?m)^\s*def \w+\(.*\):\s*$|^\s*(from [\w.]+ )?import \w+\s*$|^\s*class \w+(\(.*\))?:\s*$|\bself\.|\belif\b|^#!.*python`)},
+	{"JavaScript", regexp.MustCompile(`\bconsole\.log\(|\bfunction\s*\w*\(|=>|\brequire\(|\bmodule\.exports\b|\b(const|let|var) \w+ = `)},
+	{"TypeScript", regexp.MustCompile(`\binterface \w+ \{|: (string|number|boolean)\b|\bimport .* from ['"]|\bexport (type|interface) `)},
+	{"Java", regexp.MustCompile(`\bpublic (static )?(class|void|final)\b|\bSystem\.out\.|\bimport java\.|@Override\b`)},
+	{"C#", regexp.MustCompile(`\busing System\b|\bnamespace \w+|\bConsole\.Write|\bpublic async Task\b`)},
+	{"C", regexp.MustCompile(`#include <\w+\.h>|\bprintf\(|\bmalloc\(|\bint main\(`)},
+	{"C++", regexp.MustCompile(`#include <\w+>|\bstd::|\bcout\b|\btemplate ?<`)},
+	{"Rust", regexp.MustCompile(`\bfn \w+\(|\blet mut\b|\bimpl\b|\bprintln!\(|->\s*Result<`)},
+	{"PHP", regexp.MustCompile(`<\?php|\$this->|\becho \$`)},
+	{"Ruby", regexp.MustCompile(`(?m)^\s*def \w+(\(.*\))?\s*$|^\s*end\s*$|\bputs\b|\brequire '`)},
+	{"SQL", regexp.MustCompile(`(?i)\bselect\b.+\bfrom\b|\binsert into\b|\bcreate table\b|\bupdate \w+ set\b`)},
+	{"Shell", regexp.MustCompile(`(?m)^#!.*\b(ba|z)?sh\b|\becho "|\$\{?\w+\}?|\bfi$`)},
+	{"HTML", regexp.MustCompile(`(?i)<!doctype html|<html\b|<div\b|<body\b`)},
+}
+
+var diffHunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// diffLine é uma linha adicionada no diff, com o número na versão nova do arquivo.
+type diffLine struct {
+	Number int
+	Text   string
+}
+
+// diffFile reúne as linhas adicionadas de um arquivo do diff.
+type diffFile struct {
+	Path  string
+	Added []diffLine
+}
+
+// parseUnifiedDiff lê um diff unificado (git diff ou diff -u). Retorna erro se não
+// houver nenhum hunk ("@@ -a,b +c,d @@").
+func parseUnifiedDiff(diff string) ([]diffFile, error) {
+	var files []diffFile
+	var current *diffFile
+	lines := strings.Split(strings.ReplaceAll(diff, "\r\n", "\n"), "\n")
+	newLine, hunks, oldPath := 0, 0, ""
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			oldPath = diffPath(line[4:])
+			newLine = 0
+		case strings.HasPrefix(line, "+++ ") && i > 0 && strings.HasPrefix(lines[i-1], "--- "):
+			filePath := diffPath(line[4:])
+			if filePath == "" { // Arquivo removido: usa o caminho antigo
+				filePath = oldPath
+			}
+			files = append(files, diffFile{Path: filePath})
+			current = &files[len(files)-1]
+		case strings.HasPrefix(line, "@@"):
+			match := diffHunkHeader.FindStringSubmatch(line)
+			if match == nil {
+				return nil, fmt.Errorf("invalid hunk header on line %d", i+1)
+			}
+			if current == nil { // Diff sem cabeçalho de arquivo
+				files = append(files, diffFile{})
+				current = &files[len(files)-1]
+			}
+			newLine, _ = strconv.Atoi(match[1])
+			hunks++
+		case newLine == 0 || current == nil:
+			// Fora de um hunk (ex: "diff --git", "index ...")
+		case strings.HasPrefix(line, "+"):
+			current.Added = append(current.Added, diffLine{Number: newLine, Text: line[1:]})
+			newLine++
+		case strings.HasPrefix(line, " "):
+			newLine++
+		}
+	}
+	if hunks == 0 {
+		return nil, fmt.Errorf("no hunks found")
+	}
+	return files, nil
+}
+
+// diffPath extrai o caminho do cabeçalho "--- a/x" / "+++ b/x"; vazio para /dev/null.
+func diffPath(header string) string {
+	header = strings.TrimSpace(strings.SplitN(header, "\t", 2)[0])
+	if header == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(header, "a/") || strings.HasPrefix(header, "b/") {
+		return header[2:]
+	}
+	return header
+}
+
+// DetectCodeLanguage detecta a linguagem pelo nome do arquivo e, se não for conhecido,
+// pelos indícios no conteúdo. Retorna "plaintext" quando não há indícios.
+func DetectCodeLanguage(filename string, code string) string {
+	if language := languageFromFilename(filename); language != "" {
+		return language
+	}
+	best, bestScore := unknownCodeLanguage, 0
+	for _, hint := range languageHints {
+		if score := len(hint.Pattern.FindAllStringIndex(code, 50)); score > bestScore {
+			best, bestScore = hint.Language, score
+		}
+	}
+	return best
+}
+
+func languageFromFilename(filename string) string {
+	base := strings.ToLower(path.Base(strings.ReplaceAll(strings.TrimSpace(filename), "\\", "/")))
+	if base == "" || base == "." || base == "/" {
+		return ""
+	}
+	if language, ok := languageByExtension[base]; ok {
+		return language
+	}
+	return languageByExtension[path.Ext(base)]
+}
+
+// prepareCodeReviewInput valida a entrada da revisão: exige o código ou um diff
+// unificado válido, lista os arquivos alterados e detecta a linguagem se não informada.
+func prepareCodeReviewInput(input models.CodeReviewAIRequest) (models.CodeReviewAIRequest, error) {
+	input.Language = strings.TrimSpace(input.Language)
+	input.Filename = strings.TrimSpace(input.Filename)
+	input.Files = nil
+	if strings.TrimSpace(input.Diff) == "" {
+		input.Diff = ""
+		if strings.TrimSpace(input.Code) == "" {
+			return input, fmt.Errorf("%w: code or diff is required", ErrInvalidAIInput)
+		}
+		if input.Language == "" {
+			input.Language = DetectCodeLanguage(input.Filename, input.Code)
+		}
+		return input, nil
+	}
+
+	files, err := parseUnifiedDiff(input.Diff)
+	if err != nil {
+		return input, fmt.Errorf("%w: diff is not a valid unified diff: %s", ErrInvalidAIInput, err.Error())
+	}
+	var added strings.Builder
+	for _, file := range files {
+		if file.Path != "" {
+			input.Files = append(input.Files, file.Path)
+		}
+		for _, line := range file.Added {
+			added.WriteString(line.Text + "\n")
+		}
+	}
+	if input.Language == "" {
+		for _, filePath := range append([]string{input.Filename}, input.Files...) {
+			if input.Language = languageFromFilename(filePath); input.Language != "" {
+				break
+			}
+		}
+	}
+	if input.Language == "" {
+		input.Language = DetectCodeLanguage("", added.String()+input.Code)
+	}
+	return input, nil
+}
+
+// normalizeCodeReviewResponse padroniza os achados (severidade, categoria, linhas e
+// arquivo), ordena do mais grave para o menos grave e, se o serviço devolveu apenas os
+// achados, gera o texto da revisão a partir deles.
+func normalizeCodeReviewResponse(resp models.CodeReviewAIResponse, req models.CodeReviewAIRequest) models.CodeReviewAIResponse {
+	if resp.Language == "" {
+		resp.Language = req.Language
+	}
+	defaultFile := req.Filename
+	if len(req.Files) == 1 {
+		defaultFile = req.Files[0]
+	}
+
+	findings := make([]models.CodeReviewFinding, 0, len(resp.Findings))
+	for _, finding := range resp.Findings {
+		finding.Message = strings.TrimSpace(finding.Message)
+		if finding.Message == "" {
+			continue
+		}
+		finding.Severity = normalizeSeverity(finding.Severity)
+		finding.Category = strings.ToLower(strings.Join(strings.Fields(finding.Category), "-"))
+		if finding.Category == "" {
+			finding.Category = "general"
+		}
+		finding.SuggestedFix = strings.TrimSpace(finding.SuggestedFix)
+		finding.File = strings.TrimSpace(finding.File)
+		if finding.File == "" {
+			finding.File = defaultFile
+		}
+		if finding.StartLine < 0 {
+			finding.StartLine = 0
+		}
+		if finding.StartLine == 0 && finding.EndLine > 0 {
+			finding.StartLine = finding.EndLine
+		}
+		if finding.EndLine < finding.StartLine {
+			finding.EndLine = finding.StartLine
+		}
+		findings = append(findings, finding)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if severityRank[findings[i].Severity] != severityRank[findings[j].Severity] {
+			return severityRank[findings[i].Severity] > severityRank[findings[j].Severity]
+		}
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].StartLine < findings[j].StartLine
+	})
+	if len(findings) > maxCodeReviewFindings {
+		findings = findings[:maxCodeReviewFindings]
+	}
+	resp.Findings = findings
+
+	if strings.TrimSpace(resp.Review) == "" {
+		resp.Review = codeReviewText(findings)
+	}
+	return resp
+}
+
+// normalizeSeverity converte a severidade para um dos valores aceitos; severidades
+// desconhecidas viram "minor".
+func normalizeSeverity(severity string) string {
+	if normalized, ok := severityAliases[strings.ToLower(strings.TrimSpace(severity))]; ok {
+		return normalized
+	}
+	return SeverityMinor
+}
+
+// codeReviewText monta o texto da revisão a partir dos achados, um por linha.
+func codeReviewText(findings []models.CodeReviewFinding) string {
+	if len(findings) == 0 {
+		return "Nenhum problema encontrado."
+	}
+	lines := make([]string, 0, len(findings))
+	for _, finding := range findings {
+		line := fmt.Sprintf("- [%s/%s] ", finding.Severity, finding.Category)
+		if location := findingLocation(finding); location != "" {
+			line += location + ": "
+		}
+		line += finding.Message
+		if finding.SuggestedFix != "" {
+			line += " Sugestão: " + finding.SuggestedFix
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+var (
+	// Linha de achado no formato de codeReviewText: "- [major/bug] main.go:10-12: mensagem Sugestão: correção"
+	codeReviewFindingLine = regexp.MustCompile(`^\s*[-*•]\s*\[([^/\]]+)/([^\]]+)\]\s*(.+)$`)
+	// Local opcional no início da mensagem: "linha 10", "main.go:10-12" ou "main.go";
+	// o arquivo só é aceito se passar por looksLikeFilePath
+	codeReviewFindingLocation = regexp.MustCompile(`^(?:linha (\d+)(?:-(\d+))?|([^\s:]+):(\d+)(?:-(\d+))?|([^\s:]+)):\s+`)
+	// Extensão de arquivo: ao menos uma letra, para não confundir com abreviações ("e.g.") ou versões ("v1.2")
+	fileExtensionPattern = regexp.MustCompile(`^\.[A-Za-z][A-Za-z0-9]*$`)
+)
+
+// looksLikeFilePath diz se o início de uma linha de achado é um arquivo: um nome com
+// extensão (ex: "internal/db.go") ou um nome de arquivo conhecido (ex: "Makefile").
+// Palavras com "." ou "/" no meio de uma frase (ex: "e/ou", "e.g.") não são arquivos.
+func looksLikeFilePath(token string) bool {
+	base := path.Base(token)
+	if _, ok := languageByExtension[strings.ToLower(base)]; ok {
+		return true
+	}
+	ext := path.Ext(base)
+	return len(base) > len(ext) && fileExtensionPattern.MatchString(ext)
+}
+
+// parseStreamedCodeReview monta a revisão a partir do texto recebido por streaming: o
+// JSON da rota normal, se o serviço o enviou, ou o texto com os achados extraídos das
+// linhas no formato de codeReviewText. Linhas em outro formato ficam só no texto.
+func parseStreamedCodeReview(text string) models.CodeReviewAIResponse {
+	if trimmed := strings.TrimSpace(text); strings.HasPrefix(trimmed, "{") {
+		var resp models.CodeReviewAIResponse
+		if json.Unmarshal([]byte(trimmed), &resp) == nil && (resp.Review != "" || len(resp.Findings) > 0) {
+			return resp
+		}
+	}
+
+	resp := models.CodeReviewAIResponse{Review: text}
+	for _, line := range strings.Split(text, "\n") {
+		m := codeReviewFindingLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		finding := models.CodeReviewFinding{Severity: m[1], Category: m[2]}
+		message := m[3]
+		if loc := codeReviewFindingLocation.FindStringSubmatch(message); loc != nil {
+			switch {
+			case loc[1] != "":
+				finding.StartLine, _ = strconv.Atoi(loc[1])
+				finding.EndLine, _ = strconv.Atoi(loc[2])
+				message = message[len(loc[0]):]
+			case loc[3] != "" && looksLikeFilePath(loc[3]):
+				finding.File = loc[3]
+				finding.StartLine, _ = strconv.Atoi(loc[4])
+				finding.EndLine, _ = strconv.Atoi(loc[5])
+				message = message[len(loc[0]):]
+			case loc[6] != "" && looksLikeFilePath(loc[6]):
+				finding.File = loc[6]
+				message = message[len(loc[0]):]
+			}
+		}
+		if i := strings.LastIndex(message, " Sugestão: "); i >= 0 {
+			finding.SuggestedFix = message[i+len(" Sugestão: "):]
+			message = message[:i]
+		}
+		finding.Message = message
+		resp.Findings = append(resp.Findings, finding)
+	}
+	return resp
+}
+
+// findingLocation formata o local do achado (ex: "main.go:10-12"); vazio se não houver.
+func findingLocation(finding models.CodeReviewFinding) string {
+	location := finding.File
+	if finding.StartLine > 0 {
+		lines := strconv.Itoa(finding.StartLine)
+		if finding.EndLine > finding.StartLine {
+			lines += "-" + strconv.Itoa(finding.EndLine)
+		}
+		if location == "" {
+			location = "linha " + lines
+		} else {
+			location += ":" + lines
+		}
+	}
+	return location
+}
@@ -0,0 +1,158 @@
+package ai_services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"projeto-integrador/models"
+	"projeto-integrador/task_services"
+	"projeto-integrador/utilities"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// historyCodeReview carrega uma revisão de código do ai_request_history do usuário,
+// junto com a requisição enviada à IA (arquivo, arquivos do diff).
+func historyCodeReview(ctx context.Context, client *firestore.Client, workspaceIDPg int64, historyID string, userID string) (*models.CodeReviewAIResponse, *models.CodeReviewAIRequest, error) {
+	doc, err := aiHistoryDoc(ctx, client, workspaceIDPg, historyID, userID, ServiceCodeReview)
+	if err != nil {
+		return nil, nil, err
+	}
+	var entry struct {
+		RequestToAI    *models.CodeReviewAIRequest  `firestore:"request_to_ai"`
+		ResponseFromAI *models.CodeReviewAIResponse `firestore:"response_from_ai"`
+	}
+	if err := doc.DataTo(&entry); err != nil {
+		return nil, nil, fmt.Errorf("erro ao ler histórico de IA %s: %w", historyID, err)
+	}
+	if entry.ResponseFromAI == nil {
+		return nil, nil, ErrAIHistoryNotFound
+	}
+	if entry.RequestToAI == nil {
+		entry.RequestToAI = &models.CodeReviewAIRequest{}
+	}
+	return entry.ResponseFromAI, entry.RequestToAI, nil
+}
+
+// AttachCodeReviewToTask anexa uma revisão do histórico do usuário à tarefa. A tarefa
+// não é lida aqui: o handler confere antes que ela existe e que userID é membro.
+func AttachCodeReviewToTask(ctx context.Context, client *firestore.Client, workspaceIDPg int64, userID string, taskDocID string, historyID string) (*models.TaskCodeReview, error) {
+	response, request, err := historyCodeReview(ctx, client, workspaceIDPg, historyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	review := models.TaskCodeReview{
+		HistoryID:  historyID,
+		Language:   response.Language,
+		Files:      request.Files,
+		Review:     response.Review,
+		Findings:   response.Findings,
+		AttachedBy: userID,
+		AttachedAt: time.Now().UTC(),
+	}
+	if len(review.Files) == 0 && request.Filename != "" {
+		review.Files = []string{request.Filename}
+	}
+	if review.Findings == nil {
+		review.Findings = []models.CodeReviewFinding{}
+	}
+	if err := task_services.SaveTaskCodeReview(ctx, client, workspaceIDPg, taskDocID, review); err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// CodeReviewFindingsToTasks cria uma tarefa por achado selecionado de uma revisão do
+// histórico (sem seleção, todos os achados), com a prioridade derivada da severidade.
+// O ID de cada tarefa é derivado do registro e do índice do achado, então repetir a
+// operação não cria duplicatas.
+func CodeReviewFindingsToTasks(ctx context.Context, db *sql.DB, client *firestore.Client, workspaceIDPg int64, userID string, historyID string, selections []models.CodeReviewFindingSelection) ([]models.AcceptedTaskSuggestion, error) {
+	response, _, err := historyCodeReview(ctx, client, workspaceIDPg, historyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	findings := response.Findings
+	if len(findings) == 0 {
+		return nil, fmt.Errorf("%w: the code review has no findings", ErrInvalidTaskSuggestion)
+	}
+
+	if len(selections) == 0 {
+		for i := range findings {
+			selections = append(selections, models.CodeReviewFindingSelection{Index: i})
+		}
+	}
+	seen := make(map[int]bool, len(selections))
+	for _, selection := range selections {
+		if selection.Index < 0 || selection.Index >= len(findings) {
+			return nil, fmt.Errorf("%w: index %d out of range (0-%d)", ErrInvalidTaskSuggestion, selection.Index, len(findings)-1)
+		}
+		if seen[selection.Index] {
+			return nil, fmt.Errorf("%w: index %d selected more than once", ErrInvalidTaskSuggestion, selection.Index)
+		}
+		seen[selection.Index] = true
+	}
+
+	results := make([]models.AcceptedTaskSuggestion, 0, len(selections))
+	for _, selection := range selections {
+		result := models.AcceptedTaskSuggestion{Index: selection.Index}
+		input, err := taskInputFromFinding(findings[selection.Index], selection)
+		if err != nil {
+			result.Status, result.Error = SuggestionFailed, err.Error()
+			results = append(results, result)
+			continue
+		}
+		input.DocID = fmt.Sprintf("ai-%s-f%d", historyID, selection.Index)
+		input.AIGenerated = true
+		input.AIHistoryID = historyID
+
+		taskID, _, err := task_services.CreateTask(ctx, db, client, workspaceIDPg, userID, input)
+		switch {
+		case err == nil:
+			result.Status, result.TaskID = SuggestionCreated, taskID
+		case errors.Is(err, task_services.ErrTaskAlreadyExists):
+			result.Status, result.TaskID = SuggestionAlreadyAccepted, input.DocID
+		case errors.Is(err, task_services.ErrInvalidTask):
+			result.Status, result.Error = SuggestionFailed, err.Error()
+		default:
+			utilities.LogError(err, fmt.Sprintf("CodeReviewFindingsToTasks: Erro ao criar tarefa do achado %d do histórico %s", selection.Index, historyID))
+			result.Status, result.Error = SuggestionFailed, "failed to create task"
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// taskInputFromFinding monta a tarefa de um achado: o título leva o local do achado e
+// a descrição, a mensagem completa e a correção sugerida.
+func taskInputFromFinding(finding models.CodeReviewFinding, selection models.CodeReviewFindingSelection) (models.CreateTaskInput, error) {
+	title := finding.Message
+	if location := findingLocation(finding); location != "" {
+		title = location + ": " + title
+	}
+	var description strings.Builder
+	description.WriteString(finding.Message)
+	if finding.SuggestedFix != "" {
+		description.WriteString("\n\nSugestão de correção:\n" + finding.SuggestedFix)
+	}
+	description.WriteString(fmt.Sprintf("\n\nSeveridade: %s. Categoria: %s.", finding.Severity, finding.Category))
+
+	input := models.CreateTaskInput{
+		Title:               normalizeSuggestionTitle(title),
+		Description:         description.String(),
+		Status:              "pending",
+		Priority:            severityPriority[normalizeSeverity(finding.Severity)],
+		Labels:              []string{"code-review", finding.Category},
+		AssigneeFirebaseUID: selection.AssigneeFirebaseUID, // Validado por CreateTask
+	}
+	if selection.DueDate != "" {
+		dueDate, err := task_services.ParseImportDate(selection.DueDate)
+		if err != nil {
+			return input, err
+		}
+		input.ExpirationDate = &dueDate
+	}
+	return input, nil
+}
@@ -0,0 +1,96 @@
+package ai_services
+
+import (
+	"projeto-integrador/models"
+	"reflect"
+	"testing"
+)
+
+func TestParseStreamedCodeReview(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []models.CodeReviewFinding
+	}{
+		{
+			name: "arquivo com intervalo de linhas e sugestão",
+			text: "- [major/bug] main.go:10-12: erro ignorado Sugestão: tratar o erro",
+			want: []models.CodeReviewFinding{{File: "main.go", StartLine: 10, EndLine: 12, Severity: "major", Category: "bug", Message: "erro ignorado", SuggestedFix: "tratar o erro"}},
+		},
+		{
+			name: "arquivo com uma linha",
+			text: "- [minor/style] internal/db.go:7: nome pouco claro",
+			want: []models.CodeReviewFinding{{File: "internal/db.go", StartLine: 7, Severity: "minor", Category: "style", Message: "nome pouco claro"}},
+		},
+		{
+			name: "só a linha",
+			text: "* [info/style] linha 3-4: comentário desatualizado",
+			want: []models.CodeReviewFinding{{StartLine: 3, EndLine: 4, Severity: "info", Category: "style", Message: "comentário desatualizado"}},
+		},
+		{
+			name: "só o arquivo",
+			text: "- [critical/security] config/Dockerfile: imagem roda como root",
+			want: []models.CodeReviewFinding{{File: "config/Dockerfile", Severity: "critical", Category: "security", Message: "imagem roda como root"}},
+		},
+		{
+			name: "sem local",
+			text: "- [minor/performance] consulta dentro do laço Sugestão: buscar tudo de uma vez",
+			want: []models.CodeReviewFinding{{Severity: "minor", Category: "performance", Message: "consulta dentro do laço", SuggestedFix: "buscar tudo de uma vez"}},
+		},
+		{
+			name: "palavra com barra no início não é arquivo",
+			text: "- [info/docs] e/ou: prefira uma das duas opções",
+			want: []models.CodeReviewFinding{{Severity: "info", Category: "docs", Message: "e/ou: prefira uma das duas opções"}},
+		},
+		{
+			name: "abreviação com ponto no início não é arquivo",
+			text: "- [info/style] e.g.: use nomes descritivos",
+			want: []models.CodeReviewFinding{{Severity: "info", Category: "style", Message: "e.g.: use nomes descritivos"}},
+		},
+		{
+			name: "versão com dois-pontos e número não é arquivo",
+			text: "- [minor/deps] v1.2:3 está desatualizada",
+			want: []models.CodeReviewFinding{{Severity: "minor", Category: "deps", Message: "v1.2:3 está desatualizada"}},
+		},
+		{
+			name: "linhas fora do formato ficam só no texto",
+			text: "Resumo da revisão\n- [major/bug] app.py:1: import não usado\nObrigado!",
+			want: []models.CodeReviewFinding{{File: "app.py", StartLine: 1, Severity: "major", Category: "bug", Message: "import não usado"}},
+		},
+		{
+			name: "JSON da rota normal",
+			text: ` {"review": "ok", "findings": [{"file": "a.go", "start_line": 2, "severity": "minor", "category": "style", "message": "m"}]}`,
+			want: []models.CodeReviewFinding{{File: "a.go", StartLine: 2, Severity: "minor", Category: "style", Message: "m"}},
+		},
+		{
+			name: "texto que começa com chave mas não é JSON",
+			text: "{ não é json }\n- [info/style] linha 1: espaço sobrando",
+			want: []models.CodeReviewFinding{{StartLine: 1, Severity: "info", Category: "style", Message: "espaço sobrando"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseStreamedCodeReview(tt.text)
+			if !reflect.DeepEqual(got.Findings, tt.want) {
+				t.Errorf("findings = %+v, want %+v", got.Findings, tt.want)
+			}
+			if got.Review == "" {
+				t.Error("review vazio")
+			}
+		})
+	}
+}
+
+// O texto gerado por codeReviewText é lido de volta sem perder os achados.
+func TestParseStreamedCodeReviewRoundTrip(t *testing.T) {
+	findings := []models.CodeReviewFinding{
+		{File: "main.go", StartLine: 10, EndLine: 12, Severity: "major", Category: "bug", Message: "erro ignorado", SuggestedFix: "tratar o erro"},
+		{StartLine: 5, Severity: "info", Category: "style", Message: "linha longa"},
+		{File: "Makefile", Severity: "minor", Category: "build", Message: "alvo sem .PHONY"},
+		{Severity: "minor", Category: "design", Message: "função grande demais"},
+	}
+	got := parseStreamedCodeReview(codeReviewText(findings))
+	if !reflect.DeepEqual(got.Findings, findings) {
+		t.Errorf("findings = %+v, want %+v", got.Findings, findings)
+	}
+}
@@ -2,28 +2,32 @@ package ai_services
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
-	"projeto-integrador/database"  // Para conectar ao PostgreSQL
-	"projeto-integrador/firebase"  // Onde GetFirestoreClient() está
-	"projeto-integrador/models"    // Onde todas as suas structs de modelo estão
+	"projeto-integrador/database" // Para conectar ao PostgreSQL
+	"projeto-integrador/firebase" // Onde GetFirestoreClient() está
+	"projeto-integrador/models"   // Onde todas as suas structs de modelo estão
+	"projeto-integrador/search_services"
+	"projeto-integrador/task_services"
 	"projeto-integrador/utilities" // Seu pacote de logging
 	"strconv"                      // Para converter int64 para string
+	"strings"
+	"time"
 
 	"cloud.google.com/go/firestore"  // Para firestore.Desc e outras funcionalidades do Firestore
 	"google.golang.org/api/iterator" // Para iterator.Done
 )
 
-const maxTasksForAIContext = 15        // Limite de tarefas para enviar no contexto da IA
 const tasksSubCollectionName = "tasks" // Nome da subcoleção de tarefas no Firestore
 
-// listTasksForAIContext busca tarefas do Firestore para um workspace específico,
-// formatando-as para o contexto da IA.
+// listTasksForAIContext busca as tarefas mais recentes do Firestore para um workspace
+// específico, candidatas ao contexto da IA (o ranqueamento escolhe quais são enviadas).
 // workspaceIDPg é o ID NUMÉRICO do workspace no PostgreSQL.
-func listTasksForAIContext(ctx context.Context, firestoreClient *firestore.Client, workspaceIDPg int64, limit int) ([]models.TarefaContext, error) {
+func listTasksForAIContext(ctx context.Context, firestoreClient *firestore.Client, workspaceIDPg int64, limit int) ([]contextCandidate, error) {
 	workspaceDocIDForFirestore := strconv.FormatInt(workspaceIDPg, 10)
 	fullPathToTasks := fmt.Sprintf("workspaces/%s/%s", workspaceDocIDForFirestore, tasksSubCollectionName)
 
-	utilities.LogDebug(fmt.Sprintf("listTasksForAIContext: Iniciando busca. Path: %s, OrderBy: 'last_updated_at' Desc, Limit: %d", fullPathToTasks, limit))
+	utilities.LogDebug("listTasksForAIContext: Iniciando busca. Path: %s, OrderBy: 'last_updated_at' Desc, Limit: %d", fullPathToTasks, limit)
 
 	iter := firestoreClient.Collection("workspaces").Doc(workspaceDocIDForFirestore).Collection(tasksSubCollectionName).
 		OrderBy("last_updated_at", firestore.Desc). // Certifique-se que este é o nome do campo no Firestore
@@ -31,12 +35,12 @@ func listTasksForAIContext(ctx context.Context, firestoreClient *firestore.Clien
 		Documents(ctx)
 	defer iter.Stop()
 
-	var tarefasCtx []models.TarefaContext
+	var candidates []contextCandidate
 	docCount := 0
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
-			utilities.LogDebug(fmt.Sprintf("listTasksForAIContext: Fim da iteração. Documentos processados: %d", docCount))
+			utilities.LogDebug("listTasksForAIContext: Fim da iteração. Documentos processados: %d", docCount)
 			break
 		}
 		if err != nil {
@@ -46,7 +50,7 @@ func listTasksForAIContext(ctx context.Context, firestoreClient *firestore.Clien
 		}
 
 		docCount++
-		utilities.LogDebug(fmt.Sprintf("listTasksForAIContext: Documento encontrado - ID: %s, Path: %s", doc.Ref.ID, doc.Ref.Path))
+		utilities.LogDebug("listTasksForAIContext: Documento encontrado - ID: %s, Path: %s", doc.Ref.ID, doc.Ref.Path)
 
 		var taskDetail models.TaskDetailsFirestore
 		if errDataTo := doc.DataTo(&taskDetail); errDataTo != nil {
@@ -56,29 +60,77 @@ func listTasksForAIContext(ctx context.Context, firestoreClient *firestore.Clien
 			continue // Pula esta tarefa, mas loga o problema
 		}
 
-		utilities.LogDebug(fmt.Sprintf("listTasksForAIContext: Tarefa convertida com sucesso - Título: %s, Status: %s", taskDetail.Title, taskDetail.Status))
-		tarefasCtx = append(tarefasCtx, models.TarefaContext{
-			Titulo:     taskDetail.Title,
-			Status:     taskDetail.Status,
-			Descricao:  taskDetail.Description, // Se necessário, pode ser uma descrição curta
-			Prioridade: taskDetail.Priority,
-		})
+		utilities.LogDebug("listTasksForAIContext: Tarefa convertida com sucesso - Título: %s, Status: %s", taskDetail.Title, taskDetail.Status)
+		candidates = append(candidates, contextCandidate{ID: doc.Ref.ID, Task: taskDetail})
 	}
 
-	if len(tarefasCtx) == 0 && docCount > 0 {
-		utilities.LogInfo(fmt.Sprintf("listTasksForAIContext: %d documentos foram iterados, mas a lista de TarefaContext está vazia. Verifique erros de DataTo.", docCount))
+	if len(candidates) == 0 && docCount > 0 {
+		utilities.LogInfo("listTasksForAIContext: %d documentos foram iterados, mas a lista de candidatas está vazia. Verifique erros de DataTo.", docCount)
 	}
-	utilities.LogDebug(fmt.Sprintf("listTasksForAIContext: Finalizado. %d tarefas formatadas para o contexto da IA para o workspace ID PG %d.", len(tarefasCtx), workspaceIDPg))
-	return tarefasCtx, nil
+	utilities.LogDebug("listTasksForAIContext: Finalizado. %d tarefas candidatas ao contexto da IA para o workspace ID PG %d.", len(candidates), workspaceIDPg)
+	return candidates, nil
+}
+
+// semanticContextCandidates consulta o índice de busca (search_services) para a
+// mensagem. Retorna a similaridade de cada tarefa e as candidatas acrescidas das até
+// maxExtra tarefas mais similares que ficaram fora da janela de tarefas recentes. Se o
+// índice não estiver disponível, o ranqueamento usa só o BM25.
+func semanticContextCandidates(ctx context.Context, db *sql.DB, client *firestore.Client, workspaceIDPg int64, userMessage string, candidates []contextCandidate, maxExtra int) (map[string]float64, []contextCandidate) {
+	if strings.TrimSpace(userMessage) == "" {
+		return nil, candidates
+	}
+	similarities, err := search_services.Similarities(ctx, db, client, workspaceIDPg, userMessage, 0)
+	if err != nil {
+		utilities.LogInfo("GetContextForIA: Índice de busca indisponível para o workspace %d, usando apenas BM25: %v", workspaceIDPg, err)
+		return nil, candidates
+	}
+
+	semantic := make(map[string]float64, len(similarities))
+	for _, similarity := range similarities {
+		semantic[similarity.TaskDocID] = similarity.Score
+	}
+	known := make(map[string]bool, len(candidates))
+	for _, candidate := range candidates {
+		known[candidate.ID] = true
+	}
+	var extraRefs []*firestore.DocumentRef
+	for _, similarity := range similarities {
+		if len(extraRefs) >= maxExtra {
+			break
+		}
+		if !known[similarity.TaskDocID] {
+			extraRefs = append(extraRefs, task_services.TaskRef(client, workspaceIDPg, similarity.TaskDocID))
+		}
+	}
+	if len(extraRefs) == 0 {
+		return semantic, candidates
+	}
+	snaps, err := client.GetAll(ctx, extraRefs)
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("GetContextForIA: Erro ao buscar tarefas similares do workspace %d", workspaceIDPg))
+		return semantic, candidates
+	}
+	for _, snap := range snaps {
+		var task models.TaskDetailsFirestore
+		if !snap.Exists() || snap.DataTo(&task) != nil {
+			continue
+		}
+		candidates = append(candidates, contextCandidate{ID: snap.Ref.ID, Task: task})
+	}
+	utilities.LogDebug("GetContextForIA: %d tarefas similares à mensagem acrescentadas às candidatas.", len(candidates)-len(known))
+	return semantic, candidates
 }
 
 // GetContextForIA busca e formata os dados de um workspace para a IA.
 // workspaceIDPg é o ID numérico do workspace no PostgreSQL.
-// userMessage é a mensagem/prompt atual do usuário.
-func GetContextForIA(workspaceIDPg int64, userMessage string) (*models.IAWorkspaceContext, error) {
+// userMessage é a mensagem/prompt atual do usuário, usada para escolher as tarefas
+// mais relevantes (veja rankTasksForAIContext). As tarefas escolhidas ficam em
+// TarefasIncluidas, para serem informadas na resposta. settings define se os nomes dos
+// membros, as descrições e os comentários das tarefas entram no contexto.
+func GetContextForIA(workspaceIDPg int64, userMessage string, settings models.WorkspaceAISettings) (*models.IAWorkspaceContext, error) {
 	ctx := context.Background() // Use um contexto apropriado para suas chamadas
 
-	utilities.LogDebug(fmt.Sprintf("GetContextForIA: Montando contexto para workspace ID PG: %d, Mensagem: '%s'", workspaceIDPg, userMessage))
+	utilities.LogDebug("GetContextForIA: Montando contexto para workspace ID PG: %d, Mensagem: '%s'", workspaceIDPg, userMessage)
 
 	db, err := database.ConnectPostgres()
 	if err != nil {
@@ -93,7 +145,7 @@ func GetContextForIA(workspaceIDPg int64, userMessage string) (*models.IAWorkspa
 		utilities.LogError(err, fmt.Sprintf("GetContextForIA: Erro ao buscar info do workspace %d do PG", workspaceIDPg))
 		return nil, err
 	}
-	utilities.LogDebug(fmt.Sprintf("GetContextForIA: Informações do workspace '%s' obtidas do PG.", wsInfo.Name))
+	utilities.LogDebug("GetContextForIA: Informações do workspace '%s' obtidas do PG.", wsInfo.Name)
 
 	// 2. Buscar membros do Workspace do PostgreSQL
 	wsMembersModels, err := models.ListWorkspaceMembers(db, workspaceIDPg) // Retorna []models.WorkspaceMember
@@ -107,8 +159,11 @@ func GetContextForIA(workspaceIDPg int64, userMessage string) (*models.IAWorkspa
 			Nome: member.DisplayName,
 			Role: member.Role,
 		}
+		if !settings.IncludeMemberNames {
+			usuariosCtx[i].Nome = fmt.Sprintf("Membro %d", i+1)
+		}
 	}
-	utilities.LogDebug(fmt.Sprintf("GetContextForIA: %d membros do workspace formatados para o contexto.", len(usuariosCtx)))
+	utilities.LogDebug("GetContextForIA: %d membros do workspace formatados para o contexto.", len(usuariosCtx))
 
 	// 3. Buscar tarefas recentes/relevantes do Firestore
 	firestoreClient, err := firebase.GetFirestoreClient() // Assume que esta função está no pacote firebase
@@ -116,25 +171,45 @@ func GetContextForIA(workspaceIDPg int64, userMessage string) (*models.IAWorkspa
 		utilities.LogError(err, fmt.Sprintf("GetContextForIA: Erro ao obter cliente Firestore para workspace %d", workspaceIDPg))
 		return nil, err // Se não conseguir o cliente Firestore, não podemos buscar tarefas
 	}
+	defer firestoreClient.Close()
 
-	tarefasCtx, err := listTasksForAIContext(ctx, firestoreClient, workspaceIDPg, maxTasksForAIContext)
+	budget := aiContextBudgetFromEnv()
+	candidates, err := listTasksForAIContext(ctx, firestoreClient, workspaceIDPg, budget.Candidates)
 	if err != nil {
 		// Decidimos anteriormente não tratar isso como um erro fatal para o GetContextForIA,
 		// mas vamos logar o erro que veio de listTasksForAIContext.
-		utilities.LogInfo(fmt.Sprintf("GetContextForIA: Não foi possível buscar tarefas do Firestore para o contexto da IA para o workspace %d: %v. Continuando com lista de tarefas vazia.", workspaceIDPg, err))
-		tarefasCtx = []models.TarefaContext{} // Envia lista vazia se houve erro
+		utilities.LogInfo("GetContextForIA: Não foi possível buscar tarefas do Firestore para o contexto da IA para o workspace %d: %v. Continuando com lista de tarefas vazia.", workspaceIDPg, err)
+		candidates = nil // Envia lista vazia se houve erro
+	}
+	semantic, candidates := semanticContextCandidates(ctx, db, firestoreClient, workspaceIDPg, userMessage, candidates, budget.MaxTasks)
+	var loadComments func(taskID string) []string
+	if settings.IncludeComments {
+		loadComments = func(taskID string) []string {
+			comments, err := recentCommentsForAI(ctx, firestoreClient, workspaceIDPg, taskID)
+			if err != nil {
+				utilities.LogInfo("GetContextForIA: Não foi possível buscar comentários da tarefa %s: %v", taskID, err)
+			}
+			return comments
+		}
+	}
+	tarefasCtx, tarefasIncluidas := rankTasksForAIContext(userMessage, candidates, semantic, budget, settings.IncludeDescriptions, loadComments, time.Now())
+	utilities.LogDebug("GetContextForIA: %d de %d tarefas candidatas incluídas no contexto.", len(tarefasCtx), len(candidates))
+	descricaoGrupo := wsInfo.Description
+	if !settings.IncludeDescriptions {
+		descricaoGrupo = ""
 	}
-	utilities.LogDebug(fmt.Sprintf("GetContextForIA: %d tarefas obtidas do Firestore para o contexto.", len(tarefasCtx)))
 
 	contexto := &models.IAWorkspaceContext{
 		WorkspaceIDStr: strconv.FormatInt(workspaceIDPg, 10), // ID do workspace do PG como string
 		GrupoNome:      wsInfo.Name,
-		DescricaoGrupo: wsInfo.Description,
+		DescricaoGrupo: descricaoGrupo,
 		Usuarios:       usuariosCtx,
 		Tarefas:        tarefasCtx, // Aqui entram as tarefas buscadas do Firestore
 		MsgDoUsuario:   userMessage,
+
+		TarefasIncluidas: tarefasIncluidas,
 	}
 
-	utilities.LogDebug(fmt.Sprintf("GetContextForIA: Contexto final montado para workspace %d.", workspaceIDPg))
+	utilities.LogDebug("GetContextForIA: Contexto final montado para workspace %d.", workspaceIDPg)
 	return contexto, nil
 }
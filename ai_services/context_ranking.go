@@ -0,0 +1,262 @@
+package ai_services
+
+import (
+	"encoding/json"
+	"math"
+	"projeto-integrador/models"
+	"projeto-integrador/scheduler"
+	"projeto-integrador/search_services"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Pesos do ranqueamento das tarefas do contexto. A relevância combina o BM25 da tarefa
+// em relação à mensagem e a similaridade do índice de busca, cada um proporcional ao da
+// tarefa mais relevante; os bônus garantem
+// espaço para tarefas atrasadas, de prioridade alta e recentes mesmo quando a mensagem
+// não as menciona.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	relevanceContextWeight = 5.0 // Relevância da tarefa que melhor corresponde à mensagem
+	semanticRelevanceShare = 0.5 // Parte da relevância vinda do índice de busca, quando disponível
+
+	overdueContextBonus      = 3.0
+	highPriorityContextBonus = 1.5
+	recencyContextBonus      = 1.0 // Máximo, para a tarefa atualizada mais recentemente
+	completedContextPenalty  = 1.0
+
+	maxContextDescriptionLength = 400 // Caracteres da descrição de cada tarefa no contexto
+)
+
+// Motivos da inclusão de uma tarefa no contexto (models.ContextTaskRef.Reasons).
+const (
+	ContextReasonRelevant     = "relevant"
+	ContextReasonOverdue      = "overdue"
+	ContextReasonHighPriority = "high_priority"
+	ContextReasonRecent       = "recent"
+)
+
+// aiContextBudget limita as tarefas enviadas no contexto do assistente.
+type aiContextBudget struct {
+	MaxTasks   int // Tarefas no contexto, no máximo
+	MaxChars   int // Tamanho máximo das tarefas no contexto, em caracteres (JSON)
+	Candidates int // Tarefas mais recentes consideradas no ranqueamento
+}
+
+// aiContextBudgetFromEnv lê o orçamento do contexto.
+//
+// Variáveis de ambiente:
+//   - AI_CONTEXT_MAX_TASKS: tarefas no contexto, no máximo (padrão: 15)
+//   - AI_CONTEXT_MAX_CHARS: tamanho máximo das tarefas em caracteres (padrão: 12000, ~3000 tokens)
+//   - AI_CONTEXT_CANDIDATE_TASKS: tarefas mais recentes ranqueadas (padrão: 200)
+func aiContextBudgetFromEnv() aiContextBudget {
+	return aiContextBudget{
+		MaxTasks:   scheduler.IntFromEnv("AI_CONTEXT_MAX_TASKS", 15),
+		MaxChars:   scheduler.IntFromEnv("AI_CONTEXT_MAX_CHARS", 12000),
+		Candidates: scheduler.IntFromEnv("AI_CONTEXT_CANDIDATE_TASKS", 200),
+	}
+}
+
+// contextCandidate é uma tarefa do workspace candidata ao contexto.
+type contextCandidate struct {
+	ID   string
+	Task models.TaskDetailsFirestore
+}
+
+type rankedContextTask struct {
+	candidate contextCandidate
+	score     float64
+	reasons   []string
+}
+
+// rankTasksForAIContext escolhe as tarefas do contexto: ordena as candidatas (das mais
+// para as menos recentes) pela relevância em relação à mensagem mais os bônus, e inclui
+// as melhores enquanto couberem no orçamento. semantic é a similaridade de cada tarefa
+// com a mensagem no índice de busca (por ID; nil se o índice não está disponível).
+// O orçamento conta cada tarefa como ela vai para a IA: sem a descrição quando
+// includeDescriptions é false e com os comentários de loadComments (nil quando os
+// comentários não entram), que só são buscados para as tarefas que cabem e ficam de
+// fora se não couberem junto com a tarefa.
+// Retorna as tarefas, na ordem do ranking, e a identificação de cada uma para a resposta.
+func rankTasksForAIContext(message string, candidates []contextCandidate, semantic map[string]float64, budget aiContextBudget, includeDescriptions bool, loadComments func(taskID string) []string, now time.Time) ([]models.TarefaContext, []models.ContextTaskRef) {
+	lexical := normalizeScores(bm25Scores(search_services.QueryTerms(message), candidates))
+	relevance := lexical
+	if len(semantic) > 0 {
+		semanticScores := make([]float64, len(candidates))
+		for i, candidate := range candidates {
+			semanticScores[i] = semantic[candidate.ID]
+		}
+		semanticScores = normalizeScores(semanticScores)
+		relevance = make([]float64, len(candidates))
+		for i := range candidates {
+			relevance[i] = (1-semanticRelevanceShare)*lexical[i] + semanticRelevanceShare*semanticScores[i]
+		}
+	}
+
+	ranked := make([]rankedContextTask, len(candidates))
+	for i, candidate := range candidates {
+		task := candidate.Task
+		item := rankedContextTask{candidate: candidate}
+		if relevance[i] > 0 {
+			item.score = relevanceContextWeight * relevance[i]
+			item.reasons = append(item.reasons, ContextReasonRelevant)
+		}
+		closed := isTaskClosed(task)
+		if !closed && isTaskOverdue(task, now) {
+			item.score += overdueContextBonus
+			item.reasons = append(item.reasons, ContextReasonOverdue)
+		}
+		if !closed && task.Priority == "high" {
+			item.score += highPriorityContextBonus
+			item.reasons = append(item.reasons, ContextReasonHighPriority)
+		}
+		item.score += recencyContextBonus * (1 - float64(i)/float64(len(candidates)))
+		if len(item.reasons) == 0 {
+			item.reasons = append(item.reasons, ContextReasonRecent)
+		}
+		if closed {
+			item.score -= completedContextPenalty
+		}
+		ranked[i] = item
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+
+	tarefas := []models.TarefaContext{}
+	refs := []models.ContextTaskRef{}
+	usedChars := 0
+	for _, item := range ranked {
+		if len(tarefas) >= budget.MaxTasks {
+			break
+		}
+		tarefa := tarefaContextFromTask(item.candidate, now)
+		if !includeDescriptions {
+			tarefa.Descricao = ""
+		}
+		size := contextTaskSize(tarefa)
+		if usedChars+size > budget.MaxChars {
+			continue // Uma tarefa menor, mais abaixo no ranking, ainda pode caber
+		}
+		if loadComments != nil {
+			withComments := tarefa
+			withComments.Comentarios = loadComments(item.candidate.ID)
+			if commentsSize := contextTaskSize(withComments); usedChars+commentsSize <= budget.MaxChars {
+				tarefa, size = withComments, commentsSize
+			}
+		}
+		usedChars += size
+		tarefas = append(tarefas, tarefa)
+		refs = append(refs, models.ContextTaskRef{
+			TaskID:  item.candidate.ID,
+			Title:   item.candidate.Task.Title,
+			Reasons: item.reasons,
+			Score:   math.Round(item.score*100) / 100,
+		})
+	}
+	return tarefas, refs
+}
+
+// normalizeScores divide as pontuações pela maior, para ficarem entre 0 e 1.
+func normalizeScores(scores []float64) []float64 {
+	maxScore := 0.0
+	for _, score := range scores {
+		maxScore = math.Max(maxScore, score)
+	}
+	if maxScore == 0 {
+		return scores
+	}
+	normalized := make([]float64, len(scores))
+	for i, score := range scores {
+		normalized[i] = score / maxScore
+	}
+	return normalized
+}
+
+func isTaskOverdue(task models.TaskDetailsFirestore, now time.Time) bool {
+	return task.IsOverdue || (task.ExpirationDate != nil && task.ExpirationDate.Before(now))
+}
+
+// isTaskClosed diz se a tarefa foi concluída ou é uma ocorrência pulada de uma série
+// recorrente.
+func isTaskClosed(task models.TaskDetailsFirestore) bool {
+	return task.Status == "completed" || task.Status == "skipped"
+}
+
+func tarefaContextFromTask(candidate contextCandidate, now time.Time) models.TarefaContext {
+	task := candidate.Task
+	tarefa := models.TarefaContext{
+		ID:         candidate.ID,
+		Titulo:     task.Title,
+		Status:     task.Status,
+		Prioridade: task.Priority,
+		Descricao:  truncateRunes(strings.TrimSpace(task.Description), maxContextDescriptionLength),
+		Atrasada:   !isTaskClosed(task) && isTaskOverdue(task, now),
+	}
+	if task.ExpirationDate != nil {
+		tarefa.DataVencimento = task.ExpirationDate.UTC().Format("2006-01-02")
+	}
+	return tarefa
+}
+
+// contextTaskSize é o tamanho da tarefa no JSON enviado à IA.
+func contextTaskSize(tarefa models.TarefaContext) int {
+	data, err := json.Marshal(tarefa)
+	if err != nil {
+		return 0
+	}
+	return len(data) + 1 // Vírgula separadora
+}
+
+// bm25Scores calcula o BM25 de cada candidata para os termos da mensagem. O título
+// conta em dobro; descrição, etiquetas e checklist também entram no documento.
+func bm25Scores(terms []string, candidates []contextCandidate) []float64 {
+	scores := make([]float64, len(candidates))
+	if len(terms) == 0 || len(candidates) == 0 {
+		return scores
+	}
+
+	docs := make([]map[string]int, len(candidates))
+	lengths := make([]int, len(candidates))
+	documentFrequency := make(map[string]int)
+	totalLength := 0
+	for i, candidate := range candidates {
+		task := candidate.Task
+		parts := []string{task.Title, task.Title, task.Description, strings.Join(task.Labels, " ")}
+		for _, item := range task.Checklist {
+			parts = append(parts, item.Text)
+		}
+		docs[i] = make(map[string]int)
+		for _, term := range search_services.Tokenize(strings.Join(parts, " ")) {
+			docs[i][term]++
+			lengths[i]++
+		}
+		for term := range docs[i] {
+			documentFrequency[term]++
+		}
+		totalLength += lengths[i]
+	}
+	averageLength := float64(totalLength) / float64(len(candidates))
+	if averageLength == 0 {
+		return scores
+	}
+
+	n := float64(len(candidates))
+	for _, term := range terms {
+		df := float64(documentFrequency[term])
+		if df == 0 {
+			continue
+		}
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for i, doc := range docs {
+			tf := float64(doc[term])
+			if tf == 0 {
+				continue
+			}
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(lengths[i])/averageLength)
+			scores[i] += idf * tf * (bm25K1 + 1) / (tf + norm)
+		}
+	}
+	return scores
+}
@@ -0,0 +1,80 @@
+package ai_services
+
+import (
+	"projeto-integrador/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func contextCandidates(n int, description string) []contextCandidate {
+	candidates := make([]contextCandidate, n)
+	for i := range candidates {
+		candidates[i] = contextCandidate{ID: "t" + string(rune('a'+i)), Task: models.TaskDetailsFirestore{Title: "Tarefa", Status: "pending", Description: description}}
+	}
+	return candidates
+}
+
+func contextSize(tarefas []models.TarefaContext) int {
+	size := 0
+	for _, tarefa := range tarefas {
+		size += contextTaskSize(tarefa)
+	}
+	return size
+}
+
+// Sem include_descriptions, a descrição não ocupa o orçamento: cabem mais tarefas.
+func TestRankTasksForAIContextBudgetWithoutDescriptions(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	candidates := contextCandidates(10, strings.Repeat("descrição longa ", 20))
+	budget := aiContextBudget{MaxTasks: 10, MaxChars: 1200}
+
+	withDescriptions, _ := rankTasksForAIContext("", candidates, nil, budget, true, nil, now)
+	withoutDescriptions, refs := rankTasksForAIContext("", candidates, nil, budget, false, nil, now)
+	if len(withoutDescriptions) <= len(withDescriptions) {
+		t.Errorf("tarefas sem descrição = %d, com descrição = %d", len(withoutDescriptions), len(withDescriptions))
+	}
+	if len(refs) != len(withoutDescriptions) {
+		t.Errorf("refs = %d, tarefas = %d", len(refs), len(withoutDescriptions))
+	}
+	for _, tarefa := range withoutDescriptions {
+		if tarefa.Descricao != "" {
+			t.Errorf("descrição enviada sem include_descriptions: %q", tarefa.Descricao)
+		}
+	}
+	if size := contextSize(withDescriptions); size > budget.MaxChars {
+		t.Errorf("contexto com %d caracteres, orçamento %d", size, budget.MaxChars)
+	}
+}
+
+// Os comentários contam no orçamento e só são buscados para as tarefas que cabem.
+func TestRankTasksForAIContextBudgetWithComments(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	candidates := contextCandidates(15, "")
+	budget := aiContextBudget{MaxTasks: 15, MaxChars: 2000}
+	loaded := 0
+	loadComments := func(taskID string) []string {
+		loaded++
+		return []string{strings.Repeat("a", 200), strings.Repeat("b", 200), strings.Repeat("c", 200)}
+	}
+
+	tarefas, _ := rankTasksForAIContext("", candidates, nil, budget, true, loadComments, now)
+	if size := contextSize(tarefas); size > budget.MaxChars {
+		t.Errorf("contexto com %d caracteres, orçamento %d", size, budget.MaxChars)
+	}
+	if len(tarefas) != len(candidates) {
+		t.Errorf("tarefas = %d, want %d (as que não cabem com comentários entram sem eles)", len(tarefas), len(candidates))
+	}
+	if loaded > len(tarefas) {
+		t.Errorf("comentários buscados para %d tarefas, %d incluídas", loaded, len(tarefas))
+	}
+	withComments := 0
+	for _, tarefa := range tarefas {
+		if len(tarefa.Comentarios) > 0 {
+			withComments++
+		}
+	}
+	if withComments == 0 || withComments == len(tarefas) {
+		t.Errorf("%d de %d tarefas com comentários", withComments, len(tarefas))
+	}
+}
@@ -0,0 +1,424 @@
+package ai_services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"projeto-integrador/firebase"
+	"projeto-integrador/models"
+	"projeto-integrador/task_services"
+	"projeto-integrador/utilities"
+	"strings"
+	"time"
+)
+
+// Tipos de serviço de IA, usados no histórico e nos jobs assíncronos.
+const (
+	ServiceCodeReview    = "code_review"
+	ServiceTextSummary   = "text_summary"
+	ServiceMindMapIdeas  = "mindmap_ideas"
+	ServiceTaskAssistant = "task_assistant"
+	ServiceTaskBreakdown = "task_breakdown"
+	ServiceTaskSummary   = "task_summary"
+)
+
+var (
+	// ErrInvalidAIInput indica uma entrada do frontend inválida para o serviço.
+	ErrInvalidAIInput = errors.New("invalid AI request")
+	// ErrAIContextUnavailable indica falha ao carregar os dados do workspace para a IA.
+	ErrAIContextUnavailable = errors.New("failed to load workspace data for AI")
+)
+
+// Origem de uma execução de IA (AIExecution.Source), registrada no histórico.
+const (
+	AISourceSync   = "sync"   // Rota síncrona
+	AISourceStream = "stream" // Variante SSE
+	AISourceJob    = "job"    // Job assíncrono
+	AISourceReport = "report" // Resumo do relatório de status
+)
+
+// AIExecution é o resultado de uma requisição de IA executada.
+type AIExecution struct {
+	ServiceType string
+	Source      string      // "sync", "stream", "job" ou "report"
+	Provider    string      // Nome do provedor (AI_PROVIDER)
+	Endpoint    string      // Endpoint chamado (ex: "/code-review"); o nome do provedor se não for HTTP
+	Input       interface{} // Entrada do frontend, já validada
+	RequestToAI interface{} // Payload enviado ao provedor
+	Response    interface{} // Resposta tipada do provedor (apenas em caso de sucesso)
+	Result      AICallResult
+	Latency     time.Duration        // Duração da chamada ao provedor, com as novas tentativas
+	Redactions  []models.AIRedaction // Valores mascarados em RequestToAI
+	CacheHit    bool                 // Resposta servida pelo cache, sem chamar o provedor
+}
+
+// aiEndpoint identifica o endpoint chamado para o histórico.
+func aiEndpoint(providerName string, serviceType string, streaming bool) string {
+	path, ok := aiPathsByService[serviceType]
+	if providerName != ProviderHTTP || !ok {
+		return providerName
+	}
+	if streaming {
+		return path + "/stream"
+	}
+	return path
+}
+
+// DecodeAIInput lê e valida a entrada do frontend para o serviço. Usado tanto nas
+// requisições síncronas quanto ao executar um job, a partir do payload salvo.
+func DecodeAIInput(serviceType string, raw []byte) (interface{}, error) {
+	switch serviceType {
+	case ServiceCodeReview:
+		var input models.CodeReviewAIRequest
+		if err := json.Unmarshal(raw, &input); err != nil {
+			return nil, fmt.Errorf("%w: corpo da requisição inválido", ErrInvalidAIInput)
+		}
+		return prepareCodeReviewInput(input)
+	case ServiceTextSummary, ServiceMindMapIdeas:
+		var input struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(raw, &input); err != nil {
+			return nil, fmt.Errorf("%w: corpo da requisição inválido", ErrInvalidAIInput)
+		}
+		if strings.TrimSpace(input.Text) == "" {
+			return nil, fmt.Errorf("%w: text is required", ErrInvalidAIInput)
+		}
+		if serviceType == ServiceTextSummary {
+			return models.SummarizeTextAIRequest{Text: input.Text}, nil
+		}
+		return models.MindMapIdeasAIRequest{Text: input.Text}, nil
+	case ServiceTaskAssistant:
+		var input models.TaskAssistantUserInput
+		if err := json.Unmarshal(raw, &input); err != nil {
+			return nil, fmt.Errorf("%w: corpo da requisição inválido", ErrInvalidAIInput)
+		}
+		if strings.TrimSpace(input.UserMessage) == "" {
+			return nil, fmt.Errorf("%w: user_message is required", ErrInvalidAIInput)
+		}
+		return input, nil
+	case ServiceTaskBreakdown:
+		var input models.TaskBreakdownUserInput
+		if err := json.Unmarshal(raw, &input); err != nil {
+			return nil, fmt.Errorf("%w: corpo da requisição inválido", ErrInvalidAIInput)
+		}
+		if strings.TrimSpace(input.TaskDocID) == "" {
+			return nil, fmt.Errorf("%w: task_doc_id is required", ErrInvalidAIInput)
+		}
+		if input.MaxSubtasks < 0 || input.MaxSubtasks > maxSubtasksPerBreakdown {
+			return nil, fmt.Errorf("%w: max_subtasks must be between 1 and %d", ErrInvalidAIInput, maxSubtasksPerBreakdown)
+		}
+		if input.MaxSubtasks == 0 {
+			input.MaxSubtasks = defaultSubtasksPerBreakdown
+		}
+		return input, nil
+	case ServiceTaskSummary:
+		var input models.TaskSummaryUserInput
+		if err := json.Unmarshal(raw, &input); err != nil {
+			return nil, fmt.Errorf("%w: corpo da requisição inválido", ErrInvalidAIInput)
+		}
+		if strings.TrimSpace(input.TaskDocID) == "" {
+			return nil, fmt.Errorf("%w: task_doc_id is required", ErrInvalidAIInput)
+		}
+		return input, nil
+	}
+	return nil, fmt.Errorf("%w: serviço de IA desconhecido %q", ErrInvalidAIInput, serviceType)
+}
+
+// ExecuteAIRequest monta o payload do serviço a partir da entrada validada por
+// DecodeAIInput e chama o provedor, ou usa a resposta em cache para o mesmo payload
+// normalizado (exceto com opts.NoCache). Em caso de erro, a execução retornada ainda
+// traz o resultado da chamada (status e corpo) para a resposta ao cliente.
+func ExecuteAIRequest(ctx context.Context, provider AIProvider, workspaceIDPg int64, serviceType string, input interface{}, opts AIRequestOptions) (*AIExecution, error) {
+	exec := &AIExecution{
+		ServiceType: serviceType,
+		Source:      AISourceSync,
+		Provider:    provider.Name(),
+		Endpoint:    aiEndpoint(provider.Name(), serviceType, false),
+		Input:       input,
+	}
+	requestToAI, redactor, err := prepareRequestToAI(ctx, workspaceIDPg, serviceType, input)
+	if err != nil {
+		return exec, err
+	}
+	exec.RequestToAI, exec.Redactions = requestToAI, redactor.Redactions()
+
+	start := time.Now()
+	cache, cacheKey := aiCacheFor(provider, serviceType, requestToAI)
+	if cache != nil && !opts.NoCache {
+		if cached, ok := cache.Get(serviceType, cacheKey); ok {
+			exec.Response = markCached(redactor.Restore(cached))
+			exec.Result = AICallResult{StatusCode: http.StatusOK}
+			exec.Latency = time.Since(start)
+			exec.CacheHit = true
+			return exec, nil
+		}
+	}
+
+	exec.Response, exec.Result, err = callProvider(ctx, provider, requestToAI)
+	exec.Latency = time.Since(start)
+	if err == nil && (exec.Result.StatusCode < 200 || exec.Result.StatusCode >= 300 || exec.Response == nil) {
+		err = fmt.Errorf("provedor de IA retornou status %d", exec.Result.StatusCode)
+	}
+	if err == nil && cache != nil {
+		cache.Set(serviceType, cacheKey, exec.Response)
+	}
+	exec.Response = redactor.Restore(exec.Response)
+	return exec, err
+}
+
+// aiCacheFor retorna o cache e a chave da requisição, ou nil se o cache estiver
+// desligado para o serviço.
+func aiCacheFor(provider AIProvider, serviceType string, requestToAI interface{}) (*AIResponseCache, string) {
+	cache := GetAIResponseCache()
+	if cache.TTL(serviceType) <= 0 {
+		return nil, ""
+	}
+	key, err := AICacheKey(provider.Name(), serviceType, requestToAI)
+	if err != nil {
+		utilities.LogError(err, "aiCacheFor: Erro ao calcular chave do cache")
+		return nil, ""
+	}
+	return cache, key
+}
+
+// prepareRequestToAI confere se o workspace permite o serviço, monta o payload com as
+// preferências do workspace e mascara os dados sensíveis. O Redactor retornado restaura
+// os valores originais na resposta. Sem as configurações de IA ou as regras de
+// mascaramento do workspace, a requisição não é enviada.
+func prepareRequestToAI(ctx context.Context, workspaceIDPg int64, serviceType string, input interface{}) (interface{}, *Redactor, error) {
+	settings, err := LoadWorkspaceAISettings(workspaceIDPg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrAIContextUnavailable, err)
+	}
+	if err := CheckAIServiceEnabled(settings, serviceType); err != nil {
+		return nil, nil, err
+	}
+	requestToAI, err := buildAIRequest(ctx, workspaceIDPg, input, settings)
+	if err != nil {
+		return nil, nil, err
+	}
+	redactor, err := LoadWorkspaceRedactor(workspaceIDPg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrAIContextUnavailable, err)
+	}
+	return redactor.Redact(withAIPreferences(requestToAI, settings)), redactor, nil
+}
+
+// buildAIRequest converte a entrada do frontend no payload enviado ao provedor. Para o
+// assistente de tarefas, carrega o contexto do workspace e, se a mensagem continua uma
+// conversa, as trocas anteriores.
+func buildAIRequest(ctx context.Context, workspaceIDPg int64, input interface{}, settings models.WorkspaceAISettings) (interface{}, error) {
+	switch in := input.(type) {
+	case models.CodeReviewAIRequest:
+		return models.CodeReviewAIRequest{Code: in.Code, Language: in.Language, Filename: in.Filename, Diff: in.Diff, Files: in.Files}, nil
+	case models.SummarizeTextAIRequest:
+		return models.SummarizeTextAIRequest{Text: in.Text}, nil
+	case models.MindMapIdeasAIRequest:
+		return models.MindMapIdeasAIRequest{Text: in.Text}, nil
+	case models.TaskAssistantUserInput:
+		workspaceContext, err := GetContextForIA(workspaceIDPg, in.UserMessage, settings)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrAIContextUnavailable, err)
+		}
+		if in.ThreadID != "" {
+			firestoreClient, err := firebase.GetFirestoreClient()
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrAIContextUnavailable, err)
+			}
+			defer firestoreClient.Close()
+			workspaceContext.Historico, err = threadHistoryForAI(ctx, firestoreClient, workspaceIDPg, in.ThreadID)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrAIContextUnavailable, err)
+			}
+		}
+		return models.TaskAssistantAIRequest{WorkspaceContext: *workspaceContext}, nil
+	case models.TaskBreakdownUserInput:
+		request, err := buildTaskBreakdownRequest(ctx, workspaceIDPg, in, settings)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrAIContextUnavailable, err)
+		}
+		return *request, nil
+	case models.TaskSummaryUserInput:
+		request, err := buildTaskSummaryRequest(ctx, workspaceIDPg, in, settings)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrAIContextUnavailable, err)
+		}
+		return *request, nil
+	}
+	return nil, fmt.Errorf("%w: entrada %T não suportada", ErrInvalidAIInput, input)
+}
+
+// callProvider chama o método do provedor correspondente ao payload. A resposta é
+// retornada por valor (ex: models.CodeReviewAIResponse), ou nil em caso de falha.
+func callProvider(ctx context.Context, provider AIProvider, requestToAI interface{}) (interface{}, AICallResult, error) {
+	switch req := requestToAI.(type) {
+	case models.CodeReviewAIRequest:
+		resp, result, err := provider.CodeReview(ctx, req)
+		if resp == nil {
+			return nil, result, err
+		}
+		return normalizeCodeReviewResponse(*resp, req), result, err
+	case models.SummarizeTextAIRequest:
+		resp, result, err := provider.Summarize(ctx, req)
+		if resp == nil {
+			return nil, result, err
+		}
+		return *resp, result, err
+	case models.MindMapIdeasAIRequest:
+		resp, result, err := provider.MindMapIdeas(ctx, req)
+		if resp == nil {
+			return nil, result, err
+		}
+		return normalizeMindMapResponse(*resp, req.Text), result, err
+	case models.TaskAssistantAIRequest:
+		resp, result, err := provider.TaskAssistant(ctx, req)
+		if resp == nil {
+			return nil, result, err
+		}
+		return withContextTasks(normalizeTaskAssistantResponse(*resp), req), result, err
+	case models.TaskBreakdownAIRequest:
+		resp, result, err := provider.BreakdownTask(ctx, req)
+		if resp == nil {
+			return nil, result, err
+		}
+		resp.ParentTaskID = req.Tarefa.ID
+		return normalizeTaskBreakdownResponse(*resp, req.MaxSubtarefas), result, err
+	case models.TaskSummaryAIRequest:
+		// O serviço de IA resume o texto montado pelo backend, pelo mesmo endpoint do resumo de texto
+		resp, result, err := provider.Summarize(ctx, models.SummarizeTextAIRequest{Text: req.Text, AIPreferences: req.AIPreferences})
+		if resp == nil {
+			return nil, result, err
+		}
+		return models.TaskSummaryAIResponse{Summary: resp.Summary, Error: resp.Error, TaskDocID: req.TaskDocID}, result, err
+	}
+	return nil, AICallResult{}, fmt.Errorf("%w: payload %T não suportado", ErrInvalidAIInput, requestToAI)
+}
+
+// withContextTasks informa na resposta do assistente quais tarefas do workspace a IA
+// recebeu no contexto.
+func withContextTasks(resp models.TaskAssistantAIResponse, req models.TaskAssistantAIRequest) models.TaskAssistantAIResponse {
+	resp.ContextTasks = req.WorkspaceContext.TarefasIncluidas
+	return resp
+}
+
+// RecordAIInteraction registra a execução no ai_request_history, com sucesso ou falha,
+// e, quando a mensagem do assistente de tarefas continua uma conversa, grava a troca na
+// conversa. Em falhas sem resposta tipada, o corpo devolvido pelo provedor é registrado.
+// Requisições recusadas pelas configurações de IA do workspace não são registradas.
+// Retorna o ID do registro no histórico.
+func RecordAIInteraction(ctx context.Context, userID string, workspaceIDPg int64, exec *AIExecution, errAI error) string {
+	if errors.Is(errAI, ErrAIFeatureDisabled) {
+		return ""
+	}
+	historyResponse := exec.Response
+	if historyResponse == nil && len(exec.Result.RawResponse) > 0 {
+		historyResponse = rawAIResponseForHistory(exec.Result.RawResponse)
+	}
+	metrics := AICallMetrics{
+		Source:     exec.Source,
+		Provider:   exec.Provider,
+		Endpoint:   exec.Endpoint,
+		Latency:    exec.Latency,
+		Attempts:   exec.Result.Attempts,
+		ErrorClass: ClassifyAIError(exec.Result, errAI),
+		Redactions: exec.Redactions,
+		CacheHit:   exec.CacheHit,
+	}
+	historyID := LogAIInteraction(ctx, userID, workspaceIDPg, exec.ServiceType,
+		exec.Input, exec.RequestToAI, historyResponse, exec.Result.StatusCode, errAI, metrics)
+
+	input, isAssistant := exec.Input.(models.TaskAssistantUserInput)
+	response, hasResponse := exec.Response.(models.TaskAssistantAIResponse)
+	if errAI != nil || !isAssistant || !hasResponse || input.ThreadID == "" {
+		return historyID
+	}
+
+	firestoreClient, err := firebase.GetFirestoreClient()
+	if err != nil {
+		utilities.LogError(err, "RecordAIInteraction: Falha ao obter cliente Firestore")
+		return historyID
+	}
+	defer firestoreClient.Close()
+	turn := models.AIThreadTurn{
+		UserMessage:     input.UserMessage,
+		Suggestions:     response.Suggestions,
+		TaskSuggestions: response.TaskSuggestions,
+		HistoryID:       historyID,
+	}
+	if err := AppendAIThreadTurn(ctx, firestoreClient, workspaceIDPg, input.ThreadID, turn); err != nil {
+		utilities.LogError(err, fmt.Sprintf("RecordAIInteraction: Falha ao gravar mensagem na conversa %s do workspace %d", input.ThreadID, workspaceIDPg))
+	}
+	return historyID
+}
+
+// FinalizeAIResponse prepara a resposta bem-sucedida para o cliente, depois de
+// registrada no histórico: inclui o ID do registro nas respostas da revisão de código,
+// do assistente de tarefas e da decomposição (para anexar a revisão ou criar as tarefas
+// sugeridas), salva a árvore do mapa mental como artefato do workspace e grava o resumo
+// da tarefa na própria tarefa. Outras respostas são devolvidas sem alteração.
+func FinalizeAIResponse(ctx context.Context, userID string, workspaceIDPg int64, response interface{}, historyID string) interface{} {
+	switch resp := response.(type) {
+	case models.CodeReviewAIResponse:
+		resp.HistoryID = historyID
+		return resp
+	case models.TaskAssistantAIResponse:
+		resp.HistoryID = historyID
+		return resp
+	case models.TaskBreakdownAIResponse:
+		resp.HistoryID = historyID
+		return resp
+	case models.MindMapIdeasAIResponse:
+		resp.HistoryID = historyID
+		if resp.Tree == nil || resp.MindMapID != "" {
+			return resp
+		}
+		firestoreClient, err := firebase.GetFirestoreClient()
+		if err != nil {
+			utilities.LogError(err, "FinalizeAIResponse: Falha ao obter cliente Firestore")
+			return resp
+		}
+		defer firestoreClient.Close()
+		mindMap, err := CreateMindMap(ctx, firestoreClient, workspaceIDPg, userID, "", *resp.Tree, historyID)
+		if err != nil {
+			utilities.LogError(err, fmt.Sprintf("FinalizeAIResponse: Falha ao salvar mapa mental no workspace %d", workspaceIDPg))
+			return resp
+		}
+		resp.MindMapID = mindMap.ID
+		return resp
+	case models.TaskSummaryAIResponse:
+		resp.HistoryID = historyID
+		if strings.TrimSpace(resp.Summary) == "" {
+			return resp
+		}
+		firestoreClient, err := firebase.GetFirestoreClient()
+		if err != nil {
+			utilities.LogError(err, "FinalizeAIResponse: Falha ao obter cliente Firestore")
+			return resp
+		}
+		defer firestoreClient.Close()
+		summary := models.TaskAISummary{Summary: resp.Summary, HistoryID: historyID, GeneratedBy: userID, GeneratedAt: time.Now()}
+		if err := task_services.SaveTaskAISummary(ctx, firestoreClient, workspaceIDPg, resp.TaskDocID, summary); err != nil {
+			utilities.LogError(err, fmt.Sprintf("FinalizeAIResponse: Falha ao salvar resumo na tarefa %s do workspace %d", resp.TaskDocID, workspaceIDPg))
+		}
+		return resp
+	}
+	return response
+}
+
+// AIErrorMessage extrai a mensagem de erro mais útil de uma chamada que falhou: o
+// campo "error" devolvido pelo serviço, se houver, ou o próprio erro.
+func AIErrorMessage(result AICallResult, errAI error) string {
+	var structuredError struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(result.RawResponse, &structuredError) == nil && structuredError.Error != "" {
+		return structuredError.Error
+	}
+	if errAI != nil {
+		return errAI.Error()
+	}
+	return string(result.RawResponse)
+}
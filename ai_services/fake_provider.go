@@ -0,0 +1,209 @@
+package ai_services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"projeto-integrador/models"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// FakeErrorMarker, presente no texto/código/mensagem enviado, faz o provedor fake
+// responder como uma falha do serviço (500), para exercitar os caminhos de erro.
+const FakeErrorMarker = "#fake-error"
+
+// FakeProvider gera respostas determinísticas a partir da entrada, sem rede.
+// A mesma entrada sempre produz a mesma saída.
+type FakeProvider struct{}
+
+// NewFakeProvider cria o provedor fake.
+func NewFakeProvider() *FakeProvider { return &FakeProvider{} }
+
+func (p *FakeProvider) Name() string { return ProviderFake }
+
+func (p *FakeProvider) Summarize(ctx context.Context, req models.SummarizeTextAIRequest) (*models.SummarizeTextAIResponse, AICallResult, error) {
+	if strings.Contains(req.Text, FakeErrorMarker) {
+		return nil, fakeFailure(models.SummarizeTextAIResponse{Error: "fake provider failure"}), errFakeFailure
+	}
+	resp := models.SummarizeTextAIResponse{Summary: firstWords(req.Text, 30)}
+	return &resp, fakeSuccess(resp), nil
+}
+
+func (p *FakeProvider) CodeReview(ctx context.Context, req models.CodeReviewAIRequest) (*models.CodeReviewAIResponse, AICallResult, error) {
+	if strings.Contains(req.Code, FakeErrorMarker) || strings.Contains(req.Diff, FakeErrorMarker) {
+		return nil, fakeFailure(models.CodeReviewAIResponse{Error: "fake provider failure"}), errFakeFailure
+	}
+	// Em diffs, apenas as linhas adicionadas são revisadas.
+	files := []diffFile{{Path: req.Filename}}
+	if req.Diff != "" {
+		files, _ = parseUnifiedDiff(req.Diff)
+	} else {
+		for i, line := range strings.Split(strings.TrimRight(req.Code, "\n"), "\n") {
+			files[0].Added = append(files[0].Added, diffLine{Number: i + 1, Text: line})
+		}
+	}
+
+	lines := 0
+	findings := []models.CodeReviewFinding{}
+	for _, file := range files {
+		lines += len(file.Added)
+		for _, line := range file.Added {
+			lower := strings.ToLower(line.Text)
+			switch {
+			case strings.Contains(line.Text, "TODO"):
+				findings = append(findings, models.CodeReviewFinding{File: file.Path, StartLine: line.Number, Severity: SeverityMinor, Category: "maintainability",
+					Message: "Comentário TODO pendente.", SuggestedFix: "Resolva o TODO ou registre-o como tarefa."})
+			case (strings.Contains(lower, "password") || strings.Contains(lower, "secret")) && strings.Contains(line.Text, "=") && strings.Contains(line.Text, "\""):
+				findings = append(findings, models.CodeReviewFinding{File: file.Path, StartLine: line.Number, Severity: SeverityCritical, Category: "security",
+					Message: "Possível credencial escrita no código.", SuggestedFix: "Leia o valor de uma variável de ambiente ou de um cofre de segredos."})
+			case len([]rune(line.Text)) > 120:
+				findings = append(findings, models.CodeReviewFinding{File: file.Path, StartLine: line.Number, Severity: SeverityInfo, Category: "style",
+					Message: "Linha com mais de 120 caracteres."})
+			}
+		}
+	}
+	if lines > 50 {
+		findings = append(findings, models.CodeReviewFinding{Severity: SeverityInfo, Category: "maintainability",
+			Message: "Considere dividir o código em funções menores."})
+	}
+
+	resp := models.CodeReviewAIResponse{Findings: findings, Language: req.Language}
+	resp.Review = fmt.Sprintf("Revisão (%s, %d linhas):\n%s", req.Language, lines, codeReviewText(normalizeCodeReviewResponse(resp, req).Findings))
+	return &resp, fakeSuccess(resp), nil
+}
+
+func (p *FakeProvider) MindMapIdeas(ctx context.Context, req models.MindMapIdeasAIRequest) (*models.MindMapIdeasAIResponse, AICallResult, error) {
+	if strings.Contains(req.Text, FakeErrorMarker) {
+		return nil, fakeFailure(models.MindMapIdeasAIResponse{Error: "fake provider failure"}), errFakeFailure
+	}
+	var b strings.Builder
+	b.WriteString("- " + firstWords(req.Text, 6) + "\n")
+	for _, keyword := range topKeywords(req.Text, 5) {
+		b.WriteString("  - " + keyword + "\n")
+	}
+	resp := models.MindMapIdeasAIResponse{MindMapIdeas: strings.TrimRight(b.String(), "\n")}
+	return &resp, fakeSuccess(resp), nil
+}
+
+func (p *FakeProvider) TaskAssistant(ctx context.Context, req models.TaskAssistantAIRequest) (*models.TaskAssistantAIResponse, AICallResult, error) {
+	wc := req.WorkspaceContext
+	if strings.Contains(wc.MsgDoUsuario, FakeErrorMarker) {
+		return nil, fakeFailure(models.TaskAssistantAIResponse{Error: "fake provider failure"}), errFakeFailure
+	}
+	// Responsável proposto: o primeiro membro do contexto, pelo nome (como a IA faria)
+	assignee := ""
+	if len(wc.Usuarios) > 0 {
+		assignee = wc.Usuarios[0].Nome
+	}
+	suggestions := []string{}
+	tasks := []models.TaskSuggestion{}
+	for _, tarefa := range wc.Tarefas {
+		if tarefa.Status != "completed" {
+			suggestions = append(suggestions, fmt.Sprintf("Avançar a tarefa %q (%s)", tarefa.Titulo, tarefa.Status))
+			tasks = append(tasks, models.TaskSuggestion{
+				Title:       fmt.Sprintf("Próximo passo: %s", tarefa.Titulo),
+				Description: fmt.Sprintf("Dar andamento à tarefa %q, hoje com status %s.", tarefa.Titulo, tarefa.Status),
+				Priority:    tarefa.Prioridade,
+				Assignee:    assignee,
+			})
+		}
+		if len(suggestions) == 3 {
+			break
+		}
+	}
+	if len(suggestions) == 0 {
+		suggestions = append(suggestions, fmt.Sprintf("Criar uma tarefa para: %s", firstWords(wc.MsgDoUsuario, 12)))
+		tasks = append(tasks, models.TaskSuggestion{
+			Title:       firstWords(wc.MsgDoUsuario, 12),
+			Description: wc.MsgDoUsuario,
+			Priority:    "medium",
+			Assignee:    assignee,
+		})
+	}
+	if len(wc.Historico) > 0 {
+		suggestions = append(suggestions, fmt.Sprintf("Continuando a conversa (%d mensagens anteriores)", len(wc.Historico)))
+	}
+	resp := models.TaskAssistantAIResponse{Suggestions: suggestions, TaskSuggestions: tasks}
+	return &resp, fakeSuccess(resp), nil
+}
+
+func (p *FakeProvider) BreakdownTask(ctx context.Context, req models.TaskBreakdownAIRequest) (*models.TaskBreakdownAIResponse, AICallResult, error) {
+	tarefa := req.Tarefa
+	if strings.Contains(tarefa.Titulo+tarefa.Descricao, FakeErrorMarker) {
+		return nil, fakeFailure(models.TaskBreakdownAIResponse{Error: "fake provider failure"}), errFakeFailure
+	}
+	// Itens pendentes do checklist viram subtarefas; sem checklist, etapas padrão
+	steps := tarefa.Checklist
+	if len(steps) == 0 {
+		steps = []string{
+			"Levantar requisitos: " + tarefa.Titulo,
+			"Executar: " + tarefa.Titulo,
+			"Revisar e validar: " + tarefa.Titulo,
+		}
+	}
+	hours := map[string]float64{"high": 4, "medium": 2, "low": 1}[tarefa.Prioridade]
+	if hours == 0 {
+		hours = 2
+	}
+	resp := models.TaskBreakdownAIResponse{}
+	for i, step := range steps {
+		if req.MaxSubtarefas > 0 && i == req.MaxSubtarefas {
+			break
+		}
+		resp.Subtasks = append(resp.Subtasks, models.SubtaskSuggestion{
+			Order:         i + 1,
+			Title:         step,
+			Description:   fmt.Sprintf("Etapa %d de %q.", i+1, tarefa.Titulo),
+			EstimateHours: hours,
+		})
+	}
+	return &resp, fakeSuccess(resp), nil
+}
+
+func fakeSuccess(resp interface{}) AICallResult {
+	raw, _ := json.Marshal(resp)
+	return AICallResult{StatusCode: http.StatusOK, RawResponse: raw}
+}
+
+var errFakeFailure = fmt.Errorf("API de IA (fake) retornou status %d", http.StatusInternalServerError)
+
+func fakeFailure(body interface{}) AICallResult {
+	raw, _ := json.Marshal(body)
+	return AICallResult{StatusCode: http.StatusInternalServerError, RawResponse: raw}
+}
+
+// firstWords retorna as n primeiras palavras do texto.
+func firstWords(text string, n int) string {
+	words := strings.Fields(text)
+	if len(words) <= n {
+		return strings.Join(words, " ")
+	}
+	return strings.Join(words[:n], " ") + "..."
+}
+
+// topKeywords retorna as n palavras (4+ letras) mais frequentes, em ordem determinística.
+func topKeywords(text string, n int) []string {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if len([]rune(word)) >= 4 {
+			counts[word]++
+		}
+	}
+	keywords := make([]string, 0, len(counts))
+	for word := range counts {
+		keywords = append(keywords, word)
+	}
+	sort.Slice(keywords, func(i, j int) bool {
+		if counts[keywords[i]] != counts[keywords[j]] {
+			return counts[keywords[i]] > counts[keywords[j]]
+		}
+		return keywords[i] < keywords[j]
+	})
+	if len(keywords) > n {
+		keywords = keywords[:n]
+	}
+	return keywords
+}
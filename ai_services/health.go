@@ -0,0 +1,89 @@
+package ai_services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"projeto-integrador/scheduler"
+	"time"
+)
+
+// Estados reportados pelo health check de IA.
+const (
+	AIHealthOK          = "ok"
+	AIHealthDegraded    = "degraded"    // Serviço responde, mas o circuito ainda não fechou
+	AIHealthUnavailable = "unavailable" // Serviço não responde
+)
+
+// HealthChecker é implementado pelos provedores que sabem verificar a própria saúde.
+type HealthChecker interface {
+	Health(ctx context.Context) error
+}
+
+// AIHealthReport é o resultado de CheckHealth.
+type AIHealthReport struct {
+	Provider       string         `json:"provider"`
+	Status         string         `json:"status"`
+	Circuit        *CircuitStatus `json:"circuit,omitempty"`
+	ProbeLatencyMs int64          `json:"probe_latency_ms"`
+	Error          string         `json:"error,omitempty"`
+}
+
+// CheckHealth faz uma verificação ativa do provedor configurado e combina com o
+// estado do circuit breaker. A sondagem também serve para "acordar" o serviço
+// após um cold start.
+func CheckHealth(ctx context.Context) AIHealthReport {
+	provider := GetProvider()
+	report := AIHealthReport{Provider: provider.Name(), Status: AIHealthOK}
+
+	inner := provider
+	if resilient, ok := provider.(*ResilientProvider); ok {
+		status := resilient.Breaker().Status()
+		report.Circuit = &status
+		inner = resilient.Inner()
+	}
+
+	if checker, ok := inner.(HealthChecker); ok {
+		start := time.Now()
+		err := checker.Health(ctx)
+		report.ProbeLatencyMs = time.Since(start).Milliseconds()
+		if err != nil {
+			report.Status = AIHealthUnavailable
+			report.Error = err.Error()
+			return report
+		}
+	}
+	if report.Circuit != nil && report.Circuit.State != CircuitClosed {
+		report.Status = AIHealthDegraded
+	}
+	return report
+}
+
+// Health faz um GET em AI_API_HEALTH_PATH (padrão "/") com timeout AI_API_HEALTH_TIMEOUT
+// (padrão 10s). Qualquer resposta abaixo de 500 indica que o serviço está de pé.
+func (p *HTTPProvider) Health(ctx context.Context) error {
+	path := os.Getenv("AI_API_HEALTH_PATH")
+	if path == "" {
+		path = "/"
+	}
+	ctx, cancel := context.WithTimeout(ctx, scheduler.DurationFromEnv("AI_API_HEALTH_TIMEOUT", 10*time.Second))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("erro ao criar sondagem da API de IA: %w", err)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("API de IA não respondeu: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("API de IA retornou status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Health do provedor fake sempre é bem-sucedido.
+func (p *FakeProvider) Health(ctx context.Context) error { return nil }
@@ -0,0 +1,108 @@
+package ai_services
+
+import (
+	"encoding/xml"
+	"fmt"
+	"projeto-integrador/models"
+	"strings"
+	"time"
+)
+
+// Formatos de exportação de mapas mentais.
+const (
+	MindMapFormatMarkdown = "markdown"
+	MindMapFormatOPML     = "opml"
+	MindMapFormatMermaid  = "mermaid"
+)
+
+// MindMapOutline escreve a árvore como lista Markdown indentada (dois espaços por
+// nível). Com withNotes, as notas vêm logo abaixo do nó, como citação.
+func MindMapOutline(root models.MindMapNode, withNotes bool) string {
+	var b strings.Builder
+	var walk func(node models.MindMapNode, level int)
+	walk = func(node models.MindMapNode, level int) {
+		indent := strings.Repeat("  ", level)
+		b.WriteString(indent + "- " + node.Label + "\n")
+		if withNotes && node.Notes != "" {
+			for _, line := range strings.Split(node.Notes, "\n") {
+				b.WriteString(indent + "  > " + line + "\n")
+			}
+		}
+		for _, child := range node.Children {
+			walk(child, level+1)
+		}
+	}
+	walk(root, 0)
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// MindMapMarkdown exporta o mapa como documento Markdown: o título e a árvore em lista.
+func MindMapMarkdown(mindMap *models.MindMap) string {
+	return "# " + mindMap.Title + "\n\n" + MindMapOutline(mindMap.Root, true) + "\n"
+}
+
+type opmlDocument struct {
+	XMLName xml.Name      `xml:"opml"`
+	Version string        `xml:"version,attr"`
+	Title   string        `xml:"head>title"`
+	Created string        `xml:"head>dateCreated,omitempty"`
+	Body    []opmlOutline `xml:"body>outline"`
+}
+
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Note     string        `xml:"_note,attr,omitempty"` // Convenção usada por editores de outline
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// MindMapOPML exporta o mapa em OPML 2.0, aceito por editores de outline e de mapas mentais.
+func MindMapOPML(mindMap *models.MindMap) ([]byte, error) {
+	var convert func(node models.MindMapNode) opmlOutline
+	convert = func(node models.MindMapNode) opmlOutline {
+		outline := opmlOutline{Text: node.Label, Note: node.Notes}
+		for _, child := range node.Children {
+			outline.Outlines = append(outline.Outlines, convert(child))
+		}
+		return outline
+	}
+	doc := opmlDocument{
+		Version: "2.0",
+		Title:   mindMap.Title,
+		Created: mindMap.CreatedAt.UTC().Format(time.RFC1123Z),
+		Body:    []opmlOutline{convert(mindMap.Root)},
+	}
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("erro ao gerar OPML do mapa mental %s: %w", mindMap.ID, err)
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// MindMapMermaid exporta o mapa no diagrama "mindmap" do Mermaid. Os rótulos vão entre
+// aspas, para que parênteses e colchetes não sejam lidos como formas.
+func MindMapMermaid(mindMap *models.MindMap) string {
+	var b strings.Builder
+	b.WriteString("mindmap\n")
+	count := 0 // IDs sequenciais: os IDs dos nós podem ter caracteres inválidos no Mermaid
+	var walk func(node models.MindMapNode, level int)
+	walk = func(node models.MindMapNode, level int) {
+		indent := strings.Repeat("  ", level+1)
+		label := mermaidLabel(node.Label)
+		if level == 0 {
+			b.WriteString(indent + "root((\"" + label + "\"))\n")
+		} else {
+			count++
+			b.WriteString(fmt.Sprintf("%sn%d[\"%s\"]\n", indent, count, label))
+		}
+		for _, child := range node.Children {
+			walk(child, level+1)
+		}
+	}
+	walk(mindMap.Root, 0)
+	return b.String()
+}
+
+// mermaidLabel troca os caracteres que encerrariam o rótulo pelas entidades do Mermaid.
+func mermaidLabel(label string) string {
+	return strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(label)
+}
@@ -0,0 +1,559 @@
+package ai_services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"projeto-integrador/models"
+	"projeto-integrador/task_services"
+	"projeto-integrador/utilities"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	mindMapsSubCollectionName = "mind_maps"
+
+	maxMindMapNodes       = 500
+	maxMindMapDepth       = 8 // Raiz = 1. O Firestore limita o aninhamento dos documentos
+	maxMindMapLabelLength = 200
+	maxMindMapNotesLength = 2000
+)
+
+var (
+	// ErrMindMapNotFound indica um mapa mental inexistente no workspace.
+	ErrMindMapNotFound = errors.New("mind map not found")
+	// ErrMindMapNodeNotFound indica um nó inexistente no mapa.
+	ErrMindMapNodeNotFound = errors.New("mind map node not found")
+	// ErrInvalidMindMap indica uma alteração que deixaria a árvore inválida.
+	ErrInvalidMindMap = errors.New("invalid mind map")
+	// ErrMindMapForbidden indica uma operação reservada ao criador do mapa.
+	ErrMindMapForbidden = errors.New("only the creator can delete the mind map")
+)
+
+// MindMapsCollection retorna os mapas mentais do workspace: /workspaces/{workspace_id}/mind_maps
+func MindMapsCollection(client *firestore.Client, workspaceIDPg int64) *firestore.CollectionRef {
+	return client.Collection("workspaces").Doc(strconv.FormatInt(workspaceIDPg, 10)).Collection(mindMapsSubCollectionName)
+}
+
+// normalizeMindMapResponse garante que a resposta traga a árvore validada e o texto.
+// Se a IA só enviar o texto, a árvore é montada a partir da lista indentada; se só
+// enviar a árvore, o texto é gerado a partir dela.
+func normalizeMindMapResponse(resp models.MindMapIdeasAIResponse, requestText string) models.MindMapIdeasAIResponse {
+	var root models.MindMapNode
+	if resp.Tree != nil {
+		root = *resp.Tree
+	} else {
+		root = ParseMindMapOutline(resp.MindMapIdeas, firstWords(requestText, 8))
+	}
+	root, _ = normalizeMindMapTree(root)
+	if root.Label == "" {
+		resp.Tree = nil
+		return resp
+	}
+	resp.Tree = &root
+	if strings.TrimSpace(resp.MindMapIdeas) == "" {
+		resp.MindMapIdeas = MindMapOutline(root, false)
+	}
+	return resp
+}
+
+// ParseMindMapOutline monta a árvore a partir de uma lista Markdown indentada
+// ("- tópico", "* tópico", "1. tópico"), com títulos "#" acima dos itens. Com mais de um tópico no primeiro nível, eles
+// ficam sob uma raiz com o rótulo rootLabel.
+func ParseMindMapOutline(text string, rootLabel string) models.MindMapNode {
+	type entry struct {
+		indent int
+		node   *models.MindMapNode
+	}
+	top := &models.MindMapNode{}
+	stack := []entry{{indent: -1, node: top}}
+	for _, line := range strings.Split(text, "\n") {
+		expanded := strings.ReplaceAll(line, "\t", "  ")
+		label := strings.TrimSpace(expanded)
+		if label == "" {
+			continue
+		}
+		indent := len(expanded) - len(strings.TrimLeft(expanded, " "))
+		if hashes := len(label) - len(strings.TrimLeft(label, "#")); hashes > 0 {
+			indent = hashes - 1000 // Títulos Markdown ficam acima de qualquer item de lista
+		}
+		label = strings.TrimSpace(strings.TrimLeft(label, "#-*•+"))
+		if i := strings.Index(label, ". "); i > 0 && i <= 3 && strings.Trim(label[:i], "0123456789") == "" {
+			label = strings.TrimSpace(label[i+2:]) // Lista numerada
+		}
+		if label == "" {
+			continue
+		}
+		for len(stack) > 1 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1].node
+		parent.Children = append(parent.Children, models.MindMapNode{Label: label})
+		stack = append(stack, entry{indent: indent, node: &parent.Children[len(parent.Children)-1]})
+	}
+	if len(top.Children) == 1 {
+		return top.Children[0]
+	}
+	top.Label = rootLabel
+	if top.Label == "" && len(top.Children) > 0 {
+		top.Label = "Mapa mental"
+	}
+	return *top
+}
+
+// normalizeMindMapTree limpa os rótulos, descarta nós sem rótulo (com os filhos), garante
+// IDs únicos e corta o que passar da profundidade ou da quantidade máxima de nós.
+// Retorna a árvore e a quantidade de nós.
+func normalizeMindMapTree(root models.MindMapNode) (models.MindMapNode, int) {
+	used := make(map[string]bool)
+	count := 0
+	var walk func(node models.MindMapNode, depth int) (models.MindMapNode, bool)
+	walk = func(node models.MindMapNode, depth int) (models.MindMapNode, bool) {
+		node.Label = normalizeMindMapLabel(node.Label)
+		if node.Label == "" || depth > maxMindMapDepth || count >= maxMindMapNodes {
+			return node, false
+		}
+		node.Notes = truncateRunes(strings.TrimSpace(node.Notes), maxMindMapNotesLength)
+		node.ID = strings.TrimSpace(node.ID)
+		if node.ID == "" || len(node.ID) > 64 || used[node.ID] {
+			node.ID = newMindMapNodeID(used)
+		}
+		used[node.ID] = true
+		count++
+
+		children := make([]models.MindMapNode, 0, len(node.Children))
+		for _, child := range node.Children {
+			if child, ok := walk(child, depth+1); ok {
+				children = append(children, child)
+			}
+		}
+		node.Children = children
+		return node, true
+	}
+	root, ok := walk(root, 1)
+	if !ok {
+		return models.MindMapNode{}, 0
+	}
+	return root, count
+}
+
+func normalizeMindMapLabel(label string) string {
+	return truncateRunes(strings.Join(strings.Fields(label), " "), maxMindMapLabelLength)
+}
+
+func truncateRunes(text string, max int) string {
+	if runes := []rune(text); len(runes) > max {
+		return string(runes[:max])
+	}
+	return text
+}
+
+func newMindMapNodeID(used map[string]bool) string {
+	for {
+		id := "n" + strings.ReplaceAll(uuid.New().String(), "-", "")[:8]
+		if !used[id] {
+			return id
+		}
+	}
+}
+
+// findMindMapNode procura o nó na árvore. Retorna o nó, o pai (nil para a raiz), a
+// profundidade (raiz = 1) e os rótulos do caminho desde a raiz, sem o próprio nó.
+func findMindMapNode(root *models.MindMapNode, nodeID string) (node *models.MindMapNode, parent *models.MindMapNode, depth int, path []string) {
+	var walk func(current *models.MindMapNode, parent *models.MindMapNode, depth int, path []string) bool
+	walk = func(current *models.MindMapNode, p *models.MindMapNode, d int, labels []string) bool {
+		if current.ID == nodeID {
+			node, parent, depth, path = current, p, d, labels
+			return true
+		}
+		for i := range current.Children {
+			if walk(&current.Children[i], current, d+1, append(labels[:len(labels):len(labels)], current.Label)) {
+				return true
+			}
+		}
+		return false
+	}
+	walk(root, nil, 1, nil)
+	return node, parent, depth, path
+}
+
+// mindMapHeight retorna a quantidade de níveis da subárvore (uma folha tem altura 1).
+func mindMapHeight(node models.MindMapNode) int {
+	height := 0
+	for _, child := range node.Children {
+		if h := mindMapHeight(child); h > height {
+			height = h
+		}
+	}
+	return height + 1
+}
+
+func countMindMapNodes(node models.MindMapNode) int {
+	count := 1
+	for _, child := range node.Children {
+		count += countMindMapNodes(child)
+	}
+	return count
+}
+
+func mindMapNodeIDs(node models.MindMapNode, ids map[string]bool) map[string]bool {
+	ids[node.ID] = true
+	for _, child := range node.Children {
+		mindMapNodeIDs(child, ids)
+	}
+	return ids
+}
+
+// CreateMindMap salva a árvore como um novo mapa mental do workspace. Sem título, o
+// rótulo da raiz vira o título.
+func CreateMindMap(ctx context.Context, client *firestore.Client, workspaceIDPg int64, userID string, title string, root models.MindMapNode, historyID string) (*models.MindMap, error) {
+	root, count := normalizeMindMapTree(root)
+	if count == 0 {
+		return nil, fmt.Errorf("%w: the root node needs a label", ErrInvalidMindMap)
+	}
+	title = normalizeMindMapLabel(title)
+	if title == "" {
+		title = root.Label
+	}
+	now := time.Now()
+	mindMap := models.MindMap{
+		WorkspaceIDPg: workspaceIDPg,
+		Title:         title,
+		Root:          root,
+		NodeCount:     count,
+		HistoryID:     historyID,
+		CreatedBy:     userID,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	ref, _, err := MindMapsCollection(client, workspaceIDPg).Add(ctx, mindMap)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao salvar mapa mental: %w", err)
+	}
+	mindMap.ID = ref.ID
+	return &mindMap, nil
+}
+
+// GetMindMap busca um mapa mental do workspace.
+func GetMindMap(ctx context.Context, client *firestore.Client, workspaceIDPg int64, mindMapID string) (*models.MindMap, error) {
+	doc, err := MindMapsCollection(client, workspaceIDPg).Doc(mindMapID).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrMindMapNotFound
+		}
+		return nil, fmt.Errorf("erro ao buscar mapa mental %s: %w", mindMapID, err)
+	}
+	return mindMapFromDoc(doc)
+}
+
+func mindMapFromDoc(doc *firestore.DocumentSnapshot) (*models.MindMap, error) {
+	var mindMap models.MindMap
+	if err := doc.DataTo(&mindMap); err != nil {
+		return nil, fmt.Errorf("erro ao ler mapa mental %s: %w", doc.Ref.ID, err)
+	}
+	mindMap.ID = doc.Ref.ID
+	return &mindMap, nil
+}
+
+// ListMindMaps lista os mapas mentais do workspace (sem as árvores), os alterados mais
+// recentemente primeiro.
+func ListMindMaps(ctx context.Context, client *firestore.Client, workspaceIDPg int64) ([]models.MindMapSummary, error) {
+	iter := MindMapsCollection(client, workspaceIDPg).
+		Select("title", "node_count", "created_by", "created_at", "updated_at").Documents(ctx)
+	defer iter.Stop()
+
+	summaries := []models.MindMapSummary{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao listar mapas mentais: %w", err)
+		}
+		var mindMap models.MindMap
+		if err := doc.DataTo(&mindMap); err != nil {
+			continue
+		}
+		summaries = append(summaries, models.MindMapSummary{
+			ID:        doc.Ref.ID,
+			Title:     mindMap.Title,
+			NodeCount: mindMap.NodeCount,
+			CreatedBy: mindMap.CreatedBy,
+			CreatedAt: mindMap.CreatedAt,
+			UpdatedAt: mindMap.UpdatedAt,
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].UpdatedAt.After(summaries[j].UpdatedAt) })
+	return summaries, nil
+}
+
+// DeleteMindMap apaga o mapa mental. Só o criador pode apagar; as tarefas criadas a
+// partir dos nós são mantidas.
+func DeleteMindMap(ctx context.Context, client *firestore.Client, workspaceIDPg int64, mindMapID string, userID string) error {
+	mindMap, err := GetMindMap(ctx, client, workspaceIDPg, mindMapID)
+	if err != nil {
+		return err
+	}
+	if mindMap.CreatedBy != userID {
+		return ErrMindMapForbidden
+	}
+	if _, err := MindMapsCollection(client, workspaceIDPg).Doc(mindMapID).Delete(ctx); err != nil {
+		return fmt.Errorf("erro ao apagar mapa mental %s: %w", mindMapID, err)
+	}
+	return nil
+}
+
+// updateMindMap aplica change ao mapa dentro de uma transação, para que edições
+// simultâneas de nós não se sobrescrevam, e atualiza a contagem de nós e a data.
+func updateMindMap(ctx context.Context, client *firestore.Client, workspaceIDPg int64, mindMapID string, change func(mindMap *models.MindMap) error) (*models.MindMap, error) {
+	ref := MindMapsCollection(client, workspaceIDPg).Doc(mindMapID)
+	var updated *models.MindMap
+	err := client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return ErrMindMapNotFound
+			}
+			return err
+		}
+		mindMap, err := mindMapFromDoc(doc)
+		if err != nil {
+			return err
+		}
+		if err := change(mindMap); err != nil {
+			return err
+		}
+		mindMap.NodeCount = countMindMapNodes(mindMap.Root)
+		mindMap.UpdatedAt = time.Now()
+		updated = mindMap
+		return tx.Set(ref, mindMap)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// RenameMindMap altera o título do mapa mental.
+func RenameMindMap(ctx context.Context, client *firestore.Client, workspaceIDPg int64, mindMapID string, title string) (*models.MindMap, error) {
+	title = normalizeMindMapLabel(title)
+	if title == "" {
+		return nil, fmt.Errorf("%w: title is required", ErrInvalidMindMap)
+	}
+	return updateMindMap(ctx, client, workspaceIDPg, mindMapID, func(mindMap *models.MindMap) error {
+		mindMap.Title = title
+		return nil
+	})
+}
+
+// AddMindMapNode adiciona um nó sob ParentID (a raiz, se vazio).
+func AddMindMapNode(ctx context.Context, client *firestore.Client, workspaceIDPg int64, mindMapID string, input models.MindMapNodeInput) (*models.MindMapNode, error) {
+	node := models.MindMapNode{}
+	if input.Label != nil {
+		node.Label = normalizeMindMapLabel(*input.Label)
+	}
+	if node.Label == "" {
+		return nil, fmt.Errorf("%w: label is required", ErrInvalidMindMap)
+	}
+	if input.Notes != nil {
+		node.Notes = truncateRunes(strings.TrimSpace(*input.Notes), maxMindMapNotesLength)
+	}
+
+	_, err := updateMindMap(ctx, client, workspaceIDPg, mindMapID, func(mindMap *models.MindMap) error {
+		parentID := mindMap.Root.ID
+		if input.ParentID != nil && *input.ParentID != "" {
+			parentID = *input.ParentID
+		}
+		parent, _, depth, _ := findMindMapNode(&mindMap.Root, parentID)
+		if parent == nil {
+			return ErrMindMapNodeNotFound
+		}
+		if depth+1 > maxMindMapDepth {
+			return fmt.Errorf("%w: the tree cannot be deeper than %d levels", ErrInvalidMindMap, maxMindMapDepth)
+		}
+		if mindMap.NodeCount >= maxMindMapNodes {
+			return fmt.Errorf("%w: a mind map can have at most %d nodes", ErrInvalidMindMap, maxMindMapNodes)
+		}
+		node.ID = newMindMapNodeID(mindMapNodeIDs(mindMap.Root, make(map[string]bool)))
+		parent.Children = append(parent.Children, node)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+// UpdateMindMapNode altera o rótulo e as notas de um nó e, com ParentID, move o nó
+// (com os filhos) para outro pai. A raiz não pode ser movida.
+func UpdateMindMapNode(ctx context.Context, client *firestore.Client, workspaceIDPg int64, mindMapID string, nodeID string, input models.MindMapNodeInput) (*models.MindMapNode, error) {
+	var result models.MindMapNode
+	_, err := updateMindMap(ctx, client, workspaceIDPg, mindMapID, func(mindMap *models.MindMap) error {
+		node, parent, _, _ := findMindMapNode(&mindMap.Root, nodeID)
+		if node == nil {
+			return ErrMindMapNodeNotFound
+		}
+		if input.Label != nil {
+			label := normalizeMindMapLabel(*input.Label)
+			if label == "" {
+				return fmt.Errorf("%w: label cannot be empty", ErrInvalidMindMap)
+			}
+			node.Label = label
+		}
+		if input.Notes != nil {
+			node.Notes = truncateRunes(strings.TrimSpace(*input.Notes), maxMindMapNotesLength)
+		}
+		result = *node
+		if input.ParentID == nil || (parent != nil && *input.ParentID == parent.ID) {
+			return nil
+		}
+		return moveMindMapNode(&mindMap.Root, nodeID, *input.ParentID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteMindMapNode remove o nó e os filhos. A raiz não pode ser removida.
+func DeleteMindMapNode(ctx context.Context, client *firestore.Client, workspaceIDPg int64, mindMapID string, nodeID string) error {
+	_, err := updateMindMap(ctx, client, workspaceIDPg, mindMapID, func(mindMap *models.MindMap) error {
+		node, parent, _, _ := findMindMapNode(&mindMap.Root, nodeID)
+		if node == nil {
+			return ErrMindMapNodeNotFound
+		}
+		if parent == nil {
+			return fmt.Errorf("%w: the root node cannot be deleted", ErrInvalidMindMap)
+		}
+		removeMindMapChild(parent, nodeID)
+		return nil
+	})
+	return err
+}
+
+// moveMindMapNode move o nó (com os filhos) para o fim dos filhos de newParentID.
+func moveMindMapNode(root *models.MindMapNode, nodeID string, newParentID string) error {
+	node, parent, _, _ := findMindMapNode(root, nodeID)
+	if node == nil {
+		return ErrMindMapNodeNotFound
+	}
+	if parent == nil {
+		return fmt.Errorf("%w: the root node cannot be moved", ErrInvalidMindMap)
+	}
+	if mindMapNodeIDs(*node, make(map[string]bool))[newParentID] {
+		return fmt.Errorf("%w: a node cannot be moved under itself", ErrInvalidMindMap)
+	}
+	_, _, newParentDepth, _ := findMindMapNode(root, newParentID)
+	if newParentDepth == 0 {
+		return ErrMindMapNodeNotFound
+	}
+	if newParentDepth+mindMapHeight(*node) > maxMindMapDepth {
+		return fmt.Errorf("%w: the tree cannot be deeper than %d levels", ErrInvalidMindMap, maxMindMapDepth)
+	}
+	moved := *node
+	removeMindMapChild(parent, nodeID)
+	// Busca de novo: remover o nó pode ter deslocado o novo pai no slice
+	newParent, _, _, _ := findMindMapNode(root, newParentID)
+	newParent.Children = append(newParent.Children, moved)
+	return nil
+}
+
+func removeMindMapChild(parent *models.MindMapNode, nodeID string) {
+	for i, child := range parent.Children {
+		if child.ID == nodeID {
+			parent.Children = append(parent.Children[:i:i], parent.Children[i+1:]...)
+			return
+		}
+	}
+}
+
+// ConvertMindMapNodesToTasks cria uma tarefa para cada nó, pelo caminho normal de
+// criação: o rótulo vira o título e as notas, com o caminho do nó no mapa, a descrição.
+// O ID da tarefa é derivado do mapa e do nó, então converter de novo não cria
+// duplicatas. Mapas gerados pela IA produzem tarefas marcadas como geradas por IA.
+func ConvertMindMapNodesToTasks(ctx context.Context, db *sql.DB, client *firestore.Client, workspaceIDPg int64, userID string, mindMapID string, nodeIDs []string) ([]models.MindMapNodeTask, error) {
+	mindMap, err := GetMindMap(ctx, client, workspaceIDPg, mindMapID)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodeIDs) == 0 {
+		return nil, fmt.Errorf("%w: node_ids is required", ErrInvalidMindMap)
+	}
+
+	results := make([]models.MindMapNodeTask, 0, len(nodeIDs))
+	linked := make(map[string]string)
+	seen := make(map[string]bool, len(nodeIDs))
+	for _, nodeID := range nodeIDs {
+		if seen[nodeID] {
+			continue
+		}
+		seen[nodeID] = true
+		result := models.MindMapNodeTask{NodeID: nodeID}
+		node, _, _, path := findMindMapNode(&mindMap.Root, nodeID)
+		if node == nil {
+			result.Status, result.Error = SuggestionFailed, ErrMindMapNodeNotFound.Error()
+			results = append(results, result)
+			continue
+		}
+
+		description := node.Notes
+		if len(path) > 0 {
+			origin := fmt.Sprintf("Mapa mental %q: %s", mindMap.Title, strings.Join(append(path, node.Label), " > "))
+			description = strings.TrimSpace(description + "\n\n" + origin)
+		}
+		input := models.CreateTaskInput{
+			Title:       node.Label,
+			Description: description,
+			Status:      "pending",
+			DocID:       fmt.Sprintf("mm-%s-%s", mindMapID, nodeID),
+		}
+		if mindMap.HistoryID != "" {
+			input.AIGenerated = true
+			input.AIHistoryID = mindMap.HistoryID
+		}
+
+		taskID, _, err := task_services.CreateTask(ctx, db, client, workspaceIDPg, userID, input)
+		switch {
+		case err == nil:
+			result.Status, result.TaskID = SuggestionCreated, taskID
+		case errors.Is(err, task_services.ErrTaskAlreadyExists):
+			result.Status, result.TaskID = SuggestionAlreadyAccepted, input.DocID
+		case errors.Is(err, task_services.ErrInvalidTask):
+			result.Status, result.Error = SuggestionFailed, err.Error()
+		default:
+			utilities.LogError(err, fmt.Sprintf("ConvertMindMapNodesToTasks: Erro ao criar tarefa do nó %s do mapa %s", nodeID, mindMapID))
+			result.Status, result.Error = SuggestionFailed, "failed to create task"
+		}
+		if result.TaskID != "" && node.TaskID != result.TaskID {
+			linked[nodeID] = result.TaskID
+		}
+		results = append(results, result)
+	}
+
+	if len(linked) > 0 {
+		_, err := updateMindMap(ctx, client, workspaceIDPg, mindMapID, func(mindMap *models.MindMap) error {
+			for nodeID, taskID := range linked {
+				if node, _, _, _ := findMindMapNode(&mindMap.Root, nodeID); node != nil {
+					node.TaskID = taskID
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			// As tarefas já existem; o vínculo é refeito na próxima conversão
+			utilities.LogError(err, fmt.Sprintf("ConvertMindMapNodesToTasks: Erro ao vincular tarefas ao mapa %s", mindMapID))
+		}
+	}
+	return results, nil
+}
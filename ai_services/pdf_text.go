@@ -0,0 +1,319 @@
+package ai_services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	maxPDFDecodedBytes = 32 << 20 // Total descomprimido dos streams de um PDF
+	maxPDFTextBytes    = 1 << 20  // Texto extraído; o resumo usa bem menos que isso
+)
+
+var (
+	// Fim do dicionário de um objeto com stream: ">> stream" seguido da quebra de linha
+	pdfStreamStart = regexp.MustCompile(`>>\s*stream(?:\r\n|\n|\r)`)
+	pdfFilterName  = regexp.MustCompile(`/(\w+Decode|Fl|AHx|A85|LZW|RL|CCF|DCT)\b`)
+	pdfImageStream = regexp.MustCompile(`/Subtype\s*/Image\b`)
+)
+
+// extractPDFText extrai o texto das páginas de um PDF: percorre os streams de conteúdo
+// (sem compressão ou com FlateDecode, o caso comum) e junta as strings dos operadores de
+// texto (Tj, TJ, ' e "). É uma extração simples, sem mapear fontes: PDFs digitalizados
+// ou com fontes de glifos próprios (texto em strings hexadecimais) não têm texto
+// extraível.
+func extractPDFText(data []byte) (string, error) {
+	var b strings.Builder
+	budget := int64(maxPDFDecodedBytes)
+	for _, loc := range pdfStreamStart.FindAllIndex(data, -1) {
+		if budget <= 0 || b.Len() >= maxPDFTextBytes {
+			break
+		}
+		dictStart := bytes.LastIndex(data[:loc[0]], []byte("obj"))
+		if dictStart < 0 {
+			continue
+		}
+		dict := data[dictStart:loc[0]]
+		start := loc[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			continue
+		}
+		if pdfImageStream.Match(dict) {
+			continue
+		}
+		content, ok := decodePDFStream(dict, data[start:start+end], budget)
+		if !ok {
+			continue
+		}
+		budget -= int64(len(content))
+		if bytes.Contains(content, []byte("BT")) {
+			pdfContentText(content, &b)
+		}
+	}
+
+	var lines []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return "", errAttachmentNoText
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// decodePDFStream aplica os filtros do stream. Só FlateDecode é suportado; streams com
+// outros filtros (imagens, fontes) são ignorados.
+func decodePDFStream(dict []byte, raw []byte, budget int64) ([]byte, bool) {
+	filters := pdfFilterName.FindAllSubmatch(dict, -1)
+	if len(filters) == 0 {
+		return raw, true
+	}
+	if len(filters) > 1 || (string(filters[0][1]) != "FlateDecode" && string(filters[0][1]) != "Fl") {
+		return nil, false
+	}
+	r, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, false
+	}
+	defer r.Close()
+	// Streams com lixo no fim ainda têm o conteúdo lido até ali
+	content, _ := io.ReadAll(io.LimitReader(r, budget))
+	return content, len(content) > 0
+}
+
+// pdfContentText escreve em b o texto de um stream de conteúdo. Mudanças de linha
+// (T*, ', ", Td/TD com deslocamento vertical, Tm em outra altura e fim de bloco de
+// texto) viram quebras de linha; espaços grandes em TJ viram espaço.
+func pdfContentText(content []byte, b *strings.Builder) {
+	var (
+		texts   []string
+		numbers []float64
+		inArray bool
+		lastTmY float64
+	)
+	newline := func() {
+		if s := b.String(); s != "" && !strings.HasSuffix(s, "\n") {
+			b.WriteByte('\n')
+		}
+	}
+	space := func() {
+		if s := b.String(); s != "" && !strings.HasSuffix(s, "\n") && !strings.HasSuffix(s, " ") {
+			b.WriteByte(' ')
+		}
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case isPDFWhitespace(c):
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			text, n := pdfLiteralString(content[i:])
+			texts = append(texts, text)
+			i += n
+		case c == '<' && i+1 < len(content) && content[i+1] == '<', c == '>' && i+1 < len(content) && content[i+1] == '>':
+			i += 2
+		case c == '<':
+			text, n := pdfHexString(content[i:])
+			if text != "" {
+				texts = append(texts, text)
+			}
+			i += n
+		case c == '[':
+			inArray = true
+			i++
+		case c == ']':
+			inArray = false
+			i++
+		case c == '/':
+			i++
+			for i < len(content) && isPDFRegular(content[i]) {
+				i++
+			}
+		case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(content) && (content[j] == '.' || (content[j] >= '0' && content[j] <= '9')) {
+				j++
+			}
+			n, _ := strconv.ParseFloat(string(content[i:j]), 64)
+			if inArray && n <= -200 {
+				texts = append(texts, " ") // Deslocamento em milésimos de em: separa palavras
+			}
+			numbers = append(numbers, n)
+			i = j
+		case isPDFRegular(c) || c == '\'' || c == '"':
+			j := i + 1
+			for j < len(content) && isPDFRegular(content[j]) && c != '\'' && c != '"' {
+				j++
+			}
+			switch op := string(content[i:j]); op {
+			case "Tj", "TJ":
+				b.WriteString(strings.Join(texts, ""))
+			case "'", "\"":
+				newline()
+				b.WriteString(strings.Join(texts, ""))
+			case "T*", "ET":
+				newline()
+			case "Td", "TD":
+				if len(numbers) >= 2 && numbers[len(numbers)-1] != 0 {
+					newline()
+				} else {
+					space()
+				}
+			case "Tm":
+				if len(numbers) >= 6 {
+					if y := numbers[len(numbers)-1]; y != lastTmY {
+						newline()
+						lastTmY = y
+					} else {
+						space()
+					}
+				}
+			case "BI":
+				// Imagem embutida: os dados binários vão até o operador EI
+				if end := bytes.Index(content[j:], []byte("EI")); end >= 0 {
+					j += end + 2
+				} else {
+					j = len(content)
+				}
+			}
+			texts, numbers = texts[:0], numbers[:0]
+			i = j
+		default:
+			i++
+		}
+	}
+	newline()
+}
+
+// pdfLiteralString lê uma string "(...)" a partir de data[0] == '(' e retorna o texto e
+// quantos bytes foram consumidos.
+func pdfLiteralString(data []byte) (string, int) {
+	var raw []byte
+	depth := 0
+	i := 0
+	for ; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '(':
+			if depth > 0 {
+				raw = append(raw, c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return pdfStringText(raw), i + 1
+			}
+			raw = append(raw, c)
+		case c == '\\' && i+1 < len(data):
+			i++
+			switch e := data[i]; e {
+			case 'n':
+				raw = append(raw, '\n')
+			case 'r':
+				raw = append(raw, '\r')
+			case 't':
+				raw = append(raw, '\t')
+			case 'b', 'f':
+			case '\r':
+				if i+1 < len(data) && data[i+1] == '\n' {
+					i++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					n := 0
+					for k := 0; k < 3 && i < len(data) && data[i] >= '0' && data[i] <= '7'; k++ {
+						n = n*8 + int(data[i]-'0')
+						i++
+					}
+					i--
+					raw = append(raw, byte(n))
+				} else {
+					raw = append(raw, e) // \( \) \\ e escapes desconhecidos
+				}
+			}
+		default:
+			raw = append(raw, c)
+		}
+	}
+	return pdfStringText(raw), i
+}
+
+// pdfHexString lê uma string "<...>" e retorna o texto, vazio se os bytes não forem
+// texto legível (em geral, IDs de glifos de fontes CID).
+func pdfHexString(data []byte) (string, int) {
+	end := bytes.IndexByte(data, '>')
+	if end < 0 {
+		return "", len(data)
+	}
+	var digits []byte
+	for _, c := range data[1:end] {
+		if !isPDFWhitespace(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	raw := make([]byte, len(digits)/2)
+	for k := range raw {
+		n, err := strconv.ParseUint(string(digits[2*k:2*k+2]), 16, 8)
+		if err != nil {
+			return "", end + 1
+		}
+		raw[k] = byte(n)
+	}
+	if bytes.HasPrefix(raw, []byte{0xFE, 0xFF}) {
+		return pdfStringText(raw), end + 1
+	}
+	for _, c := range raw {
+		if c < 0x20 || c > 0x7E {
+			return "", end + 1
+		}
+	}
+	return string(raw), end + 1
+}
+
+// pdfStringText converte os bytes de uma string do PDF em texto: UTF-16BE quando começa
+// com o BOM, senão um byte por caractere (Latin-1, próximo das codificações padrão).
+func pdfStringText(raw []byte) string {
+	if len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF {
+		units := make([]uint16, 0, len(raw)/2)
+		for k := 2; k+1 < len(raw); k += 2 {
+			units = append(units, uint16(raw[k])<<8|uint16(raw[k+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, 0, len(raw))
+	for _, c := range raw {
+		if c == '\n' || c == '\r' || c == '\t' {
+			c = ' '
+		}
+		if c >= 0x20 {
+			runes = append(runes, rune(c))
+		}
+	}
+	return string(runes)
+}
+
+func isPDFWhitespace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFRegular(c byte) bool {
+	return !isPDFWhitespace(c) && !strings.ContainsRune("()<>[]{}/%", rune(c))
+}
@@ -0,0 +1,64 @@
+package ai_services
+
+import (
+	"context"
+	"os"
+	"projeto-integrador/models"
+	"projeto-integrador/utilities"
+	"strings"
+	"sync"
+)
+
+// Nomes aceitos em AI_PROVIDER.
+const (
+	ProviderHTTP = "http" // Serviço Python de IA (padrão)
+	ProviderFake = "fake" // Respostas determinísticas, para testes e desenvolvimento offline
+)
+
+// AICallResult traz os detalhes de transporte de uma chamada ao provedor, usados
+// para responder erros ao cliente e registrar o histórico.
+type AICallResult struct {
+	StatusCode  int    // Status HTTP devolvido pelo provedor (0 se nem houve resposta)
+	RawResponse []byte // Corpo bruto da resposta, se houver
+	Attempts    int    // Tentativas feitas pelo ResilientProvider (0 se o circuito estava aberto)
+}
+
+// AIProvider é o backend que executa as funcionalidades de IA. Cada método retorna a
+// resposta tipada (apenas em caso de sucesso), o resultado da chamada e um erro quando
+// o provedor falhou ou respondeu com status diferente de 2xx.
+type AIProvider interface {
+	Name() string
+	Summarize(ctx context.Context, req models.SummarizeTextAIRequest) (*models.SummarizeTextAIResponse, AICallResult, error)
+	CodeReview(ctx context.Context, req models.CodeReviewAIRequest) (*models.CodeReviewAIResponse, AICallResult, error)
+	MindMapIdeas(ctx context.Context, req models.MindMapIdeasAIRequest) (*models.MindMapIdeasAIResponse, AICallResult, error)
+	TaskAssistant(ctx context.Context, req models.TaskAssistantAIRequest) (*models.TaskAssistantAIResponse, AICallResult, error)
+	BreakdownTask(ctx context.Context, req models.TaskBreakdownAIRequest) (*models.TaskBreakdownAIResponse, AICallResult, error)
+}
+
+var (
+	providerOnce    sync.Once
+	defaultProvider AIProvider
+)
+
+// GetProvider retorna o provedor configurado em AI_PROVIDER ("http" ou "fake"), já com
+// novas tentativas e circuit breaker. A escolha é feita uma única vez, na primeira chamada.
+func GetProvider() AIProvider {
+	providerOnce.Do(func() {
+		defaultProvider = NewResilientProviderFromEnv(NewProvider(os.Getenv("AI_PROVIDER")))
+		utilities.LogInfo("AIProvider: usando provedor %q", defaultProvider.Name())
+	})
+	return defaultProvider
+}
+
+// NewProvider cria o provedor pelo nome. Nomes desconhecidos caem no provedor HTTP.
+func NewProvider(name string) AIProvider {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case ProviderFake:
+		return NewFakeProvider()
+	case "", ProviderHTTP:
+		return NewHTTPProviderFromEnv()
+	default:
+		utilities.LogInfo("AIProvider: provedor desconhecido %q, usando %q", name, ProviderHTTP)
+		return NewHTTPProviderFromEnv()
+	}
+}
@@ -0,0 +1,429 @@
+package ai_services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"projeto-integrador/database"
+	"projeto-integrador/models"
+	"projeto-integrador/scheduler"
+	"projeto-integrador/utilities"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Tipos de dado mascarados antes do envio à IA (models.AIRedaction.Type).
+const (
+	RedactionEmail  = "email"
+	RedactionPhone  = "phone"
+	RedactionCPF    = "cpf"
+	RedactionCNPJ   = "cnpj"
+	RedactionAPIKey = "api_key"
+)
+
+const (
+	MaxAIRedactionRulesPerWorkspace = 20
+	maxAIRedactionPatternLength     = 500
+	maxPlaceholderLength            = 48 // Trecho retido no streaming à espera do fim de um placeholder
+)
+
+// ErrInvalidAIRedactionRule indica uma regra de mascaramento inválida.
+var ErrInvalidAIRedactionRule = errors.New("invalid ai redaction rule")
+
+var redactionRuleNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,39}$`)
+
+// redactionRule é um padrão mascarado. Se o padrão tiver grupos e o grupo 1 participar
+// da correspondência, apenas ele é mascarado (ex: o valor em "password=...").
+type redactionRule struct {
+	kind     string // Registrado no histórico
+	label    string // Prefixo do placeholder
+	pattern  *regexp.Regexp
+	validate func(value string) bool // Descarta falsos positivos; nil aceita tudo
+}
+
+// Os tipos padrão são aplicados nesta ordem (depois das regras do workspace): chaves antes
+// de e-mails e documentos antes de telefones, para que um valor não seja mascarado em partes.
+var builtinRedactionRules = []redactionRule{
+	{
+		kind:  RedactionAPIKey,
+		label: "API_KEY",
+		pattern: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----` +
+			`|\bsk-(?:proj-|ant-)?[A-Za-z0-9_-]{20,}` +
+			`|\bAKIA[0-9A-Z]{16}\b` +
+			`|\bAIza[0-9A-Za-z_-]{35}` +
+			`|\bgh[pousr]_[A-Za-z0-9]{36,}` +
+			`|\bgithub_pat_[A-Za-z0-9_]{22,}` +
+			`|\bxox[abprs]-[A-Za-z0-9-]{10,}` +
+			`|\bglpat-[A-Za-z0-9_-]{20,}` +
+			`|\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}` +
+			`|(?i:\b(?:api[_-]?key|secret(?:[_-]?key)?|access[_-]?token|auth[_-]?token|password|passwd)\b["']?\s*[:=]\s*["']?([^\s"']{8,}))`),
+	},
+	{
+		kind:    RedactionEmail,
+		label:   "EMAIL",
+		pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	},
+	{
+		kind:     RedactionCNPJ,
+		label:    "CNPJ",
+		pattern:  regexp.MustCompile(`\b\d{2}\.?\d{3}\.?\d{3}/?\d{4}-?\d{2}\b`),
+		validate: validCNPJ,
+	},
+	{
+		kind:     RedactionCPF,
+		label:    "CPF",
+		pattern:  regexp.MustCompile(`\b\d{3}\.?\d{3}\.?\d{3}-?\d{2}\b`),
+		validate: validCPF,
+	},
+	{
+		kind:    RedactionPhone,
+		label:   "PHONE",
+		pattern: regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{2,4}\)\s?|\b\d{2,4}[\s.-])\d{3,5}[\s.-]?\d{4}\b`),
+	},
+}
+
+// redactionRulesFromEnv retorna os tipos padrão ativos.
+//
+// Variáveis de ambiente:
+//   - AI_REDACTION_ENABLED: "false" desliga o mascaramento, inclusive as regras dos workspaces (padrão: ligado)
+//   - AI_REDACTION_TYPES: tipos padrão mascarados, separados por vírgula (padrão: email,phone,cpf,cnpj,api_key)
+func redactionRulesFromEnv() (rules []redactionRule, enabled bool) {
+	if !scheduler.EnabledFromEnv("AI_REDACTION_ENABLED") {
+		return nil, false
+	}
+	raw := os.Getenv("AI_REDACTION_TYPES")
+	if raw == "" {
+		return builtinRedactionRules, true
+	}
+	selected := map[string]bool{}
+	for _, kind := range strings.Split(raw, ",") {
+		selected[strings.TrimSpace(kind)] = true
+	}
+	for _, rule := range builtinRedactionRules {
+		if selected[rule.kind] {
+			rules = append(rules, rule)
+			delete(selected, rule.kind)
+		}
+	}
+	for kind := range selected {
+		if kind != "" {
+			utilities.LogInfo("Tipo desconhecido em AI_REDACTION_TYPES: %q", kind)
+		}
+	}
+	return rules, true
+}
+
+// ValidateAIRedactionRule confere o nome e o padrão de uma regra de mascaramento do workspace.
+func ValidateAIRedactionRule(name string, pattern string) error {
+	if !redactionRuleNamePattern.MatchString(name) {
+		return fmt.Errorf("%w: name must start with a letter and contain only letters, digits and _ (max 40)", ErrInvalidAIRedactionRule)
+	}
+	if pattern == "" || len(pattern) > maxAIRedactionPatternLength {
+		return fmt.Errorf("%w: pattern must have between 1 and %d characters", ErrInvalidAIRedactionRule, maxAIRedactionPatternLength)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAIRedactionRule, err)
+	}
+	if re.MatchString("") {
+		return fmt.Errorf("%w: pattern must not match an empty string", ErrInvalidAIRedactionRule)
+	}
+	return nil
+}
+
+// Redactor mascara dados sensíveis dos payloads enviados à IA com placeholders
+// numerados por tipo ("[EMAIL_1]"). O mesmo valor recebe sempre o mesmo placeholder, e
+// Restore troca os placeholders da resposta pelos valores originais. Um Redactor vale
+// para uma única requisição e não é seguro para uso concorrente.
+type Redactor struct {
+	rules        []redactionRule
+	placeholders map[string]string // Valor original → placeholder
+	originals    map[string]string // Placeholder → valor original
+	counters     map[string]int    // Prefixo → último número usado
+	redactions   []models.AIRedaction
+	restorer     *strings.Replacer
+}
+
+// NewRedactor cria um Redactor com as regras do workspace seguidas dos tipos padrão ativos.
+// Regras do workspace com padrão inválido são ignoradas.
+func NewRedactor(workspaceRules []models.AIRedactionRule) *Redactor {
+	builtin, enabled := redactionRulesFromEnv()
+	r := &Redactor{
+		placeholders: map[string]string{},
+		originals:    map[string]string{},
+		counters:     map[string]int{},
+	}
+	if !enabled {
+		return r
+	}
+	for _, rule := range workspaceRules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			utilities.LogInfo("Regra de mascaramento %d do workspace %d ignorada: %v", rule.ID, rule.WorkspaceID, err)
+			continue
+		}
+		r.rules = append(r.rules, redactionRule{kind: "custom:" + rule.Name, label: strings.ToUpper(rule.Name), pattern: re})
+	}
+	r.rules = append(r.rules, builtin...)
+	return r
+}
+
+// LoadWorkspaceRedactor cria o Redactor de uma requisição com as regras do workspace.
+func LoadWorkspaceRedactor(workspaceIDPg int64) (*Redactor, error) {
+	if _, enabled := redactionRulesFromEnv(); !enabled {
+		return NewRedactor(nil), nil
+	}
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao conectar ao PG para carregar regras de mascaramento: %w", err)
+	}
+	defer db.Close()
+	rules, err := models.ListAIRedactionRules(db, workspaceIDPg)
+	if err != nil {
+		return nil, err
+	}
+	return NewRedactor(rules), nil
+}
+
+// RedactEmbeddingTexts mascara os textos do workspace enviados ao serviço de embeddings
+// da busca (registrado com search_services.SetEmbeddingRedactor).
+func RedactEmbeddingTexts(workspaceIDPg int64, texts []string) ([]string, error) {
+	redactor, err := LoadWorkspaceRedactor(workspaceIDPg)
+	if err != nil {
+		return nil, err
+	}
+	return redactor.Redact(texts).([]string), nil
+}
+
+// Redact retorna uma cópia de v (payload para a IA) com os textos mascarados. v não é alterado.
+func (r *Redactor) Redact(v interface{}) interface{} {
+	if len(r.rules) == 0 {
+		return v
+	}
+	return transformStrings(v, r.redactString)
+}
+
+// Restore retorna uma cópia de v (resposta da IA) com os placeholders trocados pelos
+// valores originais.
+func (r *Redactor) Restore(v interface{}) interface{} {
+	if len(r.originals) == 0 {
+		return v
+	}
+	return transformStrings(v, r.restoreString)
+}
+
+// Redactions lista os valores mascarados até agora, sem os originais.
+func (r *Redactor) Redactions() []models.AIRedaction {
+	return r.redactions
+}
+
+func (r *Redactor) redactString(s string) string {
+	for _, rule := range r.rules {
+		matches := rule.pattern.FindAllStringSubmatchIndex(s, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, m := range matches {
+			start, end := m[0], m[1]
+			if len(m) >= 4 && m[2] >= 0 {
+				start, end = m[2], m[3]
+			}
+			value := s[start:end]
+			if value == "" || (rule.validate != nil && !rule.validate(value)) {
+				continue
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(r.placeholderFor(rule, value))
+			last = end
+		}
+		b.WriteString(s[last:])
+		s = b.String()
+	}
+	return s
+}
+
+func (r *Redactor) placeholderFor(rule redactionRule, value string) string {
+	if placeholder, ok := r.placeholders[value]; ok {
+		return placeholder
+	}
+	r.counters[rule.label]++
+	placeholder := fmt.Sprintf("[%s_%d]", rule.label, r.counters[rule.label])
+	r.placeholders[value] = placeholder
+	r.originals[placeholder] = value
+	r.redactions = append(r.redactions, models.AIRedaction{Type: rule.kind, Placeholder: placeholder})
+	r.restorer = nil
+	return placeholder
+}
+
+func (r *Redactor) restoreString(s string) string {
+	if len(r.originals) == 0 || !strings.Contains(s, "[") {
+		return s
+	}
+	if r.restorer == nil {
+		pairs := make([]string, 0, 2*len(r.originals))
+		for placeholder, original := range r.originals {
+			pairs = append(pairs, placeholder, original)
+		}
+		r.restorer = strings.NewReplacer(pairs...)
+	}
+	return r.restorer.Replace(s)
+}
+
+// streamRestorer restaura os placeholders de uma resposta em streaming. Um placeholder
+// pode chegar dividido entre trechos, então o fim de um trecho que pode ser o começo de
+// um placeholder é retido até o próximo.
+type streamRestorer struct {
+	redactor *Redactor
+	pending  string
+}
+
+// Write retorna o texto restaurado que já pode ser enviado ao cliente.
+func (s *streamRestorer) Write(chunk string) string {
+	text := s.pending + chunk
+	s.pending = ""
+	if len(s.redactor.originals) == 0 {
+		return text
+	}
+	if i := strings.LastIndexByte(text, '['); i >= 0 && !strings.Contains(text[i:], "]") && len(text)-i < maxPlaceholderLength {
+		s.pending = text[i:]
+		text = text[:i]
+	}
+	return s.redactor.restoreString(text)
+}
+
+// Flush retorna o texto retido no fim do stream.
+func (s *streamRestorer) Flush() string {
+	text := s.pending
+	s.pending = ""
+	return s.redactor.restoreString(text)
+}
+
+// transformStrings retorna uma cópia profunda de v com fn aplicada a cada string
+// (campos exportados de structs, elementos de slices e valores de maps).
+func transformStrings(v interface{}, fn func(string) string) interface{} {
+	if v == nil {
+		return nil
+	}
+	return transformValue(reflect.ValueOf(v), fn).Interface()
+}
+
+func transformValue(v reflect.Value, fn func(string) string) reflect.Value {
+	switch v.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(fn(v.String())).Convert(v.Type())
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Type().Elem())
+		copied.Elem().Set(transformValue(v.Elem(), fn))
+		return copied
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(transformValue(v.Elem(), fn))
+		return copied
+	case reflect.Struct:
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if field := copied.Field(i); field.CanSet() {
+				field.Set(transformValue(v.Field(i), fn))
+			}
+		}
+		return copied
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(transformValue(v.Index(i), fn))
+		}
+		return copied
+	case reflect.Array:
+		copied := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(transformValue(v.Index(i), fn))
+		}
+		return copied
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), transformValue(iter.Value(), fn))
+		}
+		return copied
+	}
+	return v
+}
+
+// validCPF confere os dígitos verificadores de um CPF (com ou sem formatação).
+func validCPF(value string) bool {
+	digits := onlyDigits(value)
+	if len(digits) != 11 || allSameDigit(digits) {
+		return false
+	}
+	for check := 9; check <= 10; check++ {
+		sum := 0
+		for i := 0; i < check; i++ {
+			sum += digits[i] * (check + 1 - i)
+		}
+		if (sum*10)%11%10 != digits[check] {
+			return false
+		}
+	}
+	return true
+}
+
+// validCNPJ confere os dígitos verificadores de um CNPJ (com ou sem formatação).
+func validCNPJ(value string) bool {
+	digits := onlyDigits(value)
+	if len(digits) != 14 || allSameDigit(digits) {
+		return false
+	}
+	weights := []int{6, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}
+	for check := 12; check <= 13; check++ {
+		sum := 0
+		for i := 0; i < check; i++ {
+			sum += digits[i] * weights[i+13-check]
+		}
+		dv := sum % 11
+		if dv < 2 {
+			dv = 0
+		} else {
+			dv = 11 - dv
+		}
+		if dv != digits[check] {
+			return false
+		}
+	}
+	return true
+}
+
+func onlyDigits(value string) []int {
+	digits := make([]int, 0, len(value))
+	for _, c := range value {
+		if c >= '0' && c <= '9' {
+			digits = append(digits, int(c-'0'))
+		}
+	}
+	return digits
+}
+
+func allSameDigit(digits []int) bool {
+	for _, d := range digits[1:] {
+		if d != digits[0] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,216 @@
+package ai_services
+
+import (
+	"os"
+	"projeto-integrador/models"
+	"projeto-integrador/utilities"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	utilities.InitLogger()
+	os.Exit(m.Run())
+}
+
+// newTestRedactor cria um Redactor com os tipos padrão, independente do ambiente.
+func newTestRedactor(t *testing.T, workspaceRules ...models.AIRedactionRule) *Redactor {
+	t.Helper()
+	t.Setenv("AI_REDACTION_ENABLED", "")
+	t.Setenv("AI_REDACTION_TYPES", "")
+	return NewRedactor(workspaceRules)
+}
+
+func TestRedactString(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"email", "Falar com ana.souza@empresa.com.br amanhã", "Falar com [EMAIL_1] amanhã"},
+		{"mesmo valor, mesmo placeholder", "a@b.com, c@d.com e a@b.com", "[EMAIL_1], [EMAIL_2] e [EMAIL_1]"},
+		{"cpf válido formatado", "CPF 529.982.247-25", "CPF [CPF_1]"},
+		{"cpf válido sem formatação", "CPF 52998224725", "CPF [CPF_1]"},
+		{"cpf com dígito errado", "pedido 529.982.247-24", "pedido 529.982.247-24"},
+		{"cpf com dígitos repetidos", "111.111.111-11", "111.111.111-11"},
+		{"cnpj válido", "CNPJ 11.222.333/0001-81", "CNPJ [CNPJ_1]"},
+		{"cnpj inválido", "CNPJ 11.222.333/0001-80", "CNPJ 11.222.333/0001-80"},
+		{"telefone", "Ligue (11) 98765-4321", "Ligue [PHONE_1]"},
+		{"telefone internacional", "Ligue +55 11 98765-4321", "Ligue [PHONE_1]"},
+		{"chave de API", "token sk-abcdefghijklmnopqrstuvwx no código", "token [API_KEY_1] no código"},
+		{"só o valor da senha", `password = "hunter2hunter2"`, `password = "[API_KEY_1]"`},
+		{"chave antes do e-mail", "api_key=usuario@servidor.com", "api_key=[API_KEY_1]"},
+		{"texto sem dados sensíveis", "Revisar a tarefa 123 até sexta", "Revisar a tarefa 123 até sexta"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRedactor(t)
+			if got := r.Redact(tt.text); got != tt.want {
+				t.Errorf("Redact(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactWorkspaceRule(t *testing.T) {
+	r := newTestRedactor(t,
+		models.AIRedactionRule{Name: "ticket", Pattern: `TCK-\d+`},
+		models.AIRedactionRule{Name: "cliente", Pattern: `cliente:\s*(\w+)`},
+		models.AIRedactionRule{Name: "quebrada", Pattern: `(`}, // Padrão inválido é ignorado
+	)
+	got := r.Redact("TCK-42 do cliente: Acme, contato a@b.com")
+	if want := "[TICKET_1] do cliente: [CLIENTE_1], contato [EMAIL_1]"; got != want {
+		t.Errorf("Redact = %q, want %q", got, want)
+	}
+	redactions := r.Redactions()
+	if len(redactions) != 3 || redactions[0].Type != "custom:ticket" || redactions[1].Type != "custom:cliente" || redactions[2].Type != RedactionEmail {
+		t.Errorf("Redactions = %+v", redactions)
+	}
+}
+
+func TestRedactDisabled(t *testing.T) {
+	t.Setenv("AI_REDACTION_ENABLED", "false")
+	r := NewRedactor([]models.AIRedactionRule{{Name: "ticket", Pattern: `TCK-\d+`}})
+	text := "TCK-1 de a@b.com"
+	if got := r.Redact(text); got != text {
+		t.Errorf("Redact com mascaramento desligado = %q", got)
+	}
+}
+
+func TestRedactTypesFromEnv(t *testing.T) {
+	t.Setenv("AI_REDACTION_ENABLED", "")
+	t.Setenv("AI_REDACTION_TYPES", "email")
+	r := NewRedactor(nil)
+	if got, want := r.Redact("a@b.com (11) 98765-4321"), "[EMAIL_1] (11) 98765-4321"; got != want {
+		t.Errorf("Redact = %q, want %q", got, want)
+	}
+}
+
+// Redact e Restore percorrem structs, ponteiros, slices e maps sem alterar o original.
+func TestRedactRestoreRoundTrip(t *testing.T) {
+	r := newTestRedactor(t)
+	request := models.CodeReviewAIRequest{
+		Code:          `db.Connect("admin@db.local", "password=s3cr3t-s3cr3t")`,
+		Files:         []string{"docs/ana@empresa.com/notas.md"},
+		AIPreferences: models.AIPreferences{CustomInstructions: "Responder para ana@empresa.com"},
+	}
+	original := request
+	original.Files = append([]string(nil), request.Files...)
+
+	redacted := r.Redact(request).(models.CodeReviewAIRequest)
+	if !reflect.DeepEqual(request, original) {
+		t.Fatalf("Redact alterou o payload original: %+v", request)
+	}
+	for _, value := range []string{"admin@db.local", "s3cr3t-s3cr3t", "ana@empresa.com"} {
+		if strings.Contains(redacted.Code+strings.Join(redacted.Files, "")+redacted.CustomInstructions, value) {
+			t.Errorf("valor %q chegou ao payload mascarado: %+v", value, redacted)
+		}
+	}
+	if redacted.Files[0] != "docs/[EMAIL_2]/notas.md" || redacted.CustomInstructions != "Responder para [EMAIL_2]" {
+		t.Errorf("placeholders inconsistentes entre campos: %+v", redacted)
+	}
+	if restored := r.Restore(redacted).(models.CodeReviewAIRequest); !reflect.DeepEqual(restored, original) {
+		t.Errorf("Restore(Redact(x)) = %+v, want %+v", restored, original)
+	}
+
+	// Resposta da IA com placeholders em estruturas aninhadas
+	response := map[string]interface{}{
+		"review":   "O e-mail [EMAIL_1] está fixo no código",
+		"findings": []models.CodeReviewFinding{{Message: "Remova [API_KEY_1]"}},
+		"unknown":  "[EMAIL_9] não foi gerado por esta requisição",
+	}
+	restored := r.Restore(response).(map[string]interface{})
+	if got := restored["review"]; got != "O e-mail admin@db.local está fixo no código" {
+		t.Errorf("review restaurado = %q", got)
+	}
+	if got := restored["findings"].([]models.CodeReviewFinding)[0].Message; got != "Remova s3cr3t-s3cr3t" {
+		t.Errorf("finding restaurado = %q", got)
+	}
+	if got := restored["unknown"]; got != "[EMAIL_9] não foi gerado por esta requisição" {
+		t.Errorf("placeholder desconhecido foi alterado: %q", got)
+	}
+	if response["review"] != "O e-mail [EMAIL_1] está fixo no código" {
+		t.Errorf("Restore alterou a resposta original")
+	}
+}
+
+// Os placeholders "[EMAIL_1]" e "[EMAIL_10]" não podem ser confundidos na restauração.
+func TestRestoreManyPlaceholders(t *testing.T) {
+	r := newTestRedactor(t)
+	var emails []string
+	for i := 0; i < 12; i++ {
+		emails = append(emails, strings.Repeat("x", i+1)+"@b.com")
+	}
+	text := strings.Join(emails, " ")
+	redacted := r.Redact(text).(string)
+	if !strings.Contains(redacted, "[EMAIL_10]") {
+		t.Fatalf("Redact = %q", redacted)
+	}
+	if got := r.Restore(redacted); got != text {
+		t.Errorf("Restore = %q, want %q", got, text)
+	}
+}
+
+// streamText envia text ao streamRestorer dividido nos pontos informados.
+func streamText(r *Redactor, text string, cuts ...int) string {
+	restorer := &streamRestorer{redactor: r}
+	var out strings.Builder
+	last := 0
+	for _, cut := range cuts {
+		out.WriteString(restorer.Write(text[last:cut]))
+		last = cut
+	}
+	out.WriteString(restorer.Write(text[last:]))
+	out.WriteString(restorer.Flush())
+	return out.String()
+}
+
+func TestStreamRestorerSplitPlaceholders(t *testing.T) {
+	r := newTestRedactor(t)
+	r.Redact("contato ana@empresa.com, chave sk-abcdefghijklmnopqrstuvwx")
+
+	responses := []string{
+		"Avise [EMAIL_1] e remova [API_KEY_1] do código.",
+		"[EMAIL_1]",
+		"[EMAIL_1][API_KEY_1]",
+		"Lista: [a] [b] [EMAIL_1] [",
+		"Colchete sem fim [ " + strings.Repeat("texto ", 20) + " e [EMAIL_1]",
+		"[EMAIL_9] não existe",
+	}
+	for _, response := range responses {
+		want := r.Restore(response).(string)
+
+		// Cada ponto de corte possível, com um e com dois trechos
+		for i := 0; i <= len(response); i++ {
+			if got := streamText(r, response, i); got != want {
+				t.Fatalf("corte em %d de %q = %q, want %q", i, response, got, want)
+			}
+			for j := i; j <= len(response); j++ {
+				if got := streamText(r, response, i, j); got != want {
+					t.Fatalf("cortes em %d e %d de %q = %q, want %q", i, j, response, got, want)
+				}
+			}
+		}
+
+		// Um byte por trecho
+		cuts := make([]int, 0, len(response))
+		for i := 1; i < len(response); i++ {
+			cuts = append(cuts, i)
+		}
+		if got := streamText(r, response, cuts...); got != want {
+			t.Errorf("byte a byte de %q = %q, want %q", response, got, want)
+		}
+	}
+}
+
+// Sem nada mascarado, os trechos passam direto, sem retenção.
+func TestStreamRestorerWithoutRedactions(t *testing.T) {
+	restorer := &streamRestorer{redactor: newTestRedactor(t)}
+	if got := restorer.Write("texto com [colchete"); got != "texto com [colchete" {
+		t.Errorf("Write = %q", got)
+	}
+	if got := restorer.Flush(); got != "" {
+		t.Errorf("Flush = %q", got)
+	}
+}
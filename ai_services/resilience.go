@@ -0,0 +1,347 @@
+package ai_services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"projeto-integrador/models"
+	"projeto-integrador/scheduler"
+	"projeto-integrador/utilities"
+	"sync"
+	"time"
+)
+
+// ErrAIUnavailable indica que o serviço de IA está indisponível (circuito aberto ou
+// falhas transitórias após todas as tentativas). Os handlers respondem 503 com Retry-After.
+var ErrAIUnavailable = errors.New("serviço de IA temporariamente indisponível")
+
+// UnavailableError carrega a sugestão de espera para o cliente. errors.Is(err, ErrAIUnavailable)
+// é verdadeiro para ele.
+type UnavailableError struct {
+	RetryAfter time.Duration
+	Cause      error
+}
+
+func (e *UnavailableError) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("%v: %v", ErrAIUnavailable, e.Cause)
+	}
+	return ErrAIUnavailable.Error()
+}
+
+func (e *UnavailableError) Is(target error) bool { return target == ErrAIUnavailable }
+
+func (e *UnavailableError) Unwrap() error { return e.Cause }
+
+// RetryAfterSeconds é o valor do cabeçalho Retry-After (mínimo 1).
+func (e *UnavailableError) RetryAfterSeconds() int { return retryAfterSeconds(e.RetryAfter) }
+
+// Estados do circuit breaker.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// CircuitBreaker abre após failureThreshold falhas seguidas do serviço e, enquanto aberto,
+// recusa chamadas até passar cooldown. Depois disso uma única chamada de teste
+// (half-open) decide se o circuito fecha ou volta a abrir.
+type CircuitBreaker struct {
+	mu                  sync.Mutex
+	failureThreshold    int
+	cooldown            time.Duration
+	state               string
+	consecutiveFailures int
+	openedAt            time.Time
+	probeInFlight       bool
+}
+
+// CircuitStatus é o retrato do circuito exposto no health check.
+type CircuitStatus struct {
+	State               string `json:"state"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	RetryAfterSeconds   int    `json:"retry_after_seconds,omitempty"`
+}
+
+func NewCircuitBreaker(failureThreshold int, cooldown time.Duration) *CircuitBreaker {
+	if failureThreshold < 1 {
+		failureThreshold = 1
+	}
+	return &CircuitBreaker{failureThreshold: failureThreshold, cooldown: cooldown, state: CircuitClosed}
+}
+
+// Allow informa se uma chamada pode seguir; se não, quanto tempo falta para nova tentativa.
+func (cb *CircuitBreaker) Allow() (bool, time.Duration) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case CircuitOpen:
+		remaining := cb.cooldown - time.Since(cb.openedAt)
+		if remaining > 0 {
+			return false, remaining
+		}
+		cb.state = CircuitHalfOpen
+		cb.probeInFlight = true
+		return true, 0
+	case CircuitHalfOpen:
+		if cb.probeInFlight {
+			return false, cb.cooldown / 2 // Outra requisição já está testando o serviço
+		}
+		cb.probeInFlight = true
+		return true, 0
+	}
+	return true, 0
+}
+
+// RecordSuccess fecha o circuito.
+func (cb *CircuitBreaker) RecordSuccess() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state != CircuitClosed {
+		utilities.LogInfo("AI CircuitBreaker: serviço de IA respondeu, fechando o circuito")
+	}
+	cb.state = CircuitClosed
+	cb.consecutiveFailures = 0
+	cb.probeInFlight = false
+}
+
+// ReleaseProbe libera a chamada de teste sem mudar o estado do circuito: a chamada foi
+// interrompida pelo chamador e não diz nada sobre a saúde do serviço.
+func (cb *CircuitBreaker) ReleaseProbe() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.probeInFlight = false
+}
+
+// RecordFailure conta uma falha do serviço e abre o circuito ao atingir o limite
+// (ou imediatamente, se a chamada de teste do half-open falhar).
+func (cb *CircuitBreaker) RecordFailure() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.consecutiveFailures++
+	cb.probeInFlight = false
+	if cb.state == CircuitHalfOpen || cb.consecutiveFailures >= cb.failureThreshold {
+		if cb.state != CircuitOpen {
+			utilities.LogInfo("AI CircuitBreaker: abrindo o circuito após %d falhas seguidas (cooldown %v)", cb.consecutiveFailures, cb.cooldown)
+		}
+		cb.state = CircuitOpen
+		cb.openedAt = time.Now()
+	}
+}
+
+// Status retorna o estado atual do circuito.
+func (cb *CircuitBreaker) Status() CircuitStatus {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	status := CircuitStatus{State: cb.state, ConsecutiveFailures: cb.consecutiveFailures}
+	if cb.state == CircuitOpen {
+		if remaining := cb.cooldown - time.Since(cb.openedAt); remaining > 0 {
+			status.RetryAfterSeconds = retryAfterSeconds(remaining)
+		}
+	}
+	return status
+}
+
+// RetryPolicy define as novas tentativas com backoff exponencial e jitter total.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// backoff retorna a espera antes da tentativa attempt+1 (attempt começa em 1).
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	ceiling := p.BaseDelay << (attempt - 1)
+	if ceiling > p.MaxDelay || ceiling <= 0 {
+		ceiling = p.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// ResilientProvider envolve outro AIProvider com novas tentativas para falhas
+// transitórias e um circuit breaker compartilhado por todas as funcionalidades.
+//
+// Variáveis de ambiente:
+//   - AI_RETRY_MAX_ATTEMPTS: tentativas por chamada (padrão: 3)
+//   - AI_RETRY_BASE_DELAY / AI_RETRY_MAX_DELAY: backoff (padrão: 500ms / 5s)
+//   - AI_CIRCUIT_FAILURE_THRESHOLD: falhas seguidas para abrir o circuito (padrão: 5)
+//   - AI_CIRCUIT_COOLDOWN: tempo com o circuito aberto (padrão: 30s)
+type ResilientProvider struct {
+	inner   AIProvider
+	retry   RetryPolicy
+	breaker *CircuitBreaker
+}
+
+// NewResilientProviderFromEnv envolve inner com a configuração do ambiente.
+func NewResilientProviderFromEnv(inner AIProvider) *ResilientProvider {
+	return &ResilientProvider{
+		inner: inner,
+		retry: RetryPolicy{
+			MaxAttempts: scheduler.IntFromEnv("AI_RETRY_MAX_ATTEMPTS", 3),
+			BaseDelay:   scheduler.DurationFromEnv("AI_RETRY_BASE_DELAY", 500*time.Millisecond),
+			MaxDelay:    scheduler.DurationFromEnv("AI_RETRY_MAX_DELAY", 5*time.Second),
+		},
+		breaker: NewCircuitBreaker(scheduler.IntFromEnv("AI_CIRCUIT_FAILURE_THRESHOLD", 5), scheduler.DurationFromEnv("AI_CIRCUIT_COOLDOWN", 30*time.Second)),
+	}
+}
+
+func (p *ResilientProvider) Name() string { return p.inner.Name() }
+
+// Breaker expõe o circuit breaker (para o health check).
+func (p *ResilientProvider) Breaker() *CircuitBreaker { return p.breaker }
+
+// Inner retorna o provedor envolvido.
+func (p *ResilientProvider) Inner() AIProvider { return p.inner }
+
+func (p *ResilientProvider) Summarize(ctx context.Context, req models.SummarizeTextAIRequest) (*models.SummarizeTextAIResponse, AICallResult, error) {
+	var resp *models.SummarizeTextAIResponse
+	result, err := p.execute(ctx, "summarize", func(ctx context.Context) (AICallResult, error) {
+		var result AICallResult
+		var err error
+		resp, result, err = p.inner.Summarize(ctx, req)
+		return result, err
+	})
+	return resp, result, err
+}
+
+func (p *ResilientProvider) CodeReview(ctx context.Context, req models.CodeReviewAIRequest) (*models.CodeReviewAIResponse, AICallResult, error) {
+	var resp *models.CodeReviewAIResponse
+	result, err := p.execute(ctx, "code_review", func(ctx context.Context) (AICallResult, error) {
+		var result AICallResult
+		var err error
+		resp, result, err = p.inner.CodeReview(ctx, req)
+		return result, err
+	})
+	return resp, result, err
+}
+
+func (p *ResilientProvider) MindMapIdeas(ctx context.Context, req models.MindMapIdeasAIRequest) (*models.MindMapIdeasAIResponse, AICallResult, error) {
+	var resp *models.MindMapIdeasAIResponse
+	result, err := p.execute(ctx, "mindmap_ideas", func(ctx context.Context) (AICallResult, error) {
+		var result AICallResult
+		var err error
+		resp, result, err = p.inner.MindMapIdeas(ctx, req)
+		return result, err
+	})
+	return resp, result, err
+}
+
+func (p *ResilientProvider) TaskAssistant(ctx context.Context, req models.TaskAssistantAIRequest) (*models.TaskAssistantAIResponse, AICallResult, error) {
+	var resp *models.TaskAssistantAIResponse
+	result, err := p.execute(ctx, "task_assistant", func(ctx context.Context) (AICallResult, error) {
+		var result AICallResult
+		var err error
+		resp, result, err = p.inner.TaskAssistant(ctx, req)
+		return result, err
+	})
+	return resp, result, err
+}
+
+func (p *ResilientProvider) BreakdownTask(ctx context.Context, req models.TaskBreakdownAIRequest) (*models.TaskBreakdownAIResponse, AICallResult, error) {
+	var resp *models.TaskBreakdownAIResponse
+	result, err := p.execute(ctx, "task_breakdown", func(ctx context.Context) (AICallResult, error) {
+		var result AICallResult
+		var err error
+		resp, result, err = p.inner.BreakdownTask(ctx, req)
+		return result, err
+	})
+	return resp, result, err
+}
+
+// execute aplica o circuit breaker e as novas tentativas. Todas as funcionalidades de
+// IA atuais são idempotentes (não alteram estado no serviço), por isso podem ser repetidas.
+func (p *ResilientProvider) execute(ctx context.Context, operation string, call func(ctx context.Context) (AICallResult, error)) (AICallResult, error) {
+	var result AICallResult
+	var err error
+	for attempt := 1; attempt <= p.retry.MaxAttempts; attempt++ {
+		allowed, retryAfter := p.breaker.Allow()
+		if !allowed {
+			return result, &UnavailableError{RetryAfter: retryAfter, Cause: err}
+		}
+
+		result, err = call(ctx)
+		result.Attempts = attempt
+		switch {
+		case err == nil:
+			p.breaker.RecordSuccess()
+			return result, nil
+		case ctx.Err() != nil || errors.Is(err, ErrClientDisconnected):
+			// O chamador cancelou, estourou o próprio prazo ou desconectou: o serviço não
+			// chegou a responder, então o circuito fica como está
+			p.breaker.ReleaseProbe()
+			return result, err
+		case !isTransientAIFailure(result, err):
+			if result.StatusCode >= 200 && result.StatusCode < 500 {
+				p.breaker.RecordSuccess() // Erro do cliente (ex: 400): o serviço respondeu e está saudável
+			} else {
+				p.breaker.ReleaseProbe()
+			}
+			return result, err
+		}
+		p.breaker.RecordFailure()
+
+		var interrupted *streamInterruptedError
+		if errors.As(err, &interrupted) || attempt == p.retry.MaxAttempts {
+			break
+		}
+		delay := p.retry.backoff(attempt)
+		utilities.LogInfo("AIProvider: %s falhou (tentativa %d/%d, status %d): %v; nova tentativa em %v",
+			operation, attempt, p.retry.MaxAttempts, result.StatusCode, err, delay)
+		select {
+		case <-ctx.Done():
+			return result, err
+		case <-time.After(delay):
+		}
+	}
+
+	var interrupted *streamInterruptedError
+	if errors.As(err, &interrupted) {
+		return result, interrupted.err // Parte da resposta já foi entregue: não vira 503
+	}
+	retryAfter := p.breaker.Status().RetryAfterSeconds
+	if retryAfter == 0 {
+		retryAfter = retryAfterSeconds(p.retry.MaxDelay)
+	}
+	return result, &UnavailableError{RetryAfter: time.Duration(retryAfter) * time.Second, Cause: err}
+}
+
+// streamInterruptedError marca um stream que o serviço interrompeu depois de enviar
+// trechos ao cliente: conta como falha para o circuit breaker, mas não pode ser repetido
+// sem duplicar texto.
+type streamInterruptedError struct{ err error }
+
+func (e *streamInterruptedError) Error() string { return e.err.Error() }
+
+func (e *streamInterruptedError) Unwrap() error { return e.err }
+
+// isTransientAIFailure diz se a falha indica indisponibilidade do serviço (conta para o
+// circuit breaker e, exceto streams interrompidos, vale repetir): erros de rede,
+// timeouts, 408, 429, 5xx e streams interrompidos pelo serviço.
+func isTransientAIFailure(result AICallResult, err error) bool {
+	var interrupted *streamInterruptedError
+	switch {
+	case errors.As(err, &interrupted):
+		return true
+	case result.StatusCode == 0:
+		return true // Sem resposta: rede, DNS, timeout da chamada
+	case result.StatusCode == http.StatusRequestTimeout, result.StatusCode == http.StatusTooManyRequests:
+		return true
+	case result.StatusCode >= 500:
+		return true
+	}
+	return false
+}
+
+func retryAfterSeconds(d time.Duration) int {
+	seconds := int((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
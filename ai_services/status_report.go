@@ -0,0 +1,514 @@
+package ai_services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"projeto-integrador/database"
+	"projeto-integrador/firebase"
+	"projeto-integrador/models"
+	"projeto-integrador/scheduler"
+	"projeto-integrador/task_services"
+	"projeto-integrador/utilities"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	statusReportsSubCollectionName = "status_reports"
+
+	// Chave do advisory lock usada para que só uma réplica gere os relatórios agendados.
+	statusReportSchedulerLockKey int64 = 260004
+
+	StatusReportPeriod    = 7 * 24 * time.Hour // Período padrão (e dos relatórios agendados)
+	maxStatusReportTasks  = 25                 // Tarefas de cada lista do relatório
+	maxStatusReportPrompt = 20                 // Tarefas de cada lista enviadas à IA
+)
+
+var (
+	// ErrStatusReportNotFound indica um relatório inexistente no workspace.
+	ErrStatusReportNotFound = errors.New("status report not found")
+	// ErrInvalidStatusReportSchedule indica um agendamento inválido.
+	ErrInvalidStatusReportSchedule = errors.New("invalid status report schedule")
+
+	// Não há status "bloqueada": tarefas abertas com uma destas etiquetas contam como bloqueadas.
+	blockedTaskLabels = map[string]bool{"blocked": true, "bloqueada": true, "bloqueado": true, "bloqueio": true}
+)
+
+// StatusReportsCollection retorna os relatórios de status do workspace: /workspaces/{workspace_id}/status_reports
+func StatusReportsCollection(client *firestore.Client, workspaceIDPg int64) *firestore.CollectionRef {
+	return client.Collection("workspaces").Doc(strconv.FormatInt(workspaceIDPg, 10)).Collection(statusReportsSubCollectionName)
+}
+
+// isBlockedTask informa se a tarefa tem uma etiqueta de bloqueio.
+func isBlockedTask(task models.TaskDetailsFirestore) bool {
+	for _, label := range task.Labels {
+		if blockedTaskLabels[strings.ToLower(strings.TrimSpace(label))] {
+			return true
+		}
+	}
+	return false
+}
+
+func statusReportTask(taskID string, task models.TaskDetailsFirestore) models.StatusReportTask {
+	return models.StatusReportTask{
+		TaskID:              taskID,
+		Title:               task.Title,
+		Priority:            task.Priority,
+		AssigneeFirebaseUID: task.AssigneeFirebaseUID,
+		DueDate:             task.ExpirationDate,
+		CompletedAt:         task.CompletedAt,
+	}
+}
+
+// BuildStatusReport calcula os números do workspace no período [since, until): tarefas
+// concluídas e criadas no período e, ao fim dele, as abertas, atrasadas e bloqueadas,
+// além da contribuição de cada membro atual. Conclusões sem completed_by contam para o
+// responsável pela tarefa. O resumo da IA não é gerado aqui.
+func BuildStatusReport(ctx context.Context, client *firestore.Client, db *sql.DB, workspaceIDPg int64, since, until time.Time) (*models.StatusReport, error) {
+	members, err := models.ListWorkspaceMembers(db, workspaceIDPg)
+	if err != nil {
+		return nil, err
+	}
+	taskIDs, tasks, err := task_services.ListTasks(ctx, client, workspaceIDPg)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.StatusReport{
+		WorkspaceIDPg: workspaceIDPg,
+		PeriodStart:   since,
+		PeriodEnd:     until,
+		Completed:     []models.StatusReportTask{},
+		Overdue:       []models.StatusReportTask{},
+		Blocked:       []models.StatusReportTask{},
+		Members:       make([]models.StatusReportMember, len(members)),
+	}
+	byUID := make(map[string]*models.StatusReportMember, len(members))
+	for i, member := range members {
+		report.Members[i] = models.StatusReportMember{UserID: member.UserID, Name: member.DisplayName}
+		byUID[member.UserID] = &report.Members[i]
+	}
+	inPeriod := func(t time.Time) bool { return !t.Before(since) && t.Before(until) }
+
+	for i, task := range tasks {
+		if !task.CreatedAt.Before(until) {
+			continue
+		}
+		if inPeriod(task.CreatedAt) {
+			report.Totals.Created++
+			if member := byUID[task.CreatorFirebaseUID]; member != nil {
+				member.Created++
+			}
+		}
+
+		completedBeforeEnd := task.Status == "completed" && task.CompletedAt != nil && task.CompletedAt.Before(until)
+		if completedBeforeEnd {
+			if inPeriod(*task.CompletedAt) {
+				report.Totals.Completed++
+				report.Completed = append(report.Completed, statusReportTask(taskIDs[i], task))
+				completedBy := task.CompletedByFirebaseUID
+				if completedBy == "" {
+					completedBy = task.AssigneeFirebaseUID
+				}
+				if member := byUID[completedBy]; member != nil {
+					member.Completed++
+				}
+			}
+			continue
+		}
+		if task.Status == "skipped" {
+			continue // Ocorrência pulada de uma série recorrente: encerrada, sem ter sido concluída
+		}
+
+		report.Totals.Open++
+		overdue := task.ExpirationDate != nil && task.ExpirationDate.Before(until)
+		if overdue {
+			report.Totals.Overdue++
+			report.Overdue = append(report.Overdue, statusReportTask(taskIDs[i], task))
+		}
+		if isBlockedTask(task) {
+			report.Totals.Blocked++
+			report.Blocked = append(report.Blocked, statusReportTask(taskIDs[i], task))
+		}
+		if member := byUID[task.AssigneeFirebaseUID]; member != nil {
+			member.OpenAssigned++
+			if overdue {
+				member.OverdueAssigned++
+			}
+		}
+	}
+
+	sort.Slice(report.Completed, func(i, j int) bool { return report.Completed[i].CompletedAt.After(*report.Completed[j].CompletedAt) })
+	sort.SliceStable(report.Overdue, func(i, j int) bool { return report.Overdue[i].DueDate.Before(*report.Overdue[j].DueDate) })
+	sort.SliceStable(report.Members, func(i, j int) bool {
+		a, b := report.Members[i], report.Members[j]
+		if a.Completed != b.Completed {
+			return a.Completed > b.Completed
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+	report.Completed = capStatusReportTasks(report.Completed, maxStatusReportTasks)
+	report.Overdue = capStatusReportTasks(report.Overdue, maxStatusReportTasks)
+	report.Blocked = capStatusReportTasks(report.Blocked, maxStatusReportTasks)
+	return report, nil
+}
+
+func capStatusReportTasks(tasks []models.StatusReportTask, max int) []models.StatusReportTask {
+	if len(tasks) > max {
+		return tasks[:max]
+	}
+	return tasks
+}
+
+// GenerateStatusReport monta o relatório do período, pede à IA o resumo (serviço de
+// resumo de texto, cobrado de userID) e salva o relatório. Se a IA estiver desativada no
+// workspace, a cota acabar ou a chamada falhar, o relatório é salvo sem o resumo e com
+// o motivo em narrative_error.
+func GenerateStatusReport(ctx context.Context, client *firestore.Client, db *sql.DB, workspaceIDPg int64, userID string, since, until time.Time, trigger string) (*models.StatusReport, error) {
+	report, err := BuildStatusReport(ctx, client, db, workspaceIDPg, since, until)
+	if err != nil {
+		return nil, err
+	}
+	report.Trigger = trigger
+	report.GeneratedBy = userID
+	addStatusReportNarrative(ctx, db, report, userID)
+
+	report.GeneratedAt = time.Now()
+	ref, _, err := StatusReportsCollection(client, workspaceIDPg).Add(ctx, report)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao salvar relatório de status: %w", err)
+	}
+	report.ID = ref.ID
+	return report, nil
+}
+
+// addStatusReportNarrative preenche o resumo do relatório, ou o motivo de não tê-lo.
+// A chamada só é cobrada de userID se ele ainda for membro do workspace. Segue as configurações de IA do workspace: sem include_member_names, os membros são
+// enviados como "Membro N".
+func addStatusReportNarrative(ctx context.Context, db *sql.DB, report *models.StatusReport, userID string) {
+	if userID == "" {
+		report.NarrativeError = "no user to charge the AI request to"
+		return
+	}
+	// Nos relatórios agendados, userID é quem alterou o agendamento por último e pode
+	// ter saído do workspace desde então: a cota não é cobrada de quem não é membro.
+	isMember, err := models.IsWorkspaceMember(db, userID, report.WorkspaceIDPg)
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("addStatusReportNarrative: Erro ao verificar membresia de %s no workspace %d", userID, report.WorkspaceIDPg))
+		report.NarrativeError = "failed to check workspace membership"
+		return
+	}
+	if !isMember {
+		report.NarrativeError = "the user to charge the AI request to is no longer a workspace member"
+		return
+	}
+	settings, err := models.GetWorkspaceAISettings(db, report.WorkspaceIDPg)
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("addStatusReportNarrative: Erro ao carregar configurações de IA do workspace %d", report.WorkspaceIDPg))
+		report.NarrativeError = ErrAIContextUnavailable.Error()
+		return
+	}
+	if err := CheckAIServiceEnabled(settings, ServiceTextSummary); err != nil {
+		report.NarrativeError = err.Error()
+		return
+	}
+
+	input := models.SummarizeTextAIRequest{Text: statusReportPrompt(report, settings.IncludeMemberNames)}
+	if err := ReserveAIQuota(db, report.WorkspaceIDPg, userID, ServiceTextSummary, input); err != nil {
+		if !errors.Is(err, ErrAIQuotaExceeded) {
+			utilities.LogError(err, fmt.Sprintf("addStatusReportNarrative: Erro ao reservar cota de IA do workspace %d", report.WorkspaceIDPg))
+			err = errors.New("failed to check AI quota")
+		}
+		report.NarrativeError = err.Error()
+		return
+	}
+
+	exec, errAI := ExecuteAIRequest(ctx, GetProvider(), report.WorkspaceIDPg, ServiceTextSummary, input, AIRequestOptions{})
+	exec.Source = AISourceReport
+	report.HistoryID = RecordAIInteraction(ctx, userID, report.WorkspaceIDPg, exec, errAI)
+	if errAI != nil {
+		report.NarrativeError = AIErrorMessage(exec.Result, errAI)
+		return
+	}
+	if resp, ok := exec.Response.(models.SummarizeTextAIResponse); ok {
+		report.Narrative = strings.TrimSpace(resp.Summary)
+	}
+	if report.Narrative == "" {
+		report.NarrativeError = "the AI returned an empty summary"
+	}
+}
+
+// statusReportPrompt escreve os números do relatório como texto para o resumo da IA.
+func statusReportPrompt(report *models.StatusReport, includeMemberNames bool) string {
+	names := make(map[string]string, len(report.Members))
+	for i, member := range report.Members {
+		names[member.UserID] = member.Name
+		if !includeMemberNames {
+			names[member.UserID] = fmt.Sprintf("Membro %d", i+1)
+		}
+	}
+	assignee := func(task models.StatusReportTask) string {
+		if name := names[task.AssigneeFirebaseUID]; name != "" {
+			return ", responsável: " + name
+		}
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Escreva um resumo curto do andamento do projeto para o relatório de status de %s a %s.\n\n",
+		report.PeriodStart.Format("02/01/2006"), report.PeriodEnd.Format("02/01/2006"))
+	totals := report.Totals
+	fmt.Fprintf(&b, "Tarefas concluídas no período: %d. Criadas no período: %d. Abertas ao fim do período: %d, sendo %d atrasadas e %d bloqueadas.\n",
+		totals.Completed, totals.Created, totals.Open, totals.Overdue, totals.Blocked)
+
+	writeTasks := func(title string, tasks []models.StatusReportTask, detail func(models.StatusReportTask) string) {
+		if len(tasks) == 0 {
+			return
+		}
+		b.WriteString("\n" + title + ":\n")
+		for _, task := range capStatusReportTasks(tasks, maxStatusReportPrompt) {
+			b.WriteString("- " + task.Title + detail(task) + assignee(task) + "\n")
+		}
+	}
+	writeTasks("Concluídas", report.Completed, func(models.StatusReportTask) string { return "" })
+	writeTasks("Atrasadas", report.Overdue, func(task models.StatusReportTask) string {
+		return " (venceu em " + task.DueDate.Format("02/01/2006") + ")"
+	})
+	writeTasks("Bloqueadas", report.Blocked, func(models.StatusReportTask) string { return "" })
+
+	if len(report.Members) > 0 {
+		b.WriteString("\nContribuição por membro:\n")
+		for _, member := range report.Members {
+			fmt.Fprintf(&b, "- %s: %d concluídas, %d criadas, %d abertas atribuídas (%d atrasadas)\n",
+				names[member.UserID], member.Completed, member.Created, member.OpenAssigned, member.OverdueAssigned)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// StatusReportMarkdown exporta o relatório como documento Markdown.
+func StatusReportMarkdown(report *models.StatusReport) string {
+	names := make(map[string]string, len(report.Members))
+	for _, member := range report.Members {
+		names[member.UserID] = member.Name
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Relatório de status — %s a %s\n\n", report.PeriodStart.Format("02/01/2006"), report.PeriodEnd.Format("02/01/2006"))
+
+	b.WriteString("## Resumo\n\n")
+	switch {
+	case report.Narrative != "":
+		b.WriteString(report.Narrative + "\n\n")
+	case report.NarrativeError != "":
+		b.WriteString("_Resumo da IA indisponível: " + report.NarrativeError + "_\n\n")
+	}
+	totals := report.Totals
+	b.WriteString("| Concluídas | Criadas | Abertas | Atrasadas | Bloqueadas |\n|---|---|---|---|---|\n")
+	fmt.Fprintf(&b, "| %d | %d | %d | %d | %d |\n", totals.Completed, totals.Created, totals.Open, totals.Overdue, totals.Blocked)
+
+	writeTasks := func(title string, tasks []models.StatusReportTask, total int, detail func(models.StatusReportTask) string) {
+		if len(tasks) == 0 {
+			return
+		}
+		b.WriteString("\n## " + title + "\n\n")
+		for _, task := range tasks {
+			line := "- " + task.Title + detail(task)
+			if name := names[task.AssigneeFirebaseUID]; name != "" {
+				line += " — " + name
+			}
+			b.WriteString(line + "\n")
+		}
+		if total > len(tasks) {
+			fmt.Fprintf(&b, "- … e mais %d\n", total-len(tasks))
+		}
+	}
+	writeTasks("Concluídas no período", report.Completed, totals.Completed, func(models.StatusReportTask) string { return "" })
+	writeTasks("Atrasadas", report.Overdue, totals.Overdue, func(task models.StatusReportTask) string {
+		return " (venceu em " + task.DueDate.Format("02/01/2006") + ")"
+	})
+	writeTasks("Bloqueadas", report.Blocked, totals.Blocked, func(models.StatusReportTask) string { return "" })
+
+	if len(report.Members) > 0 {
+		b.WriteString("\n## Contribuição por membro\n\n| Membro | Concluídas | Criadas | Abertas | Atrasadas |\n|---|---|---|---|---|\n")
+		for _, member := range report.Members {
+			fmt.Fprintf(&b, "| %s | %d | %d | %d | %d |\n", member.Name, member.Completed, member.Created, member.OpenAssigned, member.OverdueAssigned)
+		}
+	}
+	return b.String()
+}
+
+// GetStatusReport busca um relatório de status do workspace.
+func GetStatusReport(ctx context.Context, client *firestore.Client, workspaceIDPg int64, reportID string) (*models.StatusReport, error) {
+	doc, err := StatusReportsCollection(client, workspaceIDPg).Doc(reportID).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrStatusReportNotFound
+		}
+		return nil, fmt.Errorf("erro ao buscar relatório de status %s: %w", reportID, err)
+	}
+	var report models.StatusReport
+	if err := doc.DataTo(&report); err != nil {
+		return nil, fmt.Errorf("erro ao ler relatório de status %s: %w", reportID, err)
+	}
+	report.ID = doc.Ref.ID
+	return &report, nil
+}
+
+// ListStatusReports lista os relatórios de status do workspace (sem as listas de
+// tarefas), os mais recentes primeiro.
+func ListStatusReports(ctx context.Context, client *firestore.Client, workspaceIDPg int64) ([]models.StatusReportSummary, error) {
+	iter := StatusReportsCollection(client, workspaceIDPg).
+		Select("period_start", "period_end", "trigger", "generated_by", "generated_at", "totals").Documents(ctx)
+	defer iter.Stop()
+
+	summaries := []models.StatusReportSummary{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao listar relatórios de status: %w", err)
+		}
+		var report models.StatusReport
+		if err := doc.DataTo(&report); err != nil {
+			continue
+		}
+		summaries = append(summaries, models.StatusReportSummary{
+			ID:          doc.Ref.ID,
+			PeriodStart: report.PeriodStart,
+			PeriodEnd:   report.PeriodEnd,
+			Trigger:     report.Trigger,
+			GeneratedBy: report.GeneratedBy,
+			GeneratedAt: report.GeneratedAt,
+			Totals:      report.Totals,
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].GeneratedAt.After(summaries[j].GeneratedAt) })
+	return summaries, nil
+}
+
+// ValidateStatusReportSchedule confere o dia da semana e a hora do agendamento.
+func ValidateStatusReportSchedule(schedule models.StatusReportSchedule) error {
+	if schedule.Weekday < 0 || schedule.Weekday > 6 {
+		return fmt.Errorf("%w: weekday must be between 0 (Sunday) and 6 (Saturday)", ErrInvalidStatusReportSchedule)
+	}
+	if schedule.Hour < 0 || schedule.Hour > 23 {
+		return fmt.Errorf("%w: hour must be between 0 and 23 (UTC)", ErrInvalidStatusReportSchedule)
+	}
+	return nil
+}
+
+// lastStatusReportRun retorna o horário agendado mais recente até now (UTC).
+func lastStatusReportRun(schedule models.StatusReportSchedule, now time.Time) time.Time {
+	now = now.UTC()
+	daysSince := (int(now.Weekday()) - schedule.Weekday + 7) % 7
+	scheduledAt := time.Date(now.Year(), now.Month(), now.Day(), schedule.Hour, 0, 0, 0, time.UTC).AddDate(0, 0, -daysSince)
+	if scheduledAt.After(now) {
+		scheduledAt = scheduledAt.AddDate(0, 0, -7)
+	}
+	return scheduledAt
+}
+
+// StartStatusReportScheduler inicia em background a geração dos relatórios de status
+// agendados pelos workspaces. Cada relatório cobre os 7 dias anteriores ao horário
+// agendado e o resumo da IA é cobrado de quem configurou o agendamento.
+//
+// Variáveis de ambiente:
+//   - STATUS_REPORT_SCHEDULER_ENABLED: "false" desliga o scheduler (padrão: ligado)
+//   - STATUS_REPORT_SCHEDULER_INTERVAL: intervalo entre verificações (padrão: 15m)
+//
+// Cada execução é reservada em status_report_schedules.last_run_at antes da geração,
+// para que um horário gere um único relatório mesmo com reinícios ou várias réplicas.
+func StartStatusReportScheduler(ctx context.Context) {
+	if !scheduler.EnabledFromEnv("STATUS_REPORT_SCHEDULER_ENABLED") {
+		utilities.LogInfo("Scheduler de relatórios de status desativado por STATUS_REPORT_SCHEDULER_ENABLED")
+		return
+	}
+	interval := scheduler.DurationFromEnv("STATUS_REPORT_SCHEDULER_INTERVAL", 15*time.Minute)
+	scheduler.Every(ctx, "status-reports", interval, runStatusReportScheduler)
+}
+
+func runStatusReportScheduler(ctx context.Context) {
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, "StatusReportScheduler: Erro ao conectar ao PG")
+		return
+	}
+	defer db.Close()
+
+	ran, err := scheduler.WithAdvisoryLock(ctx, db, statusReportSchedulerLockKey, func(ctx context.Context) error {
+		return generateScheduledStatusReports(ctx, db)
+	})
+	if err != nil {
+		utilities.LogError(err, "StatusReportScheduler: Erro ao gerar relatórios agendados")
+		return
+	}
+	if !ran {
+		utilities.LogDebug("StatusReportScheduler: Outra instância está gerando os relatórios, pulando este ciclo")
+	}
+}
+
+// generateScheduledStatusReports gera os relatórios cujo horário já passou. Só o
+// horário mais recente de cada workspace é gerado, e horários anteriores à última
+// alteração do agendamento são ignorados.
+func generateScheduledStatusReports(ctx context.Context, db *sql.DB) error {
+	schedules, err := models.ListEnabledStatusReportSchedules(db)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var pending []models.StatusReportSchedule
+	for _, schedule := range schedules {
+		scheduledAt := lastStatusReportRun(schedule, now)
+		if schedule.LastRunAt != nil && !schedule.LastRunAt.Before(scheduledAt) {
+			continue
+		}
+		if schedule.UpdatedAt != nil && schedule.UpdatedAt.After(scheduledAt) {
+			continue
+		}
+		pending = append(pending, schedule)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	firestoreClient, err := firebase.GetFirestoreClient()
+	if err != nil {
+		return err
+	}
+	defer firestoreClient.Close()
+
+	for _, schedule := range pending {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		scheduledAt := lastStatusReportRun(schedule, now)
+		claimed, err := models.ClaimStatusReportRun(db, schedule.WorkspaceID, scheduledAt)
+		if err != nil {
+			utilities.LogError(err, fmt.Sprintf("StatusReportScheduler: Erro ao reservar relatório do workspace %d", schedule.WorkspaceID))
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		report, err := GenerateStatusReport(ctx, firestoreClient, db, schedule.WorkspaceID, schedule.UpdatedBy,
+			scheduledAt.Add(-StatusReportPeriod), scheduledAt, models.StatusReportScheduled)
+		if err != nil {
+			// O horário já foi reservado: não é tentado de novo, o erro fica só no log.
+			utilities.LogError(err, fmt.Sprintf("StatusReportScheduler: Erro ao gerar relatório do workspace %d", schedule.WorkspaceID))
+			continue
+		}
+		utilities.LogInfo("StatusReportScheduler: Relatório %s do workspace %d gerado (%s)", report.ID, schedule.WorkspaceID, scheduledAt.Format(time.RFC3339))
+	}
+	return nil
+}
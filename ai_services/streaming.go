@@ -0,0 +1,292 @@
+package ai_services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"projeto-integrador/models"
+	"projeto-integrador/scheduler"
+	"strings"
+	"time"
+)
+
+// ErrClientDisconnected indica que o cliente fechou a conexão durante o streaming.
+var ErrClientDisconnected = errors.New("client disconnected during AI stream")
+
+// StreamingProvider é implementado pelos provedores que conseguem devolver a resposta
+// em partes. onChunk recebe cada trecho de texto na ordem; se retornar erro, o
+// streaming é interrompido e o erro é devolvido.
+type StreamingProvider interface {
+	AIProvider
+	SupportsStreaming() bool
+	Stream(ctx context.Context, serviceType string, requestToAI interface{}, onChunk func(chunk string) error) (AICallResult, error)
+}
+
+// StreamAIRequest executa a requisição enviando o texto ao cliente conforme é gerado.
+// Provedores sem streaming respondem de uma vez, como um único trecho. Ao final (ou se
+// o cliente desconectar), a resposta é montada a partir do texto recebido, para o
+// histórico de IA. Uma resposta em cache é enviada como um único trecho; respostas
+// recebidas por streaming não são gravadas no cache.
+func StreamAIRequest(ctx context.Context, provider AIProvider, workspaceIDPg int64, serviceType string, input interface{}, opts AIRequestOptions, onChunk func(chunk string) error) (*AIExecution, error) {
+	exec := &AIExecution{
+		ServiceType: serviceType,
+		Source:      AISourceStream,
+		Provider:    provider.Name(),
+		Endpoint:    aiEndpoint(provider.Name(), serviceType, false),
+		Input:       input,
+	}
+	requestToAI, redactor, err := prepareRequestToAI(ctx, workspaceIDPg, serviceType, input)
+	if err != nil {
+		return exec, err
+	}
+	exec.RequestToAI, exec.Redactions = requestToAI, redactor.Redactions()
+
+	var text strings.Builder
+	deliver := func(chunk string) error {
+		if chunk == "" {
+			return nil
+		}
+		text.WriteString(chunk)
+		if err := onChunk(chunk); err != nil {
+			return fmt.Errorf("%w: %w", ErrClientDisconnected, err)
+		}
+		return nil
+	}
+	restorer := &streamRestorer{redactor: redactor}
+	emit := func(chunk string) error {
+		return deliver(restorer.Write(chunk))
+	}
+
+	start := time.Now()
+	if cache, cacheKey := aiCacheFor(provider, serviceType, requestToAI); cache != nil && !opts.NoCache {
+		if cached, ok := cache.Get(serviceType, cacheKey); ok {
+			exec.Response = markCached(redactor.Restore(cached))
+			exec.Result = AICallResult{StatusCode: http.StatusOK}
+			exec.CacheHit = true
+			err = deliver(ResponseText(exec.Response))
+			exec.Latency = time.Since(start)
+			return exec, err
+		}
+	}
+	if streamer, ok := provider.(StreamingProvider); ok && streamer.SupportsStreaming() {
+		exec.Endpoint = aiEndpoint(provider.Name(), serviceType, true)
+		exec.Result, err = streamer.Stream(ctx, serviceType, requestToAI, emit)
+	} else {
+		var response interface{}
+		response, exec.Result, err = callProvider(ctx, provider, requestToAI)
+		if err == nil {
+			err = emit(ResponseText(response))
+		}
+	}
+	exec.Latency = time.Since(start)
+	if tail := restorer.Flush(); err == nil {
+		err = deliver(tail)
+	} else {
+		text.WriteString(tail)
+	}
+
+	if err != nil && ctx.Err() != nil && !errors.Is(err, ErrClientDisconnected) {
+		err = fmt.Errorf("%w: %w", ErrClientDisconnected, ctx.Err())
+	}
+	if text.Len() > 0 || err == nil {
+		// O texto já chegou restaurado; a requisição também precisa estar, para que os
+		// achados e as tarefas do contexto não mostrem placeholders
+		restoredRequest := redactor.Restore(requestToAI)
+		exec.Response = assembleStreamedResponse(serviceType, text.String(), restoredRequest)
+		if resp, ok := exec.Response.(models.TaskAssistantAIResponse); ok {
+			if req, ok := restoredRequest.(models.TaskAssistantAIRequest); ok {
+				exec.Response = withContextTasks(resp, req)
+			}
+		}
+	}
+	return exec, err
+}
+
+// ResponseText retorna o texto principal de uma resposta de IA, no formato enviado
+// pelo streaming.
+func ResponseText(response interface{}) string {
+	switch resp := response.(type) {
+	case models.CodeReviewAIResponse:
+		return resp.Review
+	case models.SummarizeTextAIResponse:
+		return resp.Summary
+	case models.MindMapIdeasAIResponse:
+		return resp.MindMapIdeas
+	case models.TaskAssistantAIResponse:
+		return strings.Join(resp.Suggestions, "\n")
+	}
+	return ""
+}
+
+// assembleStreamedResponse monta a resposta tipada do serviço a partir do texto recebido.
+// No code review, os achados são extraídos do texto e normalizados como na rota normal;
+// no assistente de tarefas, cada linha não vazia é uma sugestão.
+func assembleStreamedResponse(serviceType string, text string, requestToAI interface{}) interface{} {
+	switch serviceType {
+	case ServiceCodeReview:
+		req, _ := requestToAI.(models.CodeReviewAIRequest)
+		return normalizeCodeReviewResponse(parseStreamedCodeReview(text), req)
+	case ServiceTextSummary:
+		return models.SummarizeTextAIResponse{Summary: text}
+	case ServiceMindMapIdeas:
+		return models.MindMapIdeasAIResponse{MindMapIdeas: text}
+	case ServiceTaskAssistant:
+		suggestions := []string{}
+		for _, line := range strings.Split(text, "\n") {
+			line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-*•"))
+			if line != "" {
+				suggestions = append(suggestions, line)
+			}
+		}
+		return normalizeTaskAssistantResponse(models.TaskAssistantAIResponse{Suggestions: suggestions})
+	}
+	return nil
+}
+
+// SupportsStreaming informa se o provedor envolvido faz streaming.
+func (p *ResilientProvider) SupportsStreaming() bool {
+	streamer, ok := p.inner.(StreamingProvider)
+	return ok && streamer.SupportsStreaming()
+}
+
+// Stream aplica circuit breaker e novas tentativas ao streaming do provedor envolvido.
+// Uma nova tentativa só acontece se nenhum trecho chegou ao cliente.
+func (p *ResilientProvider) Stream(ctx context.Context, serviceType string, requestToAI interface{}, onChunk func(chunk string) error) (AICallResult, error) {
+	streamer, ok := p.inner.(StreamingProvider)
+	if !ok {
+		return AICallResult{}, fmt.Errorf("provedor %s não suporta streaming", p.inner.Name())
+	}
+	started := false
+	return p.execute(ctx, serviceType+"_stream", func(ctx context.Context) (AICallResult, error) {
+		result, err := streamer.Stream(ctx, serviceType, requestToAI, func(chunk string) error {
+			started = true
+			return onChunk(chunk)
+		})
+		if err != nil && started && ctx.Err() == nil && !errors.Is(err, ErrClientDisconnected) {
+			// O serviço parou no meio do stream: é uma falha, mas não há como repetir sem duplicar texto
+			err = &streamInterruptedError{err: err}
+		}
+		return result, err
+	})
+}
+
+// SupportsStreaming é verdadeiro: o provedor fake envia a resposta palavra por palavra.
+func (p *FakeProvider) SupportsStreaming() bool { return true }
+
+func (p *FakeProvider) Stream(ctx context.Context, serviceType string, requestToAI interface{}, onChunk func(chunk string) error) (AICallResult, error) {
+	response, result, err := callProvider(ctx, p, requestToAI)
+	if err != nil {
+		return result, err
+	}
+	for _, word := range strings.SplitAfter(ResponseText(response), " ") {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		if err := onChunk(word); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// SupportsStreaming indica se AI_API_STREAMING está ligado.
+func (p *HTTPProvider) SupportsStreaming() bool { return p.streaming }
+
+// Stream chama a variante de streaming do endpoint ("<caminho>/stream"), que responde
+// com Server-Sent Events: cada evento traz {"delta": "..."} (ou texto puro) e o fim é
+// indicado por "data: [DONE]". Um evento {"error": "..."} encerra o stream com erro.
+// Se o serviço não tiver a rota de streaming (404), a chamada normal é feita e a
+// resposta é enviada como um único trecho.
+//
+// Variáveis de ambiente:
+//   - AI_API_STREAMING: "true" liga o streaming pelo serviço Python (padrão: desligado)
+//   - AI_API_STREAM_TIMEOUT: duração máxima de um stream (padrão: 3m)
+func (p *HTTPProvider) Stream(ctx context.Context, serviceType string, requestToAI interface{}, onChunk func(chunk string) error) (AICallResult, error) {
+	path, ok := aiPathsByService[serviceType]
+	if !ok {
+		return AICallResult{}, fmt.Errorf("%w: serviço de IA desconhecido %q", ErrInvalidAIInput, serviceType)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.streamTimeout)
+	defer cancel()
+
+	jsonData, err := json.Marshal(requestToAI)
+	if err != nil {
+		return AICallResult{}, fmt.Errorf("erro ao preparar dados para API de IA: %w", err)
+	}
+	fullURL := p.baseURL + path + "/stream"
+	req, err := http.NewRequestWithContext(ctx, "POST", fullURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return AICallResult{}, fmt.Errorf("erro ao criar requisição para API de IA: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return AICallResult{}, fmt.Errorf("erro ao comunicar com API de IA: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		response, result, err := callProvider(ctx, p, requestToAI)
+		if err != nil {
+			return result, err
+		}
+		return result, onChunk(ResponseText(response))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		raw, _ := io.ReadAll(resp.Body)
+		return AICallResult{StatusCode: resp.StatusCode, RawResponse: raw}, fmt.Errorf("API de IA (%s) retornou status %d", fullURL, resp.StatusCode)
+	}
+
+	result := AICallResult{StatusCode: resp.StatusCode}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		data, isData := strings.CutPrefix(scanner.Text(), "data:")
+		if !isData {
+			continue // Comentários, "event:", "id:" e linhas em branco
+		}
+		data = strings.TrimPrefix(data, " ")
+		if data == "[DONE]" {
+			return result, nil
+		}
+
+		var event struct {
+			Delta *string `json:"delta"`
+			Text  *string `json:"text"`
+			Error string  `json:"error"`
+		}
+		chunk := data
+		if json.Unmarshal([]byte(data), &event) == nil {
+			switch {
+			case event.Error != "":
+				result.RawResponse = []byte(data)
+				return result, fmt.Errorf("API de IA (%s) interrompeu o stream: %s", fullURL, event.Error)
+			case event.Delta != nil:
+				chunk = *event.Delta
+			case event.Text != nil:
+				chunk = *event.Text
+			}
+		}
+		if err := onChunk(chunk); err != nil {
+			return result, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("erro ao ler stream da API de IA: %w", err)
+	}
+	return result, nil // Stream encerrado sem [DONE]: aceita o que foi recebido
+}
+
+// streamingFromEnv lê a configuração de streaming do provedor HTTP.
+func streamingFromEnv() (bool, time.Duration) {
+	return os.Getenv("AI_API_STREAMING") == "true", scheduler.DurationFromEnv("AI_API_STREAM_TIMEOUT", 3*time.Minute)
+}
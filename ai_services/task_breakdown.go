@@ -0,0 +1,188 @@
+package ai_services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"projeto-integrador/firebase"
+	"projeto-integrador/models"
+	"projeto-integrador/task_services"
+	"projeto-integrador/utilities"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+const (
+	defaultSubtasksPerBreakdown = 8
+	maxSubtasksPerBreakdown     = 20
+)
+
+// buildTaskBreakdownRequest carrega a tarefa a decompor e monta o payload para a IA.
+func buildTaskBreakdownRequest(ctx context.Context, workspaceIDPg int64, in models.TaskBreakdownUserInput, settings models.WorkspaceAISettings) (*models.TaskBreakdownAIRequest, error) {
+	firestoreClient, err := firebase.GetFirestoreClient()
+	if err != nil {
+		return nil, err
+	}
+	defer firestoreClient.Close()
+
+	task, err := task_services.GetTask(ctx, firestoreClient, workspaceIDPg, in.TaskDocID)
+	if err != nil {
+		return nil, err
+	}
+	tarefa := models.TarefaDecomposicaoContext{
+		ID:         in.TaskDocID,
+		Titulo:     task.Title,
+		Descricao:  task.Description,
+		Status:     task.Status,
+		Prioridade: task.Priority,
+	}
+	if task.ExpirationDate != nil {
+		tarefa.DataVencimento = task.ExpirationDate.Format(time.RFC3339)
+	}
+	for _, item := range task.Checklist {
+		if !item.Done {
+			tarefa.Checklist = append(tarefa.Checklist, item.Text)
+		}
+	}
+	if !settings.IncludeDescriptions {
+		tarefa.Descricao = ""
+	}
+	if settings.IncludeComments {
+		if tarefa.Comentarios, err = recentCommentsForAI(ctx, firestoreClient, workspaceIDPg, in.TaskDocID); err != nil {
+			return nil, err
+		}
+	}
+	return &models.TaskBreakdownAIRequest{Tarefa: tarefa, MaxSubtarefas: in.MaxSubtasks}, nil
+}
+
+// normalizeTaskBreakdownResponse descarta subtarefas sem título, ordena pela ordem
+// proposta (se a IA numerou todas), renumera a partir de 1 e limita a quantidade.
+func normalizeTaskBreakdownResponse(resp models.TaskBreakdownAIResponse, maxSubtasks int) models.TaskBreakdownAIResponse {
+	subtasks := make([]models.SubtaskSuggestion, 0, len(resp.Subtasks))
+	numbered := true
+	for _, subtask := range resp.Subtasks {
+		subtask.Title = normalizeSuggestionTitle(subtask.Title)
+		if subtask.Title == "" {
+			continue
+		}
+		if subtask.EstimateHours < 0 {
+			subtask.EstimateHours = 0
+		}
+		numbered = numbered && subtask.Order > 0
+		subtasks = append(subtasks, subtask)
+	}
+	if numbered {
+		sort.SliceStable(subtasks, func(i, j int) bool { return subtasks[i].Order < subtasks[j].Order })
+	}
+	if maxSubtasks > 0 && len(subtasks) > maxSubtasks {
+		subtasks = subtasks[:maxSubtasks]
+	}
+	for i := range subtasks {
+		subtasks[i].Order = i + 1
+	}
+	resp.Subtasks = subtasks
+	return resp
+}
+
+// CommitTaskBreakdown cria as subtarefas revisadas pelo usuário como tarefas filhas de
+// parent, na ordem recebida, pelo caminho normal de criação. Com historyID (a
+// decomposição da IA que originou a lista), as tarefas são marcadas como geradas por IA
+// e o ID de cada uma é derivado do registro e da posição, então repetir o envio não
+// cria duplicatas. parent deve vir carregado pelo handler, depois de conferida a
+// membresia de userID no workspace.
+func CommitTaskBreakdown(ctx context.Context, db *sql.DB, client *firestore.Client, workspaceIDPg int64, userID string, parentTaskID string, parent *models.TaskDetailsFirestore, historyID string, subtasks []models.SubtaskInput) ([]models.AcceptedTaskSuggestion, error) {
+	if len(subtasks) == 0 || len(subtasks) > maxSubtasksPerBreakdown {
+		return nil, fmt.Errorf("%w: send between 1 and %d subtasks", ErrInvalidTaskSuggestion, maxSubtasksPerBreakdown)
+	}
+	if historyID != "" {
+		doc, err := aiHistoryDoc(ctx, client, workspaceIDPg, historyID, userID, ServiceTaskBreakdown)
+		if err != nil {
+			return nil, err
+		}
+		var entry struct {
+			ResponseFromAI *models.TaskBreakdownAIResponse `firestore:"response_from_ai"`
+		}
+		if err := doc.DataTo(&entry); err != nil {
+			return nil, fmt.Errorf("erro ao ler histórico de IA %s: %w", historyID, err)
+		}
+		if entry.ResponseFromAI == nil || entry.ResponseFromAI.ParentTaskID != parentTaskID {
+			return nil, ErrAIHistoryNotFound
+		}
+	}
+
+	inputs := make([]models.CreateTaskInput, len(subtasks))
+	for i, subtask := range subtasks {
+		input, err := subtaskInput(subtask, parent)
+		if err != nil {
+			return nil, fmt.Errorf("%w: subtask %d: %v", ErrInvalidTaskSuggestion, i, err)
+		}
+		input.ParentTaskID = parentTaskID
+		input.SubtaskOrder = i + 1
+		if historyID != "" {
+			input.DocID = fmt.Sprintf("ai-%s-%d", historyID, i+1)
+			input.AIGenerated = true
+			input.AIHistoryID = historyID
+		}
+		inputs[i] = input
+	}
+
+	results := make([]models.AcceptedTaskSuggestion, 0, len(inputs))
+	for i, input := range inputs {
+		result := models.AcceptedTaskSuggestion{Index: i}
+		taskID, _, err := task_services.CreateTask(ctx, db, client, workspaceIDPg, userID, input)
+		switch {
+		case err == nil:
+			result.Status, result.TaskID = SuggestionCreated, taskID
+		case errors.Is(err, task_services.ErrTaskAlreadyExists):
+			result.Status, result.TaskID = SuggestionAlreadyAccepted, input.DocID
+		case errors.Is(err, task_services.ErrInvalidTask):
+			result.Status, result.Error = SuggestionFailed, err.Error()
+		default:
+			utilities.LogError(err, fmt.Sprintf("CommitTaskBreakdown: Erro ao criar subtarefa %d da tarefa %s", i+1, parentTaskID))
+			result.Status, result.Error = SuggestionFailed, "failed to create task"
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// subtaskInput valida uma subtarefa revisada. Prioridade e responsável vazios herdam os
+// da tarefa pai.
+func subtaskInput(subtask models.SubtaskInput, parent *models.TaskDetailsFirestore) (models.CreateTaskInput, error) {
+	input := models.CreateTaskInput{
+		Title:               normalizeSuggestionTitle(subtask.Title),
+		Description:         subtask.Description,
+		Status:              "pending",
+		Priority:            parent.Priority,
+		AssigneeFirebaseUID: parent.AssigneeFirebaseUID,
+		EstimateHours:       subtask.EstimateHours,
+	}
+	if input.Title == "" {
+		return input, errors.New("title is required")
+	}
+	if subtask.EstimateHours < 0 {
+		return input, errors.New("estimate_hours cannot be negative")
+	}
+	if strings.TrimSpace(subtask.Priority) != "" {
+		priority, err := task_services.NormalizePriority(subtask.Priority)
+		if err != nil {
+			return input, err
+		}
+		input.Priority = priority
+	}
+	if subtask.DueDate != "" {
+		dueDate, err := task_services.ParseImportDate(subtask.DueDate)
+		if err != nil {
+			return input, err
+		}
+		input.ExpirationDate = &dueDate
+	}
+	if subtask.AssigneeFirebaseUID != "" {
+		input.AssigneeFirebaseUID = subtask.AssigneeFirebaseUID // Validado por CreateTask
+	}
+	return input, nil
+}
@@ -0,0 +1,239 @@
+package ai_services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"projeto-integrador/models"
+	"projeto-integrador/task_services"
+	"projeto-integrador/utilities"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const maxTaskSuggestionTitleLength = 200
+
+// Situação de cada sugestão aceita (AcceptedTaskSuggestion.Status).
+const (
+	SuggestionCreated         = "created"
+	SuggestionAlreadyAccepted = "already_accepted"
+	SuggestionFailed          = "failed"
+)
+
+var (
+	// ErrAIHistoryNotFound indica um registro de histórico inexistente, de outro usuário
+	// ou que não é uma resposta bem-sucedida do serviço esperado.
+	ErrAIHistoryNotFound = errors.New("ai history entry not found")
+	// ErrInvalidTaskSuggestion indica uma seleção de sugestões (ou de subtarefas) inválida.
+	ErrInvalidTaskSuggestion = errors.New("invalid task suggestion selection")
+)
+
+// normalizeTaskAssistantResponse garante que a resposta tenha as duas formas das
+// sugestões: serviços que só devolvem texto geram uma tarefa por sugestão (com o texto
+// como título), e serviços que só devolvem tarefas geram o texto a partir dos títulos.
+func normalizeTaskAssistantResponse(resp models.TaskAssistantAIResponse) models.TaskAssistantAIResponse {
+	tasks := make([]models.TaskSuggestion, 0, len(resp.TaskSuggestions))
+	for _, task := range resp.TaskSuggestions {
+		task.Title = normalizeSuggestionTitle(task.Title)
+		if task.Title != "" {
+			tasks = append(tasks, task)
+		}
+	}
+	if len(tasks) == 0 {
+		for _, suggestion := range resp.Suggestions {
+			if title := normalizeSuggestionTitle(suggestion); title != "" {
+				tasks = append(tasks, models.TaskSuggestion{Title: title})
+			}
+		}
+	}
+	if len(resp.Suggestions) == 0 {
+		for _, task := range tasks {
+			resp.Suggestions = append(resp.Suggestions, task.Title)
+		}
+	}
+	resp.TaskSuggestions = tasks
+	return resp
+}
+
+func normalizeSuggestionTitle(title string) string {
+	title = strings.Join(strings.Fields(title), " ")
+	if runes := []rune(title); len(runes) > maxTaskSuggestionTitleLength {
+		title = string(runes[:maxTaskSuggestionTitleLength])
+	}
+	return title
+}
+
+// aiHistoryDoc busca um registro do ai_request_history do usuário, de uma chamada
+// bem-sucedida ao serviço informado e ainda não truncada pela retenção. O chamador decodifica a resposta no tipo do serviço.
+func aiHistoryDoc(ctx context.Context, client *firestore.Client, workspaceIDPg int64, historyID string, userID string, serviceType string) (*firestore.DocumentSnapshot, error) {
+	doc, err := HistoryCollection(client, workspaceIDPg).Doc(historyID).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrAIHistoryNotFound
+		}
+		return nil, fmt.Errorf("erro ao buscar histórico de IA %s: %w", historyID, err)
+	}
+	var entry struct {
+		UserID        string     `firestore:"user_id"`
+		AIServiceType string     `firestore:"ai_service_type"`
+		AIError       string     `firestore:"ai_error"`
+		TruncatedAt   *time.Time `firestore:"truncated_at"` // Sem a resposta, não há o que aceitar
+	}
+	if err := doc.DataTo(&entry); err != nil {
+		return nil, fmt.Errorf("erro ao ler histórico de IA %s: %w", historyID, err)
+	}
+	if entry.UserID != userID || entry.AIServiceType != serviceType || entry.AIError != "" || entry.TruncatedAt != nil {
+		return nil, ErrAIHistoryNotFound
+	}
+	return doc, nil
+}
+
+// historyTaskSuggestions carrega as tarefas sugeridas em um registro do ai_request_history
+// do usuário.
+func historyTaskSuggestions(ctx context.Context, client *firestore.Client, workspaceIDPg int64, historyID string, userID string) ([]models.TaskSuggestion, error) {
+	doc, err := aiHistoryDoc(ctx, client, workspaceIDPg, historyID, userID, ServiceTaskAssistant)
+	if err != nil {
+		return nil, err
+	}
+	var entry struct {
+		ResponseFromAI *models.TaskAssistantAIResponse `firestore:"response_from_ai"`
+	}
+	if err := doc.DataTo(&entry); err != nil {
+		return nil, fmt.Errorf("erro ao ler histórico de IA %s: %w", historyID, err)
+	}
+	if entry.ResponseFromAI == nil {
+		return nil, ErrAIHistoryNotFound
+	}
+	return entry.ResponseFromAI.TaskSuggestions, nil
+}
+
+// AcceptTaskSuggestions cria tarefas a partir das sugestões de um registro do histórico
+// pelo caminho normal de criação (task_services.CreateTask), marcadas como geradas por
+// IA. Sem seleção, todas as sugestões são aceitas. O ID de cada tarefa é derivado do
+// registro e do índice, então aceitar de novo a mesma sugestão não cria duplicatas.
+// Só lê registros do próprio userID; que ele ainda é membro do workspace, o handler confere.
+func AcceptTaskSuggestions(ctx context.Context, db *sql.DB, client *firestore.Client, workspaceIDPg int64, userID string, historyID string, selections []models.TaskSuggestionSelection) ([]models.AcceptedTaskSuggestion, error) {
+	suggestions, err := historyTaskSuggestions(ctx, client, workspaceIDPg, historyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(suggestions) == 0 {
+		return nil, fmt.Errorf("%w: the AI response has no task suggestions", ErrInvalidTaskSuggestion)
+	}
+
+	if len(selections) == 0 {
+		for i := range suggestions {
+			selections = append(selections, models.TaskSuggestionSelection{Index: i})
+		}
+	}
+	seen := make(map[int]bool, len(selections))
+	for _, selection := range selections {
+		if selection.Index < 0 || selection.Index >= len(suggestions) {
+			return nil, fmt.Errorf("%w: index %d out of range (0-%d)", ErrInvalidTaskSuggestion, selection.Index, len(suggestions)-1)
+		}
+		if seen[selection.Index] {
+			return nil, fmt.Errorf("%w: index %d selected more than once", ErrInvalidTaskSuggestion, selection.Index)
+		}
+		seen[selection.Index] = true
+	}
+
+	members, err := models.ListWorkspaceMembers(db, workspaceIDPg)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]models.AcceptedTaskSuggestion, 0, len(selections))
+	for _, selection := range selections {
+		result := models.AcceptedTaskSuggestion{Index: selection.Index}
+		input, err := taskInputFromSuggestion(suggestions[selection.Index], selection, members)
+		if err != nil {
+			result.Status, result.Error = SuggestionFailed, err.Error()
+			results = append(results, result)
+			continue
+		}
+		input.DocID = fmt.Sprintf("ai-%s-%d", historyID, selection.Index)
+		input.AIGenerated = true
+		input.AIHistoryID = historyID
+
+		taskID, _, err := task_services.CreateTask(ctx, db, client, workspaceIDPg, userID, input)
+		switch {
+		case err == nil:
+			result.Status, result.TaskID = SuggestionCreated, taskID
+		case errors.Is(err, task_services.ErrTaskAlreadyExists):
+			result.Status, result.TaskID = SuggestionAlreadyAccepted, input.DocID
+		case errors.Is(err, task_services.ErrInvalidTask):
+			result.Status, result.Error = SuggestionFailed, err.Error()
+		default:
+			utilities.LogError(err, fmt.Sprintf("AcceptTaskSuggestions: Erro ao criar tarefa da sugestão %d do histórico %s", selection.Index, historyID))
+			result.Status, result.Error = SuggestionFailed, "failed to create task"
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// taskInputFromSuggestion monta a tarefa a partir da sugestão e das alterações do
+// usuário. Prioridade, data e responsável propostos pela IA que não puderem ser
+// interpretados são ignorados; os informados pelo usuário precisam ser válidos.
+func taskInputFromSuggestion(suggestion models.TaskSuggestion, selection models.TaskSuggestionSelection, members []models.WorkspaceMember) (models.CreateTaskInput, error) {
+	input := models.CreateTaskInput{
+		Title:       suggestion.Title,
+		Description: suggestion.Description,
+		Status:      "pending",
+	}
+	if title := normalizeSuggestionTitle(selection.Title); title != "" {
+		input.Title = title
+	}
+	if selection.Description != "" {
+		input.Description = selection.Description
+	}
+
+	if selection.Priority != "" {
+		priority, err := task_services.NormalizePriority(selection.Priority)
+		if err != nil {
+			return input, err
+		}
+		input.Priority = priority
+	} else if priority, err := task_services.NormalizePriority(suggestion.Priority); err == nil {
+		input.Priority = priority
+	}
+
+	if selection.DueDate != "" {
+		dueDate, err := task_services.ParseImportDate(selection.DueDate)
+		if err != nil {
+			return input, err
+		}
+		input.ExpirationDate = &dueDate
+	} else if suggestion.DueDate != "" {
+		if dueDate, err := task_services.ParseImportDate(suggestion.DueDate); err == nil {
+			input.ExpirationDate = &dueDate
+		}
+	}
+
+	if selection.AssigneeFirebaseUID != "" {
+		input.AssigneeFirebaseUID = selection.AssigneeFirebaseUID // Validado por CreateTask
+	} else {
+		input.AssigneeFirebaseUID = matchSuggestedAssignee(suggestion.Assignee, members)
+	}
+	return input, nil
+}
+
+// matchSuggestedAssignee procura o membro proposto pela IA pelo nome, e-mail ou UID.
+// Retorna vazio se nenhum membro corresponder.
+func matchSuggestedAssignee(assignee string, members []models.WorkspaceMember) string {
+	assignee = strings.TrimSpace(assignee)
+	if assignee == "" {
+		return ""
+	}
+	for _, member := range members {
+		if member.UserID == assignee || strings.EqualFold(member.Email, assignee) || strings.EqualFold(member.DisplayName, assignee) {
+			return member.UserID
+		}
+	}
+	return ""
+}
@@ -0,0 +1,241 @@
+package ai_services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"projeto-integrador/database"
+	"projeto-integrador/firebase"
+	"projeto-integrador/models"
+	"projeto-integrador/task_services"
+	"projeto-integrador/utilities"
+	"strings"
+)
+
+const (
+	maxTaskSummaryDescriptionLength = 4000 // Caracteres da descrição
+	maxTaskSummaryComments          = 50   // Comentários mais recentes
+	maxTaskSummaryCommentLength     = 1000 // Caracteres de cada comentário
+	maxTaskSummarySubtasks          = 30
+	maxTaskSummaryCodeReviews       = 5   // Revisões anexadas mais recentes
+	maxTaskSummaryReviewLength      = 800 // Caracteres do parecer de cada revisão
+	maxTaskSummaryAttachmentLength  = 300
+	maxTaskSummaryAttachmentText    = 4000 // Caracteres do texto extraído do arquivo anexado
+)
+
+// buildTaskSummaryRequest carrega a tarefa, suas subtarefas, as revisões de código
+// anexadas e os comentários e monta o texto enviado à IA para o resumo. Segue as
+// configurações de IA do workspace: sem include_descriptions a descrição fica de fora,
+// sem include_comments os comentários ficam de fora e sem include_member_names os
+// membros aparecem como "Membro N". O texto do arquivo anexado (texto, markdown, código
+// ou PDF) vai junto com a descrição, só com include_descriptions; se o arquivo não puder
+// ser lido, vai só o nome.
+func buildTaskSummaryRequest(ctx context.Context, workspaceIDPg int64, in models.TaskSummaryUserInput, settings models.WorkspaceAISettings) (*models.TaskSummaryAIRequest, error) {
+	firestoreClient, err := firebase.GetFirestoreClient()
+	if err != nil {
+		return nil, err
+	}
+	defer firestoreClient.Close()
+
+	task, err := task_services.GetTask(ctx, firestoreClient, workspaceIDPg, in.TaskDocID)
+	if err != nil {
+		return nil, err
+	}
+	var parent *models.TaskDetailsFirestore
+	if task.ParentTaskID != "" {
+		// A tarefa pai pode ter sido apagada; o resumo segue sem ela
+		parent, _ = task_services.GetTask(ctx, firestoreClient, workspaceIDPg, task.ParentTaskID)
+	}
+	_, subtasks, err := task_services.ListSubtasks(ctx, firestoreClient, workspaceIDPg, in.TaskDocID)
+	if err != nil {
+		return nil, err
+	}
+	reviews, err := task_services.ListTaskCodeReviews(ctx, firestoreClient, workspaceIDPg, in.TaskDocID)
+	if err != nil {
+		return nil, err
+	}
+	var comments []models.TaskComment
+	if settings.IncludeComments {
+		if comments, err = task_services.ListTaskComments(ctx, firestoreClient, workspaceIDPg, in.TaskDocID); err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	members, err := models.ListWorkspaceMembers(db, workspaceIDPg)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(members))
+	for i, member := range members {
+		names[member.UserID] = member.DisplayName
+		if !settings.IncludeMemberNames {
+			names[member.UserID] = fmt.Sprintf("Membro %d", i+1)
+		}
+	}
+	nameOf := func(uid string) string {
+		if name := names[uid]; name != "" {
+			return name
+		}
+		return "ex-membro"
+	}
+
+	var attachmentContent string
+	if settings.IncludeDescriptions && task.Attachment != "" {
+		attachmentContent, err = getAttachmentFetcher().Text(ctx, task.Attachment)
+		if err != nil && !errors.Is(err, errAttachmentNotAllowed) && !errors.Is(err, errAttachmentUnsupported) {
+			// O resumo segue sem o conteúdo do anexo
+			utilities.LogError(err, fmt.Sprintf("buildTaskSummaryRequest: Erro ao extrair texto do anexo da tarefa %s", in.TaskDocID))
+		}
+	}
+
+	text := taskSummaryText(task, parent, subtasks, reviews, comments, attachmentContent, nameOf, settings)
+	return &models.TaskSummaryAIRequest{TaskDocID: in.TaskDocID, Text: text}, nil
+}
+
+// taskSummaryText escreve a tarefa como texto para o resumo: dados atuais, descrição,
+// histórico (criação, recorrência, vencimento, conclusão), checklist, subtarefas,
+// anexos (com attachmentContent, o texto do arquivo anexado, se houver) e comentários,
+// do mais antigo para o mais recente.
+func taskSummaryText(task *models.TaskDetailsFirestore, parent *models.TaskDetailsFirestore, subtasks []models.TaskDetailsFirestore,
+	reviews []models.TaskCodeReview, comments []models.TaskComment, attachmentContent string, nameOf func(uid string) string, settings models.WorkspaceAISettings) string {
+	const date = "02/01/2006"
+	var b strings.Builder
+	b.WriteString("Resuma a tarefa abaixo: o objetivo, o que foi discutido e decidido, o estado atual e as pendências ou próximos passos.\n\n")
+
+	fmt.Fprintf(&b, "Tarefa: %s\nStatus: %s\n", task.Title, task.Status)
+	if task.Priority != "" {
+		fmt.Fprintf(&b, "Prioridade: %s\n", task.Priority)
+	}
+	if task.ExpirationDate != nil {
+		fmt.Fprintf(&b, "Vencimento: %s\n", task.ExpirationDate.Format(date))
+	}
+	if task.AssigneeFirebaseUID != "" {
+		fmt.Fprintf(&b, "Responsável: %s\n", nameOf(task.AssigneeFirebaseUID))
+	}
+	if len(task.Labels) > 0 {
+		fmt.Fprintf(&b, "Etiquetas: %s\n", strings.Join(task.Labels, ", "))
+	}
+	if task.EstimateHours > 0 {
+		fmt.Fprintf(&b, "Estimativa: %gh\n", task.EstimateHours)
+	}
+	if settings.IncludeDescriptions && strings.TrimSpace(task.Description) != "" {
+		b.WriteString("\nDescrição:\n" + truncateRunes(strings.TrimSpace(task.Description), maxTaskSummaryDescriptionLength) + "\n")
+	}
+
+	b.WriteString("\nHistórico:\n")
+	fmt.Fprintf(&b, "- %s: criada por %s\n", task.CreatedAt.Format(date), nameOf(task.CreatorFirebaseUID))
+	if task.ImportSource != "" {
+		fmt.Fprintf(&b, "- importada de %s\n", task.ImportSource)
+	}
+	if task.AIGenerated {
+		b.WriteString("- criada a partir de uma sugestão da IA\n")
+	}
+	if parent != nil {
+		fmt.Fprintf(&b, "- subtarefa de %q\n", parent.Title)
+	}
+	if task.OccurrenceIndex > 1 {
+		fmt.Fprintf(&b, "- ocorrência %d de uma tarefa recorrente\n", task.OccurrenceIndex)
+	}
+	if task.OverdueSince != nil {
+		fmt.Fprintf(&b, "- %s: venceu sem ser concluída\n", task.OverdueSince.Format(date))
+	}
+	if task.CompletedAt != nil {
+		completedBy := task.CompletedByFirebaseUID
+		if completedBy == "" {
+			completedBy = task.AssigneeFirebaseUID
+		}
+		line := fmt.Sprintf("- %s: concluída", task.CompletedAt.Format(date))
+		if completedBy != "" {
+			line += " por " + nameOf(completedBy)
+		}
+		b.WriteString(line + "\n")
+	}
+	if !task.LastUpdatedAt.IsZero() {
+		fmt.Fprintf(&b, "- %s: última alteração\n", task.LastUpdatedAt.Format(date))
+	}
+
+	if len(task.Checklist) > 0 {
+		b.WriteString("\nChecklist:\n")
+		for _, item := range task.Checklist {
+			mark := " "
+			if item.Done {
+				mark = "x"
+			}
+			fmt.Fprintf(&b, "- [%s] %s\n", mark, item.Text)
+		}
+	}
+
+	if len(subtasks) > 0 {
+		b.WriteString("\nSubtarefas:\n")
+		for i, subtask := range subtasks {
+			if i == maxTaskSummarySubtasks {
+				fmt.Fprintf(&b, "- ... e mais %d\n", len(subtasks)-i)
+				break
+			}
+			line := fmt.Sprintf("- [%s] %s", subtask.Status, subtask.Title)
+			if subtask.AssigneeFirebaseUID != "" {
+				line += " (responsável: " + nameOf(subtask.AssigneeFirebaseUID) + ")"
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+
+	if task.Attachment != "" || len(reviews) > 0 {
+		b.WriteString("\nAnexos:\n")
+		if task.Attachment != "" {
+			name := truncateRunes(attachmentName(task.Attachment), maxTaskSummaryAttachmentLength)
+			if attachmentContent != "" {
+				b.WriteString("- arquivo: " + name + ", com o conteúdo:\n" + truncateRunes(attachmentContent, maxTaskSummaryAttachmentText) + "\n")
+			} else {
+				b.WriteString("- arquivo: " + name + " (conteúdo não disponível)\n")
+			}
+		}
+		for i, review := range reviews {
+			if i == maxTaskSummaryCodeReviews {
+				break
+			}
+			line := fmt.Sprintf("- revisão de código anexada em %s por %s", review.AttachedAt.Format(date), nameOf(review.AttachedBy))
+			if len(review.Files) > 0 {
+				line += " (arquivos: " + strings.Join(review.Files, ", ") + ")"
+			}
+			fmt.Fprintf(&b, "%s, %d achados", line, len(review.Findings))
+			if text := strings.TrimSpace(review.Review); text != "" {
+				b.WriteString(": " + truncateRunes(text, maxTaskSummaryReviewLength))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if len(comments) > 0 {
+		b.WriteString("\nComentários (do mais antigo para o mais recente):\n")
+		if len(comments) > maxTaskSummaryComments {
+			fmt.Fprintf(&b, "- (%d comentários mais antigos omitidos)\n", len(comments)-maxTaskSummaryComments)
+			comments = comments[len(comments)-maxTaskSummaryComments:]
+		}
+		for _, comment := range comments {
+			text := truncateRunes(strings.TrimSpace(comment.Text), maxTaskSummaryCommentLength)
+			if text == "" {
+				continue
+			}
+			fmt.Fprintf(&b, "- [%s] %s: %s\n", comment.CreatedAt.Format(date+" 15:04"), commentAuthor(comment, nameOf, settings), text)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// commentAuthor retorna o nome do autor do comentário para a IA. Comentários importados
+// de outras ferramentas trazem só o nome do autor externo.
+func commentAuthor(comment models.TaskComment, nameOf func(uid string) string, settings models.WorkspaceAISettings) string {
+	if comment.AuthorFirebaseUID != "" {
+		return nameOf(comment.AuthorFirebaseUID)
+	}
+	if comment.AuthorName != "" && settings.IncludeMemberNames {
+		return comment.AuthorName
+	}
+	return "autor externo"
+}
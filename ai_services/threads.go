@@ -0,0 +1,260 @@
+package ai_services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"projeto-integrador/models"
+	"projeto-integrador/scheduler"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	threadsSubCollectionName = "ai_threads" // Ao lado de ai_request_history
+	turnsSubCollectionName   = "turns"
+
+	maxAIThreadTitleLength = 120
+)
+
+// ErrAIThreadNotFound indica uma conversa inexistente ou de outro usuário.
+var ErrAIThreadNotFound = errors.New("ai thread not found")
+
+// ThreadsCollection retorna as conversas do workspace: /workspaces/{workspace_id}/ai_threads
+func ThreadsCollection(client *firestore.Client, workspaceIDPg int64) *firestore.CollectionRef {
+	return client.Collection("workspaces").Doc(strconv.FormatInt(workspaceIDPg, 10)).Collection(threadsSubCollectionName)
+}
+
+// NormalizeAIThreadTitle limpa espaços e limita o tamanho do título.
+func NormalizeAIThreadTitle(title string) string {
+	title = strings.Join(strings.Fields(title), " ")
+	if runes := []rune(title); len(runes) > maxAIThreadTitleLength {
+		title = string(runes[:maxAIThreadTitleLength])
+	}
+	return title
+}
+
+// CreateAIThread cria uma conversa vazia. Sem título, a primeira mensagem vira o título.
+func CreateAIThread(ctx context.Context, client *firestore.Client, workspaceIDPg int64, userID string, title string) (*models.AIThread, error) {
+	now := time.Now()
+	thread := models.AIThread{
+		WorkspaceIDPg: workspaceIDPg,
+		UserID:        userID,
+		Title:         NormalizeAIThreadTitle(title),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	ref, _, err := ThreadsCollection(client, workspaceIDPg).Add(ctx, thread)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar conversa de IA: %w", err)
+	}
+	thread.ID = ref.ID
+	return &thread, nil
+}
+
+// GetAIThread busca uma conversa do usuário.
+func GetAIThread(ctx context.Context, client *firestore.Client, workspaceIDPg int64, threadID string, userID string) (*models.AIThread, error) {
+	doc, err := ThreadsCollection(client, workspaceIDPg).Doc(threadID).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrAIThreadNotFound
+		}
+		return nil, fmt.Errorf("erro ao buscar conversa de IA %s: %w", threadID, err)
+	}
+	var thread models.AIThread
+	if err := doc.DataTo(&thread); err != nil {
+		return nil, fmt.Errorf("erro ao ler conversa de IA %s: %w", threadID, err)
+	}
+	if thread.UserID != userID {
+		return nil, ErrAIThreadNotFound
+	}
+	thread.ID = doc.Ref.ID
+	return &thread, nil
+}
+
+// ListAIThreads lista as conversas do usuário no workspace, as mais recentes primeiro.
+func ListAIThreads(ctx context.Context, client *firestore.Client, workspaceIDPg int64, userID string) ([]models.AIThread, error) {
+	iter := ThreadsCollection(client, workspaceIDPg).Where("user_id", "==", userID).Documents(ctx)
+	defer iter.Stop()
+
+	threads := []models.AIThread{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao listar conversas de IA: %w", err)
+		}
+		var thread models.AIThread
+		if err := doc.DataTo(&thread); err != nil {
+			continue
+		}
+		thread.ID = doc.Ref.ID
+		threads = append(threads, thread)
+	}
+	// Ordenado aqui para não exigir um índice composto (user_id + updated_at)
+	sort.Slice(threads, func(i, j int) bool { return threads[i].UpdatedAt.After(threads[j].UpdatedAt) })
+	return threads, nil
+}
+
+// ListAIThreadTurns lista as mensagens da conversa em ordem cronológica.
+func ListAIThreadTurns(ctx context.Context, client *firestore.Client, workspaceIDPg int64, threadID string) ([]models.AIThreadTurn, error) {
+	iter := ThreadsCollection(client, workspaceIDPg).Doc(threadID).Collection(turnsSubCollectionName).
+		OrderBy("created_at", firestore.Asc).Documents(ctx)
+	return collectAIThreadTurns(iter, threadID)
+}
+
+func collectAIThreadTurns(iter *firestore.DocumentIterator, threadID string) ([]models.AIThreadTurn, error) {
+	defer iter.Stop()
+	turns := []models.AIThreadTurn{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao buscar mensagens da conversa %s: %w", threadID, err)
+		}
+		var turn models.AIThreadTurn
+		if err := doc.DataTo(&turn); err != nil {
+			continue
+		}
+		turn.ID = doc.Ref.ID
+		turns = append(turns, turn)
+	}
+	return turns, nil
+}
+
+// RenameAIThread altera o título de uma conversa do usuário.
+func RenameAIThread(ctx context.Context, client *firestore.Client, workspaceIDPg int64, threadID string, userID string, title string) (*models.AIThread, error) {
+	thread, err := GetAIThread(ctx, client, workspaceIDPg, threadID, userID)
+	if err != nil {
+		return nil, err
+	}
+	thread.Title = NormalizeAIThreadTitle(title)
+	_, err = ThreadsCollection(client, workspaceIDPg).Doc(threadID).Update(ctx, []firestore.Update{
+		{Path: "title", Value: thread.Title},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao renomear conversa de IA %s: %w", threadID, err)
+	}
+	return thread, nil
+}
+
+// DeleteAIThread apaga a conversa e suas mensagens. As entradas do ai_request_history
+// são mantidas.
+func DeleteAIThread(ctx context.Context, client *firestore.Client, workspaceIDPg int64, threadID string, userID string) error {
+	if _, err := GetAIThread(ctx, client, workspaceIDPg, threadID, userID); err != nil {
+		return err
+	}
+	threadRef := ThreadsCollection(client, workspaceIDPg).Doc(threadID)
+
+	iter := threadRef.Collection(turnsSubCollectionName).Documents(ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("erro ao listar mensagens da conversa %s: %w", threadID, err)
+		}
+		if _, err := doc.Ref.Delete(ctx); err != nil {
+			return fmt.Errorf("erro ao apagar mensagem da conversa %s: %w", threadID, err)
+		}
+	}
+	if _, err := threadRef.Delete(ctx); err != nil {
+		return fmt.Errorf("erro ao apagar conversa de IA %s: %w", threadID, err)
+	}
+	return nil
+}
+
+// AppendAIThreadTurn grava uma troca na conversa e atualiza o contador e a data. Se a
+// conversa ainda não tem título, a mensagem do usuário vira o título.
+func AppendAIThreadTurn(ctx context.Context, client *firestore.Client, workspaceIDPg int64, threadID string, turn models.AIThreadTurn) error {
+	threadRef := ThreadsCollection(client, workspaceIDPg).Doc(threadID)
+	turnRef := threadRef.Collection(turnsSubCollectionName).NewDoc()
+	if turn.CreatedAt.IsZero() {
+		turn.CreatedAt = time.Now()
+	}
+
+	return client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(threadRef)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return ErrAIThreadNotFound // Apagada enquanto a IA respondia
+			}
+			return err
+		}
+		var thread models.AIThread
+		if err := doc.DataTo(&thread); err != nil {
+			return err
+		}
+
+		updates := []firestore.Update{
+			{Path: "turn_count", Value: firestore.Increment(1)},
+			{Path: "updated_at", Value: turn.CreatedAt},
+		}
+		if thread.Title == "" {
+			updates = append(updates, firestore.Update{Path: "title", Value: NormalizeAIThreadTitle(firstWords(turn.UserMessage, 12))})
+		}
+		if err := tx.Create(turnRef, turn); err != nil {
+			return err
+		}
+		return tx.Update(threadRef, updates)
+	})
+}
+
+// aiThreadHistoryBudget define quanto da conversa vai para a IA.
+//
+// Variáveis de ambiente:
+//   - AI_THREAD_HISTORY_TURNS: trocas anteriores enviadas, no máximo (padrão: 10)
+//   - AI_THREAD_HISTORY_CHARS: tamanho máximo do histórico em caracteres (padrão: 6000)
+func aiThreadHistoryBudget() (maxTurns int, maxChars int) {
+	return scheduler.IntFromEnv("AI_THREAD_HISTORY_TURNS", 10), scheduler.IntFromEnv("AI_THREAD_HISTORY_CHARS", 6000)
+}
+
+// threadHistoryForAI carrega as trocas mais recentes da conversa que cabem no orçamento,
+// em ordem cronológica. Trocas antigas são descartadas primeiro; uma troca nunca é
+// cortada pela metade.
+func threadHistoryForAI(ctx context.Context, client *firestore.Client, workspaceIDPg int64, threadID string) ([]models.ConversaTurno, error) {
+	maxTurns, maxChars := aiThreadHistoryBudget()
+	iter := ThreadsCollection(client, workspaceIDPg).Doc(threadID).Collection(turnsSubCollectionName).
+		OrderBy("created_at", firestore.Desc).Limit(maxTurns).Documents(ctx)
+	recent, err := collectAIThreadTurns(iter, threadID)
+	if err != nil {
+		return nil, err
+	}
+	return trimAIThreadHistory(recent, maxChars), nil
+}
+
+// trimAIThreadHistory recebe as trocas da mais recente para a mais antiga e devolve as
+// que cabem em maxChars, da mais antiga para a mais recente.
+func trimAIThreadHistory(recentFirst []models.AIThreadTurn, maxChars int) []models.ConversaTurno {
+	var kept [][2]models.ConversaTurno
+	used := 0
+	for _, turn := range recentFirst {
+		user := models.ConversaTurno{Papel: "usuario", Conteudo: turn.UserMessage}
+		assistant := models.ConversaTurno{Papel: "assistente", Conteudo: strings.Join(turn.Suggestions, "\n")}
+		size := len([]rune(user.Conteudo)) + len([]rune(assistant.Conteudo))
+		if used+size > maxChars {
+			break
+		}
+		used += size
+		kept = append(kept, [2]models.ConversaTurno{user, assistant})
+	}
+
+	history := make([]models.ConversaTurno, 0, 2*len(kept))
+	for i := len(kept) - 1; i >= 0; i-- {
+		history = append(history, kept[i][0], kept[i][1])
+	}
+	return history
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sync"
 
 	_ "github.com/lib/pq"
 )
@@ -48,3 +49,26 @@ func ConnectPostgres() (*sql.DB, error) {
 	log.Println("Conectado ao PostgreSQL com sucesso!")
 	return db, nil
 }
+
+// SharedConnection abre a conexão com o PostgreSQL no primeiro uso e a mantém aberta
+// (pool do database/sql) para os jobs em background, que consultam o banco o tempo
+// todo. O valor zero está pronto para uso.
+type SharedConnection struct {
+	mu sync.Mutex
+	db *sql.DB
+}
+
+// Get retorna a conexão compartilhada, abrindo-a se ainda não existir.
+func (c *SharedConnection) Get() (*sql.DB, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.db != nil {
+		return c.db, nil
+	}
+	db, err := ConnectPostgres()
+	if err != nil {
+		return nil, err
+	}
+	c.db = db
+	return db, nil
+}
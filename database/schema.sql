@@ -48,6 +48,176 @@ CREATE INDEX idx_tarefas_firestore_doc_id ON tarefas(firestore_doc_id);
 CREATE INDEX idx_tarefas_workspace ON tarefas(workspace_id);
 CREATE INDEX idx_tarefas_criado_por ON tarefas(criado_por);
 
+-- Lembretes de vencimento já disparados pelo scheduler (garante envio único por réplica/reinício)
+CREATE TABLE task_reminders (
+    firestore_doc_id VARCHAR(128) NOT NULL,         -- Tarefa no Firestore
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    kind VARCHAR(32) NOT NULL,                      -- 'due_soon' ou 'overdue'
+    reminder_window VARCHAR(32) NOT NULL DEFAULT '', -- Janela configurada (ex: '24h'); vazio para 'overdue'
+    due_at TIMESTAMPTZ NOT NULL,                    -- Vencimento da tarefa no momento do disparo
+    claimed_at TIMESTAMPTZ DEFAULT NOW(),           -- Reserva sem sent_at antiga (ou NULL, liberada) é retomada pelo scheduler
+    delivered_channels JSONB NOT NULL DEFAULT '[]', -- Canais que já entregaram o lembrete: ["log", "webhook"]
+    sent_at TIMESTAMPTZ,                            -- Todos os canais entregaram
+    PRIMARY KEY (firestore_doc_id, kind, reminder_window, due_at)
+);
+
+-- Links secretos de calendário (.ics) por usuário; apenas o hash do token é guardado
+CREATE TABLE calendar_feeds (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,         -- SHA-256 (hex) do token
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    workspace_id INTEGER REFERENCES workspaces(id) ON DELETE CASCADE, -- NULL = todos os workspaces do usuário
+    assigned_only BOOLEAN NOT NULL DEFAULT FALSE,   -- Apenas tarefas atribuídas ao usuário
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_accessed_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+-- Importações assíncronas de quadros de outras ferramentas (Trello, Jira)
+CREATE TABLE import_jobs (
+    id SERIAL PRIMARY KEY,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    created_by INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    source VARCHAR(32) NOT NULL,                    -- 'trello' ou 'jira'
+    status VARCHAR(32) NOT NULL DEFAULT 'queued',   -- 'queued', 'running', 'completed', 'failed'
+    total INTEGER NOT NULL DEFAULT 0,               -- Tarefas a criar
+    processed INTEGER NOT NULL DEFAULT 0,
+    created_count INTEGER NOT NULL DEFAULT 0,
+    skipped JSONB NOT NULL DEFAULT '[]',            -- Itens ignorados: [{"item": "...", "reason": "..."}]
+    warnings JSONB NOT NULL DEFAULT '[]',           -- Itens importados com ressalvas: [{"item": "...", "message": "..."}]
+    error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    heartbeat_at TIMESTAMP,                         -- Renovado pela instância que executa o job; parado há muito tempo = job interrompido
+    finished_at TIMESTAMP
+);
+
+CREATE TABLE ai_jobs (
+    id SERIAL PRIMARY KEY,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    created_by INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    service_type VARCHAR(32) NOT NULL,              -- 'code_review', 'text_summary', 'mindmap_ideas', 'task_assistant', 'task_breakdown'
+    status VARCHAR(32) NOT NULL DEFAULT 'queued',   -- 'queued', 'running', 'completed', 'failed', 'canceled'
+    payload JSONB NOT NULL,                         -- Entrada enviada pelo frontend
+    result JSONB,                                   -- Resposta da IA
+    ai_status_code INTEGER,
+    error TEXT,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    run_after TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, -- Não executar antes (novas tentativas)
+    locked_until TIMESTAMP,                         -- Lease do worker que está executando
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP,
+    no_cache BOOLEAN NOT NULL DEFAULT false         -- Ignora o cache de respostas de IA
+);
+
+-- Índice de busca semântica das tarefas: um vetor por tarefa (título, descrição e comentários)
+CREATE TABLE task_search_index (
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    task_doc_id VARCHAR(128) NOT NULL,              -- Tarefa no Firestore
+    embedder VARCHAR(128) NOT NULL,                 -- Modelo que gerou o vetor (ex: 'hashing-512')
+    content_hash VARCHAR(64) NOT NULL,              -- SHA-256 (hex) do texto indexado
+    embedding BYTEA NOT NULL,                       -- Vetor normalizado, float32 little-endian
+    task_updated_at TIMESTAMPTZ NOT NULL,           -- last_updated_at da tarefa quando foi indexada
+    indexed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (workspace_id, task_doc_id)
+);
+
+-- Política de retenção do histórico de IA (ai_request_history no Firestore) por workspace
+CREATE TABLE ai_history_retention (
+    workspace_id INTEGER PRIMARY KEY REFERENCES workspaces(id) ON DELETE CASCADE,
+    retention_days INTEGER,                         -- NULL = padrão do servidor; 0 = manter para sempre
+    mode VARCHAR(16),                               -- 'truncate' ou 'delete'; NULL = padrão do servidor
+    updated_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP,
+    purged_before TIMESTAMPTZ                       -- Entradas anteriores já processadas pela política atual
+);
+
+-- Cotas de uso da IA configuradas por workspace (além dos limites do servidor)
+CREATE TABLE ai_quotas (
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    scope VARCHAR(16) NOT NULL,                     -- 'user' (cada membro) ou 'workspace' (total)
+    service_type VARCHAR(32) NOT NULL DEFAULT '',   -- '' = soma de todos os serviços
+    requests_per_day INTEGER,                       -- NULL = sem limite
+    input_chars_per_month BIGINT,                   -- NULL = sem limite
+    updated_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP,
+    PRIMARY KEY (workspace_id, scope, service_type)
+);
+
+-- Consumo da IA por dia (UTC), membro e serviço, para as cotas
+CREATE TABLE ai_usage (
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    service_type VARCHAR(32) NOT NULL,
+    usage_date DATE NOT NULL,
+    requests INTEGER NOT NULL DEFAULT 0,
+    input_chars BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (workspace_id, user_id, service_type, usage_date)
+);
+
+-- Expressões regulares mascaradas antes do envio à IA, por workspace (além dos tipos padrão)
+CREATE TABLE ai_redaction_rules (
+    id SERIAL PRIMARY KEY,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    name VARCHAR(40) NOT NULL,                      -- Usado no placeholder: [NOME_1]
+    pattern TEXT NOT NULL,                          -- Sintaxe RE2 (Go regexp)
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (workspace_id, name)
+);
+
+-- Cache de respostas de IA compartilhado entre as réplicas (opcional, AI_CACHE_POSTGRES)
+CREATE TABLE ai_response_cache (
+    cache_key VARCHAR(128) PRIMARY KEY,             -- Serviço + hash da requisição normalizada
+    service_type VARCHAR(32) NOT NULL,
+    response JSONB NOT NULL,                        -- Resposta do provedor, ainda com os placeholders do mascaramento
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+-- Avaliações dos usuários sobre as respostas da IA (uma por entrada do histórico e usuário)
+CREATE TABLE ai_feedback (
+    id SERIAL PRIMARY KEY,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    history_id VARCHAR(128) NOT NULL,               -- Documento em ai_request_history (Firestore)
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    service_type VARCHAR(32) NOT NULL,
+    rating VARCHAR(8) NOT NULL CHECK (rating IN ('up', 'down')),
+    comment TEXT NOT NULL DEFAULT '',
+    applied BOOLEAN,                                -- NULL = não respondido
+    requested_at TIMESTAMPTZ NOT NULL,              -- Data da requisição à IA avaliada
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (workspace_id, history_id, user_id)
+);
+
+-- Configurações de IA por workspace (sem linha = padrões: tudo habilitado, sem comentários no contexto)
+CREATE TABLE workspace_ai_settings (
+    workspace_id INTEGER PRIMARY KEY REFERENCES workspaces(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT true,          -- false = nenhum dado do workspace vai para a IA
+    features JSONB NOT NULL DEFAULT '{}',           -- {"code_review": false, ...}; serviços ausentes ficam habilitados
+    include_member_names BOOLEAN NOT NULL DEFAULT true,
+    include_descriptions BOOLEAN NOT NULL DEFAULT true,
+    include_comments BOOLEAN NOT NULL DEFAULT false,
+    language VARCHAR(35) NOT NULL DEFAULT '',       -- Idioma das respostas (ex: 'pt-BR'); '' = padrão do serviço
+    custom_instructions TEXT NOT NULL DEFAULT '',
+    updated_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP
+);
+
+-- Geração semanal automática do relatório de status do workspace (horário em UTC)
+CREATE TABLE status_report_schedules (
+    workspace_id INTEGER PRIMARY KEY REFERENCES workspaces(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6), -- 0 = domingo
+    hour SMALLINT NOT NULL CHECK (hour BETWEEN 0 AND 23),
+    last_run_at TIMESTAMPTZ,                        -- Última execução agendada já feita
+    updated_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ
+);
+
 -- Índices para melhor performance
 CREATE INDEX idx_users_firebase_uid ON users(firebase_uid);
 CREATE INDEX idx_users_email ON users(email);
@@ -56,6 +226,14 @@ CREATE INDEX idx_tarefas_workspace ON tarefas(workspace_id);
 CREATE INDEX idx_tarefas_criado_por ON tarefas(criado_por);
 CREATE INDEX idx_workspace_members_user ON workspace_members(user_id);
 CREATE INDEX idx_workspace_members_workspace ON workspace_members(workspace_id);
+CREATE INDEX idx_task_reminders_workspace ON task_reminders(workspace_id);
+CREATE INDEX idx_calendar_feeds_user ON calendar_feeds(user_id);
+CREATE INDEX idx_import_jobs_workspace ON import_jobs(workspace_id);
+CREATE INDEX idx_ai_jobs_workspace_user ON ai_jobs(workspace_id, created_by);
+CREATE INDEX idx_ai_usage_workspace_date ON ai_usage(workspace_id, usage_date);
+CREATE INDEX idx_ai_response_cache_expires ON ai_response_cache(expires_at);
+CREATE INDEX idx_ai_feedback_workspace_requested ON ai_feedback(workspace_id, requested_at);
+CREATE INDEX idx_ai_jobs_pending ON ai_jobs(status, run_after) WHERE status IN ('queued', 'running');
 
 -- Função para atualizar o updated_at
 CREATE OR REPLACE FUNCTION update_updated_at_column()
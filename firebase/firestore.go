@@ -55,9 +55,9 @@ func DeleteWorkspaceAndSubcollectionsFromFirestore(tasksSubCollectionName string
 			// utilities.LogError(err, fmt.Sprintf("Erro ao comitar batch de deleção de tarefas para workspace %s", workspaceDocIDStr))
 			return fmt.Errorf("erro ao deletar batch de tarefas no workspace %s: %w", workspaceDocIDStr, err)
 		}
-		// utilities.LogInfo(fmt.Sprintf("Deletadas %d tarefas do workspace %s no Firestore.", numDeleted, workspaceDocIDStr))
+		// utilities.LogInfo("Deletadas %d tarefas do workspace %s no Firestore.", numDeleted, workspaceDocIDStr)
 	}
-	// utilities.LogInfo(fmt.Sprintf("Todas as tarefas da subcoleção do workspace %s foram deletadas do Firestore.", workspaceDocIDStr))
+	// utilities.LogInfo("Todas as tarefas da subcoleção do workspace %s foram deletadas do Firestore.", workspaceDocIDStr)
 
 	// 2. Deletar o documento principal do workspace
 	_, err := workspaceRef.Delete(ctx)
@@ -68,7 +68,7 @@ func DeleteWorkspaceAndSubcollectionsFromFirestore(tasksSubCollectionName string
 		// Para simplificar, retornamos o erro. Em produção, pode querer checar se é "not found".
 		return fmt.Errorf("erro ao deletar documento do workspace %s do Firestore: %w", workspaceDocIDStr, err)
 	}
-	// utilities.LogInfo(fmt.Sprintf("Documento do workspace %s deletado com sucesso do Firestore.", workspaceDocIDStr))
+	// utilities.LogInfo("Documento do workspace %s deletado com sucesso do Firestore.", workspaceDocIDStr)
 
 	return nil
 }
@@ -2,12 +2,15 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	"projeto-integrador/ai_services" // Onde CallAIAPI, LogAIInteraction, GetContextForIA estão
-
-	// Para GetFirestoreClient em LogAIInteraction
+	"projeto-integrador/ai_services" // Onde GetProvider, LogAIInteraction, GetContextForIA estão
+	"projeto-integrador/database"
 	"projeto-integrador/models"
 	"projeto-integrador/utilities"
 	"strconv"
@@ -29,276 +32,289 @@ func getWorkspaceIDFromPath(r *http.Request) (int64, error) {
 	return workspaceID, nil
 }
 
-// WorkspaceTaskAssistantHandler interage com a IA para dar assistência sobre tarefas.
-// Rota: /workspace/{workspace_id}/ai/task-assistant
-func WorkspaceTaskAssistantHandler(w http.ResponseWriter, r *http.Request) {
-	requestingUserFirebaseUID := r.Context().Value("userUID").(string)
-	ctx := r.Context()
-
-	workspaceIDPg, err := getWorkspaceIDFromPath(r)
-	if err != nil {
-		utilities.LogError(err, "TaskAssistantHandler: Erro ao extrair workspace_id da rota")
-		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
-		return
-	}
-
-	var frontendInput struct { // WorkspaceID não é mais esperado aqui
-		UserMessage string `json:"user_message"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&frontendInput); err != nil {
-		utilities.LogError(err, "TaskAssistantHandler: Erro ao decodificar JSON de entrada")
-		http.Error(w, `{"error": "Corpo da requisição inválido"}`, http.StatusBadRequest)
-		return
+// writeAIError responde uma falha do provedor de IA. Indisponibilidade (circuito aberto ou
+// falhas transitórias após as novas tentativas) vira 503 com Retry-After, para o frontend
+// exibir "IA temporariamente indisponível"; erros de validação (4xx) do serviço são
+// repassados e os demais viram 502.
+func writeAIError(w http.ResponseWriter, handlerName string, providerName string, result ai_services.AICallResult, errAI error) {
+	status, body := aiErrorResponse(handlerName, providerName, result, errAI)
+	if retryAfter, ok := body["retry_after_seconds"].(int); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
 	}
-	defer r.Body.Close()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
 
-	if frontendInput.UserMessage == "" {
-		http.Error(w, `{"error": "Mensagem do usuário é obrigatória"}`, http.StatusBadRequest)
-		return
+// aiErrorResponse loga a falha do provedor e monta o status e o corpo da resposta de erro.
+func aiErrorResponse(handlerName string, providerName string, result ai_services.AICallResult, errAI error) (int, map[string]interface{}) {
+	details := string(result.RawResponse)
+	if details == "" && errAI != nil {
+		details = errAI.Error()
 	}
-
-	utilities.LogInfo(fmt.Sprintf("TaskAssistantHandler: Usuário %s pediu assistência para workspace %d com a mensagem: %s",
-		requestingUserFirebaseUID, workspaceIDPg, frontendInput.UserMessage))
-
-	workspaceContextForAI, errCtx := ai_services.GetContextForIA(workspaceIDPg, frontendInput.UserMessage)
-	if errCtx != nil {
-		utilities.LogError(errCtx, "TaskAssistantHandler: Erro ao obter contexto do workspace")
-		http.Error(w, `{"error": "Falha ao carregar dados do workspace"}`, http.StatusInternalServerError)
-		return
+	utilities.LogError(errAI, fmt.Sprintf("%s: Erro do provedor de IA %s (status: %d). Resposta: %s", handlerName, providerName, result.StatusCode, details))
+
+	body := map[string]interface{}{}
+	status := http.StatusBadGateway
+	var unavailable *ai_services.UnavailableError
+	if errors.As(errAI, &unavailable) {
+		status = http.StatusServiceUnavailable
+		body["error"] = "AI temporarily unavailable"
+		body["retry_after_seconds"] = unavailable.RetryAfterSeconds()
+	} else {
+		if result.StatusCode >= 400 && result.StatusCode < 500 {
+			status = result.StatusCode
+		}
+		body["error"] = "Erro da API de IA"
+		var structuredError struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(result.RawResponse, &structuredError) == nil && structuredError.Error != "" {
+			body["error_ia"] = structuredError.Error
+		} else if details != "" {
+			body["details"] = details
+		}
 	}
+	return status, body
+}
 
-	aiRequestPayload := models.TaskAssistantAIRequest{
-		WorkspaceContext: *workspaceContextForAI,
+// AIHealthHandler verifica se o serviço de IA está respondendo e informa o estado do
+// circuit breaker. Responde 503 (com Retry-After) quando o serviço está indisponível.
+// Rota: GET /ai/health
+func AIHealthHandler(w http.ResponseWriter, r *http.Request) {
+	report := ai_services.CheckHealth(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status == ai_services.AIHealthUnavailable {
+		retryAfter := 30
+		if report.Circuit != nil && report.Circuit.RetryAfterSeconds > 0 {
+			retryAfter = report.Circuit.RetryAfterSeconds
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		w.WriteHeader(http.StatusServiceUnavailable)
 	}
-	var aiSuccessfulResponse models.TaskAssistantAIResponse
-	aiEndpointPath := "/assistente-tarefas"
-
-	statusCode, rawResponseBodyFromAI, errAI := ai_services.CallAIAPI(ctx, aiEndpointPath, aiRequestPayload, &aiSuccessfulResponse)
+	json.NewEncoder(w).Encode(report)
+}
 
-	if errAI == nil && statusCode >= 200 && statusCode < 300 {
-		// Sucesso na chamada à IA, logar e responder
-		ai_services.LogAIInteraction(
-			ctx, requestingUserFirebaseUID, workspaceIDPg, "task_assistant",
-			frontendInput, aiRequestPayload, aiSuccessfulResponse, statusCode, nil,
-		)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(aiSuccessfulResponse)
-	} else {
-		// Falha na chamada à IA, NÃO logar no histórico de sucessos. Apenas responder ao cliente.
-		var errorMsgToClient string
-		var errorDetailsForLog string
-		if rawResponseBodyFromAI != nil {
-			errorDetailsForLog = string(rawResponseBodyFromAI)
-			var structuredError models.TaskAssistantAIResponse // Tenta usar a struct de erro
-			if json.Unmarshal(rawResponseBodyFromAI, &structuredError) == nil && structuredError.Error != "" {
-				errorMsgToClient = fmt.Sprintf(`{"error_ia": "%s"}`, structuredError.Error)
-			} else {
-				errorMsgToClient = fmt.Sprintf(`{"error": "Erro da API de IA", "details": %q}`, errorDetailsForLog)
-			}
-		} else if errAI != nil {
-			errorDetailsForLog = errAI.Error()
-			errorMsgToClient = fmt.Sprintf(`{"error": "Falha na comunicação com o assistente de IA", "details": "%s"}`, errorDetailsForLog)
-		} else {
-			errorDetailsForLog = "Erro desconhecido da API de IA"
-			errorMsgToClient = `{"error": "Erro desconhecido ao contatar o assistente de IA"}`
-		}
+// Tamanho máximo do corpo das requisições de IA.
+const maxAIRequestBytes = 1 << 20
 
-		utilities.LogError(errAI, fmt.Sprintf("TaskAssistantHandler: Erro da API Python (status: %d) para %s. Resposta: %s", statusCode, aiEndpointPath, errorDetailsForLog))
-		w.Header().Set("Content-Type", "application/json")
-		http.Error(w, errorMsgToClient, statusCode)
-	}
+// aiRequest é uma requisição de IA já validada: workspace, usuário (membro) e entrada.
+type aiRequest struct {
+	workspaceID     int64
+	userFirebaseUID string
+	input           interface{}
 }
 
-// CodeReviewAIHandler recebe código do frontend e envia para a API de IA para review.
-// Rota: /workspace/{workspace_id}/ai/code-review
-func CodeReviewAIHandler(w http.ResponseWriter, r *http.Request) {
+// prepareAIRequest extrai o workspace da rota, valida a entrada do serviço, confere a
+// participação do usuário no workspace e se o workspace permite o serviço (403 se a IA
+// ou o serviço estiver desativado) e reserva a requisição nas cotas de IA (429 se
+// alguma for ultrapassada). Em caso de erro, a resposta já foi escrita e
+// db é nil; caso contrário o chamador deve fechar db.
+func prepareAIRequest(w http.ResponseWriter, r *http.Request, handlerName string, serviceType string) (*sql.DB, *aiRequest) {
 	requestingUserFirebaseUID := r.Context().Value("userUID").(string)
-	ctx := r.Context()
 
 	workspaceIDPg, err := getWorkspaceIDFromPath(r)
 	if err != nil {
-		utilities.LogError(err, "CodeReviewAIHandler: Erro ao extrair workspace_id da rota")
+		utilities.LogError(err, handlerName+": Erro ao extrair workspace_id da rota")
 		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
-		return
+		return nil, nil
 	}
 
-	var frontendInput models.CodeReviewAIRequest // Não tem mais WorkspaceID aqui
-	if err := json.NewDecoder(r.Body).Decode(&frontendInput); err != nil {
-		utilities.LogError(err, "CodeReviewAIHandler: Erro ao decodificar JSON de entrada")
+	rawInput, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxAIRequestBytes))
+	defer r.Body.Close()
+	if err != nil {
+		utilities.LogError(err, handlerName+": Erro ao ler corpo da requisição")
 		http.Error(w, `{"error": "Corpo da requisição inválido"}`, http.StatusBadRequest)
-		return
+		return nil, nil
 	}
-	defer r.Body.Close()
-
-	if frontendInput.Code == "" { /* ... validação ... */
-		return
+	frontendInput, err := ai_services.DecodeAIInput(serviceType, rawInput)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return nil, nil
 	}
-	if frontendInput.Language == "" {
-		frontendInput.Language = "Python"
+
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, handlerName+": Erro ao conectar ao PG")
+		http.Error(w, `{"error": "Database connection error"}`, http.StatusInternalServerError)
+		return nil, nil
 	}
 
-	aiRequestPayload := models.CodeReviewAIRequest{Code: frontendInput.Code, Language: frontendInput.Language}
-	var aiSuccessfulResponse models.CodeReviewAIResponse
-	aiEndpointPath := "/code-review"
+	isMember, err := models.IsWorkspaceMember(db, requestingUserFirebaseUID, workspaceIDPg)
+	if err != nil || !isMember {
+		db.Close()
+		http.Error(w, `{"error": "Forbidden"}`, http.StatusForbidden)
+		return nil, nil
+	}
 
-	statusCode, rawResponseBodyFromAI, errAI := ai_services.CallAIAPI(ctx, aiEndpointPath, aiRequestPayload, &aiSuccessfulResponse)
+	settings, err := models.GetWorkspaceAISettings(db, workspaceIDPg)
+	if err != nil {
+		db.Close()
+		utilities.LogError(err, handlerName+": Erro ao buscar configurações de IA do workspace")
+		http.Error(w, `{"error": "Failed to load AI settings"}`, http.StatusInternalServerError)
+		return nil, nil
+	}
+	if err := ai_services.CheckAIServiceEnabled(settings, serviceType); err != nil {
+		db.Close()
+		writeAIFeatureDisabled(w, serviceType)
+		return nil, nil
+	}
 
-	if errAI == nil && statusCode >= 200 && statusCode < 300 {
-		ai_services.LogAIInteraction(
-			ctx, requestingUserFirebaseUID, workspaceIDPg, "code_review",
-			frontendInput, aiRequestPayload, aiSuccessfulResponse, statusCode, nil,
-		)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(statusCode)
-		json.NewEncoder(w).Encode(aiSuccessfulResponse)
-	} else {
-		var errorMsgToClient string
-		var errorDetailsForLog string
-		var structuredError models.CodeReviewAIResponse
-		if rawResponseBodyFromAI != nil {
-			errorDetailsForLog = string(rawResponseBodyFromAI)
-			if json.Unmarshal(rawResponseBodyFromAI, &structuredError) == nil && structuredError.Error != "" {
-				errorMsgToClient = fmt.Sprintf(`{"error_ia": "%s"}`, structuredError.Error)
-			} else {
-				errorMsgToClient = fmt.Sprintf(`{"error": "Erro da API de IA", "details": %q}`, errorDetailsForLog)
+	// Mensagem em uma conversa: pela rota /ai/threads/{thread_id}/messages ou pelo campo thread_id
+	if assistantInput, ok := frontendInput.(models.TaskAssistantUserInput); ok {
+		if threadID := mux.Vars(r)["thread_id"]; threadID != "" {
+			assistantInput.ThreadID = threadID
+		}
+		if assistantInput.ThreadID != "" {
+			if status, err := checkAIThreadOwner(r.Context(), workspaceIDPg, assistantInput.ThreadID, requestingUserFirebaseUID); err != nil {
+				db.Close()
+				utilities.LogError(err, handlerName+": Erro ao buscar conversa de IA")
+				http.Error(w, `{"error": "`+http.StatusText(status)+`"}`, status)
+				return nil, nil
 			}
-		} else if errAI != nil {
-			errorDetailsForLog = errAI.Error()
-			errorMsgToClient = fmt.Sprintf(`{"error": "Falha na comunicação com a IA", "details": "%s"}`, errorDetailsForLog)
-		} else {
-			errorDetailsForLog = "Erro desconhecido da API de IA"
-			errorMsgToClient = `{"error": "Erro desconhecido ao contatar a IA"}`
 		}
+		frontendInput = assistantInput
+	}
+	taskDocID := ""
+	switch in := frontendInput.(type) {
+	case models.TaskBreakdownUserInput:
+		taskDocID = in.TaskDocID
+	case models.TaskSummaryUserInput:
+		taskDocID = in.TaskDocID
+	}
+	if taskDocID != "" {
+		if status, err := checkTaskExists(r.Context(), workspaceIDPg, taskDocID); err != nil {
+			db.Close()
+			utilities.LogError(err, handlerName+": Erro ao buscar tarefa")
+			http.Error(w, `{"error": "`+http.StatusText(status)+`"}`, status)
+			return nil, nil
+		}
+	}
 
-		utilities.LogError(errAI, fmt.Sprintf("CodeReviewAIHandler: Erro da API Python (status: %d) para %s. Resposta: %s", statusCode, aiEndpointPath, errorDetailsForLog))
-		w.Header().Set("Content-Type", "application/json")
-		http.Error(w, errorMsgToClient, statusCode)
+	// Por último, para que requisições recusadas acima não contem nas cotas
+	if err := ai_services.ReserveAIQuota(db, workspaceIDPg, requestingUserFirebaseUID, serviceType, frontendInput); err != nil {
+		db.Close()
+		var exceeded *ai_services.QuotaExceededError
+		if errors.As(err, &exceeded) {
+			w.Header().Set("Retry-After", strconv.Itoa(exceeded.RetryAfterSeconds()))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":               "AI quota exceeded",
+				"quota":               exceeded.Quota,
+				"retry_after_seconds": exceeded.RetryAfterSeconds(),
+			})
+			return nil, nil
+		}
+		utilities.LogError(err, handlerName+": Erro ao verificar cotas de IA")
+		http.Error(w, `{"error": "Failed to check AI quota"}`, http.StatusInternalServerError)
+		return nil, nil
 	}
+	return db, &aiRequest{workspaceID: workspaceIDPg, userFirebaseUID: requestingUserFirebaseUID, input: frontendInput}
 }
 
-// SummarizeTextAIHandler (adaptação similar)
-// Rota: /workspace/{workspace_id}/ai/summarize-text
-func SummarizeTextAIHandler(w http.ResponseWriter, r *http.Request) {
-	requestingUserFirebaseUID := r.Context().Value("userUID").(string)
-	ctx := r.Context()
+// writeAIFeatureDisabled responde 403 a uma requisição de IA desativada nas
+// configurações do workspace.
+func writeAIFeatureDisabled(w http.ResponseWriter, serviceType string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":        ai_services.ErrAIFeatureDisabled.Error(),
+		"service_type": serviceType,
+	})
+}
 
-	workspaceIDPg, err := getWorkspaceIDFromPath(r)
-	if err != nil {
-		utilities.LogError(err, "SummarizeTextAIHandler: Erro ao extrair workspace_id da rota")
-		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
-		return
-	}
+// aiRequestOptions lê as opções da requisição de IA na query: ?no_cache=true ignora a
+// resposta em cache.
+func aiRequestOptions(r *http.Request) ai_services.AIRequestOptions {
+	return ai_services.AIRequestOptions{NoCache: r.URL.Query().Get("no_cache") == "true"}
+}
 
-	var frontendInput models.SummarizeTextAIRequest // Não tem mais WorkspaceID
-	if err := json.NewDecoder(r.Body).Decode(&frontendInput); err != nil {
-		utilities.LogError(err, "SummarizeTextAIHandler: Erro ao decodificar JSON")
-		http.Error(w, `{"error": "Corpo da requisição inválido"}`, http.StatusBadRequest)
+// serveAIRequest trata uma requisição a um serviço de IA. Com ?async=true, a requisição
+// vira um job (202 com o ID para consulta); caso contrário a IA é chamada na hora.
+func serveAIRequest(w http.ResponseWriter, r *http.Request, handlerName string, serviceType string) {
+	ctx := r.Context()
+	db, req := prepareAIRequest(w, r, handlerName, serviceType)
+	if db == nil {
 		return
 	}
-	defer r.Body.Close()
+	defer db.Close()
 
-	if frontendInput.Text == "" { /* ... validação ... */
+	if r.URL.Query().Get("async") == "true" {
+		enqueueAIJob(w, db, handlerName, req.workspaceID, req.userFirebaseUID, serviceType, req.input, aiRequestOptions(r))
 		return
 	}
 
-	aiRequestPayload := models.SummarizeTextAIRequest{Text: frontendInput.Text}
-	var aiSuccessfulResponse models.SummarizeTextAIResponse
-	aiEndpointPath := "/summarize-text"
-
-	statusCode, rawResponseBodyFromAI, errAI := ai_services.CallAIAPI(ctx, aiEndpointPath, aiRequestPayload, &aiSuccessfulResponse)
-
-	if errAI == nil && statusCode >= 200 && statusCode < 300 {
-		ai_services.LogAIInteraction(
-			ctx, requestingUserFirebaseUID, workspaceIDPg, "text_summary",
-			frontendInput, aiRequestPayload, aiSuccessfulResponse, statusCode, nil,
-		)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(statusCode)
-		json.NewEncoder(w).Encode(aiSuccessfulResponse)
-	} else {
-		var errorMsgToClient string
-		var errorDetailsForLog string
-		var structuredError models.SummarizeTextAIResponse
-		if rawResponseBodyFromAI != nil {
-			errorDetailsForLog = string(rawResponseBodyFromAI)
-			if json.Unmarshal(rawResponseBodyFromAI, &structuredError) == nil && structuredError.Error != "" {
-				errorMsgToClient = fmt.Sprintf(`{"error_ia": "%s"}`, structuredError.Error)
-			} else {
-				errorMsgToClient = fmt.Sprintf(`{"error": "Erro da API de IA", "details": %q}`, errorDetailsForLog)
-			}
-		} else if errAI != nil {
-			errorDetailsForLog = errAI.Error()
-			errorMsgToClient = fmt.Sprintf(`{"error": "Falha na comunicação com a IA", "details": "%s"}`, errorDetailsForLog)
-		} else {
-			errorDetailsForLog = "Erro desconhecido da API de IA"
-			errorMsgToClient = `{"error": "Erro desconhecido ao contatar a IA"}`
-		}
-		utilities.LogError(errAI, fmt.Sprintf("SummarizeTextAIHandler: Erro da API Python (status: %d) para %s. Resposta: %s", statusCode, aiEndpointPath, errorDetailsForLog))
-		w.Header().Set("Content-Type", "application/json")
-		http.Error(w, errorMsgToClient, statusCode)
+	provider := ai_services.GetProvider()
+	exec, errAI := ai_services.ExecuteAIRequest(ctx, provider, req.workspaceID, serviceType, req.input, aiRequestOptions(r))
+	// A chamada já foi feita (e contada na cota): o histórico e o que a resposta grava
+	// (thread, mapa mental, resumo da tarefa) não dependem de o cliente ainda estar conectado.
+	saveCtx := context.WithoutCancel(ctx)
+	if errAI != nil {
+		// Falhas também vão para o histórico
+		ai_services.RecordAIInteraction(saveCtx, req.userFirebaseUID, req.workspaceID, exec, errAI)
 	}
-}
-
-// GenerateMindMapIdeasAIHandler (adaptação similar)
-// Rota: /workspace/{workspace_id}/ai/mindmap-ideas
-func GenerateMindMapIdeasAIHandler(w http.ResponseWriter, r *http.Request) {
-	requestingUserFirebaseUID := r.Context().Value("userUID").(string)
-	ctx := r.Context()
-
-	workspaceIDPg, err := getWorkspaceIDFromPath(r)
-	if err != nil {
-		utilities.LogError(err, "GenerateMindMapIdeasAIHandler: Erro ao extrair workspace_id da rota")
-		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+	if errors.Is(errAI, ai_services.ErrAIFeatureDisabled) {
+		writeAIFeatureDisabled(w, serviceType)
 		return
 	}
-
-	var frontendInput models.MindMapIdeasAIRequest // Não tem mais WorkspaceID
-	if err := json.NewDecoder(r.Body).Decode(&frontendInput); err != nil {
-		utilities.LogError(err, "GenerateMindMapIdeasAIHandler: Erro ao decodificar JSON")
-		http.Error(w, `{"error": "Corpo da requisição inválido"}`, http.StatusBadRequest)
+	if errors.Is(errAI, ai_services.ErrAIContextUnavailable) {
+		utilities.LogError(errAI, handlerName+": Erro ao obter contexto do workspace")
+		http.Error(w, `{"error": "Falha ao carregar dados do workspace"}`, http.StatusInternalServerError)
 		return
 	}
-	defer r.Body.Close()
-
-	if frontendInput.Text == "" { /* ... validação ... */
+	if errAI != nil {
+		writeAIError(w, handlerName, provider.Name(), exec.Result, errAI)
 		return
 	}
 
-	aiRequestPayload := models.MindMapIdeasAIRequest{Text: frontendInput.Text}
-	var aiSuccessfulResponse models.MindMapIdeasAIResponse
-	aiEndpointPath := "/mindmap-ideas"
+	historyID := ai_services.RecordAIInteraction(saveCtx, req.userFirebaseUID, req.workspaceID, exec, nil)
+	response := ai_services.FinalizeAIResponse(saveCtx, req.userFirebaseUID, req.workspaceID, exec.Response, historyID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(exec.Result.StatusCode)
+	json.NewEncoder(w).Encode(response)
+}
 
-	statusCode, rawResponseBodyFromAI, errAI := ai_services.CallAIAPI(ctx, aiEndpointPath, aiRequestPayload, &aiSuccessfulResponse)
+// TaskBreakdownAIHandler pede à IA a decomposição de uma tarefa existente em subtarefas
+// ordenadas, com estimativas. A resposta é uma prévia: as subtarefas só são criadas
+// por CreateSubtasksHandler. Aceita ?async=true.
+// Rota: POST /workspace/{workspace_id}/ai/task-breakdown
+func TaskBreakdownAIHandler(w http.ResponseWriter, r *http.Request) {
+	serveAIRequest(w, r, "TaskBreakdownAIHandler", ai_services.ServiceTaskBreakdown)
+}
 
-	if errAI == nil && statusCode >= 200 && statusCode < 300 {
-		ai_services.LogAIInteraction(
-			ctx, requestingUserFirebaseUID, workspaceIDPg, "mindmap_ideas",
-			frontendInput, aiRequestPayload, aiSuccessfulResponse, statusCode, nil,
-		)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(statusCode)
-		json.NewEncoder(w).Encode(aiSuccessfulResponse)
-	} else {
-		var errorMsgToClient string
-		var errorDetailsForLog string
-		var structuredError models.MindMapIdeasAIResponse
-		if rawResponseBodyFromAI != nil {
-			errorDetailsForLog = string(rawResponseBodyFromAI)
-			if json.Unmarshal(rawResponseBodyFromAI, &structuredError) == nil && structuredError.Error != "" {
-				errorMsgToClient = fmt.Sprintf(`{"error_ia": "%s"}`, structuredError.Error)
-			} else {
-				errorMsgToClient = fmt.Sprintf(`{"error": "Erro da API de IA", "details": %q}`, errorDetailsForLog)
-			}
-		} else if errAI != nil {
-			errorDetailsForLog = errAI.Error()
-			errorMsgToClient = fmt.Sprintf(`{"error": "Falha na comunicação com a IA", "details": "%s"}`, errorDetailsForLog)
-		} else {
-			errorDetailsForLog = "Erro desconhecido da API de IA"
-			errorMsgToClient = `{"error": "Erro desconhecido ao contatar a IA"}`
-		}
-		utilities.LogError(errAI, fmt.Sprintf("GenerateMindMapIdeasAIHandler: Erro da API Python (status: %d) para %s. Resposta: %s", statusCode, aiEndpointPath, errorDetailsForLog))
-		w.Header().Set("Content-Type", "application/json")
-		http.Error(w, errorMsgToClient, statusCode)
-	}
+// TaskSummaryAIHandler pede à IA o resumo de uma tarefa existente: o backend monta o
+// texto com a descrição, o histórico, os comentários e os anexos da tarefa (inclusive o
+// texto extraído do arquivo anexado), e o resumo fica gravado na tarefa (ai_summary),
+// visível sem chamar a IA de novo. Aceita ?async=true.
+// Rota: POST /workspace/{workspace_id}/ai/task-summary
+func TaskSummaryAIHandler(w http.ResponseWriter, r *http.Request) {
+	serveAIRequest(w, r, "TaskSummaryAIHandler", ai_services.ServiceTaskSummary)
+}
+
+// WorkspaceTaskAssistantHandler interage com a IA para dar assistência sobre tarefas.
+// Rota: /workspace/{workspace_id}/ai/task-assistant
+func WorkspaceTaskAssistantHandler(w http.ResponseWriter, r *http.Request) {
+	serveAIRequest(w, r, "TaskAssistantHandler", ai_services.ServiceTaskAssistant)
+}
+
+// CodeReviewAIHandler recebe código do frontend e envia para a API de IA para review.
+// Rota: /workspace/{workspace_id}/ai/code-review
+func CodeReviewAIHandler(w http.ResponseWriter, r *http.Request) {
+	serveAIRequest(w, r, "CodeReviewAIHandler", ai_services.ServiceCodeReview)
+}
+
+// SummarizeTextAIHandler envia um texto para a API de IA resumir.
+// Rota: /workspace/{workspace_id}/ai/summarize-text
+func SummarizeTextAIHandler(w http.ResponseWriter, r *http.Request) {
+	serveAIRequest(w, r, "SummarizeTextAIHandler", ai_services.ServiceTextSummary)
+}
+
+// GenerateMindMapIdeasAIHandler envia um texto para a API de IA gerar ideias de mapa mental.
+// Rota: /workspace/{workspace_id}/ai/mindmap-ideas
+func GenerateMindMapIdeasAIHandler(w http.ResponseWriter, r *http.Request) {
+	serveAIRequest(w, r, "GenerateMindMapIdeasAIHandler", ai_services.ServiceMindMapIdeas)
 }
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"projeto-integrador/ai_services"
+	"projeto-integrador/firebase"
+	"projeto-integrador/models"
+	"projeto-integrador/utilities"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const maxAIFeedbackReportPeriod = 366 * 24 * time.Hour
+
+// SubmitAIFeedbackHandler grava a avaliação do usuário (positiva ou negativa, comentário
+// e se a resposta foi usada) sobre uma resposta de IA que ele recebeu. Enviar de novo
+// substitui a avaliação anterior.
+// Rota: PUT /workspace/{workspace_id}/ai/history/{history_id}/feedback
+func SubmitAIFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	historyID := mux.Vars(r)["history_id"]
+	var input ai_services.AIFeedbackInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := ai_services.ValidateAIFeedback(input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	db, workspaceID, userFirebaseUID, _ := aiHistoryRequest(w, r, "SubmitAIFeedbackHandler")
+	if db == nil {
+		return
+	}
+	defer db.Close()
+
+	firestoreClient, err := firebase.GetFirestoreClient()
+	if err != nil {
+		utilities.LogError(err, "SubmitAIFeedbackHandler: Erro ao obter cliente Firestore")
+		http.Error(w, "Failed to connect to Firestore", http.StatusInternalServerError)
+		return
+	}
+	defer firestoreClient.Close()
+
+	feedback, err := ai_services.SubmitAIFeedback(r.Context(), firestoreClient, db, workspaceID, historyID, userFirebaseUID, input)
+	switch {
+	case errors.Is(err, ai_services.ErrAIHistoryNotFound):
+		http.Error(w, "AI history entry not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ai_services.ErrInvalidAIFeedback):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		utilities.LogError(err, fmt.Sprintf("SubmitAIFeedbackHandler: Erro ao salvar avaliação do histórico %s", historyID))
+		http.Error(w, "Failed to save AI feedback", http.StatusInternalServerError)
+		return
+	}
+
+	utilities.LogInfo("SubmitAIFeedbackHandler: Avaliação %q do histórico %s (workspace %d) por %s", feedback.Rating, historyID, workspaceID, userFirebaseUID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(feedback)
+}
+
+// GetAIFeedbackHandler retorna a avaliação do usuário sobre uma resposta de IA.
+// Rota: GET /workspace/{workspace_id}/ai/history/{history_id}/feedback
+func GetAIFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	historyID := mux.Vars(r)["history_id"]
+	db, workspaceID, userFirebaseUID, _ := aiHistoryRequest(w, r, "GetAIFeedbackHandler")
+	if db == nil {
+		return
+	}
+	defer db.Close()
+
+	feedback, err := models.GetAIFeedback(db, workspaceID, historyID, userFirebaseUID)
+	switch {
+	case errors.Is(err, models.ErrAIFeedbackNotFound):
+		http.Error(w, "AI feedback not found", http.StatusNotFound)
+		return
+	case err != nil:
+		utilities.LogError(err, fmt.Sprintf("GetAIFeedbackHandler: Erro ao buscar avaliação do histórico %s", historyID))
+		http.Error(w, "Failed to retrieve AI feedback", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(feedback)
+}
+
+// DeleteAIFeedbackHandler remove a avaliação do usuário sobre uma resposta de IA.
+// Rota: DELETE /workspace/{workspace_id}/ai/history/{history_id}/feedback
+func DeleteAIFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	historyID := mux.Vars(r)["history_id"]
+	db, workspaceID, userFirebaseUID, _ := aiHistoryRequest(w, r, "DeleteAIFeedbackHandler")
+	if db == nil {
+		return
+	}
+	defer db.Close()
+
+	err := models.DeleteAIFeedback(db, workspaceID, historyID, userFirebaseUID)
+	switch {
+	case errors.Is(err, models.ErrAIFeedbackNotFound):
+		http.Error(w, "AI feedback not found", http.StatusNotFound)
+		return
+	case err != nil:
+		utilities.LogError(err, fmt.Sprintf("DeleteAIFeedbackHandler: Erro ao remover avaliação do histórico %s", historyID))
+		http.Error(w, "Failed to delete AI feedback", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AIFeedbackReportHandler agrega as avaliações das respostas de IA do workspace por
+// serviço e período, com os comentários recentes (apenas admins). Padrão: últimos 90
+// dias, por semana.
+// Rota: GET /workspace/{workspace_id}/ai/feedback/report?since=&until=&service_type=&interval=
+func AIFeedbackReportHandler(w http.ResponseWriter, r *http.Request) {
+	db, workspaceID, _, isAdmin := aiHistoryRequest(w, r, "AIFeedbackReportHandler")
+	if db == nil {
+		return
+	}
+	defer db.Close()
+	if !isAdmin {
+		http.Error(w, "Forbidden: Only workspace admins can view the AI feedback report", http.StatusForbidden)
+		return
+	}
+
+	query := r.URL.Query()
+	until := time.Now()
+	if parsed, err := parseAIHistoryTime(query.Get("until")); err != nil {
+		http.Error(w, "Invalid until (use RFC3339 or YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	} else if parsed != nil {
+		until = *parsed
+	}
+	since := until.AddDate(0, 0, -90)
+	if parsed, err := parseAIHistoryTime(query.Get("since")); err != nil {
+		http.Error(w, "Invalid since (use RFC3339 or YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	} else if parsed != nil {
+		since = *parsed
+	}
+	if !since.Before(until) {
+		http.Error(w, "since must be before until", http.StatusBadRequest)
+		return
+	}
+	if until.Sub(since) > maxAIFeedbackReportPeriod {
+		http.Error(w, "The report period cannot exceed one year", http.StatusBadRequest)
+		return
+	}
+	interval := query.Get("interval")
+	switch interval {
+	case "":
+		interval = models.AIFeedbackIntervalWeek
+	case models.AIFeedbackIntervalDay, models.AIFeedbackIntervalWeek, models.AIFeedbackIntervalMonth:
+	default:
+		http.Error(w, "Invalid interval (use day, week or month)", http.StatusBadRequest)
+		return
+	}
+
+	report, err := ai_services.BuildAIFeedbackReport(db, workspaceID, since, until, query.Get("service_type"), interval)
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("AIFeedbackReportHandler: Erro ao montar relatório de avaliações do workspace %d", workspaceID))
+		http.Error(w, "Failed to build AI feedback report", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"projeto-integrador/ai_services"
+	"projeto-integrador/database"
+	"projeto-integrador/firebase"
+	"projeto-integrador/models"
+	"projeto-integrador/utilities"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	maxAIHistoryRetentionDays = 3650
+	maxAIReliabilityPeriod    = 90 * 24 * time.Hour
+)
+
+// aiHistoryRequest valida o workspace da rota e retorna o papel do usuário nele. Em
+// caso de erro (inclusive quando o usuário não é membro), a resposta já foi escrita e
+// db é nil.
+func aiHistoryRequest(w http.ResponseWriter, r *http.Request, handlerName string) (db *sql.DB, workspaceID int64, userFirebaseUID string, isAdmin bool) {
+	workspaceID, err := getWorkspaceIDFromPath(r)
+	if err != nil {
+		http.Error(w, "Invalid Workspace ID", http.StatusBadRequest)
+		return nil, 0, "", false
+	}
+	userFirebaseUID = r.Context().Value("userUID").(string)
+
+	db, err = database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, handlerName+": Erro ao conectar ao PG")
+		http.Error(w, "Database connection error", http.StatusInternalServerError)
+		return nil, 0, "", false
+	}
+
+	role, err := models.GetUserRoleInWorkspace(db, userFirebaseUID, workspaceID)
+	if err != nil || role == "" {
+		db.Close()
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil, 0, "", false
+	}
+	return db, workspaceID, userFirebaseUID, role == "admin"
+}
+
+// parseAIHistoryTime aceita RFC3339 ou AAAA-MM-DD (início do dia, UTC).
+func parseAIHistoryTime(raw string) (*time.Time, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ListAIHistoryHandler lista o histórico de IA do workspace, sem os payloads. Por padrão
+// (scope=mine) apenas as requisições do usuário; admins podem usar scope=workspace e
+// filtrar por user_id.
+// Rota: GET /workspace/{workspace_id}/ai/history?scope=&service_type=&status=&user_id=&since=&until=&cursor=&limit=
+func ListAIHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	db, workspaceID, userFirebaseUID, isAdmin := aiHistoryRequest(w, r, "ListAIHistoryHandler")
+	if db == nil {
+		return
+	}
+	db.Close() // Daqui em diante só o Firestore
+
+	query := r.URL.Query()
+	filter := ai_services.AIHistoryFilter{
+		ServiceType: query.Get("service_type"),
+		Status:      query.Get("status"),
+		Cursor:      query.Get("cursor"),
+	}
+	switch query.Get("scope") {
+	case "", "mine":
+		if query.Get("user_id") != "" && query.Get("user_id") != userFirebaseUID {
+			http.Error(w, "user_id requires scope=workspace", http.StatusBadRequest)
+			return
+		}
+		filter.UserID = userFirebaseUID
+	case "workspace":
+		if !isAdmin {
+			http.Error(w, "Forbidden: Only workspace admins can view the workspace AI history", http.StatusForbidden)
+			return
+		}
+		filter.UserID = query.Get("user_id")
+	default:
+		http.Error(w, "Invalid scope (use mine or workspace)", http.StatusBadRequest)
+		return
+	}
+	if filter.Status != "" && filter.Status != ai_services.AIHistoryStatusSuccess && filter.Status != ai_services.AIHistoryStatusError {
+		http.Error(w, "Invalid status (use success or error)", http.StatusBadRequest)
+		return
+	}
+	var err error
+	if filter.Since, err = parseAIHistoryTime(query.Get("since")); err != nil {
+		http.Error(w, "Invalid since (use RFC3339 or YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+	if filter.Until, err = parseAIHistoryTime(query.Get("until")); err != nil {
+		http.Error(w, "Invalid until (use RFC3339 or YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+	if raw := query.Get("limit"); raw != "" {
+		filter.Limit, err = strconv.Atoi(raw)
+		if err != nil || filter.Limit < 1 || filter.Limit > ai_services.MaxAIHistoryPageSize {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", ai_services.MaxAIHistoryPageSize), http.StatusBadRequest)
+			return
+		}
+	}
+
+	firestoreClient, err := firebase.GetFirestoreClient()
+	if err != nil {
+		utilities.LogError(err, "ListAIHistoryHandler: Erro ao obter cliente Firestore")
+		http.Error(w, "Failed to connect to Firestore", http.StatusInternalServerError)
+		return
+	}
+	defer firestoreClient.Close()
+
+	page, err := ai_services.ListAIHistory(r.Context(), firestoreClient, workspaceID, filter)
+	switch {
+	case errors.Is(err, ai_services.ErrInvalidAIHistoryCursor):
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	case err != nil:
+		utilities.LogError(err, fmt.Sprintf("ListAIHistoryHandler: Erro ao listar histórico de IA do workspace %d", workspaceID))
+		http.Error(w, "Failed to list AI history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// GetAIHistoryEntryHandler retorna uma entrada do histórico de IA com os payloads. Membros
+// veem as próprias entradas; admins, todas as do workspace.
+// Rota: GET /workspace/{workspace_id}/ai/history/{history_id}
+func GetAIHistoryEntryHandler(w http.ResponseWriter, r *http.Request) {
+	historyID := mux.Vars(r)["history_id"]
+	db, workspaceID, userFirebaseUID, isAdmin := aiHistoryRequest(w, r, "GetAIHistoryEntryHandler")
+	if db == nil {
+		return
+	}
+	db.Close()
+
+	firestoreClient, err := firebase.GetFirestoreClient()
+	if err != nil {
+		utilities.LogError(err, "GetAIHistoryEntryHandler: Erro ao obter cliente Firestore")
+		http.Error(w, "Failed to connect to Firestore", http.StatusInternalServerError)
+		return
+	}
+	defer firestoreClient.Close()
+
+	entry, err := ai_services.GetAIHistoryEntry(r.Context(), firestoreClient, workspaceID, historyID, userFirebaseUID, isAdmin)
+	switch {
+	case errors.Is(err, ai_services.ErrAIHistoryNotFound):
+		http.Error(w, "AI history entry not found", http.StatusNotFound)
+		return
+	case err != nil:
+		utilities.LogError(err, fmt.Sprintf("GetAIHistoryEntryHandler: Erro ao buscar histórico %s", historyID))
+		http.Error(w, "Failed to retrieve AI history entry", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+// DeleteAIHistoryEntryHandler apaga uma entrada do histórico de IA (do próprio usuário
+// ou, para admins, de qualquer membro).
+// Rota: DELETE /workspace/{workspace_id}/ai/history/{history_id}
+func DeleteAIHistoryEntryHandler(w http.ResponseWriter, r *http.Request) {
+	historyID := mux.Vars(r)["history_id"]
+	db, workspaceID, userFirebaseUID, isAdmin := aiHistoryRequest(w, r, "DeleteAIHistoryEntryHandler")
+	if db == nil {
+		return
+	}
+	db.Close()
+
+	firestoreClient, err := firebase.GetFirestoreClient()
+	if err != nil {
+		utilities.LogError(err, "DeleteAIHistoryEntryHandler: Erro ao obter cliente Firestore")
+		http.Error(w, "Failed to connect to Firestore", http.StatusInternalServerError)
+		return
+	}
+	defer firestoreClient.Close()
+
+	err = ai_services.DeleteAIHistoryEntry(r.Context(), firestoreClient, workspaceID, historyID, userFirebaseUID, isAdmin)
+	switch {
+	case errors.Is(err, ai_services.ErrAIHistoryNotFound):
+		http.Error(w, "AI history entry not found", http.StatusNotFound)
+		return
+	case err != nil:
+		utilities.LogError(err, fmt.Sprintf("DeleteAIHistoryEntryHandler: Erro ao apagar histórico %s", historyID))
+		http.Error(w, "Failed to delete AI history entry", http.StatusInternalServerError)
+		return
+	}
+
+	utilities.LogInfo("DeleteAIHistoryEntryHandler: Histórico de IA %s do workspace %d apagado por %s", historyID, workspaceID, userFirebaseUID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAIHistoryRetentionHandler retorna a política de retenção do histórico de IA do workspace.
+// Rota: GET /workspace/{workspace_id}/ai/history/retention
+func GetAIHistoryRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	db, workspaceID, _, _ := aiHistoryRequest(w, r, "GetAIHistoryRetentionHandler")
+	if db == nil {
+		return
+	}
+	defer db.Close()
+
+	defaultDays, defaultMode := ai_services.AIHistoryRetentionDefaults()
+	policy, err := models.GetAIHistoryRetention(db, workspaceID, defaultDays, defaultMode)
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("GetAIHistoryRetentionHandler: Erro ao buscar retenção do workspace %d", workspaceID))
+		http.Error(w, "Failed to retrieve AI history retention", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
+
+// UpdateAIHistoryRetentionHandler altera a política de retenção do histórico de IA do
+// workspace (apenas admins). Campos nulos ou ausentes voltam ao padrão do servidor.
+// Rota: PUT /workspace/{workspace_id}/ai/history/retention
+func UpdateAIHistoryRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		RetentionDays *int    `json:"retention_days"` // 0 = manter para sempre
+		Mode          *string `json:"mode"`           // "truncate" ou "delete"
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if input.RetentionDays != nil && (*input.RetentionDays < 0 || *input.RetentionDays > maxAIHistoryRetentionDays) {
+		http.Error(w, fmt.Sprintf("retention_days must be between 0 and %d", maxAIHistoryRetentionDays), http.StatusBadRequest)
+		return
+	}
+	if input.Mode != nil && *input.Mode != models.AIHistoryRetentionTruncate && *input.Mode != models.AIHistoryRetentionDelete {
+		http.Error(w, "Invalid mode (use truncate or delete)", http.StatusBadRequest)
+		return
+	}
+
+	db, workspaceID, userFirebaseUID, isAdmin := aiHistoryRequest(w, r, "UpdateAIHistoryRetentionHandler")
+	if db == nil {
+		return
+	}
+	defer db.Close()
+	if !isAdmin {
+		http.Error(w, "Forbidden: Only workspace admins can change the AI history retention", http.StatusForbidden)
+		return
+	}
+
+	if err := models.SetAIHistoryRetention(db, workspaceID, userFirebaseUID, input.RetentionDays, input.Mode); err != nil {
+		utilities.LogError(err, fmt.Sprintf("UpdateAIHistoryRetentionHandler: Erro ao salvar retenção do workspace %d", workspaceID))
+		http.Error(w, "Failed to update AI history retention", http.StatusInternalServerError)
+		return
+	}
+	defaultDays, defaultMode := ai_services.AIHistoryRetentionDefaults()
+	policy, err := models.GetAIHistoryRetention(db, workspaceID, defaultDays, defaultMode)
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("UpdateAIHistoryRetentionHandler: Erro ao buscar retenção do workspace %d", workspaceID))
+		http.Error(w, "Failed to retrieve AI history retention", http.StatusInternalServerError)
+		return
+	}
+
+	utilities.LogInfo("UpdateAIHistoryRetentionHandler: Retenção do histórico de IA do workspace %d alterada por %s (%d dias, %s)", workspaceID, userFirebaseUID, policy.RetentionDays, policy.Mode)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
+
+// AIReliabilityHandler retorna o relatório de confiabilidade da IA do workspace, a partir
+// do histórico: taxa de sucesso, falhas por classificação, latência e novas tentativas, no
+// total e por tipo de serviço. O período padrão são os últimos 7 dias (máximo de 90).
+// Rota: GET /workspace/{workspace_id}/ai/reliability?since=&until=&service_type=
+func AIReliabilityHandler(w http.ResponseWriter, r *http.Request) {
+	db, workspaceID, _, _ := aiHistoryRequest(w, r, "AIReliabilityHandler")
+	if db == nil {
+		return
+	}
+	db.Close() // Daqui em diante só o Firestore
+
+	query := r.URL.Query()
+	until := time.Now()
+	if parsed, err := parseAIHistoryTime(query.Get("until")); err != nil {
+		http.Error(w, "Invalid until (use RFC3339 or YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	} else if parsed != nil {
+		until = *parsed
+	}
+	since := until.AddDate(0, 0, -7)
+	if parsed, err := parseAIHistoryTime(query.Get("since")); err != nil {
+		http.Error(w, "Invalid since (use RFC3339 or YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	} else if parsed != nil {
+		since = *parsed
+	}
+	if !since.Before(until) {
+		http.Error(w, "since must be before until", http.StatusBadRequest)
+		return
+	}
+	if until.Sub(since) > maxAIReliabilityPeriod {
+		http.Error(w, "The report period cannot exceed 90 days", http.StatusBadRequest)
+		return
+	}
+
+	firestoreClient, err := firebase.GetFirestoreClient()
+	if err != nil {
+		utilities.LogError(err, "AIReliabilityHandler: Erro ao obter cliente Firestore")
+		http.Error(w, "Failed to connect to Firestore", http.StatusInternalServerError)
+		return
+	}
+	defer firestoreClient.Close()
+
+	report, err := ai_services.BuildAIReliabilityReport(r.Context(), firestoreClient, workspaceID, since, until, query.Get("service_type"))
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("AIReliabilityHandler: Erro ao montar relatório de confiabilidade do workspace %d", workspaceID))
+		http.Error(w, "Failed to build AI reliability report", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
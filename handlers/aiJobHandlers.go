@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"projeto-integrador/ai_services"
+	"projeto-integrador/database"
+	"projeto-integrador/models"
+	"projeto-integrador/utilities"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// Limite padrão de jobs de IA pendentes (queued/running) por usuário.
+const defaultMaxPendingAIJobs = 10
+
+func maxPendingAIJobs() int {
+	if n, err := strconv.Atoi(os.Getenv("AI_JOB_MAX_PENDING_PER_USER")); err == nil && n > 0 {
+		return n
+	}
+	return defaultMaxPendingAIJobs
+}
+
+// enqueueAIJob grava a entrada validada como job e responde 202 com o job criado.
+func enqueueAIJob(w http.ResponseWriter, db *sql.DB, handlerName string, workspaceID int64, userFirebaseUID string, serviceType string, frontendInput interface{}, opts ai_services.AIRequestOptions) {
+	pending, err := models.CountPendingAIJobs(db, userFirebaseUID)
+	if err != nil {
+		utilities.LogError(err, handlerName+": Erro ao contar jobs de IA pendentes")
+		http.Error(w, `{"error": "Failed to create AI job"}`, http.StatusInternalServerError)
+		return
+	}
+	if limit := maxPendingAIJobs(); pending >= limit {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":        "Too many pending AI jobs",
+			"pending_jobs": pending,
+			"limit":        limit,
+		})
+		return
+	}
+
+	payload, err := json.Marshal(frontendInput)
+	if err != nil {
+		utilities.LogError(err, handlerName+": Erro ao serializar entrada do job")
+		http.Error(w, `{"error": "Failed to create AI job"}`, http.StatusInternalServerError)
+		return
+	}
+	job, err := models.CreateAIJob(db, workspaceID, userFirebaseUID, serviceType, payload, opts.NoCache)
+	if err != nil {
+		utilities.LogError(err, handlerName+": Erro ao criar job de IA")
+		http.Error(w, `{"error": "Failed to create AI job"}`, http.StatusInternalServerError)
+		return
+	}
+	ai_services.NotifyAIJobQueued()
+
+	utilities.LogInfo("%s: Job de IA %d (%s) criado para o workspace %d pelo usuário %s", handlerName, job.ID, serviceType, workspaceID, userFirebaseUID)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/workspace/%d/ai/jobs/%d", workspaceID, job.ID))
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// aiJobRequest extrai e valida workspace e job da rota, abre a conexão e confere a
+// participação no workspace. Em caso de erro, a resposta já foi escrita e db é nil.
+func aiJobRequest(w http.ResponseWriter, r *http.Request, handlerName string) (db *sql.DB, workspaceID int64, jobID int64, userFirebaseUID string) {
+	workspaceID, err := getWorkspaceIDFromPath(r)
+	if err != nil {
+		http.Error(w, "Invalid Workspace ID", http.StatusBadRequest)
+		return nil, 0, 0, ""
+	}
+	if rawJobID, ok := mux.Vars(r)["job_id"]; ok {
+		jobID, err = strconv.ParseInt(rawJobID, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid job ID", http.StatusBadRequest)
+			return nil, 0, 0, ""
+		}
+	}
+	userFirebaseUID = r.Context().Value("userUID").(string)
+
+	db, err = database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, handlerName+": Erro ao conectar ao PG")
+		http.Error(w, "Database connection error", http.StatusInternalServerError)
+		return nil, 0, 0, ""
+	}
+
+	isMember, err := models.IsWorkspaceMember(db, userFirebaseUID, workspaceID)
+	if err != nil || !isMember {
+		db.Close()
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil, 0, 0, ""
+	}
+	return db, workspaceID, jobID, userFirebaseUID
+}
+
+// ListAIJobsHandler lista os jobs de IA recentes do usuário no workspace (sem os resultados).
+// Rota: GET /workspace/{workspace_id}/ai/jobs
+func ListAIJobsHandler(w http.ResponseWriter, r *http.Request) {
+	db, workspaceID, _, userFirebaseUID := aiJobRequest(w, r, "ListAIJobsHandler")
+	if db == nil {
+		return
+	}
+	defer db.Close()
+
+	jobs, err := models.ListAIJobs(db, workspaceID, userFirebaseUID, 50)
+	if err != nil {
+		utilities.LogError(err, "ListAIJobsHandler: Erro ao listar jobs")
+		http.Error(w, "Failed to list AI jobs", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
+
+// GetAIJobHandler retorna o status de um job de IA do usuário (o resultado, quando
+// concluído, vem em /result).
+// Rota: GET /workspace/{workspace_id}/ai/jobs/{job_id}
+func GetAIJobHandler(w http.ResponseWriter, r *http.Request) {
+	db, workspaceID, jobID, userFirebaseUID := aiJobRequest(w, r, "GetAIJobHandler")
+	if db == nil {
+		return
+	}
+	defer db.Close()
+
+	job, err := models.GetAIJob(db, workspaceID, jobID, userFirebaseUID)
+	if err != nil {
+		writeAIJobLookupError(w, "GetAIJobHandler", err)
+		return
+	}
+	job.Result = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// GetAIJobResultHandler retorna a resposta da IA de um job concluído, no mesmo formato
+// da chamada síncrona. Jobs que ainda não terminaram, falharam ou foram cancelados
+// respondem 409 com o status do job.
+// Rota: GET /workspace/{workspace_id}/ai/jobs/{job_id}/result
+func GetAIJobResultHandler(w http.ResponseWriter, r *http.Request) {
+	db, workspaceID, jobID, userFirebaseUID := aiJobRequest(w, r, "GetAIJobResultHandler")
+	if db == nil {
+		return
+	}
+	defer db.Close()
+
+	job, err := models.GetAIJob(db, workspaceID, jobID, userFirebaseUID)
+	if err != nil {
+		writeAIJobLookupError(w, "GetAIJobResultHandler", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if job.Status != models.AIJobCompleted {
+		body := map[string]interface{}{"error": "AI job has no result", "status": job.Status}
+		if job.Error != "" {
+			body["job_error"] = job.Error
+		}
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(body)
+		return
+	}
+	w.Write(job.Result)
+}
+
+// CancelAIJobHandler cancela um job de IA do usuário que ainda não terminou.
+// Rota: POST /workspace/{workspace_id}/ai/jobs/{job_id}/cancel
+func CancelAIJobHandler(w http.ResponseWriter, r *http.Request) {
+	db, workspaceID, jobID, userFirebaseUID := aiJobRequest(w, r, "CancelAIJobHandler")
+	if db == nil {
+		return
+	}
+	defer db.Close()
+
+	job, err := models.CancelAIJob(db, workspaceID, jobID, userFirebaseUID)
+	if err != nil && !errors.Is(err, models.ErrAIJobFinished) {
+		writeAIJobLookupError(w, "CancelAIJobHandler", err)
+		return
+	}
+	job.Result = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	if errors.Is(err, models.ErrAIJobFinished) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "AI job already finished", "job": job})
+		return
+	}
+	ai_services.CancelRunningAIJob(job.ID)
+	utilities.LogInfo("CancelAIJobHandler: Job de IA %d cancelado pelo usuário %s", job.ID, userFirebaseUID)
+	json.NewEncoder(w).Encode(job)
+}
+
+func writeAIJobLookupError(w http.ResponseWriter, handlerName string, err error) {
+	if errors.Is(err, models.ErrAIJobNotFound) {
+		http.Error(w, "AI job not found", http.StatusNotFound)
+		return
+	}
+	utilities.LogError(err, handlerName+": Erro ao buscar job de IA")
+	http.Error(w, "Failed to retrieve AI job", http.StatusInternalServerError)
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"projeto-integrador/ai_services"
+	"projeto-integrador/models"
+	"projeto-integrador/utilities"
+)
+
+// GetAIQuotasHandler lista as cotas de IA aplicadas ao workspace: os limites do servidor
+// (is_default) e os configurados pelos admins.
+// Rota: GET /workspace/{workspace_id}/ai/quotas
+func GetAIQuotasHandler(w http.ResponseWriter, r *http.Request) {
+	db, workspaceID, _, _ := aiHistoryRequest(w, r, "GetAIQuotasHandler")
+	if db == nil {
+		return
+	}
+	defer db.Close()
+
+	quotas, err := ai_services.EffectiveAIQuotas(db, workspaceID)
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("GetAIQuotasHandler: Erro ao buscar cotas de IA do workspace %d", workspaceID))
+		http.Error(w, "Failed to retrieve AI quotas", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"quotas": quotas})
+}
+
+// UpdateAIQuotasHandler substitui as cotas de IA configuradas no workspace (apenas
+// admins). Uma lista vazia remove todas; os limites do servidor continuam valendo.
+// Rota: PUT /workspace/{workspace_id}/ai/quotas
+func UpdateAIQuotasHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Quotas []models.AIQuota `json:"quotas"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := ai_services.ValidateAIQuotas(input.Quotas); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	db, workspaceID, userFirebaseUID, isAdmin := aiHistoryRequest(w, r, "UpdateAIQuotasHandler")
+	if db == nil {
+		return
+	}
+	defer db.Close()
+	if !isAdmin {
+		http.Error(w, "Forbidden: Only workspace admins can change the AI quotas", http.StatusForbidden)
+		return
+	}
+
+	if err := models.ReplaceAIQuotas(db, workspaceID, userFirebaseUID, input.Quotas); err != nil {
+		utilities.LogError(err, fmt.Sprintf("UpdateAIQuotasHandler: Erro ao salvar cotas de IA do workspace %d", workspaceID))
+		http.Error(w, "Failed to update AI quotas", http.StatusInternalServerError)
+		return
+	}
+	quotas, err := ai_services.EffectiveAIQuotas(db, workspaceID)
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("UpdateAIQuotasHandler: Erro ao buscar cotas de IA do workspace %d", workspaceID))
+		http.Error(w, "Failed to retrieve AI quotas", http.StatusInternalServerError)
+		return
+	}
+
+	utilities.LogInfo("UpdateAIQuotasHandler: Cotas de IA do workspace %d alteradas por %s (%d regras)", workspaceID, userFirebaseUID, len(input.Quotas))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"quotas": quotas})
+}
+
+// GetAIUsageHandler mostra o consumo de IA do usuário e do workspace no dia e no mês
+// correntes (UTC), comparado às cotas. Admins podem consultar outro membro com user_id.
+// Rota: GET /workspace/{workspace_id}/ai/usage?user_id=
+func GetAIUsageHandler(w http.ResponseWriter, r *http.Request) {
+	db, workspaceID, userFirebaseUID, isAdmin := aiHistoryRequest(w, r, "GetAIUsageHandler")
+	if db == nil {
+		return
+	}
+	defer db.Close()
+
+	targetUID := userFirebaseUID
+	if requested := r.URL.Query().Get("user_id"); requested != "" && requested != userFirebaseUID {
+		if !isAdmin {
+			http.Error(w, "Forbidden: Only workspace admins can view other members' AI usage", http.StatusForbidden)
+			return
+		}
+		role, err := models.GetUserRoleInWorkspace(db, requested, workspaceID)
+		if err != nil {
+			utilities.LogError(err, fmt.Sprintf("GetAIUsageHandler: Erro ao verificar membro %s do workspace %d", requested, workspaceID))
+			http.Error(w, "Failed to retrieve AI usage", http.StatusInternalServerError)
+			return
+		}
+		if role == "" {
+			http.Error(w, "User is not a member of this workspace", http.StatusNotFound)
+			return
+		}
+		targetUID = requested
+	}
+
+	report, err := ai_services.GetAIUsage(db, workspaceID, targetUID)
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("GetAIUsageHandler: Erro ao buscar uso de IA do workspace %d", workspaceID))
+		http.Error(w, "Failed to retrieve AI usage", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"projeto-integrador/ai_services"
+	"projeto-integrador/models"
+	"projeto-integrador/utilities"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Tamanho máximo do texto da prévia de mascaramento.
+const maxRedactionPreviewBytes = 64 << 10
+
+// ListAIRedactionRulesHandler lista as regras de mascaramento do workspace, aplicadas
+// antes dos tipos padrão em todo payload enviado à IA.
+// Rota: GET /workspace/{workspace_id}/ai/redaction-rules
+func ListAIRedactionRulesHandler(w http.ResponseWriter, r *http.Request) {
+	db, workspaceID, _, _ := aiHistoryRequest(w, r, "ListAIRedactionRulesHandler")
+	if db == nil {
+		return
+	}
+	defer db.Close()
+
+	rules, err := models.ListAIRedactionRules(db, workspaceID)
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("ListAIRedactionRulesHandler: Erro ao listar regras de mascaramento do workspace %d", workspaceID))
+		http.Error(w, "Failed to list AI redaction rules", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"rules": rules})
+}
+
+// CreateAIRedactionRuleHandler adiciona uma regra de mascaramento ao workspace (apenas admins).
+// Rota: POST /workspace/{workspace_id}/ai/redaction-rules
+func CreateAIRedactionRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name    string `json:"name"`
+		Pattern string `json:"pattern"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if err := ai_services.ValidateAIRedactionRule(input.Name, input.Pattern); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	db, workspaceID, userFirebaseUID, isAdmin := aiHistoryRequest(w, r, "CreateAIRedactionRuleHandler")
+	if db == nil {
+		return
+	}
+	defer db.Close()
+	if !isAdmin {
+		http.Error(w, "Forbidden: Only workspace admins can change the AI redaction rules", http.StatusForbidden)
+		return
+	}
+
+	existing, err := models.ListAIRedactionRules(db, workspaceID)
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("CreateAIRedactionRuleHandler: Erro ao listar regras de mascaramento do workspace %d", workspaceID))
+		http.Error(w, "Failed to create AI redaction rule", http.StatusInternalServerError)
+		return
+	}
+	if len(existing) >= ai_services.MaxAIRedactionRulesPerWorkspace {
+		http.Error(w, fmt.Sprintf("A workspace can have at most %d redaction rules", ai_services.MaxAIRedactionRulesPerWorkspace), http.StatusBadRequest)
+		return
+	}
+
+	rule, err := models.CreateAIRedactionRule(db, workspaceID, userFirebaseUID, input.Name, input.Pattern)
+	if errors.Is(err, models.ErrAIRedactionRuleExists) {
+		http.Error(w, "A redaction rule with this name already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("CreateAIRedactionRuleHandler: Erro ao criar regra de mascaramento no workspace %d", workspaceID))
+		http.Error(w, "Failed to create AI redaction rule", http.StatusInternalServerError)
+		return
+	}
+
+	utilities.LogInfo("CreateAIRedactionRuleHandler: Regra de mascaramento %q criada no workspace %d por %s", rule.Name, workspaceID, userFirebaseUID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// DeleteAIRedactionRuleHandler remove uma regra de mascaramento do workspace (apenas admins).
+// Rota: DELETE /workspace/{workspace_id}/ai/redaction-rules/{rule_id}
+func DeleteAIRedactionRuleHandler(w http.ResponseWriter, r *http.Request) {
+	ruleID, err := strconv.ParseInt(mux.Vars(r)["rule_id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	db, workspaceID, userFirebaseUID, isAdmin := aiHistoryRequest(w, r, "DeleteAIRedactionRuleHandler")
+	if db == nil {
+		return
+	}
+	defer db.Close()
+	if !isAdmin {
+		http.Error(w, "Forbidden: Only workspace admins can change the AI redaction rules", http.StatusForbidden)
+		return
+	}
+
+	err = models.DeleteAIRedactionRule(db, workspaceID, ruleID)
+	if errors.Is(err, models.ErrAIRedactionRuleNotFound) {
+		http.Error(w, "Redaction rule not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("DeleteAIRedactionRuleHandler: Erro ao remover regra de mascaramento %d do workspace %d", ruleID, workspaceID))
+		http.Error(w, "Failed to delete AI redaction rule", http.StatusInternalServerError)
+		return
+	}
+
+	utilities.LogInfo("DeleteAIRedactionRuleHandler: Regra de mascaramento %d removida do workspace %d por %s", ruleID, workspaceID, userFirebaseUID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PreviewAIRedactionHandler mostra como um texto seria enviado à IA, com as regras do
+// workspace e os tipos padrão ativos. Nada é enviado à IA nem registrado.
+// Rota: POST /workspace/{workspace_id}/ai/redaction/preview
+func PreviewAIRedactionHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRedactionPreviewBytes)).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	db, workspaceID, _, _ := aiHistoryRequest(w, r, "PreviewAIRedactionHandler")
+	if db == nil {
+		return
+	}
+	defer db.Close()
+
+	rules, err := models.ListAIRedactionRules(db, workspaceID)
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("PreviewAIRedactionHandler: Erro ao listar regras de mascaramento do workspace %d", workspaceID))
+		http.Error(w, "Failed to load AI redaction rules", http.StatusInternalServerError)
+		return
+	}
+	redactor := ai_services.NewRedactor(rules)
+	redacted := redactor.Redact(input.Text).(string)
+	redactions := redactor.Redactions()
+	if redactions == nil {
+		redactions = []models.AIRedaction{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"text": redacted, "redactions": redactions})
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"projeto-integrador/ai_services"
+	"projeto-integrador/models"
+	"projeto-integrador/utilities"
+)
+
+// GetAISettingsHandler retorna as configurações de IA do workspace (os padrões, com
+// is_default, se ainda não foram configuradas).
+// Rota: GET /workspace/{workspace_id}/ai/settings
+func GetAISettingsHandler(w http.ResponseWriter, r *http.Request) {
+	db, workspaceID, _, _ := aiHistoryRequest(w, r, "GetAISettingsHandler")
+	if db == nil {
+		return
+	}
+	defer db.Close()
+
+	settings, err := models.GetWorkspaceAISettings(db, workspaceID)
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("GetAISettingsHandler: Erro ao buscar configurações de IA do workspace %d", workspaceID))
+		http.Error(w, "Failed to retrieve AI settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// UpdateAISettingsHandler substitui as configurações de IA do workspace (apenas admins).
+// Campos omitidos voltam ao padrão.
+// Rota: PUT /workspace/{workspace_id}/ai/settings
+func UpdateAISettingsHandler(w http.ResponseWriter, r *http.Request) {
+	db, workspaceID, userFirebaseUID, isAdmin := aiHistoryRequest(w, r, "UpdateAISettingsHandler")
+	if db == nil {
+		return
+	}
+	defer db.Close()
+	if !isAdmin {
+		http.Error(w, "Forbidden: Only workspace admins can change the AI settings", http.StatusForbidden)
+		return
+	}
+
+	settings := models.DefaultWorkspaceAISettings(workspaceID)
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	settings.WorkspaceID = workspaceID
+	if err := ai_services.ValidateWorkspaceAISettings(&settings); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := models.SaveWorkspaceAISettings(db, settings, userFirebaseUID); err != nil {
+		utilities.LogError(err, fmt.Sprintf("UpdateAISettingsHandler: Erro ao salvar configurações de IA do workspace %d", workspaceID))
+		http.Error(w, "Failed to save AI settings", http.StatusInternalServerError)
+		return
+	}
+	saved, err := models.GetWorkspaceAISettings(db, workspaceID)
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("UpdateAISettingsHandler: Erro ao buscar configurações de IA do workspace %d", workspaceID))
+		http.Error(w, "Failed to retrieve AI settings", http.StatusInternalServerError)
+		return
+	}
+
+	utilities.LogInfo("UpdateAISettingsHandler: Configurações de IA do workspace %d atualizadas por %s (habilitada: %t)", workspaceID, userFirebaseUID, saved.Enabled)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
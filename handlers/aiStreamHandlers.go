@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"projeto-integrador/ai_services"
+	"projeto-integrador/utilities"
+	"sync"
+	"time"
+)
+
+// Intervalo dos comentários de keep-alive enquanto a IA não envia texto, para que
+// proxies não encerrem a conexão ociosa.
+const sseKeepAliveInterval = 15 * time.Second
+
+// sseWriter escreve eventos Server-Sent Events. Os cabeçalhos só são enviados no
+// primeiro evento, então falhas antes disso ainda podem responder com status de erro.
+type sseWriter struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	started bool
+}
+
+func newSSEWriter(w http.ResponseWriter) *sseWriter {
+	return &sseWriter{w: w, rc: http.NewResponseController(w)}
+}
+
+func (s *sseWriter) startLocked() {
+	if s.started {
+		return
+	}
+	s.started = true
+	s.w.Header().Set("Content-Type", "text/event-stream")
+	s.w.Header().Set("Cache-Control", "no-cache")
+	s.w.Header().Set("Connection", "keep-alive")
+	s.w.Header().Set("X-Accel-Buffering", "no") // Desliga o buffer de proxies nginx
+	s.w.WriteHeader(http.StatusOK)
+}
+
+// Started informa se a resposta SSE já começou.
+func (s *sseWriter) Started() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.started
+}
+
+// Event envia um evento com payload JSON e faz flush.
+func (s *sseWriter) Event(name string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.startLocked()
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", name, data); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// keepAlive envia comentários SSE periodicamente até stop ser chamado. stop só retorna
+// depois que a goroutine terminou, para que nada mais seja escrito em seguida.
+func (s *sseWriter) keepAlive(ctx context.Context, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.mu.Lock()
+				s.startLocked()
+				fmt.Fprint(s.w, ": keep-alive\n\n")
+				s.rc.Flush()
+				s.mu.Unlock()
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-exited
+	}
+}
+
+// serveAIStream trata a variante SSE de um serviço de IA: cada trecho gerado vira um
+// evento "chunk" ({"text": "..."}); no fim vem "done" com a resposta completa (mesmo
+// formato da rota síncrona) ou "error". Toda execução é registrada no histórico ao
+// final do stream: com a resposta montada, com o texto parcial se o cliente desconectar
+// ou com a falha.
+func serveAIStream(w http.ResponseWriter, r *http.Request, handlerName string, serviceType string) {
+	ctx := r.Context()
+	db, req := prepareAIRequest(w, r, handlerName, serviceType)
+	if db == nil {
+		return
+	}
+	db.Close() // O stream pode durar minutos; não há mais consultas ao PG
+
+	sse := newSSEWriter(w)
+	stopKeepAlive := sse.keepAlive(ctx, sseKeepAliveInterval)
+	provider := ai_services.GetProvider()
+	exec, errAI := ai_services.StreamAIRequest(ctx, provider, req.workspaceID, serviceType, req.input, aiRequestOptions(r), func(chunk string) error {
+		return sse.Event("chunk", map[string]string{"text": chunk})
+	})
+	stopKeepAlive()
+
+	// O contexto da requisição já pode estar cancelado (cliente desconectou)
+	historyID := ai_services.RecordAIInteraction(context.WithoutCancel(ctx), req.userFirebaseUID, req.workspaceID, exec, errAI)
+
+	switch {
+	case errAI == nil:
+		sse.Event("done", ai_services.FinalizeAIResponse(context.WithoutCancel(ctx), req.userFirebaseUID, req.workspaceID, exec.Response, historyID))
+	case errors.Is(errAI, ai_services.ErrClientDisconnected):
+		utilities.LogInfo("%s: Cliente desconectou durante o streaming (workspace %d, usuário %s)", handlerName, req.workspaceID, req.userFirebaseUID)
+	case errors.Is(errAI, ai_services.ErrAIFeatureDisabled) && !sse.Started():
+		writeAIFeatureDisabled(w, serviceType)
+	case errors.Is(errAI, ai_services.ErrAIContextUnavailable) && !sse.Started():
+		utilities.LogError(errAI, handlerName+": Erro ao obter contexto do workspace")
+		http.Error(w, `{"error": "Falha ao carregar dados do workspace"}`, http.StatusInternalServerError)
+	case !sse.Started():
+		writeAIError(w, handlerName, provider.Name(), exec.Result, errAI)
+	default:
+		_, body := aiErrorResponse(handlerName, provider.Name(), exec.Result, errAI)
+		sse.Event("error", body)
+	}
+}
+
+// TaskAssistantStreamHandler é a variante SSE do assistente de tarefas.
+// Rota: POST /workspace/{workspace_id}/ai/task-assistant/stream
+func TaskAssistantStreamHandler(w http.ResponseWriter, r *http.Request) {
+	serveAIStream(w, r, "TaskAssistantStreamHandler", ai_services.ServiceTaskAssistant)
+}
+
+// CodeReviewStreamHandler é a variante SSE do code review.
+// Rota: POST /workspace/{workspace_id}/ai/code-review/stream
+func CodeReviewStreamHandler(w http.ResponseWriter, r *http.Request) {
+	serveAIStream(w, r, "CodeReviewStreamHandler", ai_services.ServiceCodeReview)
+}
+
+// SummarizeTextStreamHandler é a variante SSE do resumo de texto.
+// Rota: POST /workspace/{workspace_id}/ai/summarize-text/stream
+func SummarizeTextStreamHandler(w http.ResponseWriter, r *http.Request) {
+	serveAIStream(w, r, "SummarizeTextStreamHandler", ai_services.ServiceTextSummary)
+}
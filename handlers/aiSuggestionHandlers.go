@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"projeto-integrador/ai_services"
+	"projeto-integrador/database"
+	"projeto-integrador/firebase"
+	"projeto-integrador/models"
+	"projeto-integrador/utilities"
+
+	"github.com/gorilla/mux"
+)
+
+// AcceptTaskSuggestionsReport é a resposta do aceite de sugestões de tarefas.
+type AcceptTaskSuggestionsReport struct {
+	HistoryID string                          `json:"history_id"`
+	Created   int                             `json:"created"`
+	Results   []models.AcceptedTaskSuggestion `json:"results"`
+}
+
+// AcceptTaskSuggestionsHandler cria tarefas a partir das sugestões de uma resposta do
+// assistente de tarefas (identificada pelo history_id devolvido com as sugestões). O
+// corpo é opcional: sem "suggestions", todas são aceitas. Aceitar de novo a mesma
+// sugestão não cria outra tarefa.
+// Rota: POST /workspace/{workspace_id}/ai/suggestions/{history_id}/accept
+func AcceptTaskSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	workspaceID, err := getWorkspaceIDFromPath(r)
+	if err != nil {
+		http.Error(w, "Invalid Workspace ID", http.StatusBadRequest)
+		return
+	}
+	historyID := mux.Vars(r)["history_id"]
+
+	var input struct {
+		Suggestions []models.TaskSuggestionSelection `json:"suggestions"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAIRequestBytes)).Decode(&input); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	requestingUserFirebaseUID := r.Context().Value("userUID").(string)
+	ctx := r.Context()
+
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, "AcceptTaskSuggestionsHandler: Erro ao conectar ao PG")
+		http.Error(w, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	isMember, err := models.IsWorkspaceMember(db, requestingUserFirebaseUID, workspaceID)
+	if err != nil || !isMember {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	firestoreClient, err := firebase.GetFirestoreClient()
+	if err != nil {
+		utilities.LogError(err, "AcceptTaskSuggestionsHandler: Erro ao obter cliente Firestore")
+		http.Error(w, "Failed to connect to Firestore", http.StatusInternalServerError)
+		return
+	}
+	defer firestoreClient.Close()
+
+	results, err := ai_services.AcceptTaskSuggestions(ctx, db, firestoreClient, workspaceID, requestingUserFirebaseUID, historyID, input.Suggestions)
+	switch {
+	case errors.Is(err, ai_services.ErrAIHistoryNotFound):
+		http.Error(w, "AI suggestions not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ai_services.ErrInvalidTaskSuggestion):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		utilities.LogError(err, fmt.Sprintf("AcceptTaskSuggestionsHandler: Erro ao aceitar sugestões do histórico %s", historyID))
+		http.Error(w, "Failed to accept AI suggestions", http.StatusInternalServerError)
+		return
+	}
+
+	report := AcceptTaskSuggestionsReport{HistoryID: historyID, Results: results}
+	for _, result := range results {
+		if result.Status == ai_services.SuggestionCreated {
+			report.Created++
+		}
+	}
+
+	utilities.LogInfo("AcceptTaskSuggestionsHandler: %d tarefas criadas a partir do histórico %s no workspace %d pelo usuário %s", report.Created, historyID, workspaceID, requestingUserFirebaseUID)
+	w.Header().Set("Content-Type", "application/json")
+	if report.Created > 0 {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(report)
+}
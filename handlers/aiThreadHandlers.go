@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"projeto-integrador/ai_services"
+	"projeto-integrador/database"
+	"projeto-integrador/firebase"
+	"projeto-integrador/models"
+	"projeto-integrador/utilities"
+
+	"cloud.google.com/go/firestore"
+	"github.com/gorilla/mux"
+)
+
+// checkAIThreadOwner confere se a conversa existe e pertence ao usuário. Em caso de
+// erro, retorna o status HTTP a responder.
+func checkAIThreadOwner(ctx context.Context, workspaceID int64, threadID string, userFirebaseUID string) (int, error) {
+	firestoreClient, err := firebase.GetFirestoreClient()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	defer firestoreClient.Close()
+
+	if _, err := ai_services.GetAIThread(ctx, firestoreClient, workspaceID, threadID, userFirebaseUID); err != nil {
+		if errors.Is(err, ai_services.ErrAIThreadNotFound) {
+			return http.StatusNotFound, err
+		}
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+// memberFirestoreRequest valida o workspace da rota, confere a participação do usuário e
+// abre o cliente Firestore. Usado pelas rotas que só acessam o Firestore (conversas,
+// mapas mentais). Em caso de erro, a resposta já foi escrita e client é nil.
+func memberFirestoreRequest(w http.ResponseWriter, r *http.Request, handlerName string) (client *firestore.Client, workspaceID int64, userFirebaseUID string) {
+	workspaceID, err := getWorkspaceIDFromPath(r)
+	if err != nil {
+		http.Error(w, "Invalid Workspace ID", http.StatusBadRequest)
+		return nil, 0, ""
+	}
+	userFirebaseUID = r.Context().Value("userUID").(string)
+
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, handlerName+": Erro ao conectar ao PG")
+		http.Error(w, "Database connection error", http.StatusInternalServerError)
+		return nil, 0, ""
+	}
+	defer db.Close()
+
+	isMember, err := models.IsWorkspaceMember(db, userFirebaseUID, workspaceID)
+	if err != nil || !isMember {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil, 0, ""
+	}
+
+	client, err = firebase.GetFirestoreClient()
+	if err != nil {
+		utilities.LogError(err, handlerName+": Erro ao obter cliente Firestore")
+		http.Error(w, "Failed to connect to Firestore", http.StatusInternalServerError)
+		return nil, 0, ""
+	}
+	return client, workspaceID, userFirebaseUID
+}
+
+func writeAIThreadError(w http.ResponseWriter, handlerName string, err error) {
+	if errors.Is(err, ai_services.ErrAIThreadNotFound) {
+		http.Error(w, "AI thread not found", http.StatusNotFound)
+		return
+	}
+	utilities.LogError(err, handlerName+": Erro ao acessar conversa de IA")
+	http.Error(w, "Failed to access AI thread", http.StatusInternalServerError)
+}
+
+// CreateAIThreadHandler cria uma conversa vazia com o assistente de tarefas.
+// Rota: POST /workspace/{workspace_id}/ai/threads
+func CreateAIThreadHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Title string `json:"title"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	client, workspaceID, userFirebaseUID := memberFirestoreRequest(w, r, "CreateAIThreadHandler")
+	if client == nil {
+		return
+	}
+	defer client.Close()
+
+	thread, err := ai_services.CreateAIThread(r.Context(), client, workspaceID, userFirebaseUID, input.Title)
+	if err != nil {
+		writeAIThreadError(w, "CreateAIThreadHandler", err)
+		return
+	}
+
+	utilities.LogInfo("CreateAIThreadHandler: Conversa %s criada no workspace %d pelo usuário %s", thread.ID, workspaceID, userFirebaseUID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(thread)
+}
+
+// ListAIThreadsHandler lista as conversas do usuário no workspace, as mais recentes primeiro.
+// Rota: GET /workspace/{workspace_id}/ai/threads
+func ListAIThreadsHandler(w http.ResponseWriter, r *http.Request) {
+	client, workspaceID, userFirebaseUID := memberFirestoreRequest(w, r, "ListAIThreadsHandler")
+	if client == nil {
+		return
+	}
+	defer client.Close()
+
+	threads, err := ai_services.ListAIThreads(r.Context(), client, workspaceID, userFirebaseUID)
+	if err != nil {
+		writeAIThreadError(w, "ListAIThreadsHandler", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(threads)
+}
+
+// GetAIThreadHandler retorna a conversa com todas as mensagens.
+// Rota: GET /workspace/{workspace_id}/ai/threads/{thread_id}
+func GetAIThreadHandler(w http.ResponseWriter, r *http.Request) {
+	client, workspaceID, userFirebaseUID := memberFirestoreRequest(w, r, "GetAIThreadHandler")
+	if client == nil {
+		return
+	}
+	defer client.Close()
+	threadID := mux.Vars(r)["thread_id"]
+
+	thread, err := ai_services.GetAIThread(r.Context(), client, workspaceID, threadID, userFirebaseUID)
+	if err != nil {
+		writeAIThreadError(w, "GetAIThreadHandler", err)
+		return
+	}
+	turns, err := ai_services.ListAIThreadTurns(r.Context(), client, workspaceID, threadID)
+	if err != nil {
+		writeAIThreadError(w, "GetAIThreadHandler", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.AIThreadWithTurns{AIThread: *thread, Turns: turns})
+}
+
+// RenameAIThreadHandler altera o título da conversa.
+// Rota: PUT /workspace/{workspace_id}/ai/threads/{thread_id}
+func RenameAIThreadHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Title string `json:"title"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if ai_services.NormalizeAIThreadTitle(input.Title) == "" {
+		http.Error(w, "Title is required", http.StatusBadRequest)
+		return
+	}
+
+	client, workspaceID, userFirebaseUID := memberFirestoreRequest(w, r, "RenameAIThreadHandler")
+	if client == nil {
+		return
+	}
+	defer client.Close()
+
+	thread, err := ai_services.RenameAIThread(r.Context(), client, workspaceID, mux.Vars(r)["thread_id"], userFirebaseUID, input.Title)
+	if err != nil {
+		writeAIThreadError(w, "RenameAIThreadHandler", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(thread)
+}
+
+// DeleteAIThreadHandler apaga a conversa e suas mensagens.
+// Rota: DELETE /workspace/{workspace_id}/ai/threads/{thread_id}
+func DeleteAIThreadHandler(w http.ResponseWriter, r *http.Request) {
+	client, workspaceID, userFirebaseUID := memberFirestoreRequest(w, r, "DeleteAIThreadHandler")
+	if client == nil {
+		return
+	}
+	defer client.Close()
+	threadID := mux.Vars(r)["thread_id"]
+
+	if err := ai_services.DeleteAIThread(r.Context(), client, workspaceID, threadID, userFirebaseUID); err != nil {
+		writeAIThreadError(w, "DeleteAIThreadHandler", err)
+		return
+	}
+
+	utilities.LogInfo("DeleteAIThreadHandler: Conversa %s do workspace %d apagada pelo usuário %s", threadID, workspaceID, userFirebaseUID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AIThreadMessageHandler continua a conversa: envia a mensagem ao assistente de tarefas
+// com as trocas anteriores e grava a nova troca. Aceita ?async=true como as demais rotas.
+// Rota: POST /workspace/{workspace_id}/ai/threads/{thread_id}/messages
+func AIThreadMessageHandler(w http.ResponseWriter, r *http.Request) {
+	serveAIRequest(w, r, "AIThreadMessageHandler", ai_services.ServiceTaskAssistant)
+}
+
+// AIThreadMessageStreamHandler é a variante SSE de AIThreadMessageHandler.
+// Rota: POST /workspace/{workspace_id}/ai/threads/{thread_id}/messages/stream
+func AIThreadMessageStreamHandler(w http.ResponseWriter, r *http.Request) {
+	serveAIStream(w, r, "AIThreadMessageStreamHandler", ai_services.ServiceTaskAssistant)
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"projeto-integrador/database"
+	"projeto-integrador/models"
+	"projeto-integrador/task_services"
+	"projeto-integrador/utilities"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Tamanho máximo do arquivo exportado do Trello/Jira.
+const maxBoardImportBytes = 20 << 20
+
+// ImportBoardHandler recebe a exportação de um quadro do Trello (JSON) ou do Jira (CSV)
+// e agenda um job assíncrono que cria as tarefas no workspace. O arquivo é lido e
+// validado antes do job ser criado, então erros de formato voltam imediatamente.
+// Rota: POST /workspace/{workspace_id}/tasks/import/board (multipart/form-data)
+// Campos: source ("trello" ou "jira"), file, status_mapping (opcional, JSON {"nome da lista": "status"})
+func ImportBoardHandler(w http.ResponseWriter, r *http.Request) {
+	workspaceID, err := getWorkspaceIDFromPath(r)
+	if err != nil {
+		http.Error(w, "Invalid Workspace ID", http.StatusBadRequest)
+		return
+	}
+	requestingUserFirebaseUID := r.Context().Value("userUID").(string)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBoardImportBytes)
+	if err := r.ParseMultipartForm(maxBoardImportBytes); err != nil {
+		http.Error(w, "Invalid multipart form or file too large", http.StatusBadRequest)
+		return
+	}
+	source := strings.ToLower(strings.TrimSpace(r.FormValue("source")))
+
+	var statusMapping map[string]string
+	if raw := r.FormValue("status_mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &statusMapping); err != nil {
+			http.Error(w, "status_mapping must be a JSON object of name to status", http.StatusBadRequest)
+			return
+		}
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "File is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, "ImportBoardHandler: Erro ao conectar ao PG")
+		http.Error(w, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	isMember, err := models.IsWorkspaceMember(db, requestingUserFirebaseUID, workspaceID)
+	if err != nil || !isMember {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	plan, err := task_services.ParseBoardExport(source, file, statusMapping)
+	if err != nil {
+		if errors.Is(err, task_services.ErrInvalidImportFile) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		utilities.LogError(err, "ImportBoardHandler: Erro ao ler arquivo de importação")
+		http.Error(w, "Failed to read import file", http.StatusInternalServerError)
+		return
+	}
+
+	job, err := models.CreateImportJob(db, workspaceID, requestingUserFirebaseUID, plan.Source, len(plan.Tasks), plan.Skipped, plan.Warnings)
+	if err != nil {
+		utilities.LogError(err, "ImportBoardHandler: Erro ao criar job de importação")
+		http.Error(w, "Failed to create import job", http.StatusInternalServerError)
+		return
+	}
+	task_services.RunBoardImport(job.ID, workspaceID, requestingUserFirebaseUID, plan)
+
+	utilities.LogInfo("ImportBoardHandler: Job %d (%s) agendado para o workspace %d com %d tarefas", job.ID, plan.Source, workspaceID, len(plan.Tasks))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// ListImportJobsHandler lista os jobs de importação recentes do workspace.
+// Rota: GET /workspace/{workspace_id}/tasks/import/jobs
+func ListImportJobsHandler(w http.ResponseWriter, r *http.Request) {
+	workspaceID, err := getWorkspaceIDFromPath(r)
+	if err != nil {
+		http.Error(w, "Invalid Workspace ID", http.StatusBadRequest)
+		return
+	}
+	requestingUserFirebaseUID := r.Context().Value("userUID").(string)
+
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, "ListImportJobsHandler: Erro ao conectar ao PG")
+		http.Error(w, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	isMember, err := models.IsWorkspaceMember(db, requestingUserFirebaseUID, workspaceID)
+	if err != nil || !isMember {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	jobs, err := models.ListImportJobs(db, workspaceID, 50)
+	if err != nil {
+		utilities.LogError(err, "ListImportJobsHandler: Erro ao listar jobs")
+		http.Error(w, "Failed to list import jobs", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
+
+// GetImportJobHandler retorna o progresso e o resumo de um job de importação.
+// Rota: GET /workspace/{workspace_id}/tasks/import/jobs/{job_id}
+func GetImportJobHandler(w http.ResponseWriter, r *http.Request) {
+	workspaceID, err := getWorkspaceIDFromPath(r)
+	if err != nil {
+		http.Error(w, "Invalid Workspace ID", http.StatusBadRequest)
+		return
+	}
+	jobID, err := strconv.ParseInt(mux.Vars(r)["job_id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+	requestingUserFirebaseUID := r.Context().Value("userUID").(string)
+
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, "GetImportJobHandler: Erro ao conectar ao PG")
+		http.Error(w, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	isMember, err := models.IsWorkspaceMember(db, requestingUserFirebaseUID, workspaceID)
+	if err != nil || !isMember {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	job, err := models.GetImportJob(db, workspaceID, jobID)
+	if err != nil {
+		if errors.Is(err, models.ErrImportJobNotFound) {
+			http.Error(w, "Import job not found", http.StatusNotFound)
+			return
+		}
+		utilities.LogError(err, "GetImportJobHandler: Erro ao buscar job")
+		http.Error(w, "Failed to retrieve import job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
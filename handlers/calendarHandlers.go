@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"projeto-integrador/database"
+	"projeto-integrador/firebase"
+	"projeto-integrador/models"
+	"projeto-integrador/task_services"
+	"projeto-integrador/utilities"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Tarefas vencidas há mais tempo que isso não entram no feed, para mantê-lo leve.
+const calendarFeedPastDays = 90
+
+// CreateCalendarFeedInput define os dados para criar um link de calendário.
+type CreateCalendarFeedInput struct {
+	Name         string `json:"name"`
+	WorkspaceID  *int64 `json:"workspace_id"`  // Opcional: restringe o feed a um workspace
+	AssignedOnly bool   `json:"assigned_only"` // Opcional: apenas tarefas atribuídas ao usuário
+}
+
+// CreateCalendarFeedHandler gera um link secreto .ics para o usuário logado.
+// O token só é exibido nesta resposta.
+// Rota: POST /user/calendar-feeds/create
+func CreateCalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	requestingUserFirebaseUID := r.Context().Value("userUID").(string)
+
+	var input CreateCalendarFeedInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, "CreateCalendarFeedHandler: Erro ao conectar ao PG")
+		http.Error(w, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	if input.WorkspaceID != nil {
+		isMember, err := models.IsWorkspaceMember(db, requestingUserFirebaseUID, *input.WorkspaceID)
+		if err != nil || !isMember {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+	}
+
+	feed, token, err := models.CreateCalendarFeed(db, requestingUserFirebaseUID, strings.TrimSpace(input.Name), input.WorkspaceID, input.AssignedOnly)
+	if err != nil {
+		utilities.LogError(err, "CreateCalendarFeedHandler: Erro ao criar feed")
+		http.Error(w, "Failed to create calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	utilities.LogInfo("CreateCalendarFeedHandler: Feed %d criado para o usuário %s", feed.ID, requestingUserFirebaseUID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Calendar feed created successfully",
+		"feed":    feed,
+		"token":   token,
+		"url":     calendarFeedURL(r, token),
+	})
+}
+
+// ListCalendarFeedsHandler lista os links de calendário ativos do usuário logado.
+// Rota: GET /user/calendar-feeds/list
+func ListCalendarFeedsHandler(w http.ResponseWriter, r *http.Request) {
+	requestingUserFirebaseUID := r.Context().Value("userUID").(string)
+
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, "ListCalendarFeedsHandler: Erro ao conectar ao PG")
+		http.Error(w, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	feeds, err := models.ListCalendarFeeds(db, requestingUserFirebaseUID)
+	if err != nil {
+		utilities.LogError(err, "ListCalendarFeedsHandler: Erro ao listar feeds")
+		http.Error(w, "Failed to list calendar feeds", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(feeds)
+}
+
+// RevokeCalendarFeedHandler revoga um link de calendário do usuário logado.
+// Rota: DELETE /user/calendar-feeds/revoke/{feed_id}
+func RevokeCalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	requestingUserFirebaseUID := r.Context().Value("userUID").(string)
+	feedID, err := strconv.ParseInt(mux.Vars(r)["feed_id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid feed ID", http.StatusBadRequest)
+		return
+	}
+
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, "RevokeCalendarFeedHandler: Erro ao conectar ao PG")
+		http.Error(w, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	if err := models.RevokeCalendarFeed(db, requestingUserFirebaseUID, feedID); err != nil {
+		if errors.Is(err, models.ErrCalendarFeedNotFound) {
+			http.Error(w, "Calendar feed not found", http.StatusNotFound)
+			return
+		}
+		utilities.LogError(err, "RevokeCalendarFeedHandler: Erro ao revogar feed")
+		http.Error(w, "Failed to revoke calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	utilities.LogInfo("RevokeCalendarFeedHandler: Feed %d revogado pelo usuário %s", feedID, requestingUserFirebaseUID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Calendar feed revoked successfully"})
+}
+
+// CalendarFeedICSHandler serve o feed .ics. A rota é pública (clientes de calendário
+// não enviam o token do Firebase): o próprio token secreto autentica o usuário.
+// A membresia é conferida a cada acesso, então quem sai de um workspace deixa
+// de ver as tarefas dele no feed.
+// Rota: GET /calendar/{token}.ics
+func CalendarFeedICSHandler(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+	ctx := context.Background()
+
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, "CalendarFeedICSHandler: Erro ao conectar ao PG")
+		http.Error(w, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	feed, err := models.GetCalendarFeedByToken(db, token)
+	if err != nil {
+		if !errors.Is(err, models.ErrCalendarFeedNotFound) {
+			utilities.LogError(err, "CalendarFeedICSHandler: Erro ao buscar feed")
+		}
+		http.Error(w, "Calendar feed not found", http.StatusNotFound)
+		return
+	}
+
+	workspaces, err := models.ListUserWorkspaceNames(db, feed.UserFirebaseUID)
+	if err != nil {
+		utilities.LogError(err, "CalendarFeedICSHandler: Erro ao buscar workspaces do usuário")
+		http.Error(w, "Failed to load calendar feed", http.StatusInternalServerError)
+		return
+	}
+	if feed.WorkspaceID != nil {
+		name, isMember := workspaces[*feed.WorkspaceID]
+		if !isMember {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		workspaces = map[int64]string{*feed.WorkspaceID: name}
+	}
+
+	firestoreClient, err := firebase.GetFirestoreClient()
+	if err != nil {
+		utilities.LogError(err, "CalendarFeedICSHandler: Erro ao obter cliente Firestore")
+		http.Error(w, "Failed to connect to Firestore", http.StatusInternalServerError)
+		return
+	}
+	defer firestoreClient.Close()
+
+	now := time.Now()
+	since := now.AddDate(0, 0, -calendarFeedPastDays)
+	var entries []task_services.CalendarTask
+	for workspaceID, workspaceName := range workspaces {
+		docIDs, tasks, err := task_services.ListTasksDueSince(ctx, firestoreClient, workspaceID, since)
+		if err != nil {
+			utilities.LogError(err, fmt.Sprintf("CalendarFeedICSHandler: Erro ao buscar tarefas do workspace %d", workspaceID))
+			continue
+		}
+		for i, task := range tasks {
+			if task.Status == "skipped" {
+				continue
+			}
+			if feed.AssignedOnly && task.AssigneeFirebaseUID != feed.UserFirebaseUID {
+				continue
+			}
+			entries = append(entries, task_services.CalendarTask{
+				WorkspaceID:   workspaceID,
+				WorkspaceName: workspaceName,
+				TaskDocID:     docIDs[i],
+				Task:          task,
+			})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Task.ExpirationDate.Before(*entries[j].Task.ExpirationDate)
+	})
+
+	calendarName := feed.Name
+	if calendarName == "" {
+		calendarName = "Tarefas"
+	}
+
+	utilities.LogDebug("CalendarFeedICSHandler: Feed %d servido com %d tarefas", feed.ID, len(entries))
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Write([]byte(task_services.BuildICalendar(calendarName, entries, now)))
+}
+
+// calendarFeedURL monta a URL pública do feed. PUBLIC_BASE_URL tem precedência;
+// sem ela, a URL é derivada da própria requisição.
+func calendarFeedURL(r *http.Request, token string) string {
+	base := strings.TrimRight(os.Getenv("PUBLIC_BASE_URL"), "/")
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	return base + "/calendar/" + token + ".ics"
+}
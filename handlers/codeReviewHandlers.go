@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"projeto-integrador/ai_services"
+	"projeto-integrador/database"
+	"projeto-integrador/firebase"
+	"projeto-integrador/models"
+	"projeto-integrador/task_services"
+	"projeto-integrador/utilities"
+
+	"github.com/gorilla/mux"
+)
+
+// AttachCodeReviewHandler anexa à tarefa uma revisão de código do usuário, identificada
+// pelo history_id devolvido por /ai/code-review. Anexar de novo a mesma revisão apenas
+// a atualiza.
+// Rota: POST /workspace/{workspace_id}/task/code-reviews/{task_doc_id}
+func AttachCodeReviewHandler(w http.ResponseWriter, r *http.Request) {
+	taskDocID := mux.Vars(r)["task_doc_id"]
+	var input struct {
+		HistoryID string `json:"history_id"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAIRequestBytes)).Decode(&input); err != nil || input.HistoryID == "" {
+		http.Error(w, "history_id is required", http.StatusBadRequest)
+		return
+	}
+
+	client, workspaceID, requestingUserFirebaseUID := memberFirestoreRequest(w, r, "AttachCodeReviewHandler")
+	if client == nil {
+		return
+	}
+	defer client.Close()
+
+	if status, err := checkTaskExists(r.Context(), workspaceID, taskDocID); err != nil {
+		if status != http.StatusNotFound {
+			utilities.LogError(err, fmt.Sprintf("AttachCodeReviewHandler: Erro ao buscar tarefa %s", taskDocID))
+		}
+		http.Error(w, "Task not found or error fetching", status)
+		return
+	}
+
+	review, err := ai_services.AttachCodeReviewToTask(r.Context(), client, workspaceID, requestingUserFirebaseUID, taskDocID, input.HistoryID)
+	switch {
+	case errors.Is(err, ai_services.ErrAIHistoryNotFound):
+		http.Error(w, "Code review not found", http.StatusNotFound)
+		return
+	case err != nil:
+		utilities.LogError(err, fmt.Sprintf("AttachCodeReviewHandler: Erro ao anexar revisão %s na tarefa %s", input.HistoryID, taskDocID))
+		http.Error(w, "Failed to attach code review", http.StatusInternalServerError)
+		return
+	}
+
+	utilities.LogInfo("AttachCodeReviewHandler: Revisão %s anexada à tarefa %s no workspace %d", input.HistoryID, taskDocID, workspaceID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(review)
+}
+
+// ListTaskCodeReviewsHandler lista as revisões de código anexadas à tarefa.
+// Rota: GET /workspace/{workspace_id}/task/code-reviews/{task_doc_id}
+func ListTaskCodeReviewsHandler(w http.ResponseWriter, r *http.Request) {
+	taskDocID := mux.Vars(r)["task_doc_id"]
+	client, workspaceID, _ := memberFirestoreRequest(w, r, "ListTaskCodeReviewsHandler")
+	if client == nil {
+		return
+	}
+	defer client.Close()
+
+	reviews, err := task_services.ListTaskCodeReviews(r.Context(), client, workspaceID, taskDocID)
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("ListTaskCodeReviewsHandler: Erro ao listar revisões da tarefa %s", taskDocID))
+		http.Error(w, "Failed to list code reviews", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reviews)
+}
+
+// CodeReviewFindingsToTasksHandler cria tarefas a partir dos achados de uma revisão de
+// código. O corpo é opcional: sem "findings", todos os achados viram tarefas. Repetir
+// a operação não cria outra tarefa para o mesmo achado.
+// Rota: POST /workspace/{workspace_id}/ai/code-reviews/{history_id}/tasks
+func CodeReviewFindingsToTasksHandler(w http.ResponseWriter, r *http.Request) {
+	workspaceID, err := getWorkspaceIDFromPath(r)
+	if err != nil {
+		http.Error(w, "Invalid Workspace ID", http.StatusBadRequest)
+		return
+	}
+	historyID := mux.Vars(r)["history_id"]
+
+	var input struct {
+		Findings []models.CodeReviewFindingSelection `json:"findings"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAIRequestBytes)).Decode(&input); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	requestingUserFirebaseUID := r.Context().Value("userUID").(string)
+	ctx := r.Context()
+
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, "CodeReviewFindingsToTasksHandler: Erro ao conectar ao PG")
+		http.Error(w, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	isMember, err := models.IsWorkspaceMember(db, requestingUserFirebaseUID, workspaceID)
+	if err != nil || !isMember {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	firestoreClient, err := firebase.GetFirestoreClient()
+	if err != nil {
+		utilities.LogError(err, "CodeReviewFindingsToTasksHandler: Erro ao obter cliente Firestore")
+		http.Error(w, "Failed to connect to Firestore", http.StatusInternalServerError)
+		return
+	}
+	defer firestoreClient.Close()
+
+	results, err := ai_services.CodeReviewFindingsToTasks(ctx, db, firestoreClient, workspaceID, requestingUserFirebaseUID, historyID, input.Findings)
+	switch {
+	case errors.Is(err, ai_services.ErrAIHistoryNotFound):
+		http.Error(w, "Code review not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ai_services.ErrInvalidTaskSuggestion):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		utilities.LogError(err, fmt.Sprintf("CodeReviewFindingsToTasksHandler: Erro ao criar tarefas da revisão %s", historyID))
+		http.Error(w, "Failed to create tasks from code review", http.StatusInternalServerError)
+		return
+	}
+
+	report := AcceptTaskSuggestionsReport{HistoryID: historyID, Results: results}
+	for _, result := range results {
+		if result.Status == ai_services.SuggestionCreated {
+			report.Created++
+		}
+	}
+
+	utilities.LogInfo("CodeReviewFindingsToTasksHandler: %d tarefas criadas a partir da revisão %s no workspace %d pelo usuário %s", report.Created, historyID, workspaceID, requestingUserFirebaseUID)
+	w.Header().Set("Content-Type", "application/json")
+	if report.Created > 0 {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(report)
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"projeto-integrador/utilities"
+	"strings"
 	"time"
 )
 
@@ -24,10 +25,19 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 		duration := time.Since(start)
 
 		// Registrar a requisição
-		utilities.LogRequest(r.Method, r.URL.Path, r.RemoteAddr, rw.statusCode, duration)
+		utilities.LogRequest(r.Method, redactedPath(r.URL.Path), r.RemoteAddr, rw.statusCode, duration)
 	})
 }
 
+// redactedPath oculta tokens secretos que fazem parte da URL (ex: feeds de calendário)
+// para que não fiquem gravados nos logs de acesso.
+func redactedPath(path string) string {
+	if strings.HasPrefix(path, "/calendar/") {
+		return "/calendar/[token].ics"
+	}
+	return path
+}
+
 // responseWriter é um wrapper para http.ResponseWriter que captura o status code
 type responseWriter struct {
 	http.ResponseWriter
@@ -39,3 +49,16 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.statusCode = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+// Flush repassa o flush ao ResponseWriter original, necessário para respostas em
+// streaming (Server-Sent Events).
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap permite que http.ResponseController alcance o ResponseWriter original.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,351 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"projeto-integrador/ai_services"
+	"projeto-integrador/database"
+	"projeto-integrador/firebase"
+	"projeto-integrador/models"
+	"projeto-integrador/utilities"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+func writeMindMapError(w http.ResponseWriter, handlerName string, err error) {
+	switch {
+	case errors.Is(err, ai_services.ErrMindMapNotFound):
+		http.Error(w, "Mind map not found", http.StatusNotFound)
+	case errors.Is(err, ai_services.ErrMindMapNodeNotFound):
+		http.Error(w, "Mind map node not found", http.StatusNotFound)
+	case errors.Is(err, ai_services.ErrInvalidMindMap):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ai_services.ErrMindMapForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		utilities.LogError(err, handlerName+": Erro ao acessar mapa mental")
+		http.Error(w, "Failed to access mind map", http.StatusInternalServerError)
+	}
+}
+
+// CreateMindMapHandler salva um mapa mental criado pelo usuário, a partir de uma árvore
+// ("root") ou de uma lista Markdown indentada ("outline"). Os mapas gerados pela IA em
+// /ai/mindmap-ideas são salvos automaticamente.
+// Rota: POST /workspace/{workspace_id}/mindmaps
+func CreateMindMapHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Title   string              `json:"title"`
+		Root    *models.MindMapNode `json:"root"`
+		Outline string              `json:"outline"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAIRequestBytes)).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	root := ai_services.ParseMindMapOutline(input.Outline, input.Title)
+	if input.Root != nil {
+		root = *input.Root
+	}
+
+	client, workspaceID, userFirebaseUID := memberFirestoreRequest(w, r, "CreateMindMapHandler")
+	if client == nil {
+		return
+	}
+	defer client.Close()
+
+	mindMap, err := ai_services.CreateMindMap(r.Context(), client, workspaceID, userFirebaseUID, input.Title, root, "")
+	if err != nil {
+		writeMindMapError(w, "CreateMindMapHandler", err)
+		return
+	}
+
+	utilities.LogInfo("CreateMindMapHandler: Mapa mental %s criado no workspace %d pelo usuário %s", mindMap.ID, workspaceID, userFirebaseUID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(mindMap)
+}
+
+// ListMindMapsHandler lista os mapas mentais do workspace, sem as árvores.
+// Rota: GET /workspace/{workspace_id}/mindmaps
+func ListMindMapsHandler(w http.ResponseWriter, r *http.Request) {
+	client, workspaceID, _ := memberFirestoreRequest(w, r, "ListMindMapsHandler")
+	if client == nil {
+		return
+	}
+	defer client.Close()
+
+	mindMaps, err := ai_services.ListMindMaps(r.Context(), client, workspaceID)
+	if err != nil {
+		writeMindMapError(w, "ListMindMapsHandler", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mindMaps)
+}
+
+// GetMindMapHandler retorna o mapa mental com a árvore completa.
+// Rota: GET /workspace/{workspace_id}/mindmaps/{mind_map_id}
+func GetMindMapHandler(w http.ResponseWriter, r *http.Request) {
+	client, workspaceID, _ := memberFirestoreRequest(w, r, "GetMindMapHandler")
+	if client == nil {
+		return
+	}
+	defer client.Close()
+
+	mindMap, err := ai_services.GetMindMap(r.Context(), client, workspaceID, mux.Vars(r)["mind_map_id"])
+	if err != nil {
+		writeMindMapError(w, "GetMindMapHandler", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mindMap)
+}
+
+// RenameMindMapHandler altera o título do mapa mental.
+// Rota: PUT /workspace/{workspace_id}/mindmaps/{mind_map_id}
+func RenameMindMapHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Title string `json:"title"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	client, workspaceID, _ := memberFirestoreRequest(w, r, "RenameMindMapHandler")
+	if client == nil {
+		return
+	}
+	defer client.Close()
+
+	mindMap, err := ai_services.RenameMindMap(r.Context(), client, workspaceID, mux.Vars(r)["mind_map_id"], input.Title)
+	if err != nil {
+		writeMindMapError(w, "RenameMindMapHandler", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mindMap)
+}
+
+// DeleteMindMapHandler apaga o mapa mental. Apenas o criador pode apagar.
+// Rota: DELETE /workspace/{workspace_id}/mindmaps/{mind_map_id}
+func DeleteMindMapHandler(w http.ResponseWriter, r *http.Request) {
+	client, workspaceID, userFirebaseUID := memberFirestoreRequest(w, r, "DeleteMindMapHandler")
+	if client == nil {
+		return
+	}
+	defer client.Close()
+	mindMapID := mux.Vars(r)["mind_map_id"]
+
+	if err := ai_services.DeleteMindMap(r.Context(), client, workspaceID, mindMapID, userFirebaseUID); err != nil {
+		writeMindMapError(w, "DeleteMindMapHandler", err)
+		return
+	}
+
+	utilities.LogInfo("DeleteMindMapHandler: Mapa mental %s do workspace %d apagado pelo usuário %s", mindMapID, workspaceID, userFirebaseUID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddMindMapNodeHandler adiciona um nó ao mapa, sob parent_id (a raiz, se omitido).
+// Rota: POST /workspace/{workspace_id}/mindmaps/{mind_map_id}/nodes
+func AddMindMapNodeHandler(w http.ResponseWriter, r *http.Request) {
+	var input models.MindMapNodeInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	client, workspaceID, _ := memberFirestoreRequest(w, r, "AddMindMapNodeHandler")
+	if client == nil {
+		return
+	}
+	defer client.Close()
+
+	node, err := ai_services.AddMindMapNode(r.Context(), client, workspaceID, mux.Vars(r)["mind_map_id"], input)
+	if err != nil {
+		writeMindMapError(w, "AddMindMapNodeHandler", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(node)
+}
+
+// UpdateMindMapNodeHandler altera o rótulo ou as notas de um nó, ou o move para outro
+// pai (parent_id).
+// Rota: PUT /workspace/{workspace_id}/mindmaps/{mind_map_id}/nodes/{node_id}
+func UpdateMindMapNodeHandler(w http.ResponseWriter, r *http.Request) {
+	var input models.MindMapNodeInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if input.Label == nil && input.Notes == nil && input.ParentID == nil {
+		http.Error(w, "No fields to update", http.StatusBadRequest)
+		return
+	}
+
+	client, workspaceID, _ := memberFirestoreRequest(w, r, "UpdateMindMapNodeHandler")
+	if client == nil {
+		return
+	}
+	defer client.Close()
+	vars := mux.Vars(r)
+
+	node, err := ai_services.UpdateMindMapNode(r.Context(), client, workspaceID, vars["mind_map_id"], vars["node_id"], input)
+	if err != nil {
+		writeMindMapError(w, "UpdateMindMapNodeHandler", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(node)
+}
+
+// DeleteMindMapNodeHandler remove um nó e seus filhos.
+// Rota: DELETE /workspace/{workspace_id}/mindmaps/{mind_map_id}/nodes/{node_id}
+func DeleteMindMapNodeHandler(w http.ResponseWriter, r *http.Request) {
+	client, workspaceID, _ := memberFirestoreRequest(w, r, "DeleteMindMapNodeHandler")
+	if client == nil {
+		return
+	}
+	defer client.Close()
+	vars := mux.Vars(r)
+
+	if err := ai_services.DeleteMindMapNode(r.Context(), client, workspaceID, vars["mind_map_id"], vars["node_id"]); err != nil {
+		writeMindMapError(w, "DeleteMindMapNodeHandler", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var unsafeFilenameChars = regexp.MustCompile(`[^\p{L}\p{N}_-]+`)
+
+// ExportMindMapHandler exporta o mapa mental em ?format=markdown (padrão), opml ou mermaid.
+// Rota: GET /workspace/{workspace_id}/mindmaps/{mind_map_id}/export
+func ExportMindMapHandler(w http.ResponseWriter, r *http.Request) {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = ai_services.MindMapFormatMarkdown
+	}
+	if format != ai_services.MindMapFormatMarkdown && format != ai_services.MindMapFormatOPML && format != ai_services.MindMapFormatMermaid {
+		http.Error(w, "Invalid format (use markdown, opml or mermaid)", http.StatusBadRequest)
+		return
+	}
+
+	client, workspaceID, _ := memberFirestoreRequest(w, r, "ExportMindMapHandler")
+	if client == nil {
+		return
+	}
+	defer client.Close()
+
+	mindMap, err := ai_services.GetMindMap(r.Context(), client, workspaceID, mux.Vars(r)["mind_map_id"])
+	if err != nil {
+		writeMindMapError(w, "ExportMindMapHandler", err)
+		return
+	}
+
+	var body []byte
+	var contentType, extension string
+	switch format {
+	case ai_services.MindMapFormatOPML:
+		body, err = ai_services.MindMapOPML(mindMap)
+		if err != nil {
+			utilities.LogError(err, "ExportMindMapHandler: Erro ao gerar OPML")
+			http.Error(w, "Failed to export mind map", http.StatusInternalServerError)
+			return
+		}
+		contentType, extension = "text/x-opml; charset=utf-8", "opml"
+	case ai_services.MindMapFormatMermaid:
+		body = []byte(ai_services.MindMapMermaid(mindMap))
+		contentType, extension = "text/plain; charset=utf-8", "mmd"
+	default:
+		body = []byte(ai_services.MindMapMarkdown(mindMap))
+		contentType, extension = "text/markdown; charset=utf-8", "md"
+	}
+
+	filename := strings.Trim(unsafeFilenameChars.ReplaceAllString(mindMap.Title, "_"), "_")
+	if filename == "" {
+		filename = "mapa-mental"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+"."+extension))
+	w.Write(body)
+}
+
+// ConvertMindMapNodesHandler cria uma tarefa para cada nó informado e vincula a tarefa
+// ao nó (task_id). Converter de novo o mesmo nó não cria outra tarefa.
+// Rota: POST /workspace/{workspace_id}/mindmaps/{mind_map_id}/tasks
+func ConvertMindMapNodesHandler(w http.ResponseWriter, r *http.Request) {
+	workspaceID, err := getWorkspaceIDFromPath(r)
+	if err != nil {
+		http.Error(w, "Invalid Workspace ID", http.StatusBadRequest)
+		return
+	}
+	mindMapID := mux.Vars(r)["mind_map_id"]
+
+	var input struct {
+		NodeIDs []string `json:"node_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(input.NodeIDs) == 0 {
+		http.Error(w, "node_ids is required", http.StatusBadRequest)
+		return
+	}
+
+	requestingUserFirebaseUID := r.Context().Value("userUID").(string)
+	ctx := r.Context()
+
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, "ConvertMindMapNodesHandler: Erro ao conectar ao PG")
+		http.Error(w, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	isMember, err := models.IsWorkspaceMember(db, requestingUserFirebaseUID, workspaceID)
+	if err != nil || !isMember {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	firestoreClient, err := firebase.GetFirestoreClient()
+	if err != nil {
+		utilities.LogError(err, "ConvertMindMapNodesHandler: Erro ao obter cliente Firestore")
+		http.Error(w, "Failed to connect to Firestore", http.StatusInternalServerError)
+		return
+	}
+	defer firestoreClient.Close()
+
+	results, err := ai_services.ConvertMindMapNodesToTasks(ctx, db, firestoreClient, workspaceID, requestingUserFirebaseUID, mindMapID, input.NodeIDs)
+	if err != nil {
+		writeMindMapError(w, "ConvertMindMapNodesHandler", err)
+		return
+	}
+
+	created := 0
+	for _, result := range results {
+		if result.Status == ai_services.SuggestionCreated {
+			created++
+		}
+	}
+
+	utilities.LogInfo("ConvertMindMapNodesHandler: %d tarefas criadas a partir do mapa mental %s no workspace %d", created, mindMapID, workspaceID)
+	w.Header().Set("Content-Type", "application/json")
+	if created > 0 {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"mind_map_id": mindMapID, "created": created, "results": results})
+}
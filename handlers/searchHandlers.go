@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"projeto-integrador/database"
+	"projeto-integrador/firebase"
+	"projeto-integrador/models"
+	"projeto-integrador/search_services"
+	"projeto-integrador/utilities"
+	"strconv"
+)
+
+// SearchTasksHandler busca tarefas do workspace por similaridade com o texto informado
+// (título, descrição, etiquetas, checklist e comentários).
+// Rota: GET /workspace/{workspace_id}/search?q=...&limit=20
+func SearchTasksHandler(w http.ResponseWriter, r *http.Request) {
+	workspaceID, err := getWorkspaceIDFromPath(r)
+	if err != nil {
+		http.Error(w, "Invalid Workspace ID", http.StatusBadRequest)
+		return
+	}
+	query := r.URL.Query().Get("q")
+	limit := search_services.DefaultSearchLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > search_services.MaxSearchLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", search_services.MaxSearchLimit), http.StatusBadRequest)
+			return
+		}
+	}
+	requestingUserFirebaseUID := r.Context().Value("userUID").(string)
+
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, "SearchTasksHandler: Erro ao conectar ao PG")
+		http.Error(w, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	isMember, err := models.IsWorkspaceMember(db, requestingUserFirebaseUID, workspaceID)
+	if err != nil || !isMember {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	firestoreClient, err := firebase.GetFirestoreClient()
+	if err != nil {
+		utilities.LogError(err, "SearchTasksHandler: Erro ao obter cliente Firestore")
+		http.Error(w, "Failed to connect to Firestore", http.StatusInternalServerError)
+		return
+	}
+	defer firestoreClient.Close()
+
+	response, err := search_services.Search(r.Context(), db, firestoreClient, workspaceID, query, limit)
+	switch {
+	case errors.Is(err, search_services.ErrEmptySearchQuery):
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	case err != nil:
+		utilities.LogError(err, fmt.Sprintf("SearchTasksHandler: Erro ao buscar tarefas do workspace %d", workspaceID))
+		http.Error(w, "Failed to search tasks", http.StatusInternalServerError)
+		return
+	}
+
+	utilities.LogDebug("SearchTasksHandler: %d resultados para '%s' no workspace %d", len(response.Results), response.Query, workspaceID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
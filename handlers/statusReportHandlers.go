@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"projeto-integrador/ai_services"
+	"projeto-integrador/firebase"
+	"projeto-integrador/models"
+	"projeto-integrador/utilities"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const maxStatusReportPeriod = 31 * 24 * time.Hour
+
+// writeStatusReport responde com o relatório em JSON ou, com ?format=markdown, como
+// documento Markdown.
+func writeStatusReport(w http.ResponseWriter, report *models.StatusReport, format string, statusCode int) {
+	if format == "markdown" {
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "relatorio-"+report.PeriodEnd.Format("2006-01-02")+".md"))
+		w.WriteHeader(statusCode)
+		w.Write([]byte(ai_services.StatusReportMarkdown(report)))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(report)
+}
+
+// statusReportFormat lê ?format= (json, o padrão, ou markdown).
+func statusReportFormat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format != "" && format != "json" && format != "markdown" {
+		http.Error(w, "Invalid format (use json or markdown)", http.StatusBadRequest)
+		return "", false
+	}
+	return format, true
+}
+
+// GenerateStatusReportHandler gera e salva o relatório de status do workspace: tarefas
+// concluídas, criadas, atrasadas e bloqueadas no período, a contribuição de cada membro
+// e um resumo escrito pela IA (cobrado do usuário). Padrão: últimos 7 dias.
+// Rota: POST /workspace/{workspace_id}/ai/status-reports?since=&until=&format=
+func GenerateStatusReportHandler(w http.ResponseWriter, r *http.Request) {
+	format, ok := statusReportFormat(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	until := time.Now()
+	if parsed, err := parseAIHistoryTime(query.Get("until")); err != nil {
+		http.Error(w, "Invalid until (use RFC3339 or YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	} else if parsed != nil {
+		until = *parsed
+	}
+	since := until.Add(-ai_services.StatusReportPeriod)
+	if parsed, err := parseAIHistoryTime(query.Get("since")); err != nil {
+		http.Error(w, "Invalid since (use RFC3339 or YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	} else if parsed != nil {
+		since = *parsed
+	}
+	if !since.Before(until) {
+		http.Error(w, "since must be before until", http.StatusBadRequest)
+		return
+	}
+	if until.Sub(since) > maxStatusReportPeriod {
+		http.Error(w, "The report period cannot exceed 31 days", http.StatusBadRequest)
+		return
+	}
+
+	db, workspaceID, userFirebaseUID, _ := aiHistoryRequest(w, r, "GenerateStatusReportHandler")
+	if db == nil {
+		return
+	}
+	defer db.Close()
+
+	firestoreClient, err := firebase.GetFirestoreClient()
+	if err != nil {
+		utilities.LogError(err, "GenerateStatusReportHandler: Erro ao obter cliente Firestore")
+		http.Error(w, "Failed to connect to Firestore", http.StatusInternalServerError)
+		return
+	}
+	defer firestoreClient.Close()
+
+	report, err := ai_services.GenerateStatusReport(r.Context(), firestoreClient, db, workspaceID, userFirebaseUID, since, until, models.StatusReportManual)
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("GenerateStatusReportHandler: Erro ao gerar relatório de status do workspace %d", workspaceID))
+		http.Error(w, "Failed to generate status report", http.StatusInternalServerError)
+		return
+	}
+
+	utilities.LogInfo("GenerateStatusReportHandler: Relatório %s do workspace %d gerado por %s (resumo: %t)", report.ID, workspaceID, userFirebaseUID, report.Narrative != "")
+	writeStatusReport(w, report, format, http.StatusCreated)
+}
+
+// ListStatusReportsHandler lista os relatórios de status do workspace, sem as listas de
+// tarefas, os mais recentes primeiro.
+// Rota: GET /workspace/{workspace_id}/ai/status-reports
+func ListStatusReportsHandler(w http.ResponseWriter, r *http.Request) {
+	client, workspaceID, _ := memberFirestoreRequest(w, r, "ListStatusReportsHandler")
+	if client == nil {
+		return
+	}
+	defer client.Close()
+
+	summaries, err := ai_services.ListStatusReports(r.Context(), client, workspaceID)
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("ListStatusReportsHandler: Erro ao listar relatórios do workspace %d", workspaceID))
+		http.Error(w, "Failed to list status reports", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summaries)
+}
+
+// GetStatusReportHandler retorna um relatório de status em JSON ou, com
+// ?format=markdown, como documento Markdown.
+// Rota: GET /workspace/{workspace_id}/ai/status-reports/{report_id}?format=
+func GetStatusReportHandler(w http.ResponseWriter, r *http.Request) {
+	format, ok := statusReportFormat(w, r)
+	if !ok {
+		return
+	}
+	client, workspaceID, _ := memberFirestoreRequest(w, r, "GetStatusReportHandler")
+	if client == nil {
+		return
+	}
+	defer client.Close()
+
+	reportID := mux.Vars(r)["report_id"]
+	report, err := ai_services.GetStatusReport(r.Context(), client, workspaceID, reportID)
+	switch {
+	case errors.Is(err, ai_services.ErrStatusReportNotFound):
+		http.Error(w, "Status report not found", http.StatusNotFound)
+		return
+	case err != nil:
+		utilities.LogError(err, fmt.Sprintf("GetStatusReportHandler: Erro ao buscar relatório %s", reportID))
+		http.Error(w, "Failed to retrieve status report", http.StatusInternalServerError)
+		return
+	}
+	writeStatusReport(w, report, format, http.StatusOK)
+}
+
+// GetStatusReportScheduleHandler retorna o agendamento semanal do relatório de status.
+// Rota: GET /workspace/{workspace_id}/ai/status-reports/schedule
+func GetStatusReportScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	db, workspaceID, _, _ := aiHistoryRequest(w, r, "GetStatusReportScheduleHandler")
+	if db == nil {
+		return
+	}
+	defer db.Close()
+
+	schedule, err := models.GetStatusReportSchedule(db, workspaceID)
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("GetStatusReportScheduleHandler: Erro ao buscar agendamento do workspace %d", workspaceID))
+		http.Error(w, "Failed to retrieve status report schedule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// UpdateStatusReportScheduleHandler ativa, desativa ou altera o agendamento semanal do
+// relatório de status (apenas admins). O resumo da IA dos relatórios agendados é
+// cobrado de quem fez a última alteração.
+// Rota: PUT /workspace/{workspace_id}/ai/status-reports/schedule
+func UpdateStatusReportScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	db, workspaceID, userFirebaseUID, isAdmin := aiHistoryRequest(w, r, "UpdateStatusReportScheduleHandler")
+	if db == nil {
+		return
+	}
+	defer db.Close()
+	if !isAdmin {
+		http.Error(w, "Forbidden: Only workspace admins can schedule the status report", http.StatusForbidden)
+		return
+	}
+
+	schedule, err := models.GetStatusReportSchedule(db, workspaceID)
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("UpdateStatusReportScheduleHandler: Erro ao buscar agendamento do workspace %d", workspaceID))
+		http.Error(w, "Failed to retrieve status report schedule", http.StatusInternalServerError)
+		return
+	}
+	var input struct {
+		Enabled *bool `json:"enabled"`
+		Weekday *int  `json:"weekday"`
+		Hour    *int  `json:"hour"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if input.Enabled != nil {
+		schedule.Enabled = *input.Enabled
+	}
+	if input.Weekday != nil {
+		schedule.Weekday = *input.Weekday
+	}
+	if input.Hour != nil {
+		schedule.Hour = *input.Hour
+	}
+	if err := ai_services.ValidateStatusReportSchedule(schedule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := models.SaveStatusReportSchedule(db, schedule, userFirebaseUID); err != nil {
+		utilities.LogError(err, fmt.Sprintf("UpdateStatusReportScheduleHandler: Erro ao salvar agendamento do workspace %d", workspaceID))
+		http.Error(w, "Failed to save status report schedule", http.StatusInternalServerError)
+		return
+	}
+	saved, err := models.GetStatusReportSchedule(db, workspaceID)
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("UpdateStatusReportScheduleHandler: Erro ao buscar agendamento do workspace %d", workspaceID))
+		http.Error(w, "Failed to retrieve status report schedule", http.StatusInternalServerError)
+		return
+	}
+
+	utilities.LogInfo("UpdateStatusReportScheduleHandler: Agendamento do relatório do workspace %d atualizado por %s (ativo: %t, dia %d, %dh UTC)", workspaceID, userFirebaseUID, saved.Enabled, saved.Weekday, saved.Hour)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
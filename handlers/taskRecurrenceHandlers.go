@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"projeto-integrador/database"
+	"projeto-integrador/firebase"
+	"projeto-integrador/models"
+	"projeto-integrador/task_services"
+	"projeto-integrador/utilities"
+
+	"cloud.google.com/go/firestore"
+	"github.com/gorilla/mux"
+	"google.golang.org/api/iterator"
+)
+
+// UpdateTaskSeriesHandler edita a série de uma tarefa recorrente: regra de recorrência
+// e campos compartilhados (título, descrição, prioridade). As alterações valem para
+// todas as ocorrências ainda abertas, e portanto para as próximas que forem geradas.
+// Também permite tornar recorrente uma tarefa avulsa ou encerrar a série.
+// Rota: PUT /workspace/{workspace_id}/task/recurrence/{task_doc_id}
+func UpdateTaskSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	taskDocID := vars["task_doc_id"]
+	workspaceID, err := getWorkspaceIDFromPath(r)
+	if err != nil || taskDocID == "" {
+		http.Error(w, "Invalid Workspace ID or Task ID", http.StatusBadRequest)
+		return
+	}
+
+	requestingUserFirebaseUID := r.Context().Value("userUID").(string)
+	ctx := context.Background()
+
+	var input models.UpdateSeriesInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, "UpdateTaskSeriesHandler: Erro ao conectar ao PG")
+		http.Error(w, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	isMember, err := models.IsWorkspaceMember(db, requestingUserFirebaseUID, workspaceID)
+	if err != nil || !isMember {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	firestoreClient, err := firebase.GetFirestoreClient()
+	if err != nil {
+		utilities.LogError(err, "UpdateTaskSeriesHandler: Erro ao obter cliente Firestore")
+		http.Error(w, "Failed to connect to Firestore", http.StatusInternalServerError)
+		return
+	}
+	defer firestoreClient.Close()
+
+	task, err := task_services.GetTask(ctx, firestoreClient, workspaceID, taskDocID)
+	if err != nil {
+		utilities.LogError(err, "UpdateTaskSeriesHandler: Erro ao buscar tarefa")
+		http.Error(w, "Task not found or error fetching", http.StatusNotFound)
+		return
+	}
+
+	var updates []firestore.Update
+	switch {
+	case input.StopRecurrence:
+		updates = append(updates, firestore.Update{Path: "recurrence", Value: firestore.Delete})
+	case input.Recurrence != nil || input.RRule != nil:
+		rrule := ""
+		if input.RRule != nil {
+			rrule = *input.RRule
+		}
+		rule, err := task_services.NormalizeRecurrence(input.Recurrence, rrule)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		task_services.AnchorRecurrence(rule, task.ExpirationDate)
+		updates = append(updates, firestore.Update{Path: "recurrence", Value: rule})
+	}
+	if input.Title != nil {
+		if *input.Title == "" {
+			http.Error(w, "Task title cannot be empty", http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, firestore.Update{Path: "title", Value: *input.Title})
+	}
+	if input.Description != nil {
+		updates = append(updates, firestore.Update{Path: "description", Value: *input.Description})
+	}
+	if input.Priority != nil {
+		updates = append(updates, firestore.Update{Path: "priority", Value: *input.Priority})
+	}
+	if len(updates) == 0 {
+		http.Error(w, "No fields to update", http.StatusBadRequest)
+		return
+	}
+	updates = append(updates, firestore.Update{Path: "last_updated_by_firebase_uid", Value: requestingUserFirebaseUID})
+	updates = append(updates, firestore.Update{Path: "last_updated_at", Value: firestore.ServerTimestamp})
+
+	// Ocorrências abertas da série; uma tarefa avulsa passa a ser a primeira de uma nova série
+	targets := []string{taskDocID}
+	if task.SeriesID != "" {
+		targets, err = openSeriesOccurrences(ctx, firestoreClient, workspaceID, task.SeriesID)
+		if err != nil {
+			utilities.LogError(err, "UpdateTaskSeriesHandler: Erro ao listar ocorrências da série")
+			http.Error(w, "Failed to load task series", http.StatusInternalServerError)
+			return
+		}
+	} else if !input.StopRecurrence {
+		updates = append(updates,
+			firestore.Update{Path: "series_id", Value: taskDocID},
+			firestore.Update{Path: "occurrence_index", Value: 1},
+		)
+	}
+
+	var updated []string
+	for _, occurrenceID := range targets {
+		if _, err := task_services.TaskRef(firestoreClient, workspaceID, occurrenceID).Update(ctx, updates); err != nil {
+			utilities.LogError(err, fmt.Sprintf("UpdateTaskSeriesHandler: Erro ao atualizar ocorrência %s", occurrenceID))
+			continue
+		}
+		task_services.NotifyTaskChanged(workspaceID, occurrenceID)
+		updated = append(updated, occurrenceID)
+	}
+	if len(updated) == 0 && len(targets) > 0 {
+		http.Error(w, "Failed to update task series", http.StatusInternalServerError)
+		return
+	}
+
+	utilities.LogInfo("UpdateTaskSeriesHandler: Série da tarefa %s atualizada no workspace %d (%d ocorrências)", taskDocID, workspaceID, len(updated))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":             "Task series updated successfully",
+		"updated_occurrences": updated,
+	})
+}
+
+// SkipTaskOccurrenceHandler pula a ocorrência atual de uma tarefa recorrente:
+// ela é marcada como "skipped" e a próxima ocorrência é gerada.
+// Rota: POST /workspace/{workspace_id}/task/skip/{task_doc_id}
+func SkipTaskOccurrenceHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	taskDocID := vars["task_doc_id"]
+	workspaceID, err := getWorkspaceIDFromPath(r)
+	if err != nil || taskDocID == "" {
+		http.Error(w, "Invalid Workspace ID or Task ID", http.StatusBadRequest)
+		return
+	}
+
+	requestingUserFirebaseUID := r.Context().Value("userUID").(string)
+	ctx := context.Background()
+
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, "SkipTaskOccurrenceHandler: Erro ao conectar ao PG")
+		http.Error(w, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	isMember, err := models.IsWorkspaceMember(db, requestingUserFirebaseUID, workspaceID)
+	if err != nil || !isMember {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	firestoreClient, err := firebase.GetFirestoreClient()
+	if err != nil {
+		utilities.LogError(err, "SkipTaskOccurrenceHandler: Erro ao obter cliente Firestore")
+		http.Error(w, "Failed to connect to Firestore", http.StatusInternalServerError)
+		return
+	}
+	defer firestoreClient.Close()
+
+	task, err := task_services.GetTask(ctx, firestoreClient, workspaceID, taskDocID)
+	if err != nil {
+		utilities.LogError(err, "SkipTaskOccurrenceHandler: Erro ao buscar tarefa")
+		http.Error(w, "Task not found or error fetching", http.StatusNotFound)
+		return
+	}
+	if task.Recurrence == nil {
+		http.Error(w, "Task is not recurring", http.StatusBadRequest)
+		return
+	}
+	if task.Status == "completed" || task.Status == "skipped" {
+		http.Error(w, "Occurrence is already closed", http.StatusConflict)
+		return
+	}
+
+	_, err = task_services.TaskRef(firestoreClient, workspaceID, taskDocID).Update(ctx, []firestore.Update{
+		{Path: "status", Value: "skipped"},
+		{Path: "is_overdue", Value: false},
+		{Path: "overdue_since", Value: firestore.Delete},
+		{Path: "last_updated_by_firebase_uid", Value: requestingUserFirebaseUID},
+		{Path: "last_updated_at", Value: firestore.ServerTimestamp},
+	})
+	if err != nil {
+		utilities.LogError(err, "SkipTaskOccurrenceHandler: Erro ao marcar ocorrência como pulada")
+		http.Error(w, "Failed to skip occurrence", http.StatusInternalServerError)
+		return
+	}
+
+	nextID, err := task_services.CreateNextOccurrence(ctx, db, firestoreClient, taskDocID, task)
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("SkipTaskOccurrenceHandler: Erro ao gerar próxima ocorrência da tarefa %s", taskDocID))
+		http.Error(w, "Occurrence skipped, but failed to create the next one", http.StatusInternalServerError)
+		return
+	}
+
+	utilities.LogInfo("SkipTaskOccurrenceHandler: Ocorrência %s pulada no workspace %d; próxima: %q", taskDocID, workspaceID, nextID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message":            "Occurrence skipped successfully",
+		"next_occurrence_id": nextID,
+	})
+}
+
+// openSeriesOccurrences lista os IDs das ocorrências ainda abertas de uma série.
+func openSeriesOccurrences(ctx context.Context, client *firestore.Client, workspaceID int64, seriesID string) ([]string, error) {
+	iter := task_services.TasksCollection(client, workspaceID).Where("series_id", "==", seriesID).Documents(ctx)
+	defer iter.Stop()
+
+	var ids []string
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao buscar ocorrências da série %s no workspace %d: %w", seriesID, workspaceID, err)
+		}
+		status, _ := doc.Data()["status"].(string)
+		if status == "completed" || status == "skipped" {
+			continue
+		}
+		ids = append(ids, doc.Ref.ID)
+	}
+	return ids, nil
+}
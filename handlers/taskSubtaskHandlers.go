@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"projeto-integrador/ai_services"
+	"projeto-integrador/database"
+	"projeto-integrador/firebase"
+	"projeto-integrador/models"
+	"projeto-integrador/task_services"
+	"projeto-integrador/utilities"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// checkTaskExists confere se a tarefa existe no workspace. Em caso de erro, retorna o
+// status HTTP a responder.
+func checkTaskExists(ctx context.Context, workspaceID int64, taskDocID string) (int, error) {
+	firestoreClient, err := firebase.GetFirestoreClient()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	defer firestoreClient.Close()
+
+	if _, err := task_services.TaskRef(firestoreClient, workspaceID, taskDocID).Get(ctx); err != nil {
+		if status.Code(err) == codes.NotFound {
+			return http.StatusNotFound, err
+		}
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+// CreateSubtasksReport é a resposta da criação de subtarefas.
+type CreateSubtasksReport struct {
+	ParentTaskID string                          `json:"parent_task_id"`
+	Created      int                             `json:"created"`
+	Results      []models.AcceptedTaskSuggestion `json:"results"`
+}
+
+// CreateSubtasksHandler cria subtarefas (tarefas filhas) de uma tarefa, na ordem
+// enviada. Normalmente recebe a prévia de /ai/task-breakdown editada pelo usuário,
+// com o history_id da decomposição para marcar as tarefas como geradas por IA.
+// Rota: POST /workspace/{workspace_id}/task/subtasks/{task_doc_id}
+func CreateSubtasksHandler(w http.ResponseWriter, r *http.Request) {
+	taskDocID := mux.Vars(r)["task_doc_id"]
+	workspaceID, err := getWorkspaceIDFromPath(r)
+	if err != nil || taskDocID == "" {
+		http.Error(w, "Invalid Workspace ID or Task ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		HistoryID string                `json:"history_id"`
+		Subtasks  []models.SubtaskInput `json:"subtasks"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAIRequestBytes)).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	requestingUserFirebaseUID := r.Context().Value("userUID").(string)
+	ctx := r.Context()
+
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, "CreateSubtasksHandler: Erro ao conectar ao PG")
+		http.Error(w, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	isMember, err := models.IsWorkspaceMember(db, requestingUserFirebaseUID, workspaceID)
+	if err != nil || !isMember {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	firestoreClient, err := firebase.GetFirestoreClient()
+	if err != nil {
+		utilities.LogError(err, "CreateSubtasksHandler: Erro ao obter cliente Firestore")
+		http.Error(w, "Failed to connect to Firestore", http.StatusInternalServerError)
+		return
+	}
+	defer firestoreClient.Close()
+
+	parent, err := task_services.GetTask(ctx, firestoreClient, workspaceID, taskDocID)
+	if err != nil {
+		utilities.LogError(err, "CreateSubtasksHandler: Erro ao buscar tarefa pai")
+		http.Error(w, "Task not found or error fetching", http.StatusNotFound)
+		return
+	}
+
+	results, err := ai_services.CommitTaskBreakdown(ctx, db, firestoreClient, workspaceID, requestingUserFirebaseUID, taskDocID, parent, input.HistoryID, input.Subtasks)
+	switch {
+	case errors.Is(err, ai_services.ErrAIHistoryNotFound):
+		http.Error(w, "AI task breakdown not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ai_services.ErrInvalidTaskSuggestion):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		utilities.LogError(err, fmt.Sprintf("CreateSubtasksHandler: Erro ao criar subtarefas da tarefa %s", taskDocID))
+		http.Error(w, "Failed to create subtasks", http.StatusInternalServerError)
+		return
+	}
+
+	report := CreateSubtasksReport{ParentTaskID: taskDocID, Results: results}
+	for _, result := range results {
+		if result.Status == ai_services.SuggestionCreated {
+			report.Created++
+		}
+	}
+
+	utilities.LogInfo("CreateSubtasksHandler: %d subtarefas criadas para a tarefa %s no workspace %d", report.Created, taskDocID, workspaceID)
+	w.Header().Set("Content-Type", "application/json")
+	if report.Created > 0 {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(report)
+}
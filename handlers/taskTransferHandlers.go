@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"projeto-integrador/database"
+	"projeto-integrador/firebase"
+	"projeto-integrador/models"
+	"projeto-integrador/task_services"
+	"projeto-integrador/utilities"
+	"strings"
+	"time"
+)
+
+// Tamanho máximo do corpo aceito na importação de tarefas.
+const maxTaskImportBytes = 5 << 20
+
+// TaskImportReport é a resposta da importação (ou da simulação, com dry_run).
+type TaskImportReport struct {
+	DryRun         bool                      `json:"dry_run"`
+	Total          int                       `json:"total"`
+	Valid          int                       `json:"valid"`
+	Created        int                       `json:"created"`
+	Failed         int                       `json:"failed"`
+	CreatedTaskIDs []string                  `json:"created_task_ids,omitempty"`
+	Errors         []task_services.ImportRow `json:"errors"` // Apenas as linhas com problemas
+}
+
+// ExportTasksHandler exporta todas as tarefas do workspace em CSV ou JSON.
+// Rota: GET /workspace/{workspace_id}/tasks/export?format=csv|json
+func ExportTasksHandler(w http.ResponseWriter, r *http.Request) {
+	workspaceID, err := getWorkspaceIDFromPath(r)
+	if err != nil {
+		http.Error(w, "Invalid Workspace ID", http.StatusBadRequest)
+		return
+	}
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = "json"
+	}
+	if format != "csv" && format != "json" {
+		http.Error(w, "Invalid format (use csv or json)", http.StatusBadRequest)
+		return
+	}
+
+	requestingUserFirebaseUID := r.Context().Value("userUID").(string)
+	ctx := context.Background()
+
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, "ExportTasksHandler: Erro ao conectar ao PG")
+		http.Error(w, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	isMember, err := models.IsWorkspaceMember(db, requestingUserFirebaseUID, workspaceID)
+	if err != nil || !isMember {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	firestoreClient, err := firebase.GetFirestoreClient()
+	if err != nil {
+		utilities.LogError(err, "ExportTasksHandler: Erro ao obter cliente Firestore")
+		http.Error(w, "Failed to connect to Firestore", http.StatusInternalServerError)
+		return
+	}
+	defer firestoreClient.Close()
+
+	docIDs, tasks, err := task_services.ListTasks(ctx, firestoreClient, workspaceID)
+	if err != nil {
+		utilities.LogError(err, "ExportTasksHandler: Erro ao buscar tarefas")
+		http.Error(w, "Failed to retrieve tasks", http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("workspace-%d-tasks-%s.%s", workspaceID, time.Now().Format("20060102"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	utilities.LogInfo("ExportTasksHandler: Exportando %d tarefas do workspace %d em %s", len(tasks), workspaceID, format)
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		if err := task_services.WriteTasksCSV(w, docIDs, tasks); err != nil {
+			utilities.LogError(err, "ExportTasksHandler: Erro ao escrever CSV")
+		}
+		return
+	}
+
+	exported := make([]task_services.ExportedTask, len(tasks))
+	for i, task := range tasks {
+		exported[i] = task_services.ExportedTask{ID: docIDs[i], TaskDetailsFirestore: task}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exported)
+}
+
+// ImportTasksHandler importa tarefas em lote a partir de um CSV ou de um array JSON
+// enviado no corpo. Cada linha é validada; linhas inválidas são reportadas e não
+// criadas. Com dry_run=true nada é gravado e apenas o relatório é retornado.
+// As tarefas são criadas por task_services.CreateTask, o mesmo caminho de CreateTaskHandler.
+// Rota: POST /workspace/{workspace_id}/tasks/import?format=csv|json&dry_run=true
+func ImportTasksHandler(w http.ResponseWriter, r *http.Request) {
+	workspaceID, err := getWorkspaceIDFromPath(r)
+	if err != nil {
+		http.Error(w, "Invalid Workspace ID", http.StatusBadRequest)
+		return
+	}
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		// Sem format explícito, decide pelo Content-Type
+		if strings.Contains(r.Header.Get("Content-Type"), "csv") {
+			format = "csv"
+		} else {
+			format = "json"
+		}
+	}
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	requestingUserFirebaseUID := r.Context().Value("userUID").(string)
+	ctx := context.Background()
+
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, "ImportTasksHandler: Erro ao conectar ao PG")
+		http.Error(w, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	isMember, err := models.IsWorkspaceMember(db, requestingUserFirebaseUID, workspaceID)
+	if err != nil || !isMember {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	members, err := models.ListWorkspaceMembers(db, workspaceID)
+	if err != nil {
+		utilities.LogError(err, "ImportTasksHandler: Erro ao buscar membros do workspace")
+		http.Error(w, "Failed to load workspace members", http.StatusInternalServerError)
+		return
+	}
+	memberSet := make(map[string]bool, len(members))
+	for _, member := range members {
+		memberSet[member.UserID] = true
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxTaskImportBytes)
+	defer body.Close()
+	rows, err := task_services.ParseImportFile(body, format, memberSet)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "Import file too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report := TaskImportReport{DryRun: dryRun, Total: len(rows), Errors: []task_services.ImportRow{}}
+	var valid []task_services.ImportRow
+	for _, row := range rows {
+		if len(row.Errors) > 0 {
+			report.Errors = append(report.Errors, row)
+			continue
+		}
+		valid = append(valid, row)
+	}
+	report.Valid = len(valid)
+	report.Failed = len(report.Errors)
+
+	if !dryRun && len(valid) > 0 {
+		firestoreClient, err := firebase.GetFirestoreClient()
+		if err != nil {
+			utilities.LogError(err, "ImportTasksHandler: Erro ao obter cliente Firestore")
+			http.Error(w, "Failed to connect to Firestore", http.StatusInternalServerError)
+			return
+		}
+		defer firestoreClient.Close()
+
+		for _, row := range valid {
+			docID, _, err := task_services.CreateTask(ctx, db, firestoreClient, workspaceID, requestingUserFirebaseUID, row.Input)
+			if err != nil {
+				utilities.LogError(err, fmt.Sprintf("ImportTasksHandler: Erro ao criar tarefa da linha %d", row.Line))
+				row.Errors = append(row.Errors, "failed to create task")
+				report.Errors = append(report.Errors, row)
+				report.Failed++
+				continue
+			}
+			report.CreatedTaskIDs = append(report.CreatedTaskIDs, docID)
+			report.Created++
+		}
+	}
+
+	utilities.LogInfo("ImportTasksHandler: Workspace %d, %d linhas (%d válidas, %d criadas, dry_run=%t)", workspaceID, report.Total, report.Valid, report.Created, dryRun)
+	w.Header().Set("Content-Type", "application/json")
+	if report.Created > 0 {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(report)
+}
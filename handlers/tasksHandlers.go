@@ -3,17 +3,18 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"projeto-integrador/database"
 	"projeto-integrador/firebase"
 	"projeto-integrador/models"
+	"projeto-integrador/task_services"
 	"projeto-integrador/utilities"
 	"strconv"
 	"time"
 
 	"cloud.google.com/go/firestore" // Para firestore.ServerTimestamp se usado
-	"github.com/google/uuid"        // Para gerar IDs únicos para o Firestore
 	"github.com/gorilla/mux"
 
 	// ... seus outros imports ...
@@ -74,74 +75,33 @@ func CreateTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if !isMember {
-		utilities.LogInfo(fmt.Sprintf("CreateTaskHandler: Usuário %s não autorizado no workspace %d", requestingUserFirebaseUID, workspaceID))
+		utilities.LogInfo("CreateTaskHandler: Usuário %s não autorizado no workspace %d", requestingUserFirebaseUID, workspaceID)
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
 
-	// Obter o users.id (inteiro) do criador para o stub PG
-	var creatorUserIDPg int64
-	err = db.QueryRow("SELECT id FROM users WHERE firebase_uid = $1", requestingUserFirebaseUID).Scan(&creatorUserIDPg)
-	if err != nil {
-		utilities.LogError(err, "CreateTaskHandler: Usuário criador não encontrado no PG")
-		http.Error(w, "Authenticated user not found in database", http.StatusInternalServerError)
-		return
-	}
-
-	// 1. Criar documento no Firestore
-	firestoreDocID := uuid.New().String() // Gerar um ID único para o documento Firestore
-	taskDetails := models.TaskDetailsFirestore{
-		Title:              input.Title,
-		Description:        input.Description,
-		Status:             input.Status,
-		Priority:           input.Priority,
-		ExpirationDate:     input.ExpirationDate,
-		Attachment:         input.Attachment, // Assume que o cliente já fez upload e está enviando metadados
-		WorkspaceIDPg:      workspaceID,
-		CreatorFirebaseUID: requestingUserFirebaseUID,
-		CreatedAt:          time.Now(), // Ou use firestore.ServerTimestamp se a struct suportar
-		LastUpdatedAt:      time.Now(), // Ou use firestore.ServerTimestamp
-	}
-
-	// O caminho no Firestore será /workspaces/{postgres_workspace_id}/tasks/{firestore_task_doc_id}
-	// Nota: {postgres_workspace_id} precisa ser uma string no caminho do Firestore.
-	workspaceDocIDForFirestore := strconv.FormatInt(workspaceID, 10)
 	firestoreClient, err := firebase.GetFirestoreClient() // Função que retorna o cliente Firestore
 	if err != nil {
 		utilities.LogError(err, "CreateTaskHandler: Erro ao obter cliente Firestore")
 		http.Error(w, "Failed to connect to Firestore", http.StatusInternalServerError)
 		return
 	}
-	_, err = firestoreClient.Collection("workspaces").Doc(workspaceDocIDForFirestore).Collection(tasksSubCollection).Doc(firestoreDocID).Set(ctx, taskDetails)
-	if err != nil {
-		utilities.LogError(err, "CreateTaskHandler: Erro ao criar tarefa no Firestore")
-		http.Error(w, "Failed to create task details", http.StatusInternalServerError)
-		return
-	}
 
-	// 2. Criar stub no PostgreSQL
-	taskStub := models.TarefaStub{
-		FirestoreDocID: firestoreDocID,
-		WorkspaceID:    workspaceID,
-		CriadoPor:      creatorUserIDPg,
-		// CreatedAt e UpdatedAt terão default no PG
-	}
-	_, err = db.Exec(`INSERT INTO tarefas (firestore_doc_id, workspace_id, criado_por) VALUES ($1, $2, $3)`,
-		taskStub.FirestoreDocID, taskStub.WorkspaceID, taskStub.CriadoPor)
+	// Cria o documento no Firestore e o stub no PG (com compensação em caso de falha)
+	firestoreDocID, _, err := task_services.CreateTask(ctx, db, firestoreClient, workspaceID, requestingUserFirebaseUID, input)
 	if err != nil {
-		utilities.LogError(err, "CreateTaskHandler: Erro ao criar stub da tarefa no PG")
-		// Tentar reverter a criação no Firestore (lógica de compensação)
-		_, delErr := firestoreClient.Collection("workspaces").Doc(workspaceDocIDForFirestore).Collection(tasksSubCollection).Doc(firestoreDocID).Delete(ctx)
-		if delErr != nil {
-			utilities.LogError(delErr, "CreateTaskHandler: FALHA AO REVERTER criação no Firestore após erro no PG")
+		if errors.Is(err, task_services.ErrInvalidTask) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
-		http.Error(w, "Failed to create task record", http.StatusInternalServerError)
+		utilities.LogError(err, "CreateTaskHandler: Erro ao criar tarefa")
+		http.Error(w, "Failed to create task", http.StatusInternalServerError)
 		return
 	}
 
 	// Retornar os detalhes completos da tarefa (do Firestore) ou apenas uma confirmação
 	// Para consistência, vamos buscar do Firestore o que foi salvo
-	createdTaskDoc, err := firestoreClient.Collection("workspaces").Doc(workspaceDocIDForFirestore).Collection(tasksSubCollection).Doc(firestoreDocID).Get(ctx)
+	finalTaskData, err := task_services.GetTask(ctx, firestoreClient, workspaceID, firestoreDocID)
 	if err != nil {
 		utilities.LogError(err, "CreateTaskHandler: Erro ao buscar tarefa recém-criada do Firestore para resposta")
 		// Ainda assim, a criação foi um sucesso, então podemos retornar 201 sem o corpo completo
@@ -150,10 +110,7 @@ func CreateTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var finalTaskData models.TaskDetailsFirestore
-	createdTaskDoc.DataTo(&finalTaskData)
-
-	utilities.LogInfo(fmt.Sprintf("CreateTaskHandler: Tarefa %s criada no workspace %d", firestoreDocID, workspaceID))
+	utilities.LogInfo("CreateTaskHandler: Tarefa %s criada no workspace %d", firestoreDocID, workspaceID)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(finalTaskData)
@@ -219,7 +176,7 @@ func ListTasksHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Se o loop terminar normalmente (incluindo o caso de não haver tarefas),
 	// 'tasks' será um array vazio ou conterá as tarefas encontradas.
-	utilities.LogInfo(fmt.Sprintf("ListTasksHandler: %d tarefas encontradas para o workspace %d", len(tasks), workspaceID))
+	utilities.LogInfo("ListTasksHandler: %d tarefas encontradas para o workspace %d", len(tasks), workspaceID)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(tasks) // Retorna a lista de tarefas (pode ser vazia)
 
@@ -277,16 +234,25 @@ func GetTaskHandler(w http.ResponseWriter, r *http.Request) {
 	// Adicionar o ID do documento à resposta se não estiver na struct
 	// Se TaskDetailsFirestore não tiver um campo ID, podemos retornar um map ou uma struct de resposta:
 	response := map[string]interface{}{
-		"id":                 taskDocID,
-		"title":              taskData.Title,
-		"description":        taskData.Description,
-		"status":             taskData.Status,
-		"priority":           taskData.Priority,
-		"expirationDate":     taskData.ExpirationDate,
-		"attachment":         taskData.Attachment,
-		"creatorFirebaseUid": taskData.CreatorFirebaseUID,
-		"createdAt":          taskData.CreatedAt,
-		"lastUpdatedAt":      taskData.LastUpdatedAt,
+		"id":                  taskDocID,
+		"title":               taskData.Title,
+		"description":         taskData.Description,
+		"status":              taskData.Status,
+		"priority":            taskData.Priority,
+		"expirationDate":      taskData.ExpirationDate,
+		"attachment":          taskData.Attachment,
+		"creatorFirebaseUid":  taskData.CreatorFirebaseUID,
+		"assigneeFirebaseUid": taskData.AssigneeFirebaseUID,
+		"labels":              taskData.Labels,
+		"checklist":           taskData.Checklist,
+		"createdAt":           taskData.CreatedAt,
+		"lastUpdatedAt":       taskData.LastUpdatedAt,
+		"isOverdue":           taskData.IsOverdue,
+		"completedAt":         taskData.CompletedAt,
+		"recurrence":          taskData.Recurrence,
+		"seriesId":            taskData.SeriesID,
+		"occurrenceIndex":     taskData.OccurrenceIndex,
+		"nextOccurrenceId":    taskData.NextOccurrenceID,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -345,9 +311,30 @@ func UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
 	if input.ExpirationDate != nil { // Se for para permitir desmarcar, precisa de lógica especial
 		updates = append(updates, firestore.Update{Path: "expiration_date", Value: input.ExpirationDate})
 	}
+	// Prazo adiado ou tarefa concluída deixam de estar atrasados; o scheduler de lembretes remarca se necessário
+	if (input.ExpirationDate != nil && input.ExpirationDate.After(time.Now())) || (input.Status != nil && *input.Status == "completed") {
+		updates = append(updates, firestore.Update{Path: "is_overdue", Value: false})
+		updates = append(updates, firestore.Update{Path: "overdue_since", Value: firestore.Delete})
+	}
 	if input.Attachment != nil { // Atualizar anexo é mais complexo (deletar antigo do storage?)
 		updates = append(updates, firestore.Update{Path: "attachment", Value: input.Attachment})
 	}
+	if input.AssigneeFirebaseUID != nil {
+		if *input.AssigneeFirebaseUID != "" {
+			isAssigneeMember, err := models.IsWorkspaceMember(db, *input.AssigneeFirebaseUID, workspaceID)
+			if err != nil || !isAssigneeMember {
+				http.Error(w, "Assignee is not a member of the workspace", http.StatusBadRequest)
+				return
+			}
+		}
+		updates = append(updates, firestore.Update{Path: "assignee_firebase_uid", Value: *input.AssigneeFirebaseUID})
+	}
+	if input.Labels != nil {
+		updates = append(updates, firestore.Update{Path: "labels", Value: *input.Labels})
+	}
+	if input.Checklist != nil {
+		updates = append(updates, firestore.Update{Path: "checklist", Value: *input.Checklist})
+	}
 
 	if len(updates) == 0 {
 		http.Error(w, "No fields to update", http.StatusBadRequest)
@@ -364,6 +351,25 @@ func UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Conclusão: registrar quem/quando concluiu e, se a tarefa for recorrente, gerar a próxima ocorrência
+	var completedTask *models.TaskDetailsFirestore
+	if input.Status != nil && *input.Status == "completed" {
+		current, err := task_services.GetTask(ctx, firestoreClient, workspaceID, taskDocID)
+		if err != nil {
+			utilities.LogError(err, "UpdateTaskHandler: Erro ao buscar tarefa antes da conclusão")
+			http.Error(w, "Task not found or error fetching", http.StatusNotFound)
+			return
+		}
+		if current.Status != "completed" {
+			updates = append(updates, firestore.Update{Path: "completed_at", Value: time.Now()})
+			updates = append(updates, firestore.Update{Path: "completed_by_firebase_uid", Value: requestingUserFirebaseUID})
+			completedTask = current
+		}
+	} else if input.Status != nil {
+		updates = append(updates, firestore.Update{Path: "completed_at", Value: firestore.Delete})
+		updates = append(updates, firestore.Update{Path: "completed_by_firebase_uid", Value: firestore.Delete})
+	}
+
 	workspaceDocIDForFirestore := strconv.FormatInt(workspaceID, 10)
 	taskRef := firestoreClient.Collection("workspaces").Doc(workspaceDocIDForFirestore).Collection(tasksSubCollection).Doc(taskDocID)
 	_, err = taskRef.Update(ctx, updates)
@@ -372,6 +378,19 @@ func UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to update task", http.StatusInternalServerError)
 		return
 	}
+	task_services.NotifyTaskChanged(workspaceID, taskDocID)
+
+	response := map[string]string{"message": "Task updated successfully"}
+	if completedTask != nil && completedTask.Recurrence != nil {
+		// A próxima ocorrência herda as edições feitas nesta mesma requisição
+		applyTaskInput(completedTask, input)
+		nextID, err := task_services.CreateNextOccurrence(ctx, db, firestoreClient, taskDocID, completedTask)
+		if err != nil {
+			utilities.LogError(err, fmt.Sprintf("UpdateTaskHandler: Erro ao gerar próxima ocorrência da tarefa %s", taskDocID))
+		} else if nextID != "" {
+			response["next_occurrence_id"] = nextID
+		}
+	}
 
 	// Atualizar o updated_at no stub do PostgreSQL
 	_, err = db.Exec("UPDATE tarefas SET updated_at = NOW() WHERE firestore_doc_id = $1 AND workspace_id = $2", taskDocID, workspaceID)
@@ -380,9 +399,31 @@ func UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
 		// A atualização no Firestore foi bem-sucedida, mas o stub PG não. Logar, mas não necessariamente reverter.
 	}
 
-	utilities.LogInfo(fmt.Sprintf("UpdateTaskHandler: Tarefa %s atualizada no workspace %d", taskDocID, workspaceID))
+	utilities.LogInfo("UpdateTaskHandler: Tarefa %s atualizada no workspace %d", taskDocID, workspaceID)
 	w.WriteHeader(http.StatusOK) // Ou retornar o documento atualizado
-	json.NewEncoder(w).Encode(map[string]string{"message": "Task updated successfully"})
+	json.NewEncoder(w).Encode(response)
+}
+
+// applyTaskInput aplica em memória os campos informados em um UpdateTaskInput.
+func applyTaskInput(task *models.TaskDetailsFirestore, input models.UpdateTaskInput) {
+	if input.Title != nil {
+		task.Title = *input.Title
+	}
+	if input.Description != nil {
+		task.Description = *input.Description
+	}
+	if input.Priority != nil {
+		task.Priority = *input.Priority
+	}
+	if input.ExpirationDate != nil {
+		task.ExpirationDate = input.ExpirationDate
+	}
+	if input.AssigneeFirebaseUID != nil {
+		task.AssigneeFirebaseUID = *input.AssigneeFirebaseUID
+	}
+	if input.Labels != nil {
+		task.Labels = *input.Labels
+	}
 }
 
 // DeleteTaskHandler deleta uma tarefa (do Firestore e o stub do PG)
@@ -428,6 +469,7 @@ func DeleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to delete task from primary store", http.StatusInternalServerError)
 		return
 	}
+	task_services.NotifyTaskDeleted(workspaceID, taskDocID)
 
 	// 2. Deletar stub do PostgreSQL
 	result, err := db.Exec("DELETE FROM tarefas WHERE firestore_doc_id = $1 AND workspace_id = $2", taskDocID, workspaceID)
@@ -439,10 +481,10 @@ func DeleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
-		utilities.LogInfo(fmt.Sprintf("DeleteTaskHandler: Stub da tarefa %s não encontrado no PG para workspace %d (ou já deletado)", taskDocID, workspaceID))
+		utilities.LogInfo("DeleteTaskHandler: Stub da tarefa %s não encontrado no PG para workspace %d (ou já deletado)", taskDocID, workspaceID)
 		// Isso pode ser OK se o Firestore foi a fonte principal da deleção.
 	}
 
-	utilities.LogInfo(fmt.Sprintf("DeleteTaskHandler: Tarefa %s deletada do workspace %d", taskDocID, workspaceID))
+	utilities.LogInfo("DeleteTaskHandler: Tarefa %s deletada do workspace %d", taskDocID, workspaceID)
 	w.WriteHeader(http.StatusNoContent)
 }
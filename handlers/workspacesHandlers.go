@@ -269,7 +269,7 @@ func DeleteWorkspaceHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := context.Background()       // Use o contexto apropriado para sua aplicação
 	tasksSubCollectionName := "tasks" // Nome da subcoleção de tarefas no Firestore
 
-	utilities.LogInfo(fmt.Sprintf("DeleteWorkspaceHandler: Iniciando deleção do workspace %d (Firestore ID: %s) do Firestore.", workspaceID, workspaceIDStr))
+	utilities.LogInfo("DeleteWorkspaceHandler: Iniciando deleção do workspace %d (Firestore ID: %s) do Firestore.", workspaceID, workspaceIDStr)
 	err = firebase.DeleteWorkspaceAndSubcollectionsFromFirestore(tasksSubCollectionName, ctx, firestoreClient, workspaceID) // Passa o workspaceID (int64)
 	if err != nil {
 		// Se a deleção no Firestore falhar, você precisa decidir se continua com a deleção no PG.
@@ -280,7 +280,7 @@ func DeleteWorkspaceHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to delete workspace data from secondary store. Aborting.", http.StatusInternalServerError)
 		return
 	}
-	utilities.LogInfo(fmt.Sprintf("DeleteWorkspaceHandler: Workspace %d e suas tarefas deletados do Firestore.", workspaceID))
+	utilities.LogInfo("DeleteWorkspaceHandler: Workspace %d e suas tarefas deletados do Firestore.", workspaceID)
 
 	db, err := database.ConnectPostgres()
 	if err != nil {
@@ -416,7 +416,7 @@ func AddUserToWorkspaceHandler(w http.ResponseWriter, r *http.Request) {
 	err = models.AddUserToWorkspace(db, workspaceID, input.Email, input.Role)
 	if err != nil {
 		if strings.Contains(err.Error(), "já é membro") || strings.Contains(err.Error(), "usuário com email") || strings.Contains(err.Error(), "não encontrado") {
-			utilities.LogInfo(fmt.Sprintf("AddUserToWorkspaceHandler: Falha ao adicionar usuário %s ao workspace %d: %s", input.Email, workspaceID, err.Error()))
+			utilities.LogInfo("AddUserToWorkspaceHandler: Falha ao adicionar usuário %s ao workspace %d: %s", input.Email, workspaceID, err.Error())
 			http.Error(w, err.Error(), http.StatusBadRequest) // Erro do cliente se usuário não existe ou já é membro
 		} else {
 			utilities.LogError(err, fmt.Sprintf("AddUserToWorkspaceHandler: Erro ao adicionar usuário %s ao workspace %d", input.Email, workspaceID))
@@ -488,7 +488,7 @@ func RemoveUserFromWorkspaceHandler(w http.ResponseWriter, r *http.Request) {
 	workspace, err := models.GetWorkspaceInfo(db, workspaceID)
 	if err != nil {
 		if err.Error() == "workspace not found" {
-			utilities.LogInfo(fmt.Sprintf("RemoveUserFromWorkspaceHandler: Workspace %d não encontrado", workspaceID))
+			utilities.LogInfo("RemoveUserFromWorkspaceHandler: Workspace %d não encontrado", workspaceID)
 			http.Error(w, "Workspace not found", http.StatusNotFound)
 		} else {
 			utilities.LogError(err, fmt.Sprintf("RemoveUserFromWorkspaceHandler: Erro ao buscar workspace %d", workspaceID))
@@ -499,7 +499,7 @@ func RemoveUserFromWorkspaceHandler(w http.ResponseWriter, r *http.Request) {
 
 	if memberFirebaseUID == workspace.OwnerUID {
 		// Usando utilities.LogWarn ou similar se você tiver diferentes níveis de log
-		utilities.LogInfo(fmt.Sprintf("RemoveUserFromWorkspaceHandler: Usuário %s tentou remover o dono (%s) do workspace %d", requestingUserUID, memberFirebaseUID, workspaceID))
+		utilities.LogInfo("RemoveUserFromWorkspaceHandler: Usuário %s tentou remover o dono (%s) do workspace %d", requestingUserUID, memberFirebaseUID, workspaceID)
 		http.Error(w, "Cannot remove the workspace owner", http.StatusBadRequest)
 		return
 	}
@@ -510,7 +510,7 @@ func RemoveUserFromWorkspaceHandler(w http.ResponseWriter, r *http.Request) {
 	if !isOwner && !isSelfRemoval {
 		// Aqui você poderia adicionar uma verificação se o requestingUserUID é um 'admin' do workspace
 		// usando uma função como models.GetUserRoleInWorkspace(db, requestingUserUID, workspaceID)
-		utilities.LogInfo(fmt.Sprintf("RemoveUserFromWorkspaceHandler: Usuário %s não autorizado a remover membro %s do workspace %d", requestingUserUID, memberFirebaseUID, workspaceID))
+		utilities.LogInfo("RemoveUserFromWorkspaceHandler: Usuário %s não autorizado a remover membro %s do workspace %d", requestingUserUID, memberFirebaseUID, workspaceID)
 		http.Error(w, "Forbidden: Only workspace owner can remove other members, or user can remove self", http.StatusForbidden)
 		return
 	}
@@ -522,7 +522,7 @@ func RemoveUserFromWorkspaceHandler(w http.ResponseWriter, r *http.Request) {
 		if strings.Contains(errMsg, "user not found in workspace") ||
 			strings.Contains(errMsg, "usuário não encontrado no sistema") ||
 			strings.Contains(errMsg, "já removido") {
-			utilities.LogInfo(fmt.Sprintf("RemoveUserFromWorkspaceHandler: Falha ao remover usuário %s do workspace %d: %s", memberFirebaseUID, workspaceID, errMsg))
+			utilities.LogInfo("RemoveUserFromWorkspaceHandler: Falha ao remover usuário %s do workspace %d: %s", memberFirebaseUID, workspaceID, errMsg)
 			http.Error(w, errMsg, http.StatusNotFound) // Ou http.StatusBadRequest dependendo do caso
 		} else {
 			utilities.LogError(err, fmt.Sprintf("RemoveUserFromWorkspaceHandler: Erro ao remover usuário %s do workspace %d", memberFirebaseUID, workspaceID))
@@ -543,7 +543,7 @@ func ListUserWorkspacesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utilities.LogInfo(fmt.Sprintf("ListUserWorkspacesHandler: Buscando workspaces para o usuário %s", requestingUserFirebaseUID))
+	utilities.LogInfo("ListUserWorkspacesHandler: Buscando workspaces para o usuário %s", requestingUserFirebaseUID)
 
 	db, err := database.ConnectPostgres()
 	if err != nil {
@@ -593,7 +593,7 @@ func ListUserWorkspacesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utilities.LogInfo(fmt.Sprintf("ListUserWorkspacesHandler: Encontrados %d workspaces para o usuário %s", len(userWorkspaces), requestingUserFirebaseUID))
+	utilities.LogInfo("ListUserWorkspacesHandler: Encontrados %d workspaces para o usuário %s", len(userWorkspaces), requestingUserFirebaseUID)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(userWorkspaces)
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
 	"log"
+	"projeto-integrador/ai_services"
 	"projeto-integrador/database"
+	"projeto-integrador/search_services"
+	"projeto-integrador/task_services"
 
 	"github.com/joho/godotenv"
 )
@@ -18,5 +22,16 @@ func main() {
 	}
 	defer db.Close()
 
+	// Jobs em background
+	ctx := context.Background()
+	task_services.StartImportJobSweeper(ctx)
+	task_services.StartReminderScheduler(ctx)
+	ai_services.StartAIJobWorkers(ctx)
+	ai_services.StartAIHistoryRetention(ctx)
+	ai_services.StartAICacheCleanup(ctx)
+	ai_services.StartStatusReportScheduler(ctx)
+	search_services.SetEmbeddingRedactor(ai_services.RedactEmbeddingTexts)
+	search_services.StartSearchIndexer(ctx)
+
 	LoadRoutes()
 }
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// GetCachedAIResponse busca uma resposta de IA ainda válida no cache do PostgreSQL
+// (tabela ai_response_cache). found é false se não houver ou se tiver expirado.
+func GetCachedAIResponse(db *sql.DB, cacheKey string) (response []byte, expiresAt time.Time, found bool, err error) {
+	err = db.QueryRow(`
+		SELECT response, expires_at FROM ai_response_cache
+		WHERE cache_key = $1 AND expires_at > NOW()
+	`, cacheKey).Scan(&response, &expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, time.Time{}, false, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, false, fmt.Errorf("erro ao buscar resposta de IA no cache: %w", err)
+	}
+	return response, expiresAt, true, nil
+}
+
+// SaveCachedAIResponse grava (ou substitui) uma resposta de IA no cache do PostgreSQL.
+func SaveCachedAIResponse(db *sql.DB, cacheKey string, serviceType string, response []byte, expiresAt time.Time) error {
+	_, err := db.Exec(`
+		INSERT INTO ai_response_cache (cache_key, service_type, response, created_at, expires_at)
+		VALUES ($1, $2, $3, NOW(), $4)
+		ON CONFLICT (cache_key) DO UPDATE SET
+			response = EXCLUDED.response,
+			created_at = NOW(),
+			expires_at = EXCLUDED.expires_at
+	`, cacheKey, serviceType, response, expiresAt)
+	if err != nil {
+		return fmt.Errorf("erro ao salvar resposta de IA no cache: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredAIResponses remove as respostas expiradas do cache do PostgreSQL e
+// retorna quantas foram removidas.
+func DeleteExpiredAIResponses(db *sql.DB) (int64, error) {
+	result, err := db.Exec(`DELETE FROM ai_response_cache WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao limpar cache de respostas de IA: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n, nil
+}
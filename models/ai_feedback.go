@@ -0,0 +1,214 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Avaliações de uma resposta de IA (AIFeedback.Rating).
+const (
+	AIFeedbackUp   = "up"
+	AIFeedbackDown = "down"
+)
+
+// Intervalos da série temporal do relatório de avaliações.
+const (
+	AIFeedbackIntervalDay   = "day"
+	AIFeedbackIntervalWeek  = "week"
+	AIFeedbackIntervalMonth = "month"
+)
+
+// ErrAIFeedbackNotFound indica que o usuário não avaliou a entrada do histórico.
+var ErrAIFeedbackNotFound = errors.New("ai feedback not found")
+
+// AIFeedback é a avaliação de um usuário sobre uma resposta de IA registrada no
+// histórico (tabela ai_feedback).
+type AIFeedback struct {
+	WorkspaceID int64     `json:"workspace_id"`
+	HistoryID   string    `json:"history_id"`
+	UserID      string    `json:"user_id"` // Firebase UID de quem avaliou
+	ServiceType string    `json:"service_type"`
+	Rating      string    `json:"rating"` // "up" ou "down"
+	Comment     string    `json:"comment,omitempty"`
+	Applied     *bool     `json:"applied"`      // A resposta foi usada? nil = não respondido
+	RequestedAt time.Time `json:"requested_at"` // Data da requisição à IA
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// AIFeedbackStats são os totais de avaliações de um serviço ou de um período.
+type AIFeedbackStats struct {
+	Total            int64   `json:"total"`
+	Up               int64   `json:"up"`
+	Down             int64   `json:"down"`
+	SatisfactionRate float64 `json:"satisfaction_rate"` // up / total
+	Applied          int64   `json:"applied"`
+	NotApplied       int64   `json:"not_applied"`
+	AppliedRate      float64 `json:"applied_rate"` // applied / (applied + not_applied)
+	WithComment      int64   `json:"with_comment"`
+}
+
+// AIFeedbackPeriod são as avaliações das requisições feitas em um período da série.
+type AIFeedbackPeriod struct {
+	PeriodStart time.Time `json:"period_start"`
+	AIFeedbackStats
+}
+
+// AIServiceFeedback são as avaliações de um serviço de IA, no total e por período.
+type AIServiceFeedback struct {
+	ServiceType string `json:"service_type"`
+	AIFeedbackStats
+	Series []AIFeedbackPeriod `json:"series"`
+}
+
+// AIFeedbackComment é um comentário recente do relatório de avaliações.
+type AIFeedbackComment struct {
+	HistoryID   string    `json:"history_id"`
+	ServiceType string    `json:"service_type"`
+	Rating      string    `json:"rating"`
+	Comment     string    `json:"comment"`
+	UserID      string    `json:"user_id"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// AIFeedbackReport agrega as avaliações das requisições de IA feitas no período.
+type AIFeedbackReport struct {
+	WorkspaceID    int64               `json:"workspace_id"`
+	Since          time.Time           `json:"since"`
+	Until          time.Time           `json:"until"`
+	Interval       string              `json:"interval"` // "day", "week" ou "month"
+	Overall        AIFeedbackStats     `json:"overall"`
+	Services       []AIServiceFeedback `json:"services"`
+	RecentComments []AIFeedbackComment `json:"recent_comments"`
+}
+
+// AIFeedbackRow são os totais de avaliações de um serviço em um período da série.
+type AIFeedbackRow struct {
+	ServiceType string
+	PeriodStart time.Time
+	AIFeedbackStats
+}
+
+// SaveAIFeedback grava (ou substitui) a avaliação do usuário sobre a entrada do histórico.
+func SaveAIFeedback(db *sql.DB, feedback AIFeedback) (*AIFeedback, error) {
+	err := db.QueryRow(`
+		INSERT INTO ai_feedback (workspace_id, history_id, user_id, service_type, rating, comment, applied, requested_at)
+		VALUES ($1, $2, (SELECT id FROM users WHERE firebase_uid = $3), $4, $5, $6, $7, $8)
+		ON CONFLICT (workspace_id, history_id, user_id) DO UPDATE SET
+			rating = EXCLUDED.rating,
+			comment = EXCLUDED.comment,
+			applied = EXCLUDED.applied,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING created_at, updated_at
+	`, feedback.WorkspaceID, feedback.HistoryID, feedback.UserID, feedback.ServiceType,
+		feedback.Rating, feedback.Comment, feedback.Applied, feedback.RequestedAt).Scan(&feedback.CreatedAt, &feedback.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao salvar avaliação do histórico de IA %s: %w", feedback.HistoryID, err)
+	}
+	return &feedback, nil
+}
+
+// GetAIFeedback retorna a avaliação do usuário sobre a entrada do histórico.
+func GetAIFeedback(db *sql.DB, workspaceID int64, historyID string, userFirebaseUID string) (*AIFeedback, error) {
+	feedback := AIFeedback{WorkspaceID: workspaceID, HistoryID: historyID, UserID: userFirebaseUID}
+	var applied sql.NullBool
+	err := db.QueryRow(`
+		SELECT f.service_type, f.rating, f.comment, f.applied, f.requested_at, f.created_at, f.updated_at
+		FROM ai_feedback f
+		JOIN users u ON u.id = f.user_id
+		WHERE f.workspace_id = $1 AND f.history_id = $2 AND u.firebase_uid = $3
+	`, workspaceID, historyID, userFirebaseUID).Scan(&feedback.ServiceType, &feedback.Rating, &feedback.Comment,
+		&applied, &feedback.RequestedAt, &feedback.CreatedAt, &feedback.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrAIFeedbackNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar avaliação do histórico de IA %s: %w", historyID, err)
+	}
+	if applied.Valid {
+		feedback.Applied = &applied.Bool
+	}
+	return &feedback, nil
+}
+
+// DeleteAIFeedback remove a avaliação do usuário sobre a entrada do histórico.
+func DeleteAIFeedback(db *sql.DB, workspaceID int64, historyID string, userFirebaseUID string) error {
+	result, err := db.Exec(`
+		DELETE FROM ai_feedback
+		WHERE workspace_id = $1 AND history_id = $2
+			AND user_id = (SELECT id FROM users WHERE firebase_uid = $3)
+	`, workspaceID, historyID, userFirebaseUID)
+	if err != nil {
+		return fmt.Errorf("erro ao remover avaliação do histórico de IA %s: %w", historyID, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrAIFeedbackNotFound
+	}
+	return nil
+}
+
+// LoadAIFeedbackRows agrega as avaliações das requisições feitas em [since, until) por
+// serviço e período (interval: "day", "week" ou "month", em UTC). serviceType vazio
+// inclui todos os serviços.
+func LoadAIFeedbackRows(db *sql.DB, workspaceID int64, since time.Time, until time.Time, serviceType string, interval string) ([]AIFeedbackRow, error) {
+	rows, err := db.Query(`
+		SELECT service_type, date_trunc($5, requested_at AT TIME ZONE 'UTC'),
+			COUNT(*),
+			COUNT(*) FILTER (WHERE rating = 'up'),
+			COUNT(*) FILTER (WHERE rating = 'down'),
+			COUNT(*) FILTER (WHERE applied),
+			COUNT(*) FILTER (WHERE NOT applied),
+			COUNT(*) FILTER (WHERE comment <> '')
+		FROM ai_feedback
+		WHERE workspace_id = $1 AND requested_at >= $2 AND requested_at < $3
+			AND ($4 = '' OR service_type = $4)
+		GROUP BY 1, 2
+		ORDER BY 1, 2
+	`, workspaceID, since, until, serviceType, interval)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao agregar avaliações de IA do workspace %d: %w", workspaceID, err)
+	}
+	defer rows.Close()
+
+	var result []AIFeedbackRow
+	for rows.Next() {
+		var row AIFeedbackRow
+		if err := rows.Scan(&row.ServiceType, &row.PeriodStart, &row.Total, &row.Up, &row.Down,
+			&row.Applied, &row.NotApplied, &row.WithComment); err != nil {
+			return nil, fmt.Errorf("erro ao ler avaliações de IA: %w", err)
+		}
+		row.PeriodStart = row.PeriodStart.UTC()
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// ListRecentAIFeedbackComments retorna os comentários mais recentes das avaliações das
+// requisições feitas em [since, until).
+func ListRecentAIFeedbackComments(db *sql.DB, workspaceID int64, since time.Time, until time.Time, serviceType string, limit int) ([]AIFeedbackComment, error) {
+	rows, err := db.Query(`
+		SELECT f.history_id, f.service_type, f.rating, f.comment, COALESCE(u.firebase_uid, ''), f.updated_at
+		FROM ai_feedback f
+		LEFT JOIN users u ON u.id = f.user_id
+		WHERE f.workspace_id = $1 AND f.requested_at >= $2 AND f.requested_at < $3
+			AND ($4 = '' OR f.service_type = $4) AND f.comment <> ''
+		ORDER BY f.updated_at DESC
+		LIMIT $5
+	`, workspaceID, since, until, serviceType, limit)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar comentários de avaliações de IA do workspace %d: %w", workspaceID, err)
+	}
+	defer rows.Close()
+
+	comments := []AIFeedbackComment{}
+	for rows.Next() {
+		var comment AIFeedbackComment
+		if err := rows.Scan(&comment.HistoryID, &comment.ServiceType, &comment.Rating, &comment.Comment, &comment.UserID, &comment.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("erro ao ler comentário de avaliação de IA: %w", err)
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
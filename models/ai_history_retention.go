@@ -0,0 +1,127 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Modos da política de retenção do histórico de IA.
+const (
+	AIHistoryRetentionTruncate = "truncate" // Remove os payloads e mantém os metadados
+	AIHistoryRetentionDelete   = "delete"   // Apaga a entrada
+)
+
+// AIHistoryRetention é a política de retenção do ai_request_history de um workspace
+// (tabela ai_history_retention). Workspaces sem configuração própria usam o padrão do
+// servidor (IsDefault).
+type AIHistoryRetention struct {
+	WorkspaceID   int64      `json:"workspace_id"`
+	RetentionDays int        `json:"retention_days"` // 0 = manter para sempre
+	Mode          string     `json:"mode"`           // "truncate" ou "delete"
+	IsDefault     bool       `json:"is_default"`
+	UpdatedBy     string     `json:"updated_by,omitempty"` // Firebase UID de quem configurou
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+	PurgedBefore  *time.Time `json:"purged_before,omitempty"` // Entradas anteriores a esta data já foram processadas
+}
+
+// ListAIHistoryRetentions retorna a política de cada workspace, usando defaultDays e
+// defaultMode para os que não têm configuração própria.
+func ListAIHistoryRetentions(db *sql.DB, defaultDays int, defaultMode string) ([]AIHistoryRetention, error) {
+	rows, err := db.Query(`
+		SELECT w.id, r.retention_days, r.mode, u.firebase_uid, r.updated_at, r.purged_before
+		FROM workspaces w
+		LEFT JOIN ai_history_retention r ON r.workspace_id = w.id
+		LEFT JOIN users u ON u.id = r.updated_by
+		ORDER BY w.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar políticas de retenção do histórico de IA: %w", err)
+	}
+	defer rows.Close()
+
+	var policies []AIHistoryRetention
+	for rows.Next() {
+		policy, err := scanAIHistoryRetention(rows, defaultDays, defaultMode)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, *policy)
+	}
+	return policies, rows.Err()
+}
+
+// GetAIHistoryRetention retorna a política do workspace (ou o padrão).
+func GetAIHistoryRetention(db *sql.DB, workspaceID int64, defaultDays int, defaultMode string) (*AIHistoryRetention, error) {
+	row := db.QueryRow(`
+		SELECT $1::int, r.retention_days, r.mode, u.firebase_uid, r.updated_at, r.purged_before
+		FROM (SELECT 1) AS one
+		LEFT JOIN ai_history_retention r ON r.workspace_id = $1
+		LEFT JOIN users u ON u.id = r.updated_by
+	`, workspaceID)
+	return scanAIHistoryRetention(row, defaultDays, defaultMode)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAIHistoryRetention(row rowScanner, defaultDays int, defaultMode string) (*AIHistoryRetention, error) {
+	var policy AIHistoryRetention
+	var days sql.NullInt64
+	var mode, updatedBy sql.NullString
+	var updatedAt, purgedBefore sql.NullTime
+	if err := row.Scan(&policy.WorkspaceID, &days, &mode, &updatedBy, &updatedAt, &purgedBefore); err != nil {
+		return nil, fmt.Errorf("erro ao ler política de retenção do histórico de IA: %w", err)
+	}
+	policy.RetentionDays, policy.Mode = defaultDays, defaultMode
+	policy.IsDefault = !days.Valid && !mode.Valid
+	if days.Valid {
+		policy.RetentionDays = int(days.Int64)
+	}
+	if mode.Valid {
+		policy.Mode = mode.String
+	}
+	policy.UpdatedBy = updatedBy.String
+	if updatedAt.Valid {
+		policy.UpdatedAt = &updatedAt.Time
+	}
+	if purgedBefore.Valid {
+		policy.PurgedBefore = &purgedBefore.Time
+	}
+	return &policy, nil
+}
+
+// SetAIHistoryRetention grava a política do workspace. retentionDays ou mode nil voltam
+// a usar o padrão do servidor. Como a nova política pode alcançar entradas que a anterior
+// não alcançava, o progresso (purged_before) é reiniciado.
+func SetAIHistoryRetention(db *sql.DB, workspaceID int64, updatedByFirebaseUID string, retentionDays *int, mode *string) error {
+	_, err := db.Exec(`
+		INSERT INTO ai_history_retention (workspace_id, retention_days, mode, updated_by, updated_at, purged_before)
+		VALUES ($1, $2, $3, (SELECT id FROM users WHERE firebase_uid = $4), NOW(), NULL)
+		ON CONFLICT (workspace_id) DO UPDATE SET
+			retention_days = EXCLUDED.retention_days,
+			mode = EXCLUDED.mode,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW(),
+			purged_before = NULL
+	`, workspaceID, retentionDays, mode, updatedByFirebaseUID)
+	if err != nil {
+		return fmt.Errorf("erro ao salvar política de retenção do histórico de IA do workspace %d: %w", workspaceID, err)
+	}
+	return nil
+}
+
+// MarkAIHistoryPurged registra que as entradas do workspace anteriores a before já
+// foram processadas pela política atual, para a próxima execução começar dali.
+func MarkAIHistoryPurged(db *sql.DB, workspaceID int64, before time.Time) error {
+	_, err := db.Exec(`
+		INSERT INTO ai_history_retention (workspace_id, purged_before)
+		VALUES ($1, $2)
+		ON CONFLICT (workspace_id) DO UPDATE SET purged_before = EXCLUDED.purged_before
+	`, workspaceID, before)
+	if err != nil {
+		return fmt.Errorf("erro ao registrar retenção do histórico de IA do workspace %d: %w", workspaceID, err)
+	}
+	return nil
+}
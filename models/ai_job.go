@@ -0,0 +1,270 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Estados de um job de IA.
+const (
+	AIJobQueued    = "queued"
+	AIJobRunning   = "running"
+	AIJobCompleted = "completed"
+	AIJobFailed    = "failed"
+	AIJobCanceled  = "canceled"
+)
+
+var (
+	// ErrAIJobNotFound indica um job inexistente, de outro workspace ou de outro usuário.
+	ErrAIJobNotFound = errors.New("ai job not found")
+	// ErrAIJobFinished indica que o job já terminou e não pode mais ser cancelado.
+	ErrAIJobFinished = errors.New("ai job already finished")
+)
+
+// AIJob é uma requisição de IA assíncrona registrada no PostgreSQL. Os jobs ficam na
+// tabela até terminar, então sobrevivem a reinícios do servidor.
+type AIJob struct {
+	ID           int64           `json:"id"`
+	WorkspaceID  int64           `json:"workspace_id"`
+	ServiceType  string          `json:"service_type"` // "code_review", "text_summary", "mindmap_ideas", "task_assistant"
+	Status       string          `json:"status"`
+	Payload      json.RawMessage `json:"-"`                // Entrada enviada pelo frontend
+	Result       json.RawMessage `json:"result,omitempty"` // Resposta da IA (apenas quando completed)
+	AIStatusCode int             `json:"ai_status_code,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	Attempts     int             `json:"attempts"`
+	CreatedBy    string          `json:"created_by"` // Firebase UID de quem criou
+	CreatedAt    time.Time       `json:"created_at"`
+	StartedAt    *time.Time      `json:"started_at,omitempty"`
+	FinishedAt   *time.Time      `json:"finished_at,omitempty"`
+	NoCache      bool            `json:"no_cache,omitempty"` // Ignora o cache de respostas de IA
+}
+
+// Finished indica se o job chegou a um estado final.
+func (j *AIJob) Finished() bool {
+	switch j.Status {
+	case AIJobCompleted, AIJobFailed, AIJobCanceled:
+		return true
+	}
+	return false
+}
+
+// CreateAIJob coloca uma requisição de IA na fila.
+func CreateAIJob(db *sql.DB, workspaceID int64, creatorFirebaseUID, serviceType string, payload json.RawMessage, noCache bool) (*AIJob, error) {
+	job := AIJob{
+		WorkspaceID: workspaceID,
+		ServiceType: serviceType,
+		Status:      AIJobQueued,
+		Payload:     payload,
+		CreatedBy:   creatorFirebaseUID,
+		NoCache:     noCache,
+	}
+	err := db.QueryRow(`
+		INSERT INTO ai_jobs (workspace_id, created_by, service_type, status, payload, no_cache)
+		SELECT $1, id, $3, $4, $5, $6 FROM users WHERE firebase_uid = $2
+		RETURNING id, created_at
+	`, workspaceID, creatorFirebaseUID, serviceType, AIJobQueued, []byte(payload), noCache).Scan(&job.ID, &job.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar job de IA: %w", err)
+	}
+	return &job, nil
+}
+
+// CountPendingAIJobs conta os jobs do usuário que ainda não terminaram.
+func CountPendingAIJobs(db *sql.DB, firebaseUID string) (int, error) {
+	var count int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM ai_jobs j JOIN users u ON j.created_by = u.id
+		WHERE u.firebase_uid = $1 AND j.status IN ($2, $3)
+	`, firebaseUID, AIJobQueued, AIJobRunning).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao contar jobs de IA pendentes: %w", err)
+	}
+	return count, nil
+}
+
+// ClaimNextAIJob reserva o próximo job disponível para um worker: um job na fila cujo
+// run_after já passou, ou um job "running" cujo lease expirou (o worker que o executava
+// parou). FOR UPDATE SKIP LOCKED permite vários workers e réplicas sem disputa.
+// Retorna nil, nil quando não há job.
+func ClaimNextAIJob(db *sql.DB, lease time.Duration) (*AIJob, error) {
+	row := db.QueryRow(`
+		UPDATE ai_jobs j SET status = $1, attempts = j.attempts + 1,
+			started_at = COALESCE(j.started_at, NOW()),
+			locked_until = NOW() + ($2::int * INTERVAL '1 second')
+		FROM users u
+		WHERE u.id = j.created_by AND j.id = (
+			SELECT id FROM ai_jobs
+			WHERE (status = $3 AND run_after <= NOW()) OR (status = $1 AND locked_until < NOW())
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+aiJobColumns, AIJobRunning, int64(lease/time.Second), AIJobQueued)
+	job, err := scanAIJob(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("erro ao reservar job de IA: %w", err)
+	}
+	return job, nil
+}
+
+// ExtendAIJobLease renova o lease de um job em execução. Retorna false quando o job não
+// está mais em execução (por exemplo, foi cancelado).
+func ExtendAIJobLease(db *sql.DB, jobID int64, lease time.Duration) (bool, error) {
+	result, err := db.Exec(`
+		UPDATE ai_jobs SET locked_until = NOW() + ($2::int * INTERVAL '1 second')
+		WHERE id = $1 AND status = $3
+	`, jobID, int64(lease/time.Second), AIJobRunning)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// CompleteAIJob grava o resultado de um job em execução. Se o job foi cancelado
+// enquanto rodava, o resultado é descartado e false é retornado.
+func CompleteAIJob(db *sql.DB, jobID int64, result json.RawMessage, aiStatusCode int) (bool, error) {
+	return finishRunningAIJob(db, jobID, AIJobCompleted, result, aiStatusCode, "")
+}
+
+// UpdateAIJobResult substitui o resultado de um job concluído.
+func UpdateAIJobResult(db *sql.DB, jobID int64, result json.RawMessage) error {
+	_, err := db.Exec(`UPDATE ai_jobs SET result = $2 WHERE id = $1 AND status = $3`, jobID, []byte(result), AIJobCompleted)
+	return err
+}
+
+// FailAIJob encerra um job em execução com erro.
+func FailAIJob(db *sql.DB, jobID int64, aiStatusCode int, jobErr string) (bool, error) {
+	return finishRunningAIJob(db, jobID, AIJobFailed, nil, aiStatusCode, jobErr)
+}
+
+func finishRunningAIJob(db *sql.DB, jobID int64, status string, result json.RawMessage, aiStatusCode int, jobErr string) (bool, error) {
+	var resultValue interface{}
+	if result != nil {
+		resultValue = []byte(result)
+	}
+	res, err := db.Exec(`
+		UPDATE ai_jobs SET status = $2, result = $3, ai_status_code = NULLIF($4, 0), error = NULLIF($5, ''),
+			finished_at = NOW(), locked_until = NULL
+		WHERE id = $1 AND status = $6
+	`, jobID, status, resultValue, aiStatusCode, jobErr, AIJobRunning)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+// RequeueAIJob devolve um job em execução para a fila, para nova tentativa após delay.
+func RequeueAIJob(db *sql.DB, jobID int64, delay time.Duration, lastErr string) (bool, error) {
+	res, err := db.Exec(`
+		UPDATE ai_jobs SET status = $2, run_after = NOW() + ($3::int * INTERVAL '1 second'),
+			error = NULLIF($4, ''), locked_until = NULL
+		WHERE id = $1 AND status = $5
+	`, jobID, AIJobQueued, int64(delay/time.Second), lastErr, AIJobRunning)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+// CancelAIJob cancela um job do usuário que ainda não terminou.
+func CancelAIJob(db *sql.DB, workspaceID, jobID int64, firebaseUID string) (*AIJob, error) {
+	job, err := GetAIJob(db, workspaceID, jobID, firebaseUID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Finished() {
+		return job, ErrAIJobFinished
+	}
+
+	res, err := db.Exec(`
+		UPDATE ai_jobs SET status = $2, finished_at = NOW(), locked_until = NULL
+		WHERE id = $1 AND status IN ($3, $4)
+	`, jobID, AIJobCanceled, AIJobQueued, AIJobRunning)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao cancelar job de IA: %w", err)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		// Terminou entre a leitura e o cancelamento
+		job, err = GetAIJob(db, workspaceID, jobID, firebaseUID)
+		if err != nil {
+			return nil, err
+		}
+		return job, ErrAIJobFinished
+	}
+	return GetAIJob(db, workspaceID, jobID, firebaseUID)
+}
+
+const aiJobColumns = `
+	j.id, j.workspace_id, j.service_type, j.status, j.payload, j.result, COALESCE(j.ai_status_code, 0),
+	COALESCE(j.error, ''), j.attempts, u.firebase_uid, j.created_at, j.started_at, j.finished_at, j.no_cache`
+
+func scanAIJob(scanner interface{ Scan(...any) error }) (*AIJob, error) {
+	var job AIJob
+	var payload, result []byte
+	var startedAt, finishedAt sql.NullTime
+	err := scanner.Scan(&job.ID, &job.WorkspaceID, &job.ServiceType, &job.Status, &payload, &result,
+		&job.AIStatusCode, &job.Error, &job.Attempts, &job.CreatedBy, &job.CreatedAt, &startedAt, &finishedAt, &job.NoCache)
+	if err != nil {
+		return nil, err
+	}
+	job.Payload = payload
+	if result != nil {
+		job.Result = result
+	}
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return &job, nil
+}
+
+// GetAIJob busca um job do usuário no workspace.
+func GetAIJob(db *sql.DB, workspaceID, jobID int64, firebaseUID string) (*AIJob, error) {
+	row := db.QueryRow(`SELECT `+aiJobColumns+`
+		FROM ai_jobs j JOIN users u ON j.created_by = u.id
+		WHERE j.id = $1 AND j.workspace_id = $2 AND u.firebase_uid = $3`, jobID, workspaceID, firebaseUID)
+	job, err := scanAIJob(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAIJobNotFound
+		}
+		return nil, fmt.Errorf("erro ao buscar job de IA: %w", err)
+	}
+	return job, nil
+}
+
+// ListAIJobs lista os jobs mais recentes do usuário no workspace (sem os resultados).
+func ListAIJobs(db *sql.DB, workspaceID int64, firebaseUID string, limit int) ([]AIJob, error) {
+	rows, err := db.Query(`SELECT `+aiJobColumns+`
+		FROM ai_jobs j JOIN users u ON j.created_by = u.id
+		WHERE j.workspace_id = $1 AND u.firebase_uid = $2
+		ORDER BY j.created_at DESC
+		LIMIT $3`, workspaceID, firebaseUID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar jobs de IA: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []AIJob{}
+	for rows.Next() {
+		job, err := scanAIJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		job.Result = nil
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
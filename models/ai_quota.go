@@ -0,0 +1,230 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Escopos de uma cota de IA (AIQuota.Scope).
+const (
+	AIQuotaScopeUser      = "user"      // Limite de cada membro do workspace
+	AIQuotaScopeWorkspace = "workspace" // Limite do workspace inteiro
+)
+
+// Métricas limitadas pelas cotas de IA.
+const (
+	AIQuotaRequestsPerDay     = "requests_per_day"
+	AIQuotaInputCharsPerMonth = "input_chars_per_month"
+)
+
+// Namespace do advisory lock (forma de duas chaves) que serializa a reserva de uso por workspace.
+const aiUsageLockNamespace = 260003
+
+// AIQuota é uma regra de cota de IA (tabela ai_quotas). ServiceType vazio limita a soma
+// de todos os serviços. Limites nil não são aplicados.
+type AIQuota struct {
+	Scope              string     `json:"scope"`                  // "user" ou "workspace"
+	ServiceType        string     `json:"service_type,omitempty"` // Vazio = todos os serviços
+	RequestsPerDay     *int64     `json:"requests_per_day"`
+	InputCharsPerMonth *int64     `json:"input_chars_per_month"`
+	IsDefault          bool       `json:"is_default,omitempty"` // Limite do servidor, não configurável pelo workspace
+	UpdatedBy          string     `json:"updated_by,omitempty"` // Firebase UID de quem configurou
+	UpdatedAt          *time.Time `json:"updated_at,omitempty"`
+}
+
+// AIQuotaStatus é o consumo de uma métrica de uma cota no período corrente.
+type AIQuotaStatus struct {
+	Scope       string    `json:"scope"`
+	ServiceType string    `json:"service_type,omitempty"`
+	Metric      string    `json:"metric"` // "requests_per_day" ou "input_chars_per_month"
+	Limit       int64     `json:"limit"`
+	Used        int64     `json:"used"`
+	Remaining   int64     `json:"remaining"`
+	ResetsAt    time.Time `json:"resets_at"`
+	IsDefault   bool      `json:"is_default,omitempty"`
+}
+
+// AIServiceUsage é o consumo de IA de um serviço no período corrente.
+type AIServiceUsage struct {
+	ServiceType                  string `json:"service_type"`
+	RequestsToday                int64  `json:"requests_today"`
+	InputCharsThisMonth          int64  `json:"input_chars_this_month"`
+	WorkspaceRequestsToday       int64  `json:"workspace_requests_today"`
+	WorkspaceInputCharsThisMonth int64  `json:"workspace_input_chars_this_month"`
+}
+
+// AIUsageReport é o consumo de IA de um membro e do workspace, comparado às cotas.
+type AIUsageReport struct {
+	WorkspaceID int64            `json:"workspace_id"`
+	UserID      string           `json:"user_id"` // Firebase UID do membro consultado
+	Day         string           `json:"day"`     // AAAA-MM-DD (UTC)
+	Month       string           `json:"month"`   // AAAA-MM (UTC)
+	Quotas      []AIQuotaStatus  `json:"quotas"`
+	Services    []AIServiceUsage `json:"services"`
+}
+
+// AIUsageRow é o consumo agregado de IA de um serviço no período corrente, do usuário
+// consultado (Mine) ou dos demais membros.
+type AIUsageRow struct {
+	Mine            bool
+	ServiceType     string
+	RequestsToday   int64
+	InputCharsMonth int64
+}
+
+// ListAIQuotas retorna as cotas configuradas no workspace.
+func ListAIQuotas(db *sql.DB, workspaceID int64) ([]AIQuota, error) {
+	rows, err := db.Query(`
+		SELECT q.scope, q.service_type, q.requests_per_day, q.input_chars_per_month, u.firebase_uid, q.updated_at
+		FROM ai_quotas q
+		LEFT JOIN users u ON u.id = q.updated_by
+		WHERE q.workspace_id = $1
+		ORDER BY q.scope, q.service_type
+	`, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar cotas de IA do workspace %d: %w", workspaceID, err)
+	}
+	defer rows.Close()
+
+	quotas := []AIQuota{}
+	for rows.Next() {
+		var quota AIQuota
+		var requests, chars sql.NullInt64
+		var updatedBy sql.NullString
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&quota.Scope, &quota.ServiceType, &requests, &chars, &updatedBy, &updatedAt); err != nil {
+			return nil, fmt.Errorf("erro ao ler cota de IA: %w", err)
+		}
+		if requests.Valid {
+			quota.RequestsPerDay = &requests.Int64
+		}
+		if chars.Valid {
+			quota.InputCharsPerMonth = &chars.Int64
+		}
+		quota.UpdatedBy = updatedBy.String
+		if updatedAt.Valid {
+			quota.UpdatedAt = &updatedAt.Time
+		}
+		quotas = append(quotas, quota)
+	}
+	return quotas, rows.Err()
+}
+
+// ReplaceAIQuotas substitui todas as cotas configuradas no workspace.
+func ReplaceAIQuotas(db *sql.DB, workspaceID int64, updatedByFirebaseUID string, quotas []AIQuota) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("erro ao iniciar transação das cotas de IA: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.Exec(`DELETE FROM ai_quotas WHERE workspace_id = $1`, workspaceID); err != nil {
+		return fmt.Errorf("erro ao remover cotas de IA do workspace %d: %w", workspaceID, err)
+	}
+	for _, quota := range quotas {
+		_, err = tx.Exec(`
+			INSERT INTO ai_quotas (workspace_id, scope, service_type, requests_per_day, input_chars_per_month, updated_by, updated_at)
+			VALUES ($1, $2, $3, $4, $5, (SELECT id FROM users WHERE firebase_uid = $6), NOW())
+		`, workspaceID, quota.Scope, quota.ServiceType, quota.RequestsPerDay, quota.InputCharsPerMonth, updatedByFirebaseUID)
+		if err != nil {
+			return fmt.Errorf("erro ao salvar cota de IA do workspace %d: %w", workspaceID, err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("erro ao salvar cotas de IA do workspace %d: %w", workspaceID, err)
+	}
+	return nil
+}
+
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// LoadAIUsage retorna o consumo de IA do workspace no dia e no mês de now (UTC), por
+// serviço, separando o do usuário informado.
+func LoadAIUsage(db *sql.DB, workspaceID int64, userFirebaseUID string, now time.Time) ([]AIUsageRow, error) {
+	return loadAIUsage(db, workspaceID, userFirebaseUID, now)
+}
+
+func loadAIUsage(q queryer, workspaceID int64, userFirebaseUID string, now time.Time) ([]AIUsageRow, error) {
+	today, monthStart := AIUsagePeriod(now)
+	rows, err := q.Query(`
+		SELECT COALESCE(u.firebase_uid = $2, false), a.service_type,
+			COALESCE(SUM(a.requests) FILTER (WHERE a.usage_date = $3), 0),
+			COALESCE(SUM(a.input_chars), 0)
+		FROM ai_usage a
+		LEFT JOIN users u ON u.id = a.user_id
+		WHERE a.workspace_id = $1 AND a.usage_date >= $4
+		GROUP BY 1, 2
+	`, workspaceID, userFirebaseUID, today, monthStart)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar uso de IA do workspace %d: %w", workspaceID, err)
+	}
+	defer rows.Close()
+
+	var usage []AIUsageRow
+	for rows.Next() {
+		var row AIUsageRow
+		if err := rows.Scan(&row.Mine, &row.ServiceType, &row.RequestsToday, &row.InputCharsMonth); err != nil {
+			return nil, fmt.Errorf("erro ao ler uso de IA: %w", err)
+		}
+		usage = append(usage, row)
+	}
+	return usage, rows.Err()
+}
+
+// AIUsagePeriod retorna o dia e o primeiro dia do mês de now, em UTC, que delimitam as cotas.
+func AIUsagePeriod(now time.Time) (today time.Time, monthStart time.Time) {
+	now = now.UTC()
+	today = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return today, monthStart
+}
+
+// ReserveAIUsage registra uma requisição de IA do usuário, com inputChars caracteres de
+// entrada, se check aprovar o consumo atual do workspace. As reservas do mesmo workspace
+// são serializadas, então requisições simultâneas não ultrapassam as cotas. O erro de
+// check é devolvido sem alteração.
+func ReserveAIUsage(db *sql.DB, workspaceID int64, userFirebaseUID string, serviceType string, inputChars int64, now time.Time, check func(usage []AIUsageRow) error) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("erro ao iniciar transação do uso de IA: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.Exec(`SELECT pg_advisory_xact_lock($1, $2)`, aiUsageLockNamespace, workspaceID); err != nil {
+		return fmt.Errorf("erro ao bloquear uso de IA do workspace %d: %w", workspaceID, err)
+	}
+	usage, err := loadAIUsage(tx, workspaceID, userFirebaseUID, now)
+	if err != nil {
+		return err
+	}
+	if err = check(usage); err != nil {
+		return err
+	}
+
+	today, _ := AIUsagePeriod(now)
+	_, err = tx.Exec(`
+		INSERT INTO ai_usage (workspace_id, user_id, service_type, usage_date, requests, input_chars)
+		SELECT $1, id, $3, $4, 1, $5 FROM users WHERE firebase_uid = $2
+		ON CONFLICT (workspace_id, user_id, service_type, usage_date) DO UPDATE SET
+			requests = ai_usage.requests + 1,
+			input_chars = ai_usage.input_chars + EXCLUDED.input_chars
+	`, workspaceID, userFirebaseUID, serviceType, today, inputChars)
+	if err != nil {
+		return fmt.Errorf("erro ao registrar uso de IA do workspace %d: %w", workspaceID, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("erro ao registrar uso de IA do workspace %d: %w", workspaceID, err)
+	}
+	return nil
+}
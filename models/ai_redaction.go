@@ -0,0 +1,89 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrAIRedactionRuleNotFound indica uma regra de mascaramento inexistente ou de outro workspace.
+	ErrAIRedactionRuleNotFound = errors.New("ai redaction rule not found")
+	// ErrAIRedactionRuleExists indica que o workspace já tem uma regra com o mesmo nome.
+	ErrAIRedactionRuleExists = errors.New("ai redaction rule already exists")
+)
+
+// AIRedaction é um valor mascarado antes do envio à IA, registrado no histórico sem o
+// valor original.
+type AIRedaction struct {
+	Type        string `json:"type" firestore:"type"`               // "email", "phone", "cpf", "cnpj", "api_key" ou "custom:<nome>"
+	Placeholder string `json:"placeholder" firestore:"placeholder"` // Ex: "[EMAIL_1]"
+}
+
+// AIRedactionRule é uma expressão regular do workspace mascarada antes do envio à IA
+// (tabela ai_redaction_rules), além dos tipos padrão.
+type AIRedactionRule struct {
+	ID          int64     `json:"id"`
+	WorkspaceID int64     `json:"workspace_id"`
+	Name        string    `json:"name"`    // Usado no placeholder: "[NOME_1]"
+	Pattern     string    `json:"pattern"` // Sintaxe RE2; com um grupo, apenas o grupo 1 é mascarado
+	CreatedBy   string    `json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ListAIRedactionRules retorna as regras de mascaramento do workspace.
+func ListAIRedactionRules(db *sql.DB, workspaceID int64) ([]AIRedactionRule, error) {
+	rows, err := db.Query(`
+		SELECT r.id, r.workspace_id, r.name, r.pattern, COALESCE(u.firebase_uid, ''), r.created_at
+		FROM ai_redaction_rules r
+		LEFT JOIN users u ON u.id = r.created_by
+		WHERE r.workspace_id = $1
+		ORDER BY r.id
+	`, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar regras de mascaramento do workspace %d: %w", workspaceID, err)
+	}
+	defer rows.Close()
+
+	rules := []AIRedactionRule{}
+	for rows.Next() {
+		var rule AIRedactionRule
+		if err := rows.Scan(&rule.ID, &rule.WorkspaceID, &rule.Name, &rule.Pattern, &rule.CreatedBy, &rule.CreatedAt); err != nil {
+			return nil, fmt.Errorf("erro ao ler regra de mascaramento: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// CreateAIRedactionRule adiciona uma regra de mascaramento ao workspace. O padrão deve
+// ter sido validado pelo chamador.
+func CreateAIRedactionRule(db *sql.DB, workspaceID int64, creatorFirebaseUID string, name string, pattern string) (*AIRedactionRule, error) {
+	rule := AIRedactionRule{WorkspaceID: workspaceID, Name: name, Pattern: pattern, CreatedBy: creatorFirebaseUID}
+	err := db.QueryRow(`
+		INSERT INTO ai_redaction_rules (workspace_id, name, pattern, created_by)
+		VALUES ($1, $2, $3, (SELECT id FROM users WHERE firebase_uid = $4))
+		RETURNING id, created_at
+	`, workspaceID, name, pattern, creatorFirebaseUID).Scan(&rule.ID, &rule.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") || strings.Contains(err.Error(), "violates unique constraint") {
+			return nil, ErrAIRedactionRuleExists
+		}
+		return nil, fmt.Errorf("erro ao criar regra de mascaramento no workspace %d: %w", workspaceID, err)
+	}
+	return &rule, nil
+}
+
+// DeleteAIRedactionRule remove uma regra de mascaramento do workspace.
+func DeleteAIRedactionRule(db *sql.DB, workspaceID int64, ruleID int64) error {
+	result, err := db.Exec(`DELETE FROM ai_redaction_rules WHERE id = $1 AND workspace_id = $2`, ruleID, workspaceID)
+	if err != nil {
+		return fmt.Errorf("erro ao remover regra de mascaramento %d: %w", ruleID, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrAIRedactionRuleNotFound
+	}
+	return nil
+}
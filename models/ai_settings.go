@@ -0,0 +1,105 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// WorkspaceAISettings são as configurações de IA do workspace (tabela
+// workspace_ai_settings). Workspaces sem configuração usam DefaultWorkspaceAISettings.
+type WorkspaceAISettings struct {
+	WorkspaceID         int64           `json:"workspace_id"`
+	Enabled             bool            `json:"enabled"`  // false = nenhum dado do workspace é enviado à IA
+	Features            map[string]bool `json:"features"` // Serviço de IA -> habilitado; serviços ausentes ficam habilitados
+	IncludeMemberNames  bool            `json:"include_member_names"`
+	IncludeDescriptions bool            `json:"include_descriptions"` // Descrições do workspace e das tarefas
+	IncludeComments     bool            `json:"include_comments"`     // Comentários recentes das tarefas
+	Language            string          `json:"language,omitempty"`   // Idioma das respostas, ex: "pt-BR" (vazio = padrão do serviço)
+	CustomInstructions  string          `json:"custom_instructions,omitempty"`
+	IsDefault           bool            `json:"is_default,omitempty"` // O workspace ainda não configurou
+	UpdatedBy           string          `json:"updated_by,omitempty"` // Firebase UID de quem configurou
+	UpdatedAt           *time.Time      `json:"updated_at,omitempty"`
+}
+
+// DefaultWorkspaceAISettings retorna as configurações de um workspace que não configurou
+// a IA: tudo habilitado, exceto os comentários das tarefas no contexto.
+func DefaultWorkspaceAISettings(workspaceID int64) WorkspaceAISettings {
+	return WorkspaceAISettings{
+		WorkspaceID:         workspaceID,
+		Enabled:             true,
+		Features:            map[string]bool{},
+		IncludeMemberNames:  true,
+		IncludeDescriptions: true,
+		IsDefault:           true,
+	}
+}
+
+// ServiceEnabled informa se o serviço de IA pode ser usado no workspace.
+func (s WorkspaceAISettings) ServiceEnabled(serviceType string) bool {
+	enabled, configured := s.Features[serviceType]
+	return s.Enabled && (!configured || enabled)
+}
+
+// GetWorkspaceAISettings retorna as configurações de IA do workspace, ou os padrões se
+// ele ainda não configurou.
+func GetWorkspaceAISettings(db *sql.DB, workspaceID int64) (WorkspaceAISettings, error) {
+	settings := WorkspaceAISettings{WorkspaceID: workspaceID}
+	var features []byte
+	var updatedBy sql.NullString
+	var updatedAt sql.NullTime
+	err := db.QueryRow(`
+		SELECT s.enabled, s.features, s.include_member_names, s.include_descriptions, s.include_comments,
+			s.language, s.custom_instructions, u.firebase_uid, s.updated_at
+		FROM workspace_ai_settings s
+		LEFT JOIN users u ON u.id = s.updated_by
+		WHERE s.workspace_id = $1
+	`, workspaceID).Scan(&settings.Enabled, &features, &settings.IncludeMemberNames, &settings.IncludeDescriptions,
+		&settings.IncludeComments, &settings.Language, &settings.CustomInstructions, &updatedBy, &updatedAt)
+	if err == sql.ErrNoRows {
+		return DefaultWorkspaceAISettings(workspaceID), nil
+	}
+	if err != nil {
+		return settings, fmt.Errorf("erro ao buscar configurações de IA do workspace %d: %w", workspaceID, err)
+	}
+	if err := json.Unmarshal(features, &settings.Features); err != nil {
+		return settings, fmt.Errorf("erro ao ler serviços de IA do workspace %d: %w", workspaceID, err)
+	}
+	if settings.Features == nil {
+		settings.Features = map[string]bool{}
+	}
+	settings.UpdatedBy = updatedBy.String
+	if updatedAt.Valid {
+		settings.UpdatedAt = &updatedAt.Time
+	}
+	return settings, nil
+}
+
+// SaveWorkspaceAISettings grava (ou substitui) as configurações de IA do workspace.
+func SaveWorkspaceAISettings(db *sql.DB, settings WorkspaceAISettings, updatedByFirebaseUID string) error {
+	features, err := json.Marshal(settings.Features)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar serviços de IA: %w", err)
+	}
+	_, err = db.Exec(`
+		INSERT INTO workspace_ai_settings (workspace_id, enabled, features, include_member_names, include_descriptions,
+			include_comments, language, custom_instructions, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, (SELECT id FROM users WHERE firebase_uid = $9), NOW())
+		ON CONFLICT (workspace_id) DO UPDATE SET
+			enabled = EXCLUDED.enabled,
+			features = EXCLUDED.features,
+			include_member_names = EXCLUDED.include_member_names,
+			include_descriptions = EXCLUDED.include_descriptions,
+			include_comments = EXCLUDED.include_comments,
+			language = EXCLUDED.language,
+			custom_instructions = EXCLUDED.custom_instructions,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+	`, settings.WorkspaceID, settings.Enabled, features, settings.IncludeMemberNames, settings.IncludeDescriptions,
+		settings.IncludeComments, settings.Language, settings.CustomInstructions, updatedByFirebaseUID)
+	if err != nil {
+		return fmt.Errorf("erro ao salvar configurações de IA do workspace %d: %w", settings.WorkspaceID, err)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// AIThread é uma conversa persistente de um usuário com o assistente de tarefas de um
+// workspace. Fica em /workspaces/{workspace_id}/ai_threads/{thread_id}, ao lado do
+// ai_request_history, e as mensagens na subcoleção "turns".
+type AIThread struct {
+	ID            string    `json:"id" firestore:"-"`
+	WorkspaceIDPg int64     `json:"workspace_id" firestore:"workspace_id_pg"`
+	UserID        string    `json:"user_id" firestore:"user_id"` // Firebase UID do dono da conversa
+	Title         string    `json:"title" firestore:"title"`
+	TurnCount     int       `json:"turn_count" firestore:"turn_count"`
+	CreatedAt     time.Time `json:"created_at" firestore:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" firestore:"updated_at"`
+}
+
+// AIThreadTurn é uma troca da conversa: a mensagem do usuário e a resposta da IA.
+type AIThreadTurn struct {
+	ID              string           `json:"id" firestore:"-"`
+	UserMessage     string           `json:"user_message" firestore:"user_message"`
+	Suggestions     []string         `json:"suggestions" firestore:"suggestions"`
+	TaskSuggestions []TaskSuggestion `json:"task_suggestions,omitempty" firestore:"task_suggestions,omitempty"`
+	HistoryID       string           `json:"history_id,omitempty" firestore:"history_id,omitempty"` // Documento em ai_request_history
+	CreatedAt       time.Time        `json:"created_at" firestore:"created_at"`
+}
+
+// AIThreadWithTurns é a conversa com todas as mensagens, em ordem cronológica.
+type AIThreadWithTurns struct {
+	AIThread
+	Turns []AIThreadTurn `json:"turns"`
+}
@@ -14,13 +14,26 @@ type UsuarioContext struct {
 
 // TarefaContext fornece detalhes da tarefa para a IA (versão simplificada para o prompt)
 type TarefaContext struct {
-	Titulo     string `json:"titulo"`
-	Status     string `json:"status,omitempty"`
-	Prioridade string `json:"prioridade,omitempty"`
-	Descricao  string `json:"descricao,omitempty"` // Descrição curta ou resumo da tarefa
+	ID             string   `json:"id,omitempty"` // ID do documento da tarefa no Firestore
+	Titulo         string   `json:"titulo"`
+	Status         string   `json:"status,omitempty"`
+	Prioridade     string   `json:"prioridade,omitempty"`
+	Descricao      string   `json:"descricao,omitempty"`       // Descrição curta ou resumo da tarefa
+	DataVencimento string   `json:"data_vencimento,omitempty"` // AAAA-MM-DD
+	Atrasada       bool     `json:"atrasada,omitempty"`
+	Comentarios    []string `json:"comentarios,omitempty"` // Comentários mais recentes, se o workspace permitir
 	// Adicione outros campos se forem relevantes para a IA, como "descricao_curta"
 }
 
+// ContextTaskRef identifica uma tarefa incluída no contexto enviado à IA e por que ela
+// foi escolhida.
+type ContextTaskRef struct {
+	TaskID  string   `json:"task_id" firestore:"task_id"`
+	Title   string   `json:"title" firestore:"title"`
+	Reasons []string `json:"reasons" firestore:"reasons"` // "relevant", "overdue", "high_priority" e/ou "recent"
+	Score   float64  `json:"score" firestore:"score"`
+}
+
 // IAWorkspaceContext representa o JSON de contexto a ser enviado para a IA
 type IAWorkspaceContext struct {
 	WorkspaceIDStr string           `json:"workspace_id_str"` // ID do workspace (string)
@@ -29,63 +42,289 @@ type IAWorkspaceContext struct {
 	Usuarios       []UsuarioContext `json:"usuarios"`
 	Tarefas        []TarefaContext  `json:"tarefas_recentes"`
 	// Descricao      string           `json:"descricao"`            // Lista de tarefas relevantes
-	MsgDoUsuario string `json:"msg_do_usuario_atual"` // O prompt/pergunta atual do usuário
+	MsgDoUsuario string          `json:"msg_do_usuario_atual"`         // O prompt/pergunta atual do usuário
+	Historico    []ConversaTurno `json:"historico_conversa,omitempty"` // Trocas anteriores da conversa (mais antigas primeiro)
+
+	TarefasIncluidas []ContextTaskRef `json:"-" firestore:"-"` // Tarefas escolhidas para o contexto (não enviado à IA)
+}
+
+// ConversaTurno é uma mensagem anterior da conversa enviada à IA como histórico.
+type ConversaTurno struct {
+	Papel    string `json:"papel"` // "usuario" ou "assistente"
+	Conteudo string `json:"conteudo"`
 }
 
 // historico de requisições à IA
 // AIRequestHistoryEntry representa um registro de requisição à IA no Firestore.
 type AIRequestHistoryEntry struct {
-	UserID        string    `firestore:"user_id"`         // Firebase UID do usuário que fez a requisição
-	WorkspaceIDPg int64     `firestore:"workspace_id_pg"` // ID numérico do workspace no PostgreSQL
-	AIServiceType string    `firestore:"ai_service_type"` // Ex: "code_review", "text_summary", "task_assistant"
-	Timestamp     time.Time `firestore:"timestamp"`       // Data/Hora da requisição. O SDK Go converte para Timestamp do Firestore.
+	ID            string    `json:"id" firestore:"-"`
+	UserID        string    `json:"user_id" firestore:"user_id"`                 // Firebase UID do usuário que fez a requisição
+	WorkspaceIDPg int64     `json:"workspace_id" firestore:"workspace_id_pg"`    // ID numérico do workspace no PostgreSQL
+	AIServiceType string    `json:"ai_service_type" firestore:"ai_service_type"` // Ex: "code_review", "text_summary", "task_assistant"
+	Timestamp     time.Time `json:"timestamp" firestore:"timestamp"`             // Data/Hora da requisição. O SDK Go converte para Timestamp do Firestore.
 	// Alternativamente, use interface{} e atribua firestore.ServerTimestamp
-	FrontendRequestPayload interface{} `firestore:"frontend_request_payload,omitempty"` // Payload original que o frontend enviou ao backend Go
-	RequestToAI            interface{} `firestore:"request_to_ai"`                      // Payload que o backend Go enviou para a API Python de IA
-	ResponseFromAI         interface{} `firestore:"response_from_ai,omitempty"`         // Payload que a API Python de IA retornou (em caso de sucesso)
-	AIStatusCode           int         `firestore:"ai_status_code"`                     // Status HTTP retornado pela API de IA
-	AIError                string      `firestore:"ai_error,omitempty"`                 // Mensagem de erro, se a chamada à API de IA falhou ou a IA retornou um erro
+	FrontendRequestPayload interface{}   `json:"frontend_request_payload,omitempty" firestore:"frontend_request_payload,omitempty"` // Payload original que o frontend enviou ao backend Go
+	RequestToAI            interface{}   `json:"request_to_ai,omitempty" firestore:"request_to_ai"`                                 // Payload que o backend Go enviou para a API Python de IA
+	ResponseFromAI         interface{}   `json:"response_from_ai,omitempty" firestore:"response_from_ai,omitempty"`                 // Payload que a API Python de IA retornou (em caso de sucesso)
+	AIStatusCode           int           `json:"ai_status_code" firestore:"ai_status_code"`                                         // Status HTTP retornado pela API de IA
+	AIError                string        `json:"ai_error,omitempty" firestore:"ai_error,omitempty"`                                 // Mensagem de erro, se a chamada à API de IA falhou ou a IA retornou um erro
+	ThreadID               string        `json:"thread_id,omitempty" firestore:"thread_id,omitempty"`                               // Conversa do assistente de tarefas, se houver
+	ErrorClass             string        `json:"error_class,omitempty" firestore:"error_class,omitempty"`                           // Classificação da falha (ex: "timeout", "server_error"); vazio em caso de sucesso
+	Source                 string        `json:"source,omitempty" firestore:"source,omitempty"`                                     // "sync", "stream", "job" ou "report"
+	Provider               string        `json:"provider,omitempty" firestore:"provider,omitempty"`                                 // Provedor de IA (AI_PROVIDER)
+	Endpoint               string        `json:"endpoint,omitempty" firestore:"endpoint,omitempty"`                                 // Endpoint chamado no provedor
+	LatencyMs              int64         `json:"latency_ms" firestore:"latency_ms"`                                                 // Duração da chamada ao provedor, com as novas tentativas
+	Attempts               int           `json:"attempts" firestore:"attempts"`                                                     // Tentativas feitas (0 se o circuito estava aberto)
+	Redactions             []AIRedaction `json:"redactions,omitempty" firestore:"redactions,omitempty"`                             // Valores mascarados em request_to_ai (sem os originais)
+	CacheHit               bool          `json:"cache_hit,omitempty" firestore:"cache_hit,omitempty"`                               // Resposta do cache de IA, sem chamada ao provedor
+	TruncatedAt            *time.Time    `json:"truncated_at,omitempty" firestore:"truncated_at,omitempty"`                         // Payloads removidos pela política de retenção
+}
+
+// AIHistoryPage é uma página da listagem do histórico de IA. As entradas vêm sem os
+// payloads; NextCursor (o ID da última entrada) busca a próxima página.
+type AIHistoryPage struct {
+	Entries    []AIRequestHistoryEntry `json:"entries"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+}
+
+// AIPreferences são as preferências do workspace enviadas em todo payload à IA
+// (veja WorkspaceAISettings).
+type AIPreferences struct {
+	ResponseLanguage   string `json:"response_language,omitempty"`   // Ex: "pt-BR"
+	CustomInstructions string `json:"custom_instructions,omitempty"` // Instruções do workspace para o serviço
 }
 
 // Para Code Review
+// Envie o código ("code") ou um diff unificado ("diff"). Sem "language", a linguagem é
+// detectada pelo nome do arquivo ou pelo conteúdo.
 type CodeReviewAIRequest struct {
-	Code     string `json:"code"`
-	Language string `json:"language"`
+	Code     string   `json:"code,omitempty"`
+	Language string   `json:"language"`
+	Filename string   `json:"filename,omitempty"`
+	Diff     string   `json:"diff,omitempty"`
+	Files    []string `json:"files,omitempty"` // Arquivos alterados no diff (preenchido pelo backend)
+	AIPreferences
 }
 
 type CodeReviewAIResponse struct {
-	Review string `json:"review,omitempty"`
-	Error  string `json:"error,omitempty"` // Para capturar erros da API de IA
+	Review    string              `json:"review,omitempty" firestore:"review,omitempty"`
+	Findings  []CodeReviewFinding `json:"findings" firestore:"findings"`
+	Language  string              `json:"language,omitempty" firestore:"language,omitempty"`
+	Error     string              `json:"error,omitempty" firestore:"error,omitempty"` // Para capturar erros da API de IA
+	HistoryID string              `json:"history_id,omitempty" firestore:"-"`          // Registro no ai_request_history (para anexar à tarefa ou criar tarefas)
+	Cached    bool                `json:"cached,omitempty" firestore:"-"`              // Resposta servida pelo cache de IA
+}
+
+// CodeReviewFinding é um problema apontado na revisão. As linhas referem-se ao código
+// enviado ou, em diffs, à versão nova do arquivo; 0 quando o achado não tem local.
+type CodeReviewFinding struct {
+	File         string `json:"file,omitempty" firestore:"file,omitempty"`
+	StartLine    int    `json:"start_line,omitempty" firestore:"start_line,omitempty"`
+	EndLine      int    `json:"end_line,omitempty" firestore:"end_line,omitempty"`
+	Severity     string `json:"severity" firestore:"severity"` // "info", "minor", "major" ou "critical"
+	Category     string `json:"category" firestore:"category"` // Ex: "bug", "security", "performance", "style"
+	Message      string `json:"message" firestore:"message"`
+	SuggestedFix string `json:"suggested_fix,omitempty" firestore:"suggested_fix,omitempty"`
+}
+
+// TaskCodeReview é uma revisão de código anexada a uma tarefa, na subcoleção
+// code_reviews da tarefa (o ID do documento é o history_id da revisão).
+type TaskCodeReview struct {
+	HistoryID  string              `json:"history_id" firestore:"-"`
+	Language   string              `json:"language,omitempty" firestore:"language,omitempty"`
+	Files      []string            `json:"files,omitempty" firestore:"files,omitempty"`
+	Review     string              `json:"review,omitempty" firestore:"review,omitempty"`
+	Findings   []CodeReviewFinding `json:"findings" firestore:"findings"`
+	AttachedBy string              `json:"attached_by" firestore:"attached_by"`
+	AttachedAt time.Time           `json:"attached_at" firestore:"attached_at"`
+}
+
+// CodeReviewFindingSelection escolhe um achado da revisão para virar tarefa.
+type CodeReviewFindingSelection struct {
+	Index               int    `json:"index"`
+	AssigneeFirebaseUID string `json:"assignee_firebase_uid,omitempty"`
+	DueDate             string `json:"due_date,omitempty"`
 }
 
 // Para Resumo de Texto
 type SummarizeTextAIRequest struct {
 	Text string `json:"text"`
+	AIPreferences
 }
 
 type SummarizeTextAIResponse struct {
 	Summary string `json:"summary,omitempty"`
 	Error   string `json:"error,omitempty"`
+	Cached  bool   `json:"cached,omitempty" firestore:"-"` // Resposta servida pelo cache de IA
 }
 
 // Para Geração de Ideias para Mapa Mental
 type MindMapIdeasAIRequest struct {
 	Text string `json:"text"`
+	AIPreferences
 }
 
 type MindMapIdeasAIResponse struct {
-	MindMapIdeas string `json:"mind_map_ideas,omitempty"` // Ou poderia ser uma estrutura mais complexa
-	Error        string `json:"error,omitempty"`
+	MindMapIdeas string       `json:"mind_map_ideas,omitempty"`                  // Tópicos em lista Markdown indentada
+	Tree         *MindMapNode `json:"tree,omitempty" firestore:"tree,omitempty"` // Árvore validada (montada a partir do texto se a IA não enviar)
+	Error        string       `json:"error,omitempty"`
+	MindMapID    string       `json:"mind_map_id,omitempty" firestore:"-"` // Mapa salvo no workspace
+	HistoryID    string       `json:"history_id,omitempty" firestore:"-"`
+	Cached       bool         `json:"cached,omitempty" firestore:"-"` // Resposta servida pelo cache de IA
 }
 
 // Para o Assistente de Tarefas do Workspace (usando o contexto que definimos antes)
 // Supondo que IAWorkspaceContext já está definido em outro lugar (ex: services ou models)
 // type TaskAssistantAIRequest models.IAWorkspaceContext // Se for exatamente o mesmo
 type TaskAssistantAIResponse struct {
-	Suggestions []string `json:"suggestions,omitempty"` // Exemplo
-	Error       string   `json:"error,omitempty"`
+	Suggestions     []string         `json:"suggestions,omitempty"`                                             // Texto das sugestões, para exibição
+	TaskSuggestions []TaskSuggestion `json:"task_suggestions,omitempty" firestore:"task_suggestions,omitempty"` // Tarefas propostas, que podem ser aceitas
+	Error           string           `json:"error,omitempty"`
+	HistoryID       string           `json:"history_id,omitempty" firestore:"-"`                          // Registro no ai_request_history, usado para aceitar as tarefas
+	ContextTasks    []ContextTaskRef `json:"context_tasks,omitempty" firestore:"context_tasks,omitempty"` // Tarefas do workspace que a IA recebeu no contexto
+	Cached          bool             `json:"cached,omitempty" firestore:"-"`                              // Resposta servida pelo cache de IA
+}
+
+// TaskSuggestion é uma tarefa proposta pelo assistente. Os campos seguem os da tarefa;
+// a data e o responsável vêm como texto da IA e só são interpretados ao aceitar.
+type TaskSuggestion struct {
+	Title       string `json:"title" firestore:"title"`
+	Description string `json:"description,omitempty" firestore:"description,omitempty"`
+	Priority    string `json:"priority,omitempty" firestore:"priority,omitempty"`
+	DueDate     string `json:"due_date,omitempty" firestore:"due_date,omitempty"` // Ex: "2025-06-30"
+	Assignee    string `json:"assignee,omitempty" firestore:"assignee,omitempty"` // Nome ou e-mail do membro proposto
+}
+
+// TaskAssistantUserInput é o que o frontend envia ao assistente de tarefas.
+type TaskAssistantUserInput struct {
+	UserMessage string `json:"user_message" firestore:"user_message"`
+	ThreadID    string `json:"thread_id,omitempty" firestore:"thread_id,omitempty"` // Continua uma conversa (veja AIThread)
 }
 
 type TaskAssistantAIRequest struct {
 	WorkspaceContext IAWorkspaceContext `json:"workspace_context"`
+	AIPreferences
+}
+
+// Para a Decomposição de Tarefas em Subtarefas
+// TaskBreakdownUserInput é o que o frontend envia para decompor uma tarefa existente.
+type TaskBreakdownUserInput struct {
+	TaskDocID   string `json:"task_doc_id" firestore:"task_doc_id"`
+	MaxSubtasks int    `json:"max_subtasks,omitempty" firestore:"max_subtasks,omitempty"` // Padrão: 8, máximo: 20
+}
+
+// TarefaDecomposicaoContext é a tarefa enviada à IA para decomposição.
+type TarefaDecomposicaoContext struct {
+	ID             string   `json:"id"` // ID do documento no Firestore
+	Titulo         string   `json:"titulo"`
+	Descricao      string   `json:"descricao,omitempty"`
+	Status         string   `json:"status,omitempty"`
+	Prioridade     string   `json:"prioridade,omitempty"`
+	DataVencimento string   `json:"data_vencimento,omitempty"` // RFC3339
+	Checklist      []string `json:"checklist,omitempty"`       // Itens ainda não concluídos
+	Comentarios    []string `json:"comentarios,omitempty"`     // Comentários mais recentes, se o workspace permitir
+}
+
+type TaskBreakdownAIRequest struct {
+	Tarefa        TarefaDecomposicaoContext `json:"tarefa"`
+	MaxSubtarefas int                       `json:"max_subtarefas"`
+	AIPreferences
+}
+
+type TaskBreakdownAIResponse struct {
+	Subtasks     []SubtaskSuggestion `json:"subtasks,omitempty" firestore:"subtasks,omitempty"`
+	Error        string              `json:"error,omitempty"`
+	ParentTaskID string              `json:"parent_task_id,omitempty" firestore:"parent_task_id,omitempty"` // Preenchido pelo backend
+	HistoryID    string              `json:"history_id,omitempty" firestore:"-"`
+	Cached       bool                `json:"cached,omitempty" firestore:"-"` // Resposta servida pelo cache de IA
+}
+
+// TaskSummaryUserInput é o que o frontend envia para resumir uma tarefa existente.
+type TaskSummaryUserInput struct {
+	TaskDocID string `json:"task_doc_id" firestore:"task_doc_id"`
+}
+
+// TaskSummaryAIRequest é enviado ao endpoint de resumo: o texto é montado pelo backend
+// com a descrição, o histórico, os comentários e os anexos da tarefa.
+type TaskSummaryAIRequest struct {
+	TaskDocID string `json:"task_doc_id"`
+	Text      string `json:"text"`
+	AIPreferences
+}
+
+type TaskSummaryAIResponse struct {
+	Summary   string `json:"summary,omitempty" firestore:"summary,omitempty"`
+	Error     string `json:"error,omitempty"`
+	TaskDocID string `json:"task_doc_id,omitempty" firestore:"task_doc_id,omitempty"` // Preenchido pelo backend
+	HistoryID string `json:"history_id,omitempty" firestore:"-"`
+	Cached    bool   `json:"cached,omitempty" firestore:"-"` // Resposta servida pelo cache de IA
+}
+
+// SubtaskSuggestion é uma subtarefa proposta pela IA, na ordem de execução.
+type SubtaskSuggestion struct {
+	Order         int     `json:"order" firestore:"order"` // 1, 2, 3...
+	Title         string  `json:"title" firestore:"title"`
+	Description   string  `json:"description,omitempty" firestore:"description,omitempty"`
+	EstimateHours float64 `json:"estimate_hours,omitempty" firestore:"estimate_hours,omitempty"`
+}
+
+// SubtaskInput é uma subtarefa revisada pelo usuário, criada como tarefa filha.
+type SubtaskInput struct {
+	Title               string  `json:"title"`
+	Description         string  `json:"description,omitempty"`
+	EstimateHours       float64 `json:"estimate_hours,omitempty"`
+	Priority            string  `json:"priority,omitempty"` // Padrão: a prioridade da tarefa pai
+	DueDate             string  `json:"due_date,omitempty"`
+	AssigneeFirebaseUID string  `json:"assignee_firebase_uid,omitempty"`
+}
+
+// TaskSuggestionSelection escolhe uma sugestão de tarefa para aceitar, pelo índice em
+// task_suggestions. Os demais campos, se preenchidos, substituem os da sugestão.
+type TaskSuggestionSelection struct {
+	Index               int    `json:"index"`
+	Title               string `json:"title,omitempty"`
+	Description         string `json:"description,omitempty"`
+	Priority            string `json:"priority,omitempty"`
+	DueDate             string `json:"due_date,omitempty"`
+	AssigneeFirebaseUID string `json:"assignee_firebase_uid,omitempty"`
+}
+
+// AcceptedTaskSuggestion é o resultado de aceitar uma sugestão de tarefa.
+type AcceptedTaskSuggestion struct {
+	Index  int    `json:"index"`
+	Status string `json:"status"` // "created", "already_accepted" ou "failed"
+	TaskID string `json:"task_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// AILatencyStats resume a latência das chamadas ao provedor, em milissegundos.
+type AILatencyStats struct {
+	Samples int   `json:"samples"` // Chamadas com latência registrada
+	AvgMs   int64 `json:"avg_ms"`
+	P50Ms   int64 `json:"p50_ms"`
+	P95Ms   int64 `json:"p95_ms"`
+	MaxMs   int64 `json:"max_ms"`
+}
+
+// AIReliabilityStats agrega as chamadas de IA de um período (de um serviço ou no total).
+type AIReliabilityStats struct {
+	ServiceType   string         `json:"service_type,omitempty"`
+	Total         int            `json:"total"`
+	Succeeded     int            `json:"succeeded"`
+	Failed        int            `json:"failed"`
+	SuccessRate   float64        `json:"success_rate"`    // 0 a 1
+	ErrorsByClass map[string]int `json:"errors_by_class"` // Ex: {"timeout": 3, "server_error": 1}
+	Retried       int            `json:"retried"`         // Chamadas que precisaram de mais de uma tentativa
+	AvgAttempts   float64        `json:"avg_attempts"`
+	Latency       AILatencyStats `json:"latency"`
+}
+
+// AIReliabilityReport é o relatório de confiabilidade da IA de um workspace.
+type AIReliabilityReport struct {
+	WorkspaceID int64                `json:"workspace_id"`
+	Since       time.Time            `json:"since"`
+	Until       time.Time            `json:"until"`
+	Partial     bool                 `json:"partial,omitempty"` // Período com mais entradas do que o relatório lê
+	Totals      AIReliabilityStats   `json:"totals"`
+	Services    []AIReliabilityStats `json:"services"`
 }
@@ -0,0 +1,163 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrCalendarFeedNotFound indica token inexistente, revogado ou de outro usuário.
+var ErrCalendarFeedNotFound = errors.New("calendar feed not found")
+
+// CalendarFeed é um link secreto de calendário (.ics) de um usuário.
+// O token em si nunca é armazenado, apenas seu hash SHA-256.
+type CalendarFeed struct {
+	ID             int64      `json:"id"`
+	Name           string     `json:"name"`
+	WorkspaceID    *int64     `json:"workspace_id,omitempty"` // nil = todos os workspaces do usuário
+	AssignedOnly   bool       `json:"assigned_only"`          // Apenas tarefas atribuídas ao usuário
+	CreatedAt      time.Time  `json:"created_at"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+}
+
+// CalendarFeedOwner é o feed resolvido a partir de um token, com o dono do feed.
+type CalendarFeedOwner struct {
+	CalendarFeed
+	UserFirebaseUID string
+}
+
+// HashCalendarToken retorna o hash (hex) usado para localizar o token no banco.
+func HashCalendarToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateCalendarFeed gera um novo token secreto para o usuário e registra o feed.
+// O token é retornado apenas aqui; depois disso só o hash fica disponível.
+func CreateCalendarFeed(db *sql.DB, userFirebaseUID string, name string, workspaceID *int64, assignedOnly bool) (*CalendarFeed, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", fmt.Errorf("erro ao gerar token do calendário: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	feed := CalendarFeed{Name: name, WorkspaceID: workspaceID, AssignedOnly: assignedOnly}
+	err := db.QueryRow(`
+		INSERT INTO calendar_feeds (user_id, token_hash, name, workspace_id, assigned_only)
+		SELECT id, $2, $3, $4, $5 FROM users WHERE firebase_uid = $1
+		RETURNING id, created_at
+	`, userFirebaseUID, HashCalendarToken(token), name, workspaceID, assignedOnly).Scan(&feed.ID, &feed.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", errors.New("usuário não encontrado")
+		}
+		return nil, "", fmt.Errorf("erro ao criar feed de calendário: %w", err)
+	}
+	return &feed, token, nil
+}
+
+// ListCalendarFeeds lista os feeds ativos (não revogados) do usuário.
+func ListCalendarFeeds(db *sql.DB, userFirebaseUID string) ([]CalendarFeed, error) {
+	rows, err := db.Query(`
+		SELECT cf.id, cf.name, cf.workspace_id, cf.assigned_only, cf.created_at, cf.last_accessed_at
+		FROM calendar_feeds cf
+		JOIN users u ON cf.user_id = u.id
+		WHERE u.firebase_uid = $1 AND cf.revoked_at IS NULL
+		ORDER BY cf.created_at DESC
+	`, userFirebaseUID)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar feeds de calendário: %w", err)
+	}
+	defer rows.Close()
+
+	feeds := []CalendarFeed{}
+	for rows.Next() {
+		var feed CalendarFeed
+		var workspaceID sql.NullInt64
+		var lastAccessed sql.NullTime
+		if err := rows.Scan(&feed.ID, &feed.Name, &workspaceID, &feed.AssignedOnly, &feed.CreatedAt, &lastAccessed); err != nil {
+			return nil, err
+		}
+		if workspaceID.Valid {
+			feed.WorkspaceID = &workspaceID.Int64
+		}
+		if lastAccessed.Valid {
+			feed.LastAccessedAt = &lastAccessed.Time
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds, rows.Err()
+}
+
+// RevokeCalendarFeed revoga um feed do usuário; o link deixa de funcionar imediatamente.
+func RevokeCalendarFeed(db *sql.DB, userFirebaseUID string, feedID int64) error {
+	result, err := db.Exec(`
+		UPDATE calendar_feeds SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL
+		  AND user_id = (SELECT id FROM users WHERE firebase_uid = $2)
+	`, feedID, userFirebaseUID)
+	if err != nil {
+		return fmt.Errorf("erro ao revogar feed de calendário: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrCalendarFeedNotFound
+	}
+	return nil
+}
+
+// GetCalendarFeedByToken resolve um token ativo e registra o acesso.
+func GetCalendarFeedByToken(db *sql.DB, token string) (*CalendarFeedOwner, error) {
+	var feed CalendarFeedOwner
+	var workspaceID sql.NullInt64
+	err := db.QueryRow(`
+		UPDATE calendar_feeds cf SET last_accessed_at = NOW()
+		FROM users u
+		WHERE cf.user_id = u.id AND cf.token_hash = $1 AND cf.revoked_at IS NULL
+		RETURNING cf.id, cf.name, cf.workspace_id, cf.assigned_only, cf.created_at, u.firebase_uid
+	`, HashCalendarToken(token)).Scan(&feed.ID, &feed.Name, &workspaceID, &feed.AssignedOnly, &feed.CreatedAt, &feed.UserFirebaseUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrCalendarFeedNotFound
+		}
+		return nil, fmt.Errorf("erro ao buscar feed de calendário: %w", err)
+	}
+	if workspaceID.Valid {
+		feed.WorkspaceID = &workspaceID.Int64
+	}
+	return &feed, nil
+}
+
+// ListUserWorkspaceNames retorna id -> nome dos workspaces dos quais o usuário é membro
+// no momento da consulta.
+func ListUserWorkspaceNames(db *sql.DB, userFirebaseUID string) (map[int64]string, error) {
+	rows, err := db.Query(`
+		SELECT w.id, w.name
+		FROM workspaces w
+		JOIN workspace_members wm ON wm.workspace_id = w.id
+		JOIN users u ON wm.user_id = u.id
+		WHERE u.firebase_uid = $1
+	`, userFirebaseUID)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar workspaces do usuário: %w", err)
+	}
+	defer rows.Close()
+
+	workspaces := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		workspaces[id] = name
+	}
+	return workspaces, rows.Err()
+}
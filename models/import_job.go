@@ -0,0 +1,209 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Estados de um job de importação.
+const (
+	ImportJobQueued    = "queued"
+	ImportJobRunning   = "running"
+	ImportJobCompleted = "completed"
+	ImportJobFailed    = "failed"
+)
+
+// ErrImportJobNotFound indica um job inexistente ou de outro workspace.
+var ErrImportJobNotFound = errors.New("import job not found")
+
+// ImportSkippedItem descreve um item da origem que não virou tarefa, e por quê.
+type ImportSkippedItem struct {
+	Item   string `json:"item"`
+	Reason string `json:"reason"`
+}
+
+// ImportWarning descreve um item da origem que virou tarefa, mas com alguma informação
+// perdida (ex: data de vencimento não reconhecida).
+type ImportWarning struct {
+	Item    string `json:"item"`
+	Message string `json:"message"`
+}
+
+// ImportJob é uma importação assíncrona de quadro (Trello/Jira) registrada no PostgreSQL.
+type ImportJob struct {
+	ID          int64               `json:"id"`
+	WorkspaceID int64               `json:"workspace_id"`
+	Source      string              `json:"source"`
+	Status      string              `json:"status"`
+	Total       int                 `json:"total"`
+	Processed   int                 `json:"processed"`
+	Created     int                 `json:"created"`
+	Skipped     []ImportSkippedItem `json:"skipped"`
+	Warnings    []ImportWarning     `json:"warnings"`
+	Error       string              `json:"error,omitempty"`
+	CreatedBy   string              `json:"created_by"` // Firebase UID de quem iniciou
+	CreatedAt   time.Time           `json:"created_at"`
+	StartedAt   *time.Time          `json:"started_at,omitempty"`
+	FinishedAt  *time.Time          `json:"finished_at,omitempty"`
+}
+
+// CreateImportJob registra um job na fila. skipped e warnings já trazem os itens
+// descartados e as ressalvas da leitura do arquivo; total é o número de tarefas a criar.
+func CreateImportJob(db *sql.DB, workspaceID int64, creatorFirebaseUID, source string, total int, skipped []ImportSkippedItem, warnings []ImportWarning) (*ImportJob, error) {
+	if skipped == nil {
+		skipped = []ImportSkippedItem{}
+	}
+	if warnings == nil {
+		warnings = []ImportWarning{}
+	}
+	skippedJSON, err := json.Marshal(skipped)
+	if err != nil {
+		return nil, err
+	}
+	warningsJSON, err := json.Marshal(warnings)
+	if err != nil {
+		return nil, err
+	}
+
+	job := ImportJob{
+		WorkspaceID: workspaceID,
+		Source:      source,
+		Status:      ImportJobQueued,
+		Total:       total,
+		Skipped:     skipped,
+		Warnings:    warnings,
+		CreatedBy:   creatorFirebaseUID,
+	}
+	err = db.QueryRow(`
+		INSERT INTO import_jobs (workspace_id, created_by, source, status, total, skipped, warnings)
+		SELECT $1, id, $3, $4, $5, $6, $7 FROM users WHERE firebase_uid = $2
+		RETURNING id, created_at
+	`, workspaceID, creatorFirebaseUID, source, ImportJobQueued, total, skippedJSON, warningsJSON).Scan(&job.ID, &job.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar job de importação: %w", err)
+	}
+	return &job, nil
+}
+
+// StartImportJob marca o job como em execução.
+func StartImportJob(db *sql.DB, jobID int64) error {
+	_, err := db.Exec(`UPDATE import_jobs SET status = $2, started_at = NOW(), heartbeat_at = NOW() WHERE id = $1`, jobID, ImportJobRunning)
+	return err
+}
+
+// TouchImportJob renova o heartbeat de um job em execução, indicando que a instância
+// que o executa continua ativa.
+func TouchImportJob(db *sql.DB, jobID int64) error {
+	_, err := db.Exec(`UPDATE import_jobs SET heartbeat_at = NOW() WHERE id = $1 AND status = $2`, jobID, ImportJobRunning)
+	return err
+}
+
+// UpdateImportJobProgress registra o andamento do job.
+func UpdateImportJobProgress(db *sql.DB, jobID int64, processed, created int, skipped []ImportSkippedItem, warnings []ImportWarning) error {
+	skippedJSON, err := json.Marshal(skipped)
+	if err != nil {
+		return err
+	}
+	warningsJSON, err := json.Marshal(warnings)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+		UPDATE import_jobs SET processed = $2, created_count = $3, skipped = $4, warnings = $5, heartbeat_at = NOW()
+		WHERE id = $1
+	`, jobID, processed, created, skippedJSON, warningsJSON)
+	return err
+}
+
+// FinishImportJob encerra o job com o status final (completed ou failed).
+func FinishImportJob(db *sql.DB, jobID int64, status string, jobErr string) error {
+	_, err := db.Exec(`
+		UPDATE import_jobs SET status = $2, error = NULLIF($3, ''), finished_at = NOW()
+		WHERE id = $1
+	`, jobID, status, jobErr)
+	return err
+}
+
+// FailInterruptedImportJobs marca como falhos os jobs em andamento cujo heartbeat (ou,
+// se o job nem começou, a criação) é mais antigo que timeout: a instância que os
+// executava parou (os jobs rodam em memória e não são retomados). Jobs de outras
+// instâncias ativas continuam renovando o heartbeat e não são afetados.
+func FailInterruptedImportJobs(db *sql.DB, timeout time.Duration) (int64, error) {
+	result, err := db.Exec(`
+		UPDATE import_jobs SET status = $1, error = 'interrupted by server restart', finished_at = NOW()
+		WHERE status IN ($2, $3) AND COALESCE(heartbeat_at, created_at) < NOW() - ($4::int * INTERVAL '1 second')
+	`, ImportJobFailed, ImportJobQueued, ImportJobRunning, int64(timeout/time.Second))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const importJobColumns = `
+	j.id, j.workspace_id, j.source, j.status, j.total, j.processed, j.created_count,
+	j.skipped, j.warnings, COALESCE(j.error, ''), u.firebase_uid, j.created_at, j.started_at, j.finished_at`
+
+func scanImportJob(scanner interface{ Scan(...any) error }) (*ImportJob, error) {
+	var job ImportJob
+	var skippedJSON, warningsJSON []byte
+	var startedAt, finishedAt sql.NullTime
+	err := scanner.Scan(&job.ID, &job.WorkspaceID, &job.Source, &job.Status, &job.Total, &job.Processed,
+		&job.Created, &skippedJSON, &warningsJSON, &job.Error, &job.CreatedBy, &job.CreatedAt, &startedAt, &finishedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(skippedJSON, &job.Skipped); err != nil {
+		return nil, fmt.Errorf("erro ao ler itens ignorados do job %d: %w", job.ID, err)
+	}
+	if err := json.Unmarshal(warningsJSON, &job.Warnings); err != nil {
+		return nil, fmt.Errorf("erro ao ler ressalvas do job %d: %w", job.ID, err)
+	}
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return &job, nil
+}
+
+// GetImportJob busca um job do workspace.
+func GetImportJob(db *sql.DB, workspaceID, jobID int64) (*ImportJob, error) {
+	row := db.QueryRow(`SELECT `+importJobColumns+`
+		FROM import_jobs j JOIN users u ON j.created_by = u.id
+		WHERE j.id = $1 AND j.workspace_id = $2`, jobID, workspaceID)
+	job, err := scanImportJob(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrImportJobNotFound
+		}
+		return nil, fmt.Errorf("erro ao buscar job de importação: %w", err)
+	}
+	return job, nil
+}
+
+// ListImportJobs lista os jobs mais recentes do workspace.
+func ListImportJobs(db *sql.DB, workspaceID int64, limit int) ([]ImportJob, error) {
+	rows, err := db.Query(`SELECT `+importJobColumns+`
+		FROM import_jobs j JOIN users u ON j.created_by = u.id
+		WHERE j.workspace_id = $1
+		ORDER BY j.created_at DESC
+		LIMIT $2`, workspaceID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar jobs de importação: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []ImportJob{}
+	for rows.Next() {
+		job, err := scanImportJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
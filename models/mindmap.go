@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// MindMapNode é um nó do mapa mental. Os filhos ficam aninhados no próprio nó.
+type MindMapNode struct {
+	ID       string        `json:"id" firestore:"id"`
+	Label    string        `json:"label" firestore:"label"`
+	Notes    string        `json:"notes,omitempty" firestore:"notes,omitempty"`
+	TaskID   string        `json:"task_id,omitempty" firestore:"task_id,omitempty"` // Tarefa criada a partir do nó
+	Children []MindMapNode `json:"children,omitempty" firestore:"children,omitempty"`
+}
+
+// MindMap é um mapa mental salvo como artefato do workspace, em
+// /workspaces/{workspace_id}/mind_maps/{mind_map_id}. Qualquer membro pode abrir e
+// editar; só o criador pode apagar.
+type MindMap struct {
+	ID            string      `json:"id" firestore:"-"`
+	WorkspaceIDPg int64       `json:"workspace_id" firestore:"workspace_id_pg"`
+	Title         string      `json:"title" firestore:"title"`
+	Root          MindMapNode `json:"root" firestore:"root"`
+	NodeCount     int         `json:"node_count" firestore:"node_count"`
+	HistoryID     string      `json:"history_id,omitempty" firestore:"history_id,omitempty"` // Resposta da IA que gerou o mapa
+	CreatedBy     string      `json:"created_by" firestore:"created_by"`                     // Firebase UID
+	CreatedAt     time.Time   `json:"created_at" firestore:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at" firestore:"updated_at"`
+}
+
+// MindMapSummary é o mapa sem a árvore, para listagens.
+type MindMapSummary struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	NodeCount int       `json:"node_count"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MindMapNodeInput adiciona ou altera um nó. Na alteração, campos nil são mantidos e
+// ParentID move o nó (com os filhos) para outro pai.
+type MindMapNodeInput struct {
+	ParentID *string `json:"parent_id"`
+	Label    *string `json:"label"`
+	Notes    *string `json:"notes"`
+}
+
+// MindMapNodeTask é o resultado de converter um nó em tarefa.
+type MindMapNodeTask struct {
+	NodeID string `json:"node_id"`
+	Status string `json:"status"` // "created", "already_accepted" ou "failed"
+	TaskID string `json:"task_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
@@ -16,25 +16,79 @@ type TarefaStub struct {
 	UpdatedAt      time.Time `json:"updated_at"`       // Data da última atualização do registro no PostgreSQL
 }
 
+// ChecklistItem é um item de checklist de uma tarefa.
+type ChecklistItem struct {
+	Text string `json:"text" firestore:"text"`
+	Done bool   `json:"done" firestore:"done"`
+}
+
+// TaskComment é um comentário da subcoleção workspaces/{id}/tasks/{doc}/comments.
+type TaskComment struct {
+	ID                string    `json:"id" firestore:"-"`
+	AuthorFirebaseUID string    `json:"author_firebase_uid,omitempty" firestore:"author_firebase_uid,omitempty"`
+	AuthorName        string    `json:"author_name,omitempty" firestore:"author_name,omitempty"` // Nome do autor externo (comentários importados)
+	Text              string    `json:"text" firestore:"text"`
+	CreatedAt         time.Time `json:"created_at" firestore:"created_at"`
+}
+
 type TaskAttachment struct {
 	Filename string `json:"filename" firestore:"filename"`
 	URL      string `json:"url" firestore:"url"`
 	Filetype string `json:"filetype" firestore:"filetype,omitempty"`
 }
 
+// TaskAISummary é o resumo da descrição, do histórico e da discussão de uma tarefa
+// escrito pela IA, guardado na própria tarefa.
+type TaskAISummary struct {
+	Summary     string    `json:"summary" firestore:"summary"`
+	HistoryID   string    `json:"history_id,omitempty" firestore:"history_id,omitempty"` // Registro em ai_request_history
+	GeneratedBy string    `json:"generated_by" firestore:"generated_by"`                 // Firebase UID
+	GeneratedAt time.Time `json:"generated_at" firestore:"generated_at"`
+}
+
 // TaskDetailsFirestore representa os detalhes de uma tarefa armazenados no Firestore.
 type TaskDetailsFirestore struct {
-	Title          string     `json:"title" firestore:"title"`
-	Description    string     `json:"description" firestore:"description,omitempty"`
-	Status         string     `json:"status" firestore:"status"`               // ex: "pending", "in_progress", "completed"
-	Priority       string     `json:"priority" firestore:"priority,omitempty"` // ex: "low", "medium", "high"
-	ExpirationDate *time.Time `json:"expiration_date,omitempty" firestore:"expiration_date,omitempty"`
-	Attachment     string     `json:"attachment,omitempty" firestore:"attachment,omitempty"`
+	Title          string          `json:"title" firestore:"title"`
+	Description    string          `json:"description" firestore:"description,omitempty"`
+	Status         string          `json:"status" firestore:"status"`               // ex: "pending", "in_progress", "completed"
+	Priority       string          `json:"priority" firestore:"priority,omitempty"` // ex: "low", "medium", "high"
+	ExpirationDate *time.Time      `json:"expiration_date,omitempty" firestore:"expiration_date,omitempty"`
+	Attachment     string          `json:"attachment,omitempty" firestore:"attachment,omitempty"`
+	IsOverdue      bool            `json:"is_overdue,omitempty" firestore:"is_overdue,omitempty"`       // Marcado pelo scheduler de lembretes
+	OverdueSince   *time.Time      `json:"overdue_since,omitempty" firestore:"overdue_since,omitempty"` // Data de vencimento que foi ultrapassada
+	Labels         []string        `json:"labels,omitempty" firestore:"labels,omitempty"`
+	Checklist      []ChecklistItem `json:"checklist,omitempty" firestore:"checklist,omitempty"`
+
+	// Origem de tarefas importadas de outras ferramentas (ex: "trello", "jira")
+	ImportSource string `json:"import_source,omitempty" firestore:"import_source,omitempty"`
+	ExternalID   string `json:"external_id,omitempty" firestore:"external_id,omitempty"`
+
+	// Tarefas criadas a partir de sugestões do assistente de IA
+	AIGenerated bool   `json:"ai_generated,omitempty" firestore:"ai_generated,omitempty"`
+	AIHistoryID string `json:"ai_history_id,omitempty" firestore:"ai_history_id,omitempty"` // Registro em ai_request_history que sugeriu a tarefa
 
-	WorkspaceIDPg      int64     `json:"-" firestore:"workspace_id_pg"` // ID do workspace no PostgreSQL
-	CreatorFirebaseUID string    `json:"creator_firebase_uid" firestore:"creator_firebase_uid"`
-	CreatedAt          time.Time `json:"created_at" firestore:"created_at"`           // Idealmente um firestore.ServerTimestamp na escrita
-	LastUpdatedAt      time.Time `json:"last_updated_at" firestore:"last_updated_at"` // Idealmente um firestore.ServerTimestamp na escrita/atualização
+	// Subtarefas: tarefas filhas guardam a tarefa pai e a posição na decomposição
+	ParentTaskID  string  `json:"parent_task_id,omitempty" firestore:"parent_task_id,omitempty"`
+	SubtaskOrder  int     `json:"subtask_order,omitempty" firestore:"subtask_order,omitempty"`
+	EstimateHours float64 `json:"estimate_hours,omitempty" firestore:"estimate_hours,omitempty"`
+
+	// Recorrência: todas as ocorrências de uma série compartilham o SeriesID (ID da primeira ocorrência)
+	Recurrence       *RecurrenceRule `json:"recurrence,omitempty" firestore:"recurrence,omitempty"`
+	SeriesID         string          `json:"series_id,omitempty" firestore:"series_id,omitempty"`
+	OccurrenceIndex  int             `json:"occurrence_index,omitempty" firestore:"occurrence_index,omitempty"` // Posição na série (1 = primeira)
+	NextOccurrenceID string          `json:"next_occurrence_id,omitempty" firestore:"next_occurrence_id,omitempty"`
+
+	// Último resumo da tarefa gerado por /ai/task-summary
+	AISummary *TaskAISummary `json:"ai_summary,omitempty" firestore:"ai_summary,omitempty"`
+
+	CompletedAt            *time.Time `json:"completed_at,omitempty" firestore:"completed_at,omitempty"`
+	CompletedByFirebaseUID string     `json:"completed_by_firebase_uid,omitempty" firestore:"completed_by_firebase_uid,omitempty"`
+
+	WorkspaceIDPg       int64     `json:"-" firestore:"workspace_id_pg"` // ID do workspace no PostgreSQL
+	CreatorFirebaseUID  string    `json:"creator_firebase_uid" firestore:"creator_firebase_uid"`
+	AssigneeFirebaseUID string    `json:"assignee_firebase_uid,omitempty" firestore:"assignee_firebase_uid,omitempty"` // Membro responsável pela tarefa (opcional)
+	CreatedAt           time.Time `json:"created_at" firestore:"created_at"`                                           // Idealmente um firestore.ServerTimestamp na escrita
+	LastUpdatedAt       time.Time `json:"last_updated_at" firestore:"last_updated_at"`                                 // Idealmente um firestore.ServerTimestamp na escrita/atualização
 }
 
 // Para escrita, você pode querer uma struct de input que não inclua campos gerados pelo servidor como CreatedAt
@@ -45,7 +99,25 @@ type CreateTaskInput struct {
 	Priority       string     `json:"priority"`
 	ExpirationDate *time.Time `json:"expiration_date"`
 
-	Attachment string `json:"attachment"`
+	Attachment          string          `json:"attachment"`
+	AssigneeFirebaseUID string          `json:"assignee_firebase_uid"` // Deve ser membro do workspace
+	Labels              []string        `json:"labels"`
+	Checklist           []ChecklistItem `json:"checklist"`
+
+	Recurrence *RecurrenceRule `json:"recurrence,omitempty"` // Regra de recorrência como objeto...
+	RRule      string          `json:"rrule,omitempty"`      // ...ou como string RRULE (ex: "FREQ=WEEKLY;BYDAY=MO")
+
+	// Campos definidos apenas pelo servidor (não vêm do JSON do cliente)
+	DocID           string  `json:"-"` // ID do documento no Firestore; gerado se vazio
+	SeriesID        string  `json:"-"`
+	OccurrenceIndex int     `json:"-"`
+	ImportSource    string  `json:"-"`
+	ExternalID      string  `json:"-"`
+	AIGenerated     bool    `json:"-"`
+	AIHistoryID     string  `json:"-"`
+	ParentTaskID    string  `json:"-"`
+	SubtaskOrder    int     `json:"-"`
+	EstimateHours   float64 `json:"-"`
 }
 
 type UpdateTaskInput struct {
@@ -55,4 +127,19 @@ type UpdateTaskInput struct {
 	Priority       *string    `json:"priority"`
 	ExpirationDate *time.Time `json:"expiration_date"`
 	Attachment     *string    `json:"attachment"` // Para atualizar ou remover, pode ser complexo
+	// Responsável pela tarefa; string vazia remove a atribuição
+	AssigneeFirebaseUID *string          `json:"assignee_firebase_uid"`
+	Labels              *[]string        `json:"labels"`
+	Checklist           *[]ChecklistItem `json:"checklist"`
+}
+
+// UpdateSeriesInput edita uma série de tarefas recorrentes. Os campos informados são
+// aplicados a todas as ocorrências ainda abertas (e, portanto, às próximas geradas).
+type UpdateSeriesInput struct {
+	Recurrence     *RecurrenceRule `json:"recurrence"`
+	RRule          *string         `json:"rrule"`
+	StopRecurrence bool            `json:"stop_recurrence"` // Encerra a série: nenhuma nova ocorrência será gerada
+	Title          *string         `json:"title"`
+	Description    *string         `json:"description"`
+	Priority       *string         `json:"priority"`
 }
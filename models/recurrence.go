@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Frequências suportadas pela regra de recorrência (subconjunto do RRULE do iCalendar).
+const (
+	RecurrenceDaily   = "daily"
+	RecurrenceWeekly  = "weekly"
+	RecurrenceMonthly = "monthly"
+	RecurrenceYearly  = "yearly"
+)
+
+// RecurrenceRule descreve como uma tarefa se repete.
+// Equivale a um RRULE com FREQ, INTERVAL, BYDAY, BYMONTHDAY, COUNT e UNTIL.
+type RecurrenceRule struct {
+	Freq       string     `json:"freq" firestore:"freq"`                                     // daily, weekly, monthly, yearly
+	Interval   int        `json:"interval,omitempty" firestore:"interval,omitempty"`         // A cada N períodos (padrão 1)
+	ByDay      []string   `json:"by_day,omitempty" firestore:"by_day,omitempty"`             // Dias da semana no formato RRULE: MO, TU, WE, TH, FR, SA, SU (só weekly)
+	ByMonthDay int        `json:"by_month_day,omitempty" firestore:"by_month_day,omitempty"` // Dia do mês (1..31, ou -1 para o último dia; monthly e yearly)
+	ByMonth    int        `json:"by_month,omitempty" firestore:"by_month,omitempty"`         // Mês (1..12; só yearly)
+	Count      int        `json:"count,omitempty" firestore:"count,omitempty"`               // Número total de ocorrências da série (0 = sem limite)
+	Until      *time.Time `json:"until,omitempty" firestore:"until,omitempty"`               // Última data possível para uma ocorrência
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Tipos de lembrete emitidos pelo scheduler de vencimento de tarefas.
+const (
+	ReminderKindDueSoon = "due_soon" // Tarefa vence dentro de uma das janelas configuradas
+	ReminderKindOverdue = "overdue"  // Tarefa já passou da data de vencimento
+)
+
+// ReminderEvent é o evento entregue aos canais de notificação quando um lembrete dispara.
+type ReminderEvent struct {
+	Kind          string    `json:"kind" firestore:"kind"`                         // ReminderKindDueSoon ou ReminderKindOverdue
+	Window        string    `json:"window,omitempty" firestore:"window,omitempty"` // Janela que disparou o lembrete (ex: "24h"), vazio para overdue
+	WorkspaceIDPg int64     `json:"workspace_id" firestore:"workspace_id_pg"`
+	TaskDocID     string    `json:"task_id" firestore:"task_doc_id"`
+	TaskTitle     string    `json:"task_title" firestore:"task_title"`
+	DueAt         time.Time `json:"due_at" firestore:"due_at"`
+	Recipients    []string  `json:"recipients" firestore:"recipients"` // Firebase UIDs que devem ser avisados
+	FiredAt       time.Time `json:"fired_at" firestore:"fired_at"`
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// TaskSearchEntry é o vetor de uma tarefa no índice de busca (tabela task_search_index).
+type TaskSearchEntry struct {
+	WorkspaceID   int64
+	TaskDocID     string
+	Embedder      string    // Embedder que gerou o vetor (ex: "hashing-512")
+	ContentHash   string    // SHA-256 do texto indexado, para pular tarefas sem mudança
+	Embedding     []float32 // Normalizado (norma 1)
+	TaskUpdatedAt time.Time // last_updated_at da tarefa quando foi indexada
+	IndexedAt     time.Time
+}
+
+// TaskSearchResult é uma tarefa encontrada pela busca.
+type TaskSearchResult struct {
+	TaskID         string     `json:"task_id"`
+	Title          string     `json:"title"`
+	Status         string     `json:"status"`
+	Priority       string     `json:"priority,omitempty"`
+	ExpirationDate *time.Time `json:"expiration_date,omitempty"`
+	Snippet        string     `json:"snippet,omitempty"` // Início da descrição
+	Score          float64    `json:"score"`             // Similaridade com a consulta (0 a 1)
+}
+
+// TaskSearchResponse é a resposta de /search.
+type TaskSearchResponse struct {
+	Query    string             `json:"query"`
+	Embedder string             `json:"embedder"`
+	Results  []TaskSearchResult `json:"results"`
+}
+
+// UpsertTaskSearchEntry grava (ou substitui) o vetor da tarefa.
+func UpsertTaskSearchEntry(db *sql.DB, entry TaskSearchEntry) error {
+	_, err := db.Exec(`
+		INSERT INTO task_search_index (workspace_id, task_doc_id, embedder, content_hash, embedding, task_updated_at, indexed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (workspace_id, task_doc_id) DO UPDATE SET
+			embedder = EXCLUDED.embedder,
+			content_hash = EXCLUDED.content_hash,
+			embedding = EXCLUDED.embedding,
+			task_updated_at = EXCLUDED.task_updated_at,
+			indexed_at = NOW()
+	`, entry.WorkspaceID, entry.TaskDocID, entry.Embedder, entry.ContentHash, encodeEmbedding(entry.Embedding), entry.TaskUpdatedAt)
+	if err != nil {
+		return fmt.Errorf("erro ao gravar tarefa %s no índice de busca: %w", entry.TaskDocID, err)
+	}
+	return nil
+}
+
+// TouchTaskSearchEntry atualiza apenas o last_updated_at registrado, para tarefas
+// alteradas sem mudança no texto indexado.
+func TouchTaskSearchEntry(db *sql.DB, workspaceID int64, taskDocID string, taskUpdatedAt time.Time) error {
+	_, err := db.Exec(`UPDATE task_search_index SET task_updated_at = $3, indexed_at = NOW() WHERE workspace_id = $1 AND task_doc_id = $2`,
+		workspaceID, taskDocID, taskUpdatedAt)
+	if err != nil {
+		return fmt.Errorf("erro ao atualizar tarefa %s no índice de busca: %w", taskDocID, err)
+	}
+	return nil
+}
+
+// DeleteTaskSearchEntries remove tarefas do índice.
+func DeleteTaskSearchEntries(db *sql.DB, workspaceID int64, taskDocIDs ...string) error {
+	for _, taskDocID := range taskDocIDs {
+		if _, err := db.Exec(`DELETE FROM task_search_index WHERE workspace_id = $1 AND task_doc_id = $2`, workspaceID, taskDocID); err != nil {
+			return fmt.Errorf("erro ao remover tarefa %s do índice de busca: %w", taskDocID, err)
+		}
+	}
+	return nil
+}
+
+// ListTaskSearchEntries retorna o índice do workspace. Com withEmbeddings false, os
+// vetores não são carregados (para comparar o índice com as tarefas).
+func ListTaskSearchEntries(db *sql.DB, workspaceID int64, withEmbeddings bool) ([]TaskSearchEntry, error) {
+	embeddingColumn := "''::bytea"
+	if withEmbeddings {
+		embeddingColumn = "embedding"
+	}
+	rows, err := db.Query(`
+		SELECT task_doc_id, embedder, content_hash, `+embeddingColumn+`, task_updated_at, indexed_at
+		FROM task_search_index WHERE workspace_id = $1
+	`, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar índice de busca do workspace %d: %w", workspaceID, err)
+	}
+	defer rows.Close()
+
+	var entries []TaskSearchEntry
+	for rows.Next() {
+		entry := TaskSearchEntry{WorkspaceID: workspaceID}
+		var embedding []byte
+		if err := rows.Scan(&entry.TaskDocID, &entry.Embedder, &entry.ContentHash, &embedding, &entry.TaskUpdatedAt, &entry.IndexedAt); err != nil {
+			return nil, fmt.Errorf("erro ao ler índice de busca do workspace %d: %w", workspaceID, err)
+		}
+		entry.Embedding = decodeEmbedding(embedding)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// encodeEmbedding serializa o vetor como float32 little-endian.
+func encodeEmbedding(vector []float32) []byte {
+	data := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(v))
+	}
+	return data
+}
+
+func decodeEmbedding(data []byte) []float32 {
+	vector := make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return vector
+}
@@ -0,0 +1,176 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Origem de um relatório de status (StatusReport.Trigger).
+const (
+	StatusReportManual    = "manual"
+	StatusReportScheduled = "scheduled"
+)
+
+// StatusReportTask é uma tarefa listada no relatório de status.
+type StatusReportTask struct {
+	TaskID              string     `json:"task_id" firestore:"task_id"`
+	Title               string     `json:"title" firestore:"title"`
+	Priority            string     `json:"priority,omitempty" firestore:"priority,omitempty"`
+	AssigneeFirebaseUID string     `json:"assignee_firebase_uid,omitempty" firestore:"assignee_firebase_uid,omitempty"`
+	DueDate             *time.Time `json:"due_date,omitempty" firestore:"due_date,omitempty"`
+	CompletedAt         *time.Time `json:"completed_at,omitempty" firestore:"completed_at,omitempty"`
+}
+
+// StatusReportMember é a contribuição de um membro no período.
+type StatusReportMember struct {
+	UserID          string `json:"user_id" firestore:"user_id"` // Firebase UID
+	Name            string `json:"name" firestore:"name"`
+	Completed       int    `json:"completed" firestore:"completed"`               // Tarefas concluídas pelo membro no período
+	Created         int    `json:"created" firestore:"created"`                   // Tarefas criadas pelo membro no período
+	OpenAssigned    int    `json:"open_assigned" firestore:"open_assigned"`       // Tarefas abertas atribuídas ao fim do período
+	OverdueAssigned int    `json:"overdue_assigned" firestore:"overdue_assigned"` // Das abertas, as atrasadas
+}
+
+// StatusReportTotals são os números do workspace no período.
+type StatusReportTotals struct {
+	Completed int `json:"completed" firestore:"completed"` // Concluídas no período
+	Created   int `json:"created" firestore:"created"`     // Criadas no período
+	Open      int `json:"open" firestore:"open"`           // Abertas ao fim do período
+	Overdue   int `json:"overdue" firestore:"overdue"`     // Abertas e vencidas ao fim do período
+	Blocked   int `json:"blocked" firestore:"blocked"`     // Abertas com etiqueta de bloqueio
+}
+
+// StatusReport é um relatório de status do workspace, salvo em
+// /workspaces/{workspace_id}/status_reports.
+type StatusReport struct {
+	ID             string               `json:"id" firestore:"-"`
+	WorkspaceIDPg  int64                `json:"workspace_id" firestore:"workspace_id_pg"`
+	PeriodStart    time.Time            `json:"period_start" firestore:"period_start"`
+	PeriodEnd      time.Time            `json:"period_end" firestore:"period_end"`
+	Trigger        string               `json:"trigger" firestore:"trigger"`                               // "manual" ou "scheduled"
+	GeneratedBy    string               `json:"generated_by,omitempty" firestore:"generated_by,omitempty"` // Firebase UID (no agendado, quem configurou o agendamento)
+	GeneratedAt    time.Time            `json:"generated_at" firestore:"generated_at"`
+	Totals         StatusReportTotals   `json:"totals" firestore:"totals"`
+	Completed      []StatusReportTask   `json:"completed" firestore:"completed"`
+	Overdue        []StatusReportTask   `json:"overdue" firestore:"overdue"`
+	Blocked        []StatusReportTask   `json:"blocked" firestore:"blocked"`
+	Members        []StatusReportMember `json:"members" firestore:"members"`
+	Narrative      string               `json:"narrative,omitempty" firestore:"narrative,omitempty"`             // Resumo escrito pela IA
+	NarrativeError string               `json:"narrative_error,omitempty" firestore:"narrative_error,omitempty"` // Por que o resumo não foi gerado
+	HistoryID      string               `json:"history_id,omitempty" firestore:"history_id,omitempty"`           // Registro do resumo no ai_request_history
+}
+
+// StatusReportSummary é o relatório sem as listas, para listagens.
+type StatusReportSummary struct {
+	ID          string             `json:"id"`
+	PeriodStart time.Time          `json:"period_start"`
+	PeriodEnd   time.Time          `json:"period_end"`
+	Trigger     string             `json:"trigger"`
+	GeneratedBy string             `json:"generated_by,omitempty"`
+	GeneratedAt time.Time          `json:"generated_at"`
+	Totals      StatusReportTotals `json:"totals"`
+}
+
+// StatusReportSchedule é a geração semanal automática do relatório de status
+// (tabela status_report_schedules). Weekday e Hour são em UTC.
+type StatusReportSchedule struct {
+	WorkspaceID int64      `json:"workspace_id"`
+	Enabled     bool       `json:"enabled"`
+	Weekday     int        `json:"weekday"` // 0 = domingo ... 6 = sábado
+	Hour        int        `json:"hour"`    // 0-23 (UTC)
+	LastRunAt   *time.Time `json:"last_run_at,omitempty"`
+	UpdatedBy   string     `json:"updated_by,omitempty"` // Firebase UID de quem configurou
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+// GetStatusReportSchedule retorna o agendamento do workspace; sem agendamento, um
+// desativado para segunda-feira às 9h (UTC).
+func GetStatusReportSchedule(db *sql.DB, workspaceID int64) (StatusReportSchedule, error) {
+	schedule := StatusReportSchedule{WorkspaceID: workspaceID}
+	var lastRunAt, updatedAt sql.NullTime
+	var updatedBy sql.NullString
+	err := db.QueryRow(`
+		SELECT s.enabled, s.weekday, s.hour, s.last_run_at, u.firebase_uid, s.updated_at
+		FROM status_report_schedules s
+		LEFT JOIN users u ON u.id = s.updated_by
+		WHERE s.workspace_id = $1
+	`, workspaceID).Scan(&schedule.Enabled, &schedule.Weekday, &schedule.Hour, &lastRunAt, &updatedBy, &updatedAt)
+	if err == sql.ErrNoRows {
+		return StatusReportSchedule{WorkspaceID: workspaceID, Weekday: int(time.Monday), Hour: 9}, nil
+	}
+	if err != nil {
+		return schedule, fmt.Errorf("erro ao buscar agendamento do relatório do workspace %d: %w", workspaceID, err)
+	}
+	if lastRunAt.Valid {
+		schedule.LastRunAt = &lastRunAt.Time
+	}
+	schedule.UpdatedBy = updatedBy.String
+	if updatedAt.Valid {
+		schedule.UpdatedAt = &updatedAt.Time
+	}
+	return schedule, nil
+}
+
+// SaveStatusReportSchedule grava (ou substitui) o agendamento do workspace.
+func SaveStatusReportSchedule(db *sql.DB, schedule StatusReportSchedule, updatedByFirebaseUID string) error {
+	_, err := db.Exec(`
+		INSERT INTO status_report_schedules (workspace_id, enabled, weekday, hour, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, (SELECT id FROM users WHERE firebase_uid = $5), NOW())
+		ON CONFLICT (workspace_id) DO UPDATE SET
+			enabled = EXCLUDED.enabled,
+			weekday = EXCLUDED.weekday,
+			hour = EXCLUDED.hour,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+	`, schedule.WorkspaceID, schedule.Enabled, schedule.Weekday, schedule.Hour, updatedByFirebaseUID)
+	if err != nil {
+		return fmt.Errorf("erro ao salvar agendamento do relatório do workspace %d: %w", schedule.WorkspaceID, err)
+	}
+	return nil
+}
+
+// ListEnabledStatusReportSchedules retorna os agendamentos ativos.
+func ListEnabledStatusReportSchedules(db *sql.DB) ([]StatusReportSchedule, error) {
+	rows, err := db.Query(`
+		SELECT s.workspace_id, s.weekday, s.hour, s.last_run_at, COALESCE(u.firebase_uid, ''), s.updated_at
+		FROM status_report_schedules s
+		LEFT JOIN users u ON u.id = s.updated_by
+		WHERE s.enabled
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar agendamentos de relatórios: %w", err)
+	}
+	defer rows.Close()
+
+	var schedules []StatusReportSchedule
+	for rows.Next() {
+		schedule := StatusReportSchedule{Enabled: true}
+		var lastRunAt, updatedAt sql.NullTime
+		if err := rows.Scan(&schedule.WorkspaceID, &schedule.Weekday, &schedule.Hour, &lastRunAt, &schedule.UpdatedBy, &updatedAt); err != nil {
+			return nil, fmt.Errorf("erro ao ler agendamento de relatório: %w", err)
+		}
+		if lastRunAt.Valid {
+			schedule.LastRunAt = &lastRunAt.Time
+		}
+		if updatedAt.Valid {
+			schedule.UpdatedAt = &updatedAt.Time
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, rows.Err()
+}
+
+// ClaimStatusReportRun marca a execução agendada para scheduledAt. Retorna false se ela
+// já foi feita (ou o agendamento foi desativado nesse meio tempo).
+func ClaimStatusReportRun(db *sql.DB, workspaceID int64, scheduledAt time.Time) (bool, error) {
+	result, err := db.Exec(`
+		UPDATE status_report_schedules SET last_run_at = $2
+		WHERE workspace_id = $1 AND enabled AND (last_run_at IS NULL OR last_run_at < $2)
+	`, workspaceID, scheduledAt)
+	if err != nil {
+		return false, fmt.Errorf("erro ao reservar relatório agendado do workspace %d: %w", workspaceID, err)
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
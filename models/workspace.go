@@ -331,3 +331,21 @@ func IsWorkspaceMember(db *sql.DB, userFirebaseUID string, workspaceID int64) (b
 	}
 	return exists, nil
 }
+
+// GetUserRoleInWorkspace retorna o papel do usuário no workspace ("admin", "member")
+// ou "" se ele não é membro.
+func GetUserRoleInWorkspace(db *sql.DB, userFirebaseUID string, workspaceID int64) (string, error) {
+	var role string
+	err := db.QueryRow(`
+        SELECT wm.role FROM workspace_members wm
+        JOIN users u ON u.id = wm.user_id
+        WHERE u.firebase_uid = $1 AND wm.workspace_id = $2
+    `, userFirebaseUID, workspaceID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("falha ao buscar papel do usuário no workspace: %w", err)
+	}
+	return role, nil
+}
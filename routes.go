@@ -34,6 +34,12 @@ func LoadRoutes() {
 	r.HandleFunc("/users/info/{id}", handlers.AuthMiddleware(handlers.GetUserHandler)).Methods("GET")                    //ok
 	r.HandleFunc("/user/my-workspaces/list", handlers.AuthMiddleware(handlers.ListUserWorkspacesHandler)).Methods("GET") //ok
 
+	// --- Rotas de Feeds de Calendário (.ics) ---
+	r.HandleFunc("/user/calendar-feeds/create", handlers.AuthMiddleware(handlers.CreateCalendarFeedHandler)).Methods("POST")
+	r.HandleFunc("/user/calendar-feeds/list", handlers.AuthMiddleware(handlers.ListCalendarFeedsHandler)).Methods("GET")
+	r.HandleFunc("/user/calendar-feeds/revoke/{feed_id}", handlers.AuthMiddleware(handlers.RevokeCalendarFeedHandler)).Methods("DELETE")
+	r.HandleFunc("/calendar/{token:[0-9a-f]+}.ics", handlers.CalendarFeedICSHandler).Methods("GET") // Pública: autenticada pelo token
+
 	// --- Rotas de Workspace (protegidas) ---
 	r.HandleFunc("/workspace/create", handlers.AuthMiddleware(handlers.CreateWorkspaceHandler)).Methods("POST")                                  //ok
 	r.HandleFunc("/workspace/info/{workspace_id}", handlers.AuthMiddleware(handlers.GetWorkspaceInfoHandler)).Methods("GET")                     //ok
@@ -49,12 +55,76 @@ func LoadRoutes() {
 	r.HandleFunc("/workspace/{workspace_id}/task/info/{task_doc_id}", handlers.AuthMiddleware(handlers.GetTaskHandler)).Methods("GET")         //ok
 	r.HandleFunc("/workspace/{workspace_id}/task/update/{task_doc_id}", handlers.AuthMiddleware(handlers.UpdateTaskHandler)).Methods("PUT")    //ok
 	r.HandleFunc("/workspace/{workspace_id}/task/delete/{task_doc_id}", handlers.AuthMiddleware(handlers.DeleteTaskHandler)).Methods("DELETE") //ok
+	r.HandleFunc("/workspace/{workspace_id}/task/recurrence/{task_doc_id}", handlers.AuthMiddleware(handlers.UpdateTaskSeriesHandler)).Methods("PUT")
+	r.HandleFunc("/workspace/{workspace_id}/task/skip/{task_doc_id}", handlers.AuthMiddleware(handlers.SkipTaskOccurrenceHandler)).Methods("POST")
+	r.HandleFunc("/workspace/{workspace_id}/task/subtasks/{task_doc_id}", handlers.AuthMiddleware(handlers.CreateSubtasksHandler)).Methods("POST")
+	r.HandleFunc("/workspace/{workspace_id}/task/code-reviews/{task_doc_id}", handlers.AuthMiddleware(handlers.AttachCodeReviewHandler)).Methods("POST")
+	r.HandleFunc("/workspace/{workspace_id}/task/code-reviews/{task_doc_id}", handlers.AuthMiddleware(handlers.ListTaskCodeReviewsHandler)).Methods("GET")
+	r.HandleFunc("/workspace/{workspace_id}/tasks/export", handlers.AuthMiddleware(handlers.ExportTasksHandler)).Methods("GET")
+	r.HandleFunc("/workspace/{workspace_id}/tasks/import", handlers.AuthMiddleware(handlers.ImportTasksHandler)).Methods("POST")
+	r.HandleFunc("/workspace/{workspace_id}/tasks/import/board", handlers.AuthMiddleware(handlers.ImportBoardHandler)).Methods("POST")
+	r.HandleFunc("/workspace/{workspace_id}/tasks/import/jobs", handlers.AuthMiddleware(handlers.ListImportJobsHandler)).Methods("GET")
+	r.HandleFunc("/workspace/{workspace_id}/tasks/import/jobs/{job_id}", handlers.AuthMiddleware(handlers.GetImportJobHandler)).Methods("GET")
+	r.HandleFunc("/workspace/{workspace_id}/search", handlers.AuthMiddleware(handlers.SearchTasksHandler)).Methods("GET")
 
 	// --- Rotas para Funcionalidades de IA (protegidas) ---
 	r.HandleFunc("/workspace/{workspace_id}/ai/summarize-text", handlers.AuthMiddleware(handlers.SummarizeTextAIHandler)).Methods("POST")
 	r.HandleFunc("/workspace/{workspace_id}/ai/code-review", handlers.AuthMiddleware(handlers.CodeReviewAIHandler)).Methods("POST")
 	r.HandleFunc("/workspace/{workspace_id}/ai/mindmap-ideas", handlers.AuthMiddleware(handlers.GenerateMindMapIdeasAIHandler)).Methods("POST")
 	r.HandleFunc("/workspace/{workspace_id}/ai/task-assistant", handlers.AuthMiddleware(handlers.WorkspaceTaskAssistantHandler)).Methods("POST")
+	r.HandleFunc("/workspace/{workspace_id}/ai/task-breakdown", handlers.AuthMiddleware(handlers.TaskBreakdownAIHandler)).Methods("POST")
+	r.HandleFunc("/workspace/{workspace_id}/ai/task-summary", handlers.AuthMiddleware(handlers.TaskSummaryAIHandler)).Methods("POST")
+	r.HandleFunc("/workspace/{workspace_id}/ai/task-assistant/stream", handlers.AuthMiddleware(handlers.TaskAssistantStreamHandler)).Methods("POST")
+	r.HandleFunc("/workspace/{workspace_id}/ai/code-review/stream", handlers.AuthMiddleware(handlers.CodeReviewStreamHandler)).Methods("POST")
+	r.HandleFunc("/workspace/{workspace_id}/ai/summarize-text/stream", handlers.AuthMiddleware(handlers.SummarizeTextStreamHandler)).Methods("POST")
+	r.HandleFunc("/workspace/{workspace_id}/ai/threads", handlers.AuthMiddleware(handlers.CreateAIThreadHandler)).Methods("POST")
+	r.HandleFunc("/workspace/{workspace_id}/ai/threads", handlers.AuthMiddleware(handlers.ListAIThreadsHandler)).Methods("GET")
+	r.HandleFunc("/workspace/{workspace_id}/ai/threads/{thread_id}", handlers.AuthMiddleware(handlers.GetAIThreadHandler)).Methods("GET")
+	r.HandleFunc("/workspace/{workspace_id}/ai/threads/{thread_id}", handlers.AuthMiddleware(handlers.RenameAIThreadHandler)).Methods("PUT")
+	r.HandleFunc("/workspace/{workspace_id}/ai/threads/{thread_id}", handlers.AuthMiddleware(handlers.DeleteAIThreadHandler)).Methods("DELETE")
+	r.HandleFunc("/workspace/{workspace_id}/ai/threads/{thread_id}/messages", handlers.AuthMiddleware(handlers.AIThreadMessageHandler)).Methods("POST")
+	r.HandleFunc("/workspace/{workspace_id}/ai/threads/{thread_id}/messages/stream", handlers.AuthMiddleware(handlers.AIThreadMessageStreamHandler)).Methods("POST")
+	r.HandleFunc("/workspace/{workspace_id}/ai/suggestions/{history_id}/accept", handlers.AuthMiddleware(handlers.AcceptTaskSuggestionsHandler)).Methods("POST")
+	r.HandleFunc("/workspace/{workspace_id}/ai/code-reviews/{history_id}/tasks", handlers.AuthMiddleware(handlers.CodeReviewFindingsToTasksHandler)).Methods("POST")
+	r.HandleFunc("/workspace/{workspace_id}/mindmaps", handlers.AuthMiddleware(handlers.CreateMindMapHandler)).Methods("POST")
+	r.HandleFunc("/workspace/{workspace_id}/mindmaps", handlers.AuthMiddleware(handlers.ListMindMapsHandler)).Methods("GET")
+	r.HandleFunc("/workspace/{workspace_id}/mindmaps/{mind_map_id}", handlers.AuthMiddleware(handlers.GetMindMapHandler)).Methods("GET")
+	r.HandleFunc("/workspace/{workspace_id}/mindmaps/{mind_map_id}", handlers.AuthMiddleware(handlers.RenameMindMapHandler)).Methods("PUT")
+	r.HandleFunc("/workspace/{workspace_id}/mindmaps/{mind_map_id}", handlers.AuthMiddleware(handlers.DeleteMindMapHandler)).Methods("DELETE")
+	r.HandleFunc("/workspace/{workspace_id}/mindmaps/{mind_map_id}/nodes", handlers.AuthMiddleware(handlers.AddMindMapNodeHandler)).Methods("POST")
+	r.HandleFunc("/workspace/{workspace_id}/mindmaps/{mind_map_id}/nodes/{node_id}", handlers.AuthMiddleware(handlers.UpdateMindMapNodeHandler)).Methods("PUT")
+	r.HandleFunc("/workspace/{workspace_id}/mindmaps/{mind_map_id}/nodes/{node_id}", handlers.AuthMiddleware(handlers.DeleteMindMapNodeHandler)).Methods("DELETE")
+	r.HandleFunc("/workspace/{workspace_id}/mindmaps/{mind_map_id}/export", handlers.AuthMiddleware(handlers.ExportMindMapHandler)).Methods("GET")
+	r.HandleFunc("/workspace/{workspace_id}/mindmaps/{mind_map_id}/tasks", handlers.AuthMiddleware(handlers.ConvertMindMapNodesHandler)).Methods("POST")
+	r.HandleFunc("/workspace/{workspace_id}/ai/jobs", handlers.AuthMiddleware(handlers.ListAIJobsHandler)).Methods("GET")
+	r.HandleFunc("/workspace/{workspace_id}/ai/jobs/{job_id}", handlers.AuthMiddleware(handlers.GetAIJobHandler)).Methods("GET")
+	r.HandleFunc("/workspace/{workspace_id}/ai/jobs/{job_id}/result", handlers.AuthMiddleware(handlers.GetAIJobResultHandler)).Methods("GET")
+	r.HandleFunc("/workspace/{workspace_id}/ai/jobs/{job_id}/cancel", handlers.AuthMiddleware(handlers.CancelAIJobHandler)).Methods("POST")
+	r.HandleFunc("/workspace/{workspace_id}/ai/history", handlers.AuthMiddleware(handlers.ListAIHistoryHandler)).Methods("GET")
+	r.HandleFunc("/workspace/{workspace_id}/ai/history/retention", handlers.AuthMiddleware(handlers.GetAIHistoryRetentionHandler)).Methods("GET") // Antes de {history_id}
+	r.HandleFunc("/workspace/{workspace_id}/ai/history/retention", handlers.AuthMiddleware(handlers.UpdateAIHistoryRetentionHandler)).Methods("PUT")
+	r.HandleFunc("/workspace/{workspace_id}/ai/history/{history_id}", handlers.AuthMiddleware(handlers.GetAIHistoryEntryHandler)).Methods("GET")
+	r.HandleFunc("/workspace/{workspace_id}/ai/history/{history_id}", handlers.AuthMiddleware(handlers.DeleteAIHistoryEntryHandler)).Methods("DELETE")
+	r.HandleFunc("/workspace/{workspace_id}/ai/history/{history_id}/feedback", handlers.AuthMiddleware(handlers.SubmitAIFeedbackHandler)).Methods("PUT")
+	r.HandleFunc("/workspace/{workspace_id}/ai/history/{history_id}/feedback", handlers.AuthMiddleware(handlers.GetAIFeedbackHandler)).Methods("GET")
+	r.HandleFunc("/workspace/{workspace_id}/ai/history/{history_id}/feedback", handlers.AuthMiddleware(handlers.DeleteAIFeedbackHandler)).Methods("DELETE")
+	r.HandleFunc("/workspace/{workspace_id}/ai/feedback/report", handlers.AuthMiddleware(handlers.AIFeedbackReportHandler)).Methods("GET")
+	r.HandleFunc("/workspace/{workspace_id}/ai/reliability", handlers.AuthMiddleware(handlers.AIReliabilityHandler)).Methods("GET")
+	r.HandleFunc("/workspace/{workspace_id}/ai/quotas", handlers.AuthMiddleware(handlers.GetAIQuotasHandler)).Methods("GET")
+	r.HandleFunc("/workspace/{workspace_id}/ai/quotas", handlers.AuthMiddleware(handlers.UpdateAIQuotasHandler)).Methods("PUT")
+	r.HandleFunc("/workspace/{workspace_id}/ai/usage", handlers.AuthMiddleware(handlers.GetAIUsageHandler)).Methods("GET")
+	r.HandleFunc("/workspace/{workspace_id}/ai/redaction-rules", handlers.AuthMiddleware(handlers.ListAIRedactionRulesHandler)).Methods("GET")
+	r.HandleFunc("/workspace/{workspace_id}/ai/redaction-rules", handlers.AuthMiddleware(handlers.CreateAIRedactionRuleHandler)).Methods("POST")
+	r.HandleFunc("/workspace/{workspace_id}/ai/redaction-rules/{rule_id}", handlers.AuthMiddleware(handlers.DeleteAIRedactionRuleHandler)).Methods("DELETE")
+	r.HandleFunc("/workspace/{workspace_id}/ai/redaction/preview", handlers.AuthMiddleware(handlers.PreviewAIRedactionHandler)).Methods("POST")
+	r.HandleFunc("/workspace/{workspace_id}/ai/settings", handlers.AuthMiddleware(handlers.GetAISettingsHandler)).Methods("GET")
+	r.HandleFunc("/workspace/{workspace_id}/ai/settings", handlers.AuthMiddleware(handlers.UpdateAISettingsHandler)).Methods("PUT")
+	r.HandleFunc("/workspace/{workspace_id}/ai/status-reports", handlers.AuthMiddleware(handlers.GenerateStatusReportHandler)).Methods("POST")
+	r.HandleFunc("/workspace/{workspace_id}/ai/status-reports", handlers.AuthMiddleware(handlers.ListStatusReportsHandler)).Methods("GET")
+	r.HandleFunc("/workspace/{workspace_id}/ai/status-reports/schedule", handlers.AuthMiddleware(handlers.GetStatusReportScheduleHandler)).Methods("GET")
+	r.HandleFunc("/workspace/{workspace_id}/ai/status-reports/schedule", handlers.AuthMiddleware(handlers.UpdateStatusReportScheduleHandler)).Methods("PUT")
+	r.HandleFunc("/workspace/{workspace_id}/ai/status-reports/{report_id}", handlers.AuthMiddleware(handlers.GetStatusReportHandler)).Methods("GET")
+	r.HandleFunc("/ai/health", handlers.AuthMiddleware(handlers.AIHealthHandler)).Methods("GET")
 	// Opção B: Com workspace_id na rota (handler precisaria ser ajustado para ler da rota e do corpo)
 	// r.HandleFunc("/workspace/{workspace_id}/ai/task-assistant", handlers.AuthMiddleware(handlers.WorkspaceTaskAssistantHandler)).Methods("POST")
 
@@ -0,0 +1,107 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"projeto-integrador/utilities"
+	"strconv"
+	"time"
+)
+
+// Every executa job imediatamente e depois a cada interval, até que ctx seja cancelado.
+// Roda em uma goroutine própria; pânicos dentro do job são recuperados e logados
+// para não derrubar o servidor.
+func Every(ctx context.Context, name string, interval time.Duration, job func(ctx context.Context)) {
+	go func() {
+		utilities.LogInfo("Scheduler %s: iniciado com intervalo de %v", name, interval)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			runSafely(ctx, name, job)
+			select {
+			case <-ctx.Done():
+				utilities.LogInfo("Scheduler %s: encerrado", name)
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func runSafely(ctx context.Context, name string, job func(ctx context.Context)) {
+	defer func() {
+		if p := recover(); p != nil {
+			utilities.LogError(fmt.Errorf("%v", p), fmt.Sprintf("Scheduler %s: pânico durante execução", name))
+		}
+	}()
+	job(ctx)
+}
+
+// WithAdvisoryLock executa fn somente se conseguir o advisory lock lockKey no PostgreSQL.
+// Serve para que apenas uma réplica do servidor execute um job periódico por vez.
+// Retorna ran=false (sem erro) quando outra instância já detém o lock.
+func WithAdvisoryLock(ctx context.Context, db *sql.DB, lockKey int64, fn func(ctx context.Context) error) (ran bool, err error) {
+	// O advisory lock é por sessão, então fixamos uma única conexão do pool.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("erro ao obter conexão para advisory lock: %w", err)
+	}
+	defer conn.Close()
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", lockKey).Scan(&acquired); err != nil {
+		return false, fmt.Errorf("erro ao tentar advisory lock %d: %w", lockKey, err)
+	}
+	if !acquired {
+		return false, nil
+	}
+	defer func() {
+		if _, unlockErr := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey); unlockErr != nil {
+			utilities.LogError(unlockErr, fmt.Sprintf("Scheduler: falha ao liberar advisory lock %d", lockKey))
+		}
+	}()
+
+	return true, fn(ctx)
+}
+
+// IntFromEnv lê um inteiro positivo da variável de ambiente key, retornando def se ela
+// estiver vazia ou inválida.
+func IntFromEnv(key string, def int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		utilities.LogInfo("Valor inválido para %s (%q), usando padrão %d", key, raw, def)
+		return def
+	}
+	return n
+}
+
+// DurationFromEnv lê uma duração (ex: "5m", "1h") da variável de ambiente key,
+// retornando def se ela estiver vazia ou inválida.
+func DurationFromEnv(key string, def time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		utilities.LogInfo("Valor inválido para %s (%q), usando padrão %v", key, value, def)
+		return def
+	}
+	return d
+}
+
+// EnabledFromEnv retorna false apenas se a variável key estiver explicitamente desligada.
+func EnabledFromEnv(key string) bool {
+	switch os.Getenv(key) {
+	case "false", "0", "no", "off":
+		return false
+	}
+	return true
+}
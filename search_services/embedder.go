@@ -0,0 +1,336 @@
+package search_services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"projeto-integrador/models"
+	"projeto-integrador/scheduler"
+	"projeto-integrador/utilities"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Embedder transforma textos em vetores. Vetores de textos parecidos têm alta
+// similaridade de cosseno. Name identifica o modelo: vetores de embedders diferentes
+// não são comparáveis, então o índice guarda o nome junto com cada vetor.
+type Embedder interface {
+	Name() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+const (
+	EmbedderHashing = "hashing"
+	EmbedderHTTP    = "http"
+
+	defaultHashingDimensions = 512
+)
+
+// HashingEmbedder gera vetores localmente, sem rede: cada termo (e cada trigrama de
+// caracteres do termo, para aproximar plurais e variações) é somado em uma posição do
+// vetor escolhida por hash, com peso logarítmico da frequência.
+type HashingEmbedder struct {
+	dimensions int
+}
+
+// NewHashingEmbedder cria o embedder local com a dimensão informada.
+func NewHashingEmbedder(dimensions int) *HashingEmbedder {
+	if dimensions < 16 {
+		dimensions = defaultHashingDimensions
+	}
+	return &HashingEmbedder{dimensions: dimensions}
+}
+
+func (e *HashingEmbedder) Name() string {
+	return EmbedderHashing + "-" + strconv.Itoa(e.dimensions)
+}
+
+func (e *HashingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.embed(text)
+	}
+	return vectors, nil
+}
+
+func (e *HashingEmbedder) embed(text string) []float32 {
+	counts := make(map[string]float64)
+	for _, token := range Tokenize(text) {
+		counts[token]++
+		padded := []rune("#" + token + "#")
+		for i := 0; i+3 <= len(padded); i++ {
+			counts["3:"+string(padded[i:i+3])] += 0.5
+		}
+	}
+
+	vector := make([]float32, e.dimensions)
+	for feature, count := range counts {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		weight := float32(1 + math.Log(count+1))
+		if sum&(1<<63) != 0 { // Sinal por hash reduz o efeito de colisões
+			weight = -weight
+		}
+		vector[sum%uint64(e.dimensions)] += weight
+	}
+	return normalizeVector(vector)
+}
+
+// HTTPEmbedder chama um serviço de embeddings compatível com a API de embeddings da
+// OpenAI: POST {"model": "...", "input": ["..."]}, resposta {"data": [{"index": 0, "embedding": [...]}]}.
+type HTTPEmbedder struct {
+	url       string
+	model     string
+	apiKey    string
+	batchSize int
+	client    *http.Client
+}
+
+func (e *HTTPEmbedder) Name() string {
+	return EmbedderHTTP + ":" + e.model
+}
+
+func (e *HTTPEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += e.batchSize {
+		end := start + e.batchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batch, err := e.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (e *HTTPEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{"model": e.model, "input": texts})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao serializar requisição de embeddings: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar requisição de embeddings: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao chamar serviço de embeddings: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler resposta do serviço de embeddings: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("serviço de embeddings retornou status %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+
+	var parsed struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return nil, fmt.Errorf("resposta inválida do serviço de embeddings: %w", err)
+	}
+	if len(parsed.Data) != len(texts) {
+		return nil, fmt.Errorf("serviço de embeddings retornou %d vetores para %d textos", len(parsed.Data), len(texts))
+	}
+	vectors := make([][]float32, len(texts))
+	for _, item := range parsed.Data {
+		if item.Index < 0 || item.Index >= len(texts) || len(item.Embedding) == 0 {
+			return nil, fmt.Errorf("serviço de embeddings retornou um vetor inválido (índice %d)", item.Index)
+		}
+		vectors[item.Index] = normalizeVector(item.Embedding)
+	}
+	return vectors, nil
+}
+
+var (
+	embedderOnce    sync.Once
+	embedder        Embedder
+	hashingEmbedder *HashingEmbedder
+)
+
+// GetEmbedder retorna o embedder configurado (criado uma vez por processo).
+//
+// Variáveis de ambiente:
+//   - SEARCH_EMBEDDER: "hashing" (padrão, local) ou "http"
+//   - SEARCH_HASHING_DIMENSIONS: dimensão dos vetores do embedder local (padrão: 512)
+//   - SEARCH_EMBEDDING_URL: endpoint do serviço de embeddings (obrigatório para "http")
+//   - SEARCH_EMBEDDING_MODEL: modelo enviado ao serviço (padrão: "text-embedding-3-small")
+//   - SEARCH_EMBEDDING_API_KEY: token enviado como Bearer (opcional)
+//   - SEARCH_EMBEDDING_TIMEOUT: timeout de cada chamada (padrão: 30s)
+//   - SEARCH_EMBEDDING_BATCH_SIZE: textos por chamada (padrão: 64)
+func GetEmbedder() Embedder {
+	loadEmbedders()
+	return embedder
+}
+
+// getHashingEmbedder retorna o embedder local, usado também pelos workspaces que não
+// podem enviar dados ao embedder externo.
+func getHashingEmbedder() *HashingEmbedder {
+	loadEmbedders()
+	return hashingEmbedder
+}
+
+func loadEmbedders() {
+	embedderOnce.Do(func() {
+		hashingEmbedder = NewHashingEmbedder(scheduler.IntFromEnv("SEARCH_HASHING_DIMENSIONS", defaultHashingDimensions))
+		embedder = embedderFromEnv(hashingEmbedder)
+		utilities.LogInfo("Busca: usando embedder %s", embedder.Name())
+	})
+}
+
+func embedderFromEnv(hashing *HashingEmbedder) Embedder {
+	switch name := strings.TrimSpace(os.Getenv("SEARCH_EMBEDDER")); name {
+	case "", EmbedderHashing:
+		return hashing
+	case EmbedderHTTP:
+		url := os.Getenv("SEARCH_EMBEDDING_URL")
+		if url == "" {
+			utilities.LogInfo("Busca: SEARCH_EMBEDDER=http sem SEARCH_EMBEDDING_URL, usando o embedder local")
+			return hashing
+		}
+		model := os.Getenv("SEARCH_EMBEDDING_MODEL")
+		if model == "" {
+			model = "text-embedding-3-small"
+		}
+		return &HTTPEmbedder{
+			url:       url,
+			model:     model,
+			apiKey:    os.Getenv("SEARCH_EMBEDDING_API_KEY"),
+			batchSize: scheduler.IntFromEnv("SEARCH_EMBEDDING_BATCH_SIZE", 64),
+			client:    &http.Client{Timeout: scheduler.DurationFromEnv("SEARCH_EMBEDDING_TIMEOUT", 30*time.Second)},
+		}
+	default:
+		utilities.LogInfo("Busca: embedder desconhecido %q, usando o embedder local", name)
+		return hashing
+	}
+}
+
+// EmbeddingRedactor mascara os textos de um workspace antes do envio ao serviço de
+// embeddings externo. Os placeholders não precisam ser restaurados: os vetores só servem
+// para comparar similaridade.
+type EmbeddingRedactor func(workspaceIDPg int64, texts []string) ([]string, error)
+
+var (
+	embeddingRedactorMu sync.RWMutex
+	embeddingRedactor   EmbeddingRedactor
+)
+
+// SetEmbeddingRedactor registra o mascaramento aplicado aos textos enviados ao embedder
+// externo (ex: ai_services.RedactEmbeddingTexts). Sem ele, nenhum workspace usa o
+// embedder externo.
+func SetEmbeddingRedactor(redactor EmbeddingRedactor) {
+	embeddingRedactorMu.Lock()
+	defer embeddingRedactorMu.Unlock()
+	embeddingRedactor = redactor
+}
+
+func getEmbeddingRedactor() EmbeddingRedactor {
+	embeddingRedactorMu.RLock()
+	defer embeddingRedactorMu.RUnlock()
+	return embeddingRedactor
+}
+
+// workspaceEmbedder é o embedder usado para as tarefas e as consultas de um workspace.
+// Com redact definido, o embedder é externo: os textos são mascarados antes do envio.
+type workspaceEmbedder struct {
+	Embedder
+	workspaceIDPg       int64
+	redact              EmbeddingRedactor
+	includeDescriptions bool
+	includeComments     bool
+}
+
+// embedderForWorkspace escolhe o embedder do workspace. O embedder local não envia nada
+// para fora do processo e indexa o texto completo das tarefas. O embedder externo só é
+// usado se o workspace mantém a IA habilitada e se há um EmbeddingRedactor registrado;
+// as descrições e os comentários só são enviados a ele com include_descriptions e
+// include_comments. Nos outros casos, o workspace usa o embedder local.
+func embedderForWorkspace(db *sql.DB, workspaceIDPg int64) (*workspaceEmbedder, error) {
+	local := &workspaceEmbedder{Embedder: getHashingEmbedder(), workspaceIDPg: workspaceIDPg, includeDescriptions: true, includeComments: true}
+	configured := GetEmbedder()
+	if _, ok := configured.(*HashingEmbedder); ok {
+		return local, nil
+	}
+	redact := getEmbeddingRedactor()
+	if redact == nil {
+		return local, nil
+	}
+	settings, err := models.GetWorkspaceAISettings(db, workspaceIDPg)
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Enabled {
+		return local, nil
+	}
+	return &workspaceEmbedder{
+		Embedder:            configured,
+		workspaceIDPg:       workspaceIDPg,
+		redact:              redact,
+		includeDescriptions: settings.IncludeDescriptions,
+		includeComments:     settings.IncludeComments,
+	}, nil
+}
+
+func (e *workspaceEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if e.redact != nil {
+		redacted, err := e.redact(e.workspaceIDPg, texts)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao mascarar textos do workspace %d para o serviço de embeddings: %w", e.workspaceIDPg, err)
+		}
+		texts = redacted
+	}
+	return e.Embedder.Embed(ctx, texts)
+}
+
+// normalizeVector devolve o vetor com norma 1, para que o produto escalar seja a
+// similaridade de cosseno. Vetores nulos ficam como estão.
+func normalizeVector(vector []float32) []float32 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return vector
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector
+}
+
+// cosineSimilarity assume vetores normalizados; vetores de tamanhos diferentes (de
+// outro modelo) têm similaridade 0.
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot
+}
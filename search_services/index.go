@@ -0,0 +1,311 @@
+package search_services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"projeto-integrador/database"
+	"projeto-integrador/firebase"
+	"projeto-integrador/models"
+	"projeto-integrador/scheduler"
+	"projeto-integrador/task_services"
+	"projeto-integrador/utilities"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	maxIndexedComments   = 50   // Comentários mais recentes de cada tarefa incluídos no texto indexado
+	maxIndexedTextLength = 8000 // Caracteres do texto indexado por tarefa
+	indexerDebounce      = time.Second
+)
+
+// taskIndexText monta o texto indexado da tarefa: título, descrição, etiquetas,
+// checklist e comentários. Sem includeDescription a descrição fica de fora.
+func taskIndexText(task models.TaskDetailsFirestore, comments []models.TaskComment, includeDescription bool) string {
+	parts := []string{task.Title, strings.Join(task.Labels, " ")}
+	if includeDescription {
+		parts = append(parts, task.Description)
+	}
+	for _, item := range task.Checklist {
+		parts = append(parts, item.Text)
+	}
+	if len(comments) > maxIndexedComments {
+		comments = comments[len(comments)-maxIndexedComments:]
+	}
+	for _, comment := range comments {
+		parts = append(parts, comment.Text)
+	}
+	text := strings.Join(strings.Fields(strings.Join(parts, "\n")), " ")
+	if runes := []rune(text); len(runes) > maxIndexedTextLength {
+		text = string(runes[:maxIndexedTextLength])
+	}
+	return text
+}
+
+func contentHash(embedderName string, text string) string {
+	sum := sha256.Sum256([]byte(embedderName + "\x00" + text))
+	return hex.EncodeToString(sum[:])
+}
+
+// indexTasks (re)indexa as tarefas informadas. Tarefas cujo texto não mudou desde a
+// última indexação (mesmo hash) só têm o last_updated_at registrado atualizado.
+// Retorna quantas tarefas tiveram o vetor recalculado.
+func indexTasks(ctx context.Context, db *sql.DB, client *firestore.Client, embedder *workspaceEmbedder, workspaceIDPg int64, docIDs []string, tasks []models.TaskDetailsFirestore, existing map[string]models.TaskSearchEntry) (int, error) {
+	var pending []models.TaskSearchEntry
+	var texts []string
+	for i, docID := range docIDs {
+		var comments []models.TaskComment
+		if embedder.includeComments {
+			var err error
+			comments, err = task_services.ListTaskComments(ctx, client, workspaceIDPg, docID)
+			if err != nil {
+				return 0, err
+			}
+		}
+		text := taskIndexText(tasks[i], comments, embedder.includeDescriptions)
+		entry := models.TaskSearchEntry{
+			WorkspaceID:   workspaceIDPg,
+			TaskDocID:     docID,
+			Embedder:      embedder.Name(),
+			ContentHash:   contentHash(embedder.Name(), text),
+			TaskUpdatedAt: tasks[i].LastUpdatedAt,
+		}
+		if current, ok := existing[docID]; ok && current.ContentHash == entry.ContentHash {
+			if err := models.TouchTaskSearchEntry(db, workspaceIDPg, docID, entry.TaskUpdatedAt); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		pending = append(pending, entry)
+		texts = append(texts, text)
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	vectors, err := embedder.Embed(ctx, texts)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao gerar embeddings do workspace %d: %w", workspaceIDPg, err)
+	}
+	for i := range pending {
+		pending[i].Embedding = vectors[i]
+		if err := models.UpsertTaskSearchEntry(db, pending[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(pending), nil
+}
+
+// SyncWorkspaceIndex deixa o índice do workspace igual às tarefas: indexa as tarefas
+// novas ou alteradas desde a última indexação (pelo last_updated_at), as indexadas por
+// outro embedder, e remove as que não existem mais.
+func SyncWorkspaceIndex(ctx context.Context, db *sql.DB, client *firestore.Client, workspaceIDPg int64) error {
+	embedder, err := embedderForWorkspace(db, workspaceIDPg)
+	if err != nil {
+		return err
+	}
+	entries, err := models.ListTaskSearchEntries(db, workspaceIDPg, false)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]models.TaskSearchEntry, len(entries))
+	for _, entry := range entries {
+		existing[entry.TaskDocID] = entry
+	}
+
+	docIDs, tasks, err := task_services.ListTasks(ctx, client, workspaceIDPg)
+	if err != nil {
+		return err
+	}
+	var staleIDs []string
+	var staleTasks []models.TaskDetailsFirestore
+	stale := make(map[string]models.TaskSearchEntry)
+	for i, docID := range docIDs {
+		entry, ok := existing[docID]
+		delete(existing, docID)
+		if ok && entry.Embedder == embedder.Name() && !tasks[i].LastUpdatedAt.After(entry.TaskUpdatedAt) {
+			continue
+		}
+		if ok && entry.Embedder == embedder.Name() {
+			stale[docID] = entry // Mesmo embedder: o hash ainda pode evitar um novo vetor
+		}
+		staleIDs = append(staleIDs, docID)
+		staleTasks = append(staleTasks, tasks[i])
+	}
+
+	// O que sobrou em existing são tarefas que não existem mais
+	removed := make([]string, 0, len(existing))
+	for docID := range existing {
+		removed = append(removed, docID)
+	}
+	if err := models.DeleteTaskSearchEntries(db, workspaceIDPg, removed...); err != nil {
+		return err
+	}
+
+	indexed, err := indexTasks(ctx, db, client, embedder, workspaceIDPg, staleIDs, staleTasks, stale)
+	if err != nil {
+		return err
+	}
+	if indexed > 0 || len(removed) > 0 {
+		utilities.LogInfo("Busca: índice do workspace %d sincronizado (%d tarefas indexadas, %d removidas)", workspaceIDPg, indexed, len(removed))
+	}
+	return nil
+}
+
+// reindexTask atualiza uma tarefa no índice depois de uma alteração: remove a tarefa
+// se ela não existe mais ou recalcula o vetor se o texto mudou.
+func reindexTask(ctx context.Context, db *sql.DB, client *firestore.Client, workspaceIDPg int64, taskDocID string) error {
+	task, err := task_services.GetTask(ctx, client, workspaceIDPg, taskDocID)
+	if status.Code(err) == codes.NotFound {
+		return models.DeleteTaskSearchEntries(db, workspaceIDPg, taskDocID)
+	}
+	if err != nil {
+		return err
+	}
+	entries, err := models.ListTaskSearchEntries(db, workspaceIDPg, false)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]models.TaskSearchEntry)
+	for _, entry := range entries {
+		if entry.TaskDocID == taskDocID {
+			existing[taskDocID] = entry
+		}
+	}
+	embedder, err := embedderForWorkspace(db, workspaceIDPg)
+	if err != nil {
+		return err
+	}
+	_, err = indexTasks(ctx, db, client, embedder, workspaceIDPg, []string{taskDocID}, []models.TaskDetailsFirestore{*task}, existing)
+	return err
+}
+
+// workspaceSyncs guarda quando o índice de cada workspace foi sincronizado por este
+// processo, e serializa as sincronizações de um mesmo workspace.
+var workspaceSyncs = struct {
+	sync.Mutex
+	lastSync map[int64]time.Time
+	locks    map[int64]*sync.Mutex
+}{lastSync: make(map[int64]time.Time), locks: make(map[int64]*sync.Mutex)}
+
+// EnsureWorkspaceIndexed sincroniza o índice do workspace se ele não foi sincronizado
+// por este processo há mais de SEARCH_SYNC_INTERVAL (padrão: 10m). As alterações feitas
+// por este processo já chegam ao índice pelo indexador; a sincronização cobre tarefas
+// anteriores ao índice e alterações feitas por outras instâncias.
+func EnsureWorkspaceIndexed(ctx context.Context, db *sql.DB, client *firestore.Client, workspaceIDPg int64) error {
+	workspaceSyncs.Lock()
+	lock, ok := workspaceSyncs.locks[workspaceIDPg]
+	if !ok {
+		lock = &sync.Mutex{}
+		workspaceSyncs.locks[workspaceIDPg] = lock
+	}
+	workspaceSyncs.Unlock()
+
+	lock.Lock()
+	defer lock.Unlock()
+	workspaceSyncs.Lock()
+	lastSync := workspaceSyncs.lastSync[workspaceIDPg]
+	workspaceSyncs.Unlock()
+	if time.Since(lastSync) < scheduler.DurationFromEnv("SEARCH_SYNC_INTERVAL", 10*time.Minute) {
+		return nil
+	}
+
+	if err := SyncWorkspaceIndex(ctx, db, client, workspaceIDPg); err != nil {
+		return err
+	}
+	workspaceSyncs.Lock()
+	workspaceSyncs.lastSync[workspaceIDPg] = time.Now()
+	workspaceSyncs.Unlock()
+	return nil
+}
+
+// taskChange é uma alteração de tarefa pendente de indexação.
+type taskChange struct {
+	workspaceIDPg int64
+	taskDocID     string
+}
+
+// searchIndexer aplica ao índice as alterações de tarefas feitas por este processo.
+type searchIndexer struct {
+	changes chan taskChange
+	db      database.SharedConnection // Conexão única (pool do database/sql) do indexador
+}
+
+// StartSearchIndexer registra o indexador nas alterações de tarefas (task_services.OnTaskChange)
+// e inicia a goroutine que atualiza o índice de busca.
+//
+// Variáveis de ambiente:
+//   - SEARCH_INDEX_ENABLED: "false" desliga a indexação incremental (a busca ainda
+//     sincroniza o índice periodicamente)
+func StartSearchIndexer(ctx context.Context) {
+	if !scheduler.EnabledFromEnv("SEARCH_INDEX_ENABLED") {
+		utilities.LogInfo("Indexação incremental da busca desativada por SEARCH_INDEX_ENABLED")
+		return
+	}
+	indexer := &searchIndexer{changes: make(chan taskChange, 1000)}
+	task_services.OnTaskChange(func(workspaceIDPg int64, taskDocID string, deleted bool) {
+		select {
+		case indexer.changes <- taskChange{workspaceIDPg: workspaceIDPg, taskDocID: taskDocID}:
+		default:
+			// Fila cheia: a próxima sincronização do workspace corrige o índice
+			utilities.LogInfo("Busca: fila de indexação cheia, alteração da tarefa %s adiada", taskDocID)
+		}
+	})
+	go indexer.run(ctx)
+}
+
+// run agrupa as alterações recebidas em um intervalo curto (várias alterações da mesma
+// tarefa viram uma) e as aplica ao índice.
+func (ix *searchIndexer) run(ctx context.Context) {
+	for {
+		var first taskChange
+		select {
+		case <-ctx.Done():
+			return
+		case first = <-ix.changes:
+		}
+		batch := map[taskChange]bool{first: true}
+		timer := time.NewTimer(indexerDebounce)
+	collect:
+		for {
+			select {
+			case change := <-ix.changes:
+				batch[change] = true
+			case <-timer.C:
+				break collect
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+		ix.apply(ctx, batch)
+	}
+}
+
+func (ix *searchIndexer) apply(ctx context.Context, batch map[taskChange]bool) {
+	db, err := ix.db.Get()
+	if err != nil {
+		utilities.LogError(err, "SearchIndexer: Erro ao conectar ao PG")
+		return
+	}
+	client, err := firebase.GetFirestoreClient()
+	if err != nil {
+		utilities.LogError(err, "SearchIndexer: Erro ao obter cliente Firestore")
+		return
+	}
+	defer client.Close()
+
+	for change := range batch {
+		if err := reindexTask(ctx, db, client, change.workspaceIDPg, change.taskDocID); err != nil {
+			utilities.LogError(err, fmt.Sprintf("SearchIndexer: Erro ao indexar tarefa %s do workspace %d", change.taskDocID, change.workspaceIDPg))
+		}
+	}
+}
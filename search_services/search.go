@@ -0,0 +1,138 @@
+package search_services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"projeto-integrador/models"
+	"projeto-integrador/task_services"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+
+	minSearchScore   = 0.08 // Similaridade mínima para uma tarefa aparecer na busca
+	maxSnippetLength = 160
+)
+
+// ErrEmptySearchQuery indica uma consulta sem texto. Os handlers devem responder 400.
+var ErrEmptySearchQuery = errors.New("consulta de busca vazia")
+
+// TaskSimilarity é a similaridade de uma tarefa indexada com uma consulta.
+type TaskSimilarity struct {
+	TaskDocID string
+	Score     float64
+}
+
+// Similarities sincroniza o índice do workspace (se necessário) e retorna as tarefas
+// indexadas com similaridade de pelo menos minScore com a consulta, da mais para a
+// menos similar.
+func Similarities(ctx context.Context, db *sql.DB, client *firestore.Client, workspaceIDPg int64, query string, minScore float64) ([]TaskSimilarity, error) {
+	similarities, _, err := workspaceSimilarities(ctx, db, client, workspaceIDPg, query, minScore)
+	return similarities, err
+}
+
+// workspaceSimilarities é Similarities retornando também o nome do embedder usado no
+// workspace.
+func workspaceSimilarities(ctx context.Context, db *sql.DB, client *firestore.Client, workspaceIDPg int64, query string, minScore float64) ([]TaskSimilarity, string, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, "", ErrEmptySearchQuery
+	}
+	if err := EnsureWorkspaceIndexed(ctx, db, client, workspaceIDPg); err != nil {
+		return nil, "", err
+	}
+
+	embedder, err := embedderForWorkspace(db, workspaceIDPg)
+	if err != nil {
+		return nil, "", err
+	}
+	vectors, err := embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, "", fmt.Errorf("erro ao gerar embedding da consulta: %w", err)
+	}
+	entries, err := models.ListTaskSearchEntries(db, workspaceIDPg, true)
+	if err != nil {
+		return nil, "", err
+	}
+
+	similarities := []TaskSimilarity{}
+	for _, entry := range entries {
+		if entry.Embedder != embedder.Name() {
+			continue
+		}
+		score := cosineSimilarity(vectors[0], entry.Embedding)
+		if score >= minScore {
+			similarities = append(similarities, TaskSimilarity{TaskDocID: entry.TaskDocID, Score: score})
+		}
+	}
+	sort.SliceStable(similarities, func(i, j int) bool { return similarities[i].Score > similarities[j].Score })
+	return similarities, embedder.Name(), nil
+}
+
+// Search busca as tarefas do workspace mais similares à consulta (título, descrição,
+// etiquetas, checklist e comentários). limit fora de 1..MaxSearchLimit usa o padrão.
+func Search(ctx context.Context, db *sql.DB, client *firestore.Client, workspaceIDPg int64, query string, limit int) (*models.TaskSearchResponse, error) {
+	if limit < 1 || limit > MaxSearchLimit {
+		limit = DefaultSearchLimit
+	}
+	similarities, embedderName, err := workspaceSimilarities(ctx, db, client, workspaceIDPg, query, minSearchScore)
+	if err != nil {
+		return nil, err
+	}
+	if len(similarities) > limit {
+		similarities = similarities[:limit]
+	}
+
+	response := &models.TaskSearchResponse{
+		Query:    strings.TrimSpace(query),
+		Embedder: embedderName,
+		Results:  []models.TaskSearchResult{},
+	}
+	if len(similarities) == 0 {
+		return response, nil
+	}
+
+	refs := make([]*firestore.DocumentRef, len(similarities))
+	for i, similarity := range similarities {
+		refs[i] = task_services.TaskRef(client, workspaceIDPg, similarity.TaskDocID)
+	}
+	snaps, err := client.GetAll(ctx, refs)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar tarefas encontradas: %w", err)
+	}
+	for i, snap := range snaps {
+		if !snap.Exists() {
+			continue // Removida depois da última indexação
+		}
+		var task models.TaskDetailsFirestore
+		if err := snap.DataTo(&task); err != nil {
+			continue
+		}
+		response.Results = append(response.Results, models.TaskSearchResult{
+			TaskID:         similarities[i].TaskDocID,
+			Title:          task.Title,
+			Status:         task.Status,
+			Priority:       task.Priority,
+			ExpirationDate: task.ExpirationDate,
+			Snippet:        snippet(task.Description),
+			Score:          math.Round(similarities[i].Score*1000) / 1000,
+		})
+	}
+	return response, nil
+}
+
+func snippet(description string) string {
+	text := strings.Join(strings.Fields(description), " ")
+	runes := []rune(text)
+	if len(runes) <= maxSnippetLength {
+		return text
+	}
+	return strings.TrimSpace(string(runes[:maxSnippetLength])) + "…"
+}
@@ -0,0 +1,53 @@
+package search_services
+
+import (
+	"strings"
+	"unicode"
+)
+
+// accentFolder remove os acentos mais comuns, para "revisão" casar com "revisao".
+var accentFolder = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n",
+)
+
+// searchStopWords são palavras comuns (português e inglês) ignoradas na busca.
+var searchStopWords = map[string]bool{
+	"a": true, "o": true, "as": true, "os": true, "um": true, "uma": true, "de": true, "do": true, "da": true,
+	"dos": true, "das": true, "e": true, "em": true, "no": true, "na": true, "nos": true, "nas": true,
+	"para": true, "por": true, "com": true, "que": true, "se": true, "ao": true, "me": true, "meu": true,
+	"minha": true, "qual": true, "quais": true, "como": true, "sobre": true, "tem": true, "ha": true,
+	"the": true, "an": true, "and": true, "or": true, "of": true, "to": true, "in": true, "on": true,
+	"for": true, "with": true, "is": true, "are": true, "what": true, "which": true, "my": true,
+}
+
+// Tokenize normaliza o texto para a busca: minúsculas, sem acentos, separado em
+// letras e dígitos e sem palavras comuns.
+func Tokenize(text string) []string {
+	text = accentFolder.Replace(strings.ToLower(text))
+	words := strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	tokens := words[:0]
+	for _, word := range words {
+		if len(word) > 1 && !searchStopWords[word] {
+			tokens = append(tokens, word)
+		}
+	}
+	return tokens
+}
+
+// QueryTerms são os termos distintos da consulta.
+func QueryTerms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, token := range Tokenize(query) {
+		if !seen[token] {
+			seen[token] = true
+			terms = append(terms, token)
+		}
+	}
+	return terms
+}
@@ -0,0 +1,527 @@
+package task_services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"projeto-integrador/database"
+	"projeto-integrador/firebase"
+	"projeto-integrador/models"
+	"projeto-integrador/scheduler"
+	"projeto-integrador/utilities"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Origens suportadas pelo importador de quadros.
+const (
+	BoardSourceTrello = "trello"
+	BoardSourceJira   = "jira"
+)
+
+// A cada quantas tarefas o progresso do job é gravado no PostgreSQL.
+const importProgressEvery = 10
+
+// Intervalo de renovação do heartbeat de um job de importação em execução.
+const importJobHeartbeatInterval = 30 * time.Second
+
+// BoardTask é uma tarefa pronta para ser criada, com os comentários da origem.
+type BoardTask struct {
+	ExternalID string // ID do cartão/issue na ferramenta de origem
+	Input      models.CreateTaskInput
+	Comments   []models.TaskComment
+}
+
+// BoardImportPlan é o resultado da leitura do arquivo: o que será criado, o que foi
+// ignorado e o que será criado com ressalvas.
+type BoardImportPlan struct {
+	Source   string
+	Tasks    []BoardTask
+	Skipped  []models.ImportSkippedItem
+	Warnings []models.ImportWarning
+}
+
+// ParseBoardExport lê a exportação de acordo com a origem. statusMapping permite
+// definir o status de cada lista (Trello) ou status (Jira) pelo nome; nomes sem
+// mapeamento são inferidos (ex: "Doing" → in_progress) e, na dúvida, viram "pending".
+func ParseBoardExport(source string, r io.Reader, statusMapping map[string]string) (*BoardImportPlan, error) {
+	mapping := make(map[string]string, len(statusMapping))
+	for name, status := range statusMapping {
+		normalized, err := NormalizeStatus(status)
+		if err != nil {
+			return nil, fmt.Errorf("%w: status_mapping[%q]: %v", ErrInvalidImportFile, name, err)
+		}
+		mapping[strings.ToLower(strings.TrimSpace(name))] = normalized
+	}
+
+	var plan *BoardImportPlan
+	var err error
+	switch source {
+	case BoardSourceTrello:
+		plan, err = parseTrelloBoard(r, mapping)
+	case BoardSourceJira:
+		plan, err = parseJiraCSV(r, mapping)
+	default:
+		return nil, fmt.Errorf("%w: unsupported source %q (use trello or jira)", ErrInvalidImportFile, source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(plan.Tasks) > MaxImportRows {
+		return nil, fmt.Errorf("%w: at most %d tasks per import", ErrInvalidImportFile, MaxImportRows)
+	}
+	return plan, nil
+}
+
+// resolveBoardStatus converte o nome de uma coluna/status da origem para um status da API.
+func resolveBoardStatus(name string, mapping map[string]string) string {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if status, ok := mapping[key]; ok {
+		return status
+	}
+	if status, err := NormalizeStatus(key); err == nil && status != "skipped" { // Quadros não têm séries recorrentes
+		return status
+	}
+	switch {
+	case strings.Contains(key, "done"), strings.Contains(key, "conclu"), strings.Contains(key, "closed"),
+		strings.Contains(key, "resolved"), strings.Contains(key, "complete"):
+		return "completed"
+	case strings.Contains(key, "progress"), strings.Contains(key, "doing"), strings.Contains(key, "andamento"),
+		strings.Contains(key, "review"), strings.Contains(key, "revis"):
+		return "in_progress"
+	}
+	return "pending"
+}
+
+// --- Trello ---
+
+type trelloBoard struct {
+	Name  string `json:"name"`
+	Lists []struct {
+		ID     string `json:"id"`
+		Name   string `json:"name"`
+		Closed bool   `json:"closed"`
+	} `json:"lists"`
+	Cards []struct {
+		ID          string  `json:"id"`
+		Name        string  `json:"name"`
+		Desc        string  `json:"desc"`
+		IDList      string  `json:"idList"`
+		Closed      bool    `json:"closed"`
+		Due         *string `json:"due"`
+		DueComplete bool    `json:"dueComplete"`
+		Labels      []struct {
+			Name  string `json:"name"`
+			Color string `json:"color"`
+		} `json:"labels"`
+	} `json:"cards"`
+	Checklists []struct {
+		IDCard     string `json:"idCard"`
+		Name       string `json:"name"`
+		CheckItems []struct {
+			Name  string  `json:"name"`
+			State string  `json:"state"`
+			Pos   float64 `json:"pos"`
+		} `json:"checkItems"`
+	} `json:"checklists"`
+	Actions []struct {
+		Type string `json:"type"`
+		Date string `json:"date"`
+		Data struct {
+			Text string `json:"text"`
+			Card struct {
+				ID string `json:"id"`
+			} `json:"card"`
+		} `json:"data"`
+		MemberCreator struct {
+			FullName string `json:"fullName"`
+		} `json:"memberCreator"`
+	} `json:"actions"`
+}
+
+func parseTrelloBoard(r io.Reader, mapping map[string]string) (*BoardImportPlan, error) {
+	var board trelloBoard
+	if err := json.NewDecoder(r).Decode(&board); err != nil {
+		return nil, fmt.Errorf("%w: invalid Trello JSON: %w", ErrInvalidImportFile, err)
+	}
+	if len(board.Lists) == 0 && len(board.Cards) == 0 {
+		return nil, fmt.Errorf("%w: no lists or cards found (is this a Trello board export?)", ErrInvalidImportFile)
+	}
+
+	plan := &BoardImportPlan{Source: BoardSourceTrello}
+
+	type listInfo struct {
+		name   string
+		closed bool
+	}
+	lists := make(map[string]listInfo, len(board.Lists))
+	for _, list := range board.Lists {
+		lists[list.ID] = listInfo{name: list.Name, closed: list.Closed}
+	}
+
+	checklistsPerCard := make(map[string]int)
+	for _, checklist := range board.Checklists {
+		checklistsPerCard[checklist.IDCard]++
+	}
+	checklists := make(map[string][]models.ChecklistItem)
+	for _, checklist := range board.Checklists {
+		items := checklist.CheckItems
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Pos < items[j].Pos })
+		for _, item := range items {
+			text := item.Name
+			if checklistsPerCard[checklist.IDCard] > 1 && checklist.Name != "" {
+				text = checklist.Name + ": " + text // Preserva o nome quando o cartão tem várias checklists
+			}
+			checklists[checklist.IDCard] = append(checklists[checklist.IDCard], models.ChecklistItem{
+				Text: text,
+				Done: item.State == "complete",
+			})
+		}
+	}
+
+	comments := make(map[string][]models.TaskComment)
+	for _, action := range board.Actions {
+		if action.Type != "commentCard" || action.Data.Text == "" {
+			continue
+		}
+		createdAt, err := time.Parse(time.RFC3339, action.Date)
+		if err != nil {
+			createdAt = time.Now()
+		}
+		cardID := action.Data.Card.ID
+		comments[cardID] = append(comments[cardID], models.TaskComment{
+			AuthorName: action.MemberCreator.FullName,
+			Text:       action.Data.Text,
+			CreatedAt:  createdAt,
+		})
+	}
+
+	for _, card := range board.Cards {
+		item := fmt.Sprintf("card %q", card.Name)
+		list, listFound := lists[card.IDList]
+		switch {
+		case strings.TrimSpace(card.Name) == "":
+			plan.Skipped = append(plan.Skipped, models.ImportSkippedItem{Item: "card " + card.ID, Reason: "card has no title"})
+			continue
+		case card.Closed:
+			plan.Skipped = append(plan.Skipped, models.ImportSkippedItem{Item: item, Reason: "card is archived"})
+			continue
+		case !listFound:
+			plan.Skipped = append(plan.Skipped, models.ImportSkippedItem{Item: item, Reason: "card list not found in export"})
+			continue
+		case list.closed:
+			plan.Skipped = append(plan.Skipped, models.ImportSkippedItem{Item: item, Reason: fmt.Sprintf("list %q is archived", list.name)})
+			continue
+		}
+
+		input := models.CreateTaskInput{
+			Title:       strings.TrimSpace(card.Name),
+			Description: card.Desc,
+			Status:      resolveBoardStatus(list.name, mapping),
+			Checklist:   checklists[card.ID],
+		}
+		if card.Due != nil && *card.Due != "" {
+			if due, err := time.Parse(time.RFC3339, *card.Due); err == nil {
+				input.ExpirationDate = &due
+			}
+			if card.DueComplete {
+				input.Status = "completed"
+			}
+		}
+		for _, label := range card.Labels {
+			name := label.Name
+			if name == "" {
+				name = label.Color // Etiquetas sem nome no Trello são identificadas pela cor
+			}
+			if name != "" {
+				input.Labels = append(input.Labels, name)
+			}
+		}
+
+		cardComments := comments[card.ID]
+		sort.SliceStable(cardComments, func(i, j int) bool { return cardComments[i].CreatedAt.Before(cardComments[j].CreatedAt) })
+		plan.Tasks = append(plan.Tasks, BoardTask{ExternalID: card.ID, Input: input, Comments: cardComments})
+	}
+	return plan, nil
+}
+
+// --- Jira ---
+
+// Formatos de data usados pelas exportações CSV do Jira (dependem do idioma da conta).
+var jiraDateLayouts = []string{
+	"02/Jan/06 3:04 PM",
+	"02/Jan/06 15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	time.RFC3339,
+}
+
+var jiraPriorities = map[string]string{
+	"highest": "high",
+	"high":    "high",
+	"medium":  "medium",
+	"low":     "low",
+	"lowest":  "low",
+}
+
+func parseJiraDate(raw string) (time.Time, bool) {
+	raw = strings.TrimSpace(raw)
+	for _, layout := range jiraDateLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			if layout == "2006-01-02" {
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, true
+		}
+	}
+	if t, err := ParseImportDate(raw); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+func parseJiraCSV(r io.Reader, mapping map[string]string) (*BoardImportPlan, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid Jira CSV: %w", ErrInvalidImportFile, err)
+	}
+	// O Jira repete colunas (Labels, Comment...) quando há vários valores.
+	columns := make(map[string][]int)
+	for i, name := range header {
+		key := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[key] = append(columns[key], i)
+	}
+	if len(columns["summary"]) == 0 {
+		return nil, fmt.Errorf("%w: Jira CSV must contain a Summary column", ErrInvalidImportFile)
+	}
+	first := func(values []string, column string) string {
+		for _, i := range columns[column] {
+			if i < len(values) && strings.TrimSpace(values[i]) != "" {
+				return strings.TrimSpace(values[i])
+			}
+		}
+		return ""
+	}
+	all := func(values []string, column string) []string {
+		var result []string
+		for _, i := range columns[column] {
+			if i < len(values) && strings.TrimSpace(values[i]) != "" {
+				result = append(result, strings.TrimSpace(values[i]))
+			}
+		}
+		return result
+	}
+
+	plan := &BoardImportPlan{Source: BoardSourceJira}
+	for {
+		values, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid Jira CSV: %w", ErrInvalidImportFile, err)
+		}
+
+		key := first(values, "issue key")
+		externalID := key
+		if externalID == "" {
+			externalID = first(values, "issue id")
+		}
+		summary := first(values, "summary")
+		item := fmt.Sprintf("issue %s", key)
+		if key == "" {
+			item = fmt.Sprintf("issue %q", summary)
+		}
+		if summary == "" {
+			plan.Skipped = append(plan.Skipped, models.ImportSkippedItem{Item: item, Reason: "issue has no summary"})
+			continue
+		}
+		if externalID == "" {
+			plan.Skipped = append(plan.Skipped, models.ImportSkippedItem{Item: item, Reason: "issue has no key or id"})
+			continue
+		}
+		if issueType := strings.ToLower(first(values, "issue type")); issueType == "epic" {
+			plan.Skipped = append(plan.Skipped, models.ImportSkippedItem{Item: item, Reason: "epics are not imported as tasks"})
+			continue
+		}
+
+		input := models.CreateTaskInput{
+			Title:       summary,
+			Description: first(values, "description"),
+			Status:      resolveBoardStatus(first(values, "status"), mapping),
+			Priority:    jiraPriorities[strings.ToLower(first(values, "priority"))],
+			Labels:      all(values, "labels"),
+		}
+		if raw := first(values, "due date"); raw != "" {
+			if due, ok := parseJiraDate(raw); ok {
+				input.ExpirationDate = &due
+			} else {
+				plan.Warnings = append(plan.Warnings, models.ImportWarning{Item: item, Message: fmt.Sprintf("due date %q not recognized; imported without due date", raw)})
+			}
+		}
+
+		var comments []models.TaskComment
+		for _, raw := range all(values, "comment") {
+			comments = append(comments, parseJiraComment(raw))
+		}
+		plan.Tasks = append(plan.Tasks, BoardTask{ExternalID: externalID, Input: input, Comments: comments})
+	}
+	return plan, nil
+}
+
+// parseJiraComment interpreta o formato "data;autor;texto" usado nas colunas Comment.
+func parseJiraComment(raw string) models.TaskComment {
+	comment := models.TaskComment{Text: raw, CreatedAt: time.Now()}
+	parts := strings.SplitN(raw, ";", 3)
+	if len(parts) == 3 {
+		if createdAt, ok := parseJiraDate(parts[0]); ok {
+			comment.CreatedAt = createdAt
+			comment.AuthorName = strings.TrimSpace(parts[1])
+			comment.Text = strings.TrimSpace(parts[2])
+		}
+	}
+	return comment
+}
+
+// --- Execução ---
+
+// boardTaskDocID gera um ID estável por workspace e item de origem, de modo que
+// reimportar o mesmo quadro não duplique tarefas.
+func boardTaskDocID(source string, workspaceID int64, externalID string) string {
+	safe := strings.NewReplacer("/", "_", ".", "_").Replace(externalID)
+	return fmt.Sprintf("%s-%d-%s", source, workspaceID, safe)
+}
+
+// RunBoardImport executa o job em background: cria as tarefas do plano pelo mesmo
+// caminho de CreateTask, grava os comentários e registra o progresso em import_jobs.
+func RunBoardImport(jobID, workspaceID int64, creatorFirebaseUID string, plan *BoardImportPlan) {
+	go func() {
+		ctx := context.Background()
+		db, err := database.ConnectPostgres()
+		if err != nil {
+			utilities.LogError(err, fmt.Sprintf("RunBoardImport: Erro ao conectar ao PG (job %d)", jobID))
+			return
+		}
+		defer db.Close()
+
+		fail := func(err error) {
+			utilities.LogError(err, fmt.Sprintf("RunBoardImport: Job %d falhou", jobID))
+			if finishErr := models.FinishImportJob(db, jobID, models.ImportJobFailed, err.Error()); finishErr != nil {
+				utilities.LogError(finishErr, fmt.Sprintf("RunBoardImport: Erro ao registrar falha do job %d", jobID))
+			}
+		}
+		defer func() {
+			if rec := recover(); rec != nil {
+				fail(fmt.Errorf("panic: %v", rec))
+			}
+		}()
+
+		if err := models.StartImportJob(db, jobID); err != nil {
+			fail(err)
+			return
+		}
+		heartbeatCtx, stopHeartbeat := context.WithCancel(ctx)
+		defer stopHeartbeat()
+		go importJobHeartbeat(heartbeatCtx, db, jobID)
+		firestoreClient, err := firebase.GetFirestoreClient()
+		if err != nil {
+			fail(err)
+			return
+		}
+		defer firestoreClient.Close()
+
+		skipped := append([]models.ImportSkippedItem{}, plan.Skipped...)
+		warnings := append([]models.ImportWarning{}, plan.Warnings...)
+		created := 0
+		for i, task := range plan.Tasks {
+			input := task.Input
+			input.DocID = boardTaskDocID(plan.Source, workspaceID, task.ExternalID)
+			input.ImportSource = plan.Source
+			input.ExternalID = task.ExternalID
+			item := fmt.Sprintf("%s %q", task.ExternalID, input.Title)
+
+			docID, _, err := CreateTask(ctx, db, firestoreClient, workspaceID, creatorFirebaseUID, input)
+			switch {
+			case errors.Is(err, ErrTaskAlreadyExists):
+				skipped = append(skipped, models.ImportSkippedItem{Item: item, Reason: "already imported"})
+			case err != nil:
+				utilities.LogError(err, fmt.Sprintf("RunBoardImport: Erro ao criar tarefa %s (job %d)", task.ExternalID, jobID))
+				skipped = append(skipped, models.ImportSkippedItem{Item: item, Reason: "failed to create task"})
+			default:
+				created++
+				for _, comment := range task.Comments {
+					if _, err := AddTaskComment(ctx, firestoreClient, workspaceID, docID, comment); err != nil {
+						utilities.LogError(err, fmt.Sprintf("RunBoardImport: Erro ao importar comentário da tarefa %s", docID))
+						warnings = append(warnings, models.ImportWarning{Item: item, Message: "a comment could not be imported"})
+					}
+				}
+			}
+
+			if (i+1)%importProgressEvery == 0 || i == len(plan.Tasks)-1 {
+				if err := models.UpdateImportJobProgress(db, jobID, i+1, created, skipped, warnings); err != nil {
+					utilities.LogError(err, fmt.Sprintf("RunBoardImport: Erro ao registrar progresso do job %d", jobID))
+				}
+			}
+		}
+
+		if err := models.UpdateImportJobProgress(db, jobID, len(plan.Tasks), created, skipped, warnings); err != nil {
+			utilities.LogError(err, fmt.Sprintf("RunBoardImport: Erro ao registrar progresso do job %d", jobID))
+		}
+		if err := models.FinishImportJob(db, jobID, models.ImportJobCompleted, ""); err != nil {
+			utilities.LogError(err, fmt.Sprintf("RunBoardImport: Erro ao finalizar job %d", jobID))
+		}
+		utilities.LogInfo("RunBoardImport: Job %d concluído: %d tarefas criadas, %d itens ignorados", jobID, created, len(skipped))
+	}()
+}
+
+// importJobHeartbeat renova o heartbeat do job até ctx ser cancelado, para que outras
+// instâncias não o deem como interrompido.
+func importJobHeartbeat(ctx context.Context, db *sql.DB, jobID int64) {
+	ticker := time.NewTicker(importJobHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := models.TouchImportJob(db, jobID); err != nil {
+				utilities.LogError(err, fmt.Sprintf("RunBoardImport: Erro ao renovar heartbeat do job %d", jobID))
+			}
+		}
+	}
+}
+
+// StartImportJobSweeper marca periodicamente como falhos os jobs de importação sem
+// heartbeat há mais de IMPORT_JOB_TIMEOUT (padrão: 2m), inclusive logo na inicialização:
+// a instância que os executava parou. Jobs de outras réplicas ativas não são afetados.
+func StartImportJobSweeper(ctx context.Context) {
+	timeout := scheduler.DurationFromEnv("IMPORT_JOB_TIMEOUT", 2*time.Minute)
+	if timeout < 2*importJobHeartbeatInterval {
+		timeout = 2 * importJobHeartbeatInterval
+	}
+	scheduler.Every(ctx, "import-jobs", timeout, func(ctx context.Context) {
+		db, err := database.ConnectPostgres()
+		if err != nil {
+			utilities.LogError(err, "ImportJobSweeper: Erro ao conectar ao PG")
+			return
+		}
+		defer db.Close()
+		interrupted, err := models.FailInterruptedImportJobs(db, timeout)
+		if err != nil {
+			utilities.LogError(err, "ImportJobSweeper: Erro ao encerrar jobs de importação interrompidos")
+			return
+		}
+		if interrupted > 0 {
+			utilities.LogInfo("ImportJobSweeper: %d jobs de importação interrompidos marcados como falhos", interrupted)
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package task_services
+
+import (
+	"context"
+	"fmt"
+	"projeto-integrador/models"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+const codeReviewsSubCollectionName = "code_reviews" // Subcoleção de revisões de código anexadas a cada tarefa
+
+// CodeReviewsCollection retorna a subcoleção de revisões de código de uma tarefa:
+// /workspaces/{workspace_id}/tasks/{task_doc_id}/code_reviews
+func CodeReviewsCollection(client *firestore.Client, workspaceIDPg int64, taskDocID string) *firestore.CollectionRef {
+	return TaskRef(client, workspaceIDPg, taskDocID).Collection(codeReviewsSubCollectionName)
+}
+
+// SaveTaskCodeReview anexa a revisão à tarefa, usando o history_id como ID do
+// documento: anexar de novo a mesma revisão apenas a sobrescreve.
+func SaveTaskCodeReview(ctx context.Context, client *firestore.Client, workspaceIDPg int64, taskDocID string, review models.TaskCodeReview) error {
+	if _, err := CodeReviewsCollection(client, workspaceIDPg, taskDocID).Doc(review.HistoryID).Set(ctx, review); err != nil {
+		return fmt.Errorf("erro ao anexar revisão %s na tarefa %s: %w", review.HistoryID, taskDocID, err)
+	}
+	return nil
+}
+
+// ListTaskCodeReviews lista as revisões anexadas à tarefa, das mais recentes para as
+// mais antigas.
+func ListTaskCodeReviews(ctx context.Context, client *firestore.Client, workspaceIDPg int64, taskDocID string) ([]models.TaskCodeReview, error) {
+	iter := CodeReviewsCollection(client, workspaceIDPg, taskDocID).OrderBy("attached_at", firestore.Desc).Documents(ctx)
+	defer iter.Stop()
+
+	reviews := []models.TaskCodeReview{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao buscar revisões da tarefa %s: %w", taskDocID, err)
+		}
+		var review models.TaskCodeReview
+		if err := doc.DataTo(&review); err != nil {
+			continue
+		}
+		review.HistoryID = doc.Ref.ID
+		reviews = append(reviews, review)
+	}
+	return reviews, nil
+}
@@ -0,0 +1,52 @@
+package task_services
+
+import (
+	"context"
+	"fmt"
+	"projeto-integrador/models"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+const commentsSubCollectionName = "comments" // Subcoleção de comentários de cada tarefa
+
+// CommentsCollection retorna a subcoleção de comentários de uma tarefa:
+// /workspaces/{workspace_id}/tasks/{task_doc_id}/comments
+func CommentsCollection(client *firestore.Client, workspaceIDPg int64, taskDocID string) *firestore.CollectionRef {
+	return TaskRef(client, workspaceIDPg, taskDocID).Collection(commentsSubCollectionName)
+}
+
+// AddTaskComment grava um comentário na tarefa e retorna o ID gerado.
+func AddTaskComment(ctx context.Context, client *firestore.Client, workspaceIDPg int64, taskDocID string, comment models.TaskComment) (string, error) {
+	ref, _, err := CommentsCollection(client, workspaceIDPg, taskDocID).Add(ctx, comment)
+	if err != nil {
+		return "", fmt.Errorf("erro ao adicionar comentário na tarefa %s: %w", taskDocID, err)
+	}
+	NotifyTaskChanged(workspaceIDPg, taskDocID)
+	return ref.ID, nil
+}
+
+// ListTaskComments lista os comentários da tarefa em ordem cronológica.
+func ListTaskComments(ctx context.Context, client *firestore.Client, workspaceIDPg int64, taskDocID string) ([]models.TaskComment, error) {
+	iter := CommentsCollection(client, workspaceIDPg, taskDocID).OrderBy("created_at", firestore.Asc).Documents(ctx)
+	defer iter.Stop()
+
+	comments := []models.TaskComment{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao buscar comentários da tarefa %s: %w", taskDocID, err)
+		}
+		var comment models.TaskComment
+		if err := doc.DataTo(&comment); err != nil {
+			continue
+		}
+		comment.ID = doc.Ref.ID
+		comments = append(comments, comment)
+	}
+	return comments, nil
+}
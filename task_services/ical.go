@@ -0,0 +1,107 @@
+package task_services
+
+import (
+	"fmt"
+	"projeto-integrador/models"
+	"strings"
+	"time"
+)
+
+const (
+	icalTimeLayout = "20060102T150405Z"
+	icalLineLimit  = 75 // Tamanho máximo de linha em octetos (RFC 5545, seção 3.1)
+)
+
+// CalendarTask é uma tarefa com vencimento a ser exportada no feed .ics.
+type CalendarTask struct {
+	WorkspaceID   int64
+	WorkspaceName string
+	TaskDocID     string
+	Task          models.TaskDetailsFirestore
+}
+
+// BuildICalendar gera um VCALENDAR com um VEVENT por tarefa. Cada evento dura
+// 30 minutos terminando no vencimento; o UID é estável para que os clientes de
+// calendário atualizem o evento em vez de duplicá-lo.
+func BuildICalendar(calendarName string, tasks []CalendarTask, now time.Time) string {
+	var b strings.Builder
+	writeICalLine(&b, "BEGIN:VCALENDAR")
+	writeICalLine(&b, "VERSION:2.0")
+	writeICalLine(&b, "PRODID:-//Projeto Integrador//Tarefas//PT-BR")
+	writeICalLine(&b, "CALSCALE:GREGORIAN")
+	writeICalLine(&b, "METHOD:PUBLISH")
+	writeICalLine(&b, "X-WR-CALNAME:"+escapeICalText(calendarName))
+
+	stamp := now.UTC().Format(icalTimeLayout)
+	for _, ct := range tasks {
+		if ct.Task.ExpirationDate == nil {
+			continue
+		}
+		due := ct.Task.ExpirationDate.UTC()
+
+		writeICalLine(&b, "BEGIN:VEVENT")
+		writeICalLine(&b, fmt.Sprintf("UID:%s@workspace-%d.projeto-integrador", ct.TaskDocID, ct.WorkspaceID))
+		writeICalLine(&b, "DTSTAMP:"+stamp)
+		writeICalLine(&b, "DTSTART:"+due.Add(-30*time.Minute).Format(icalTimeLayout))
+		writeICalLine(&b, "DTEND:"+due.Format(icalTimeLayout))
+		if !ct.Task.LastUpdatedAt.IsZero() {
+			writeICalLine(&b, "LAST-MODIFIED:"+ct.Task.LastUpdatedAt.UTC().Format(icalTimeLayout))
+		}
+
+		summary := ct.Task.Title
+		if ct.Task.Status == "completed" {
+			summary = "✔ " + summary
+		}
+		if ct.WorkspaceName != "" {
+			summary = fmt.Sprintf("[%s] %s", ct.WorkspaceName, summary)
+		}
+		writeICalLine(&b, "SUMMARY:"+escapeICalText(summary))
+
+		description := ct.Task.Description
+		details := []string{"Status: " + ct.Task.Status}
+		if ct.Task.Priority != "" {
+			details = append(details, "Prioridade: "+ct.Task.Priority)
+		}
+		if description != "" {
+			description += "\n\n"
+		}
+		description += strings.Join(details, "\n")
+		writeICalLine(&b, "DESCRIPTION:"+escapeICalText(description))
+		if ct.WorkspaceName != "" {
+			writeICalLine(&b, "CATEGORIES:"+escapeICalText(ct.WorkspaceName))
+		}
+		writeICalLine(&b, "END:VEVENT")
+	}
+
+	writeICalLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+// escapeICalText escapa valores TEXT conforme a RFC 5545 (seção 3.3.11).
+func escapeICalText(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", `\n`)
+	return replacer.Replace(s)
+}
+
+// writeICalLine escreve a linha terminada em CRLF, dobrando-a em 75 octetos sem
+// quebrar caracteres UTF-8 no meio.
+func writeICalLine(b *strings.Builder, line string) {
+	limit := icalLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isUTF8Start(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = icalLineLimit - 1 // O espaço de continuação conta no limite
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func isUTF8Start(c byte) bool {
+	return c&0xC0 != 0x80
+}
@@ -0,0 +1,113 @@
+package task_services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"projeto-integrador/firebase"
+	"projeto-integrador/models"
+	"projeto-integrador/utilities"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReminderNotifier é um canal de entrega de lembretes (log, webhook, Firestore...).
+type ReminderNotifier interface {
+	Name() string
+	Notify(ctx context.Context, event models.ReminderEvent) error
+}
+
+// logNotifier apenas registra o lembrete no log do servidor.
+type logNotifier struct{}
+
+func (logNotifier) Name() string { return "log" }
+
+func (logNotifier) Notify(ctx context.Context, event models.ReminderEvent) error {
+	utilities.LogInfo("Lembrete [%s %s]: tarefa %s (%q) do workspace %d vence em %s. Destinatários: %v",
+		event.Kind, event.Window, event.TaskDocID, event.TaskTitle, event.WorkspaceIDPg, event.DueAt.Format(time.RFC3339), event.Recipients)
+	return nil
+}
+
+// webhookNotifier envia o evento como JSON via POST para uma URL configurada.
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (n *webhookNotifier) Name() string { return "webhook" }
+
+func (n *webhookNotifier) Notify(ctx context.Context, event models.ReminderEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar lembrete: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("erro ao criar requisição do webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("erro ao chamar webhook de lembretes: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook de lembretes retornou status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// firestoreNotifier grava o lembrete em workspaces/{id}/notifications para exibição no app.
+type firestoreNotifier struct{}
+
+func (firestoreNotifier) Name() string { return "firestore" }
+
+func (firestoreNotifier) Notify(ctx context.Context, event models.ReminderEvent) error {
+	client, err := firebase.GetFirestoreClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	workspaceDocID := strconv.FormatInt(event.WorkspaceIDPg, 10)
+	_, _, err = client.Collection("workspaces").Doc(workspaceDocID).Collection("notifications").Add(ctx, event)
+	if err != nil {
+		return fmt.Errorf("erro ao gravar notificação no Firestore: %w", err)
+	}
+	return nil
+}
+
+// notifiersFromEnv monta os canais listados em TASK_REMINDER_CHANNELS (ex: "log,webhook,firestore").
+// Sem configuração, apenas o canal de log é usado.
+func notifiersFromEnv() []ReminderNotifier {
+	channels := os.Getenv("TASK_REMINDER_CHANNELS")
+	if channels == "" {
+		channels = "log"
+	}
+
+	var notifiers []ReminderNotifier
+	for _, channel := range strings.Split(channels, ",") {
+		switch strings.TrimSpace(channel) {
+		case "log":
+			notifiers = append(notifiers, logNotifier{})
+		case "webhook":
+			url := os.Getenv("TASK_REMINDER_WEBHOOK_URL")
+			if url == "" {
+				utilities.LogInfo("Canal de lembretes 'webhook' ignorado: TASK_REMINDER_WEBHOOK_URL não definida")
+				continue
+			}
+			notifiers = append(notifiers, &webhookNotifier{url: url, client: &http.Client{Timeout: 10 * time.Second}})
+		case "firestore":
+			notifiers = append(notifiers, firestoreNotifier{})
+		case "":
+		default:
+			utilities.LogInfo("Canal de lembretes desconhecido ignorado: %q", channel)
+		}
+	}
+	return notifiers
+}
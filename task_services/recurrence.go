@@ -0,0 +1,302 @@
+package task_services
+
+import (
+	"errors"
+	"fmt"
+	"projeto-integrador/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidRecurrence indica uma regra de recorrência malformada ou não suportada.
+var ErrInvalidRecurrence = errors.New("regra de recorrência inválida")
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// ParseRRule converte uma string RRULE (ex: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE")
+// para RecurrenceRule. O prefixo "RRULE:" é opcional.
+func ParseRRule(rrule string) (*models.RecurrenceRule, error) {
+	rrule = strings.TrimPrefix(strings.TrimSpace(rrule), "RRULE:")
+	if rrule == "" {
+		return nil, fmt.Errorf("%w: RRULE vazia", ErrInvalidRecurrence)
+	}
+
+	rule := &models.RecurrenceRule{}
+	for _, part := range strings.Split(rrule, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: parte %q sem '='", ErrInvalidRecurrence, part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = strings.ToLower(value)
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: INTERVAL %q", ErrInvalidRecurrence, value)
+			}
+			rule.Interval = n
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				rule.ByDay = append(rule.ByDay, strings.ToUpper(strings.TrimSpace(day)))
+			}
+		case "BYMONTHDAY":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: BYMONTHDAY %q", ErrInvalidRecurrence, value)
+			}
+			rule.ByMonthDay = n
+		case "BYMONTH":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: BYMONTH %q", ErrInvalidRecurrence, value)
+			}
+			rule.ByMonth = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: COUNT %q", ErrInvalidRecurrence, value)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := parseRRuleDate(value)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = &until
+		default:
+			return nil, fmt.Errorf("%w: parâmetro %s não suportado", ErrInvalidRecurrence, key)
+		}
+	}
+
+	if err := ValidateRecurrence(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// parseRRuleDate lê o UNTIL. Como no RRULE o UNTIL é inclusivo, uma data sem horário
+// vale até o fim daquele dia (UTC).
+func parseRRuleDate(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.Parse("20060102", value); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("%w: UNTIL %q", ErrInvalidRecurrence, value)
+}
+
+// ValidateRecurrence normaliza (interval padrão 1, dias em maiúsculas) e valida a regra.
+func ValidateRecurrence(rule *models.RecurrenceRule) error {
+	rule.Freq = strings.ToLower(strings.TrimSpace(rule.Freq))
+	switch rule.Freq {
+	case models.RecurrenceDaily, models.RecurrenceWeekly, models.RecurrenceMonthly, models.RecurrenceYearly:
+	default:
+		return fmt.Errorf("%w: freq %q (use daily, weekly, monthly ou yearly)", ErrInvalidRecurrence, rule.Freq)
+	}
+	if rule.Interval == 0 {
+		rule.Interval = 1
+	}
+	if rule.Interval < 0 {
+		return fmt.Errorf("%w: interval deve ser positivo", ErrInvalidRecurrence)
+	}
+	if rule.Count < 0 {
+		return fmt.Errorf("%w: count deve ser positivo", ErrInvalidRecurrence)
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != models.RecurrenceWeekly {
+		return fmt.Errorf("%w: by_day só é suportado com freq weekly", ErrInvalidRecurrence)
+	}
+	for i, day := range rule.ByDay {
+		day = strings.ToUpper(strings.TrimSpace(day))
+		if _, ok := rruleWeekdays[day]; !ok {
+			return fmt.Errorf("%w: dia da semana %q", ErrInvalidRecurrence, day)
+		}
+		rule.ByDay[i] = day
+	}
+	if rule.ByMonthDay != 0 {
+		if rule.Freq != models.RecurrenceMonthly && rule.Freq != models.RecurrenceYearly {
+			return fmt.Errorf("%w: by_month_day só é suportado com freq monthly ou yearly", ErrInvalidRecurrence)
+		}
+		if rule.ByMonthDay < -1 || rule.ByMonthDay > 31 {
+			return fmt.Errorf("%w: by_month_day deve estar entre 1 e 31, ou -1", ErrInvalidRecurrence)
+		}
+	}
+	if rule.ByMonth != 0 {
+		if rule.Freq != models.RecurrenceYearly {
+			return fmt.Errorf("%w: by_month só é suportado com freq yearly", ErrInvalidRecurrence)
+		}
+		if rule.ByMonth < 1 || rule.ByMonth > 12 {
+			return fmt.Errorf("%w: by_month deve estar entre 1 e 12", ErrInvalidRecurrence)
+		}
+	}
+	return nil
+}
+
+// NormalizeRecurrence resolve a recorrência informada pelo cliente, seja como objeto
+// ou como string RRULE. Retorna nil se nenhuma das duas foi informada.
+func NormalizeRecurrence(rule *models.RecurrenceRule, rrule string) (*models.RecurrenceRule, error) {
+	if strings.TrimSpace(rrule) != "" {
+		return ParseRRule(rrule)
+	}
+	if rule == nil {
+		return nil, nil
+	}
+	normalized := *rule
+	normalized.ByDay = append([]string(nil), rule.ByDay...)
+	if err := ValidateRecurrence(&normalized); err != nil {
+		return nil, err
+	}
+	return &normalized, nil
+}
+
+// AnchorRecurrence fixa na regra a data do vencimento da ocorrência: o dia do mês de
+// uma série mensal sem by_month_day, e o mês e o dia de uma série anual sem by_month e
+// by_month_day. Assim um mês curto (31/01 → 28/02) ou um ano não bissexto (29/02 →
+// 28/02) não desloca as ocorrências seguintes.
+func AnchorRecurrence(rule *models.RecurrenceRule, due *time.Time) {
+	if rule == nil || due == nil {
+		return
+	}
+	switch rule.Freq {
+	case models.RecurrenceMonthly:
+		if rule.ByMonthDay == 0 {
+			rule.ByMonthDay = due.Day()
+		}
+	case models.RecurrenceYearly:
+		if rule.ByMonth == 0 {
+			rule.ByMonth = int(due.Month())
+		}
+		if rule.ByMonthDay == 0 {
+			rule.ByMonthDay = due.Day()
+		}
+	}
+}
+
+// RRuleString serializa a regra no formato RRULE do iCalendar.
+func RRuleString(rule *models.RecurrenceRule) string {
+	parts := []string{"FREQ=" + strings.ToUpper(rule.Freq)}
+	if rule.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(rule.Interval))
+	}
+	if len(rule.ByDay) > 0 {
+		parts = append(parts, "BYDAY="+strings.Join(rule.ByDay, ","))
+	}
+	if rule.ByMonth != 0 {
+		parts = append(parts, "BYMONTH="+strconv.Itoa(rule.ByMonth))
+	}
+	if rule.ByMonthDay != 0 {
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(rule.ByMonthDay))
+	}
+	if rule.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(rule.Count))
+	}
+	if rule.Until != nil {
+		parts = append(parts, "UNTIL="+rule.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// NextOccurrence calcula a data da ocorrência seguinte a partir de from (o vencimento da
+// ocorrência atual). occurrenceIndex é a posição da ocorrência atual na série (1 = primeira).
+// Retorna ok=false quando a série terminou por COUNT ou UNTIL.
+func NextOccurrence(rule *models.RecurrenceRule, from time.Time, occurrenceIndex int) (next time.Time, ok bool) {
+	if rule == nil {
+		return time.Time{}, false
+	}
+	if rule.Count > 0 && occurrenceIndex >= rule.Count {
+		return time.Time{}, false
+	}
+	interval := rule.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	switch rule.Freq {
+	case models.RecurrenceDaily:
+		next = from.AddDate(0, 0, interval)
+	case models.RecurrenceWeekly:
+		next = nextWeekly(rule.ByDay, from, interval)
+	case models.RecurrenceMonthly:
+		day := rule.ByMonthDay
+		if day == 0 {
+			day = from.Day()
+		}
+		next = addMonthsClamped(from, interval, day)
+	case models.RecurrenceYearly:
+		month, day := time.Month(rule.ByMonth), rule.ByMonthDay
+		if month == 0 {
+			month = from.Month()
+		}
+		if day == 0 {
+			day = from.Day()
+		}
+		next = addMonthsClamped(from, 12*interval+int(month-from.Month()), day)
+	default:
+		return time.Time{}, false
+	}
+
+	if rule.Until != nil && next.After(*rule.Until) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// nextWeekly retorna o próximo dia listado em byDay após from, considerando apenas
+// semanas múltiplas de interval a partir da semana de from.
+func nextWeekly(byDay []string, from time.Time, interval int) time.Time {
+	if len(byDay) == 0 {
+		return from.AddDate(0, 0, 7*interval)
+	}
+	allowed := make(map[time.Weekday]bool, len(byDay))
+	for _, day := range byDay {
+		allowed[rruleWeekdays[day]] = true
+	}
+
+	fromWeek := weekNumber(from)
+	for offset := 1; offset <= 7*interval+7; offset++ {
+		candidate := from.AddDate(0, 0, offset)
+		if !allowed[candidate.Weekday()] {
+			continue
+		}
+		if (weekNumber(candidate)-fromWeek)%interval == 0 {
+			return candidate
+		}
+	}
+	return from.AddDate(0, 0, 7*interval)
+}
+
+// weekNumber numera as semanas (iniciando na segunda-feira, como o WKST padrão do RRULE)
+// a partir da data de calendário de t, sem ser afetado por horário de verão.
+func weekNumber(t time.Time) int {
+	y, m, d := t.Date()
+	days := int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400)
+	// 01/01/1970 foi uma quinta-feira; +3 alinha o início da semana na segunda.
+	return (days + 3) / 7
+}
+
+// addMonthsClamped avança months meses e posiciona no dia day, limitado ao último dia
+// do mês de destino (ex: 31/01 + 1 mês = 28/02). day = -1 significa o último dia.
+func addMonthsClamped(from time.Time, months int, day int) time.Time {
+	y, m, _ := from.Date()
+	firstOfTarget := time.Date(y, m+time.Month(months), 1, from.Hour(), from.Minute(), from.Second(), 0, from.Location())
+	lastDay := firstOfTarget.AddDate(0, 1, -1).Day()
+	if day == -1 || day > lastDay {
+		day = lastDay
+	}
+	return firstOfTarget.AddDate(0, 0, day-1)
+}
@@ -0,0 +1,224 @@
+package task_services
+
+import (
+	"errors"
+	"projeto-integrador/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func utcDate(y int, m time.Month, d, hour, min int) time.Time {
+	return time.Date(y, m, d, hour, min, 0, 0, time.UTC)
+}
+
+func TestParseRRule(t *testing.T) {
+	until := utcDate(2025, 6, 30, 0, 0)
+	untilEndOfDay := time.Date(2025, 6, 30, 23, 59, 59, 0, time.UTC)
+	tests := []struct {
+		name  string
+		rrule string
+		want  *models.RecurrenceRule
+	}{
+		{"prefixo e dias em minúsculas", "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=mo, we",
+			&models.RecurrenceRule{Freq: "weekly", Interval: 2, ByDay: []string{"MO", "WE"}}},
+		{"último dia do mês com count", "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=5",
+			&models.RecurrenceRule{Freq: "monthly", Interval: 1, ByMonthDay: -1, Count: 5}},
+		{"until só com data vale o dia inteiro", "FREQ=DAILY;UNTIL=20250630",
+			&models.RecurrenceRule{Freq: "daily", Interval: 1, Until: &untilEndOfDay}},
+		{"until com horário UTC", "FREQ=YEARLY;UNTIL=20250630T000000Z",
+			&models.RecurrenceRule{Freq: "yearly", Interval: 1, Until: &until}},
+		{"anual com mês e dia", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29",
+			&models.RecurrenceRule{Freq: "yearly", Interval: 1, ByMonth: 2, ByMonthDay: 29}},
+		{"ponto e vírgula sobrando", "FREQ=DAILY;;",
+			&models.RecurrenceRule{Freq: "daily", Interval: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRRule(tt.rrule)
+			if err != nil {
+				t.Fatalf("ParseRRule(%q) erro inesperado: %v", tt.rrule, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRRule(%q) = %+v, want %+v", tt.rrule, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRRuleInvalid(t *testing.T) {
+	for _, rrule := range []string{
+		"",
+		"RRULE:",
+		"FREQ",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;BYMONTHDAY=-2",
+		"FREQ=MONTHLY;BYMONTH=2",
+		"FREQ=YEARLY;BYMONTH=13",
+		"FREQ=YEARLY;BYMONTH=x",
+		"FREQ=DAILY;INTERVAL=-1",
+		"FREQ=DAILY;COUNT=abc",
+		"FREQ=DAILY;COUNT=-1",
+		"FREQ=DAILY;UNTIL=30/06/2025",
+		"FREQ=DAILY;BYSETPOS=1",
+	} {
+		if _, err := ParseRRule(rrule); !errors.Is(err, ErrInvalidRecurrence) {
+			t.Errorf("ParseRRule(%q) erro = %v, want ErrInvalidRecurrence", rrule, err)
+		}
+	}
+}
+
+// O UNTIL é inclusivo: com uma data sem horário, a ocorrência daquele dia ainda entra.
+func TestNextOccurrenceDateOnlyUntil(t *testing.T) {
+	rule, err := ParseRRule("FREQ=DAILY;UNTIL=20261031")
+	if err != nil {
+		t.Fatalf("ParseRRule: %v", err)
+	}
+	if next, ok := NextOccurrence(rule, utcDate(2026, 10, 30, 18, 0), 1); !ok || !next.Equal(utcDate(2026, 10, 31, 18, 0)) {
+		t.Errorf("NextOccurrence = %v, %t; want %v", next, ok, utcDate(2026, 10, 31, 18, 0))
+	}
+	if _, ok := NextOccurrence(rule, utcDate(2026, 10, 31, 18, 0), 2); ok {
+		t.Errorf("ocorrência depois do UNTIL")
+	}
+}
+
+func TestRRuleStringRoundTrip(t *testing.T) {
+	for _, rrule := range []string{
+		"FREQ=DAILY",
+		"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR",
+		"FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=12",
+		"FREQ=YEARLY;UNTIL=20300101T000000Z",
+		"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29",
+	} {
+		rule, err := ParseRRule(rrule)
+		if err != nil {
+			t.Fatalf("ParseRRule(%q): %v", rrule, err)
+		}
+		if got := RRuleString(rule); got != rrule {
+			t.Errorf("RRuleString(ParseRRule(%q)) = %q", rrule, got)
+		}
+	}
+}
+
+func TestNextOccurrence(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   models.RecurrenceRule
+		from   time.Time
+		index  int
+		want   time.Time
+		wantOk bool
+	}{
+		{"diária", models.RecurrenceRule{Freq: "daily"}, utcDate(2025, 6, 1, 10, 0), 1, utcDate(2025, 6, 2, 10, 0), true},
+		{"diária vira o ano", models.RecurrenceRule{Freq: "daily", Interval: 3}, utcDate(2024, 12, 30, 9, 0), 1, utcDate(2025, 1, 2, 9, 0), true},
+		{"semanal sem by_day", models.RecurrenceRule{Freq: "weekly", Interval: 2}, utcDate(2025, 6, 6, 18, 0), 1, utcDate(2025, 6, 20, 18, 0), true},
+		{"semanal quarta para segunda", models.RecurrenceRule{Freq: "weekly", ByDay: []string{"MO", "WE"}}, utcDate(2025, 6, 4, 9, 0), 1, utcDate(2025, 6, 9, 9, 0), true},
+		{"semanal segunda para quarta", models.RecurrenceRule{Freq: "weekly", ByDay: []string{"MO", "WE"}}, utcDate(2025, 6, 9, 9, 0), 1, utcDate(2025, 6, 11, 9, 0), true},
+		{"quinzenal pula a semana seguinte", models.RecurrenceRule{Freq: "weekly", Interval: 2, ByDay: []string{"MO", "FR"}}, utcDate(2025, 6, 6, 9, 0), 1, utcDate(2025, 6, 16, 9, 0), true},
+		// A semana começa na segunda (WKST=MO): o domingo 08/06 pertence à semana de 02/06
+		{"quinzenal com domingo no fim da semana", models.RecurrenceRule{Freq: "weekly", Interval: 2, ByDay: []string{"SU", "MO"}}, utcDate(2025, 6, 8, 9, 0), 1, utcDate(2025, 6, 16, 9, 0), true},
+		{"semanal vira o ano", models.RecurrenceRule{Freq: "weekly", ByDay: []string{"MO"}}, utcDate(2024, 12, 30, 9, 0), 1, utcDate(2025, 1, 6, 9, 0), true},
+		{"mensal dia 31 em fevereiro", models.RecurrenceRule{Freq: "monthly", ByMonthDay: 31}, utcDate(2025, 1, 31, 9, 0), 1, utcDate(2025, 2, 28, 9, 0), true},
+		{"mensal dia 31 volta ao dia 31", models.RecurrenceRule{Freq: "monthly", ByMonthDay: 31}, utcDate(2025, 2, 28, 9, 0), 2, utcDate(2025, 3, 31, 9, 0), true},
+		{"mensal dia 31 em abril", models.RecurrenceRule{Freq: "monthly", ByMonthDay: 31}, utcDate(2025, 3, 31, 9, 0), 3, utcDate(2025, 4, 30, 9, 0), true},
+		{"mensal dia 30 em ano bissexto", models.RecurrenceRule{Freq: "monthly", ByMonthDay: 30}, utcDate(2024, 1, 30, 9, 0), 1, utcDate(2024, 2, 29, 9, 0), true},
+		{"mensal último dia", models.RecurrenceRule{Freq: "monthly", ByMonthDay: -1}, utcDate(2025, 2, 28, 9, 0), 1, utcDate(2025, 3, 31, 9, 0), true},
+		{"mensal vira o ano", models.RecurrenceRule{Freq: "monthly", Interval: 2}, utcDate(2025, 11, 15, 9, 0), 1, utcDate(2026, 1, 15, 9, 0), true},
+		{"anual de 29/02", models.RecurrenceRule{Freq: "yearly"}, utcDate(2024, 2, 29, 9, 0), 1, utcDate(2025, 2, 28, 9, 0), true},
+		{"anual ancorado em 29/02 volta ao dia 29", models.RecurrenceRule{Freq: "yearly", ByMonth: 2, ByMonthDay: 29}, utcDate(2027, 2, 28, 9, 0), 4, utcDate(2028, 2, 29, 9, 0), true},
+		{"anual no último dia de fevereiro", models.RecurrenceRule{Freq: "yearly", Interval: 2, ByMonth: 2, ByMonthDay: -1}, utcDate(2026, 2, 28, 9, 0), 1, utcDate(2028, 2, 29, 9, 0), true},
+		{"count ainda não atingido", models.RecurrenceRule{Freq: "daily", Count: 3}, utcDate(2025, 6, 1, 10, 0), 2, utcDate(2025, 6, 2, 10, 0), true},
+		{"count atingido", models.RecurrenceRule{Freq: "daily", Count: 3}, utcDate(2025, 6, 1, 10, 0), 3, time.Time{}, false},
+		{"until igual à próxima", models.RecurrenceRule{Freq: "daily", Until: ptrTime(utcDate(2025, 6, 2, 10, 0))}, utcDate(2025, 6, 1, 10, 0), 1, utcDate(2025, 6, 2, 10, 0), true},
+		{"until antes da próxima", models.RecurrenceRule{Freq: "daily", Until: ptrTime(utcDate(2025, 6, 2, 9, 59))}, utcDate(2025, 6, 1, 10, 0), 1, time.Time{}, false},
+		{"until no fim do mês curto", models.RecurrenceRule{Freq: "monthly", ByMonthDay: 31, Until: ptrTime(utcDate(2025, 2, 27, 0, 0))}, utcDate(2025, 1, 31, 9, 0), 1, time.Time{}, false},
+		{"freq desconhecida", models.RecurrenceRule{Freq: "hourly"}, utcDate(2025, 6, 1, 10, 0), 1, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := NextOccurrence(&tt.rule, tt.from, tt.index)
+			if ok != tt.wantOk || !got.Equal(tt.want) {
+				t.Errorf("NextOccurrence(%+v, %v, %d) = %v, %t; want %v, %t", tt.rule, tt.from, tt.index, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+// A série mensal criada no dia 31 não deve ficar presa no dia 28 depois de fevereiro.
+func TestNextOccurrenceMonthlyAnchoredSeries(t *testing.T) {
+	rule := &models.RecurrenceRule{Freq: "monthly", Interval: 1}
+	due := utcDate(2025, 1, 31, 9, 0)
+	AnchorRecurrence(rule, &due)
+
+	want := []time.Time{utcDate(2025, 2, 28, 9, 0), utcDate(2025, 3, 31, 9, 0), utcDate(2025, 4, 30, 9, 0), utcDate(2025, 5, 31, 9, 0)}
+	for i, expected := range want {
+		next, ok := NextOccurrence(rule, due, i+1)
+		if !ok || !next.Equal(expected) {
+			t.Fatalf("ocorrência %d = %v, %t; want %v", i+2, next, ok, expected)
+		}
+		due = next
+	}
+}
+
+// A série anual criada em 29/02 vence em 28/02 nos anos comuns e volta ao dia 29 no
+// ano bissexto seguinte.
+func TestNextOccurrenceYearlyAnchoredSeries(t *testing.T) {
+	rule := &models.RecurrenceRule{Freq: "yearly", Interval: 1}
+	due := utcDate(2024, 2, 29, 9, 0)
+	AnchorRecurrence(rule, &due)
+
+	want := []time.Time{utcDate(2025, 2, 28, 9, 0), utcDate(2026, 2, 28, 9, 0), utcDate(2027, 2, 28, 9, 0), utcDate(2028, 2, 29, 9, 0)}
+	for i, expected := range want {
+		next, ok := NextOccurrence(rule, due, i+1)
+		if !ok || !next.Equal(expected) {
+			t.Fatalf("ocorrência %d = %v, %t; want %v", i+2, next, ok, expected)
+		}
+		due = next
+	}
+}
+
+func TestAnchorRecurrence(t *testing.T) {
+	due := utcDate(2025, 1, 31, 9, 0)
+	tests := []struct {
+		name         string
+		rule         models.RecurrenceRule
+		due          *time.Time
+		wantMonthDay int
+		wantMonth    int
+	}{
+		{"mensal sem dia", models.RecurrenceRule{Freq: "monthly"}, &due, 31, 0},
+		{"mensal com dia informado", models.RecurrenceRule{Freq: "monthly", ByMonthDay: -1}, &due, -1, 0},
+		{"mensal sem vencimento", models.RecurrenceRule{Freq: "monthly"}, nil, 0, 0},
+		{"anual sem mês e dia", models.RecurrenceRule{Freq: "yearly"}, &due, 31, 1},
+		{"anual com mês e dia informados", models.RecurrenceRule{Freq: "yearly", ByMonth: 3, ByMonthDay: 15}, &due, 15, 3},
+		{"semanal", models.RecurrenceRule{Freq: "weekly"}, &due, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AnchorRecurrence(&tt.rule, tt.due)
+			if tt.rule.ByMonthDay != tt.wantMonthDay || tt.rule.ByMonth != tt.wantMonth {
+				t.Errorf("ByMonthDay, ByMonth = %d, %d; want %d, %d", tt.rule.ByMonthDay, tt.rule.ByMonth, tt.wantMonthDay, tt.wantMonth)
+			}
+		})
+	}
+}
+
+// O vencimento mantém o horário local mesmo atravessando a mudança de horário de verão.
+func TestNextOccurrenceKeepsLocalTimeAcrossDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("fuso horário indisponível: %v", err)
+	}
+	from := time.Date(2025, 3, 29, 9, 0, 0, 0, berlin)
+	rule := &models.RecurrenceRule{Freq: "weekly", ByDay: []string{"SU", "SA"}}
+	next, ok := NextOccurrence(rule, from, 1)
+	if want := time.Date(2025, 3, 30, 9, 0, 0, 0, berlin); !ok || !next.Equal(want) {
+		t.Errorf("NextOccurrence = %v, %t; want %v", next, ok, want)
+	}
+}
+
+func ptrTime(t time.Time) *time.Time { return &t }
@@ -0,0 +1,328 @@
+package task_services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"projeto-integrador/database"
+	"projeto-integrador/firebase"
+	"projeto-integrador/models"
+	"projeto-integrador/scheduler"
+	"projeto-integrador/utilities"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// Chave do advisory lock usada para que só uma réplica varra as tarefas por vez.
+const reminderSchedulerLockKey int64 = 260001
+
+// Reserva sem envio registrado há mais que isso é considerada abandonada (processo
+// encerrado entre a reserva e a entrega) e pode ser retomada.
+const reminderClaimTimeout = 15 * time.Minute
+
+// reminderWindow é uma janela "vence em até X" configurada em TASK_REMINDER_WINDOWS.
+type reminderWindow struct {
+	label    string
+	duration time.Duration
+}
+
+type reminderRunner struct {
+	windows   []reminderWindow // Ordenadas da menor para a maior
+	notifiers []ReminderNotifier
+}
+
+// StartReminderScheduler inicia em background a varredura periódica de tarefas com
+// expiration_date, marcando as atrasadas e emitindo lembretes pelos canais configurados.
+//
+// Variáveis de ambiente:
+//   - TASK_REMINDER_ENABLED: "false" desliga o scheduler (padrão: ligado)
+//   - TASK_REMINDER_INTERVAL: intervalo entre varreduras (padrão: 5m)
+//   - TASK_REMINDER_WINDOWS: janelas de aviso antes do vencimento (padrão: "24h,1h")
+//   - TASK_REMINDER_CHANNELS: canais de entrega (padrão: "log")
+//
+// Cada lembrete é "reservado" na tabela task_reminders antes do envio; a chave única
+// garante que ele dispare uma única vez mesmo com reinícios ou várias réplicas. Os
+// canais que falharem são tentados de novo nas varreduras seguintes, sem repetir os
+// que já entregaram. Uma reserva que não chegou a ser enviada é retomada após
+// reminderClaimTimeout.
+func StartReminderScheduler(ctx context.Context) {
+	if !scheduler.EnabledFromEnv("TASK_REMINDER_ENABLED") {
+		utilities.LogInfo("Scheduler de lembretes desativado por TASK_REMINDER_ENABLED")
+		return
+	}
+
+	runner := &reminderRunner{
+		windows:   reminderWindowsFromEnv(),
+		notifiers: notifiersFromEnv(),
+	}
+	interval := scheduler.DurationFromEnv("TASK_REMINDER_INTERVAL", 5*time.Minute)
+	scheduler.Every(ctx, "task-reminders", interval, runner.run)
+}
+
+func reminderWindowsFromEnv() []reminderWindow {
+	raw := os.Getenv("TASK_REMINDER_WINDOWS")
+	if raw == "" {
+		raw = "24h,1h"
+	}
+
+	var windows []reminderWindow
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		d, err := time.ParseDuration(part)
+		if err != nil || d <= 0 {
+			utilities.LogInfo("Janela de lembrete inválida ignorada: %q", part)
+			continue
+		}
+		windows = append(windows, reminderWindow{label: part, duration: d})
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].duration < windows[j].duration })
+	return windows
+}
+
+func (r *reminderRunner) run(ctx context.Context) {
+	db, err := database.ConnectPostgres()
+	if err != nil {
+		utilities.LogError(err, "ReminderScheduler: Erro ao conectar ao PG")
+		return
+	}
+	defer db.Close()
+
+	ran, err := scheduler.WithAdvisoryLock(ctx, db, reminderSchedulerLockKey, func(ctx context.Context) error {
+		return r.scan(ctx, db)
+	})
+	if err != nil {
+		utilities.LogError(err, "ReminderScheduler: Erro durante a varredura de tarefas")
+		return
+	}
+	if !ran {
+		utilities.LogDebug("ReminderScheduler: Outra instância está executando a varredura, pulando este ciclo")
+	}
+}
+
+func (r *reminderRunner) scan(ctx context.Context, db *sql.DB) error {
+	firestoreClient, err := firebase.GetFirestoreClient()
+	if err != nil {
+		return err
+	}
+	defer firestoreClient.Close()
+
+	rows, err := db.QueryContext(ctx, "SELECT id FROM workspaces")
+	if err != nil {
+		return fmt.Errorf("erro ao listar workspaces para lembretes: %w", err)
+	}
+	var workspaceIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("erro ao ler workspace para lembretes: %w", err)
+		}
+		workspaceIDs = append(workspaceIDs, id)
+	}
+	rows.Close()
+
+	now := time.Now()
+	for _, workspaceID := range workspaceIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := r.scanWorkspace(ctx, db, firestoreClient, workspaceID, now); err != nil {
+			// Um workspace com problema não deve impedir os lembretes dos demais.
+			utilities.LogError(err, fmt.Sprintf("ReminderScheduler: Erro ao processar workspace %d", workspaceID))
+		}
+	}
+	return nil
+}
+
+func (r *reminderRunner) scanWorkspace(ctx context.Context, db *sql.DB, firestoreClient *firestore.Client, workspaceID int64, now time.Time) error {
+	horizon := now
+	if len(r.windows) > 0 {
+		horizon = now.Add(r.windows[len(r.windows)-1].duration)
+	}
+
+	iter := TasksCollection(firestoreClient, workspaceID).
+		Where("expiration_date", "<=", horizon).
+		Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("erro ao buscar tarefas com vencimento no workspace %d: %w", workspaceID, err)
+		}
+
+		var task models.TaskDetailsFirestore
+		if err := doc.DataTo(&task); err != nil {
+			utilities.LogError(err, fmt.Sprintf("ReminderScheduler: Erro ao converter tarefa %s", doc.Ref.ID))
+			continue
+		}
+		if task.ExpirationDate == nil || task.Status == "completed" || task.Status == "skipped" {
+			continue
+		}
+
+		due := *task.ExpirationDate
+		if !now.Before(due) {
+			r.handleOverdue(ctx, db, doc.Ref, workspaceID, task, due, now)
+		} else {
+			r.handleDueSoon(ctx, db, doc.Ref.ID, workspaceID, task, due, now)
+		}
+	}
+}
+
+func (r *reminderRunner) handleOverdue(ctx context.Context, db *sql.DB, taskRef *firestore.DocumentRef, workspaceID int64, task models.TaskDetailsFirestore, due, now time.Time) {
+	if !task.IsOverdue {
+		_, err := taskRef.Update(ctx, []firestore.Update{
+			{Path: "is_overdue", Value: true},
+			{Path: "overdue_since", Value: due},
+		})
+		if err != nil {
+			utilities.LogError(err, fmt.Sprintf("ReminderScheduler: Erro ao marcar tarefa %s como atrasada", taskRef.ID))
+		}
+	}
+
+	event := newReminderEvent(models.ReminderKindOverdue, "", workspaceID, taskRef.ID, task, due, now)
+	r.fire(ctx, db, event)
+}
+
+func (r *reminderRunner) handleDueSoon(ctx context.Context, db *sql.DB, taskDocID string, workspaceID int64, task models.TaskDetailsFirestore, due, now time.Time) {
+	notified := false
+	for _, window := range r.windows {
+		if due.Sub(now) > window.duration {
+			continue
+		}
+		event := newReminderEvent(models.ReminderKindDueSoon, window.label, workspaceID, taskDocID, task, due, now)
+		if !notified {
+			// Só a menor janela aplicável gera aviso; as maiores são apenas registradas
+			// para não dispararem depois (ex: servidor parado durante a janela de 24h).
+			r.fire(ctx, db, event)
+			notified = true
+			continue
+		}
+		if err := suppressReminder(ctx, db, event); err != nil {
+			utilities.LogError(err, fmt.Sprintf("ReminderScheduler: Erro ao registrar lembrete %s/%s da tarefa %s", event.Kind, event.Window, taskDocID))
+		}
+	}
+}
+
+func newReminderEvent(kind, window string, workspaceID int64, taskDocID string, task models.TaskDetailsFirestore, due, now time.Time) models.ReminderEvent {
+	recipients := []string{task.CreatorFirebaseUID}
+	if task.AssigneeFirebaseUID != "" && task.AssigneeFirebaseUID != task.CreatorFirebaseUID {
+		recipients = append(recipients, task.AssigneeFirebaseUID)
+	}
+	return models.ReminderEvent{
+		Kind:          kind,
+		Window:        window,
+		WorkspaceIDPg: workspaceID,
+		TaskDocID:     taskDocID,
+		TaskTitle:     task.Title,
+		DueAt:         due,
+		Recipients:    recipients,
+		FiredAt:       now,
+	}
+}
+
+// fire reserva o lembrete e, se esta instância for a primeira a reservá-lo (ou a reserva
+// anterior foi liberada ou abandonada), entrega aos canais que ainda não o entregaram.
+// Com todos os canais entregues o lembrete fica enviado; se algum falhar, a reserva é
+// liberada e só os canais que falharam são tentados na próxima varredura.
+func (r *reminderRunner) fire(ctx context.Context, db *sql.DB, event models.ReminderEvent) {
+	claimed, delivered, err := claimReminder(ctx, db, event)
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("ReminderScheduler: Erro ao reservar lembrete %s da tarefa %s", event.Kind, event.TaskDocID))
+		return
+	}
+	if !claimed {
+		return // Já enviado anteriormente (ou reservado por outra réplica)
+	}
+
+	done := make(map[string]bool, len(delivered))
+	for _, channel := range delivered {
+		done[channel] = true
+	}
+	failed := 0
+	for _, notifier := range r.notifiers {
+		if done[notifier.Name()] {
+			continue
+		}
+		if err := notifier.Notify(ctx, event); err != nil {
+			utilities.LogError(err, fmt.Sprintf("ReminderScheduler: Canal %s falhou para tarefa %s", notifier.Name(), event.TaskDocID))
+			failed++
+			continue
+		}
+		delivered = append(delivered, notifier.Name())
+	}
+
+	if err := finishReminder(ctx, db, event, delivered, failed == 0); err != nil {
+		utilities.LogError(err, fmt.Sprintf("ReminderScheduler: Erro ao registrar envio do lembrete da tarefa %s", event.TaskDocID))
+	}
+}
+
+// claimReminder tenta inserir o lembrete em task_reminders. Retorna claimed=true apenas
+// para quem efetivamente inseriu a linha ou retomou uma reserva liberada ou abandonada
+// (sem sent_at e reservada há mais de reminderClaimTimeout), junto com os canais que já
+// entregaram o lembrete; a chave única impede disparos duplicados.
+func claimReminder(ctx context.Context, db *sql.DB, event models.ReminderEvent) (claimed bool, delivered []string, err error) {
+	var raw []byte
+	err = db.QueryRowContext(ctx, `
+		INSERT INTO task_reminders (firestore_doc_id, workspace_id, kind, reminder_window, due_at, claimed_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (firestore_doc_id, kind, reminder_window, due_at) DO UPDATE SET claimed_at = NOW()
+		WHERE task_reminders.sent_at IS NULL
+		  AND (task_reminders.claimed_at IS NULL OR task_reminders.claimed_at < NOW() - make_interval(secs => $6))
+		RETURNING delivered_channels
+	`, event.TaskDocID, event.WorkspaceIDPg, event.Kind, event.Window, event.DueAt, reminderClaimTimeout.Seconds()).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, fmt.Errorf("erro ao reservar lembrete: %w", err)
+	}
+	if err := json.Unmarshal(raw, &delivered); err != nil {
+		return false, nil, fmt.Errorf("erro ao ler canais entregues do lembrete: %w", err)
+	}
+	return true, delivered, nil
+}
+
+// finishReminder grava os canais que entregaram o lembrete. Com sent=true o lembrete
+// fica enviado; caso contrário a reserva é liberada para os canais que falharam serem
+// tentados na próxima varredura.
+func finishReminder(ctx context.Context, db *sql.DB, event models.ReminderEvent, delivered []string, sent bool) error {
+	if delivered == nil {
+		delivered = []string{}
+	}
+	raw, err := json.Marshal(delivered)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `
+		UPDATE task_reminders
+		SET delivered_channels = $5,
+		    sent_at = CASE WHEN $6 THEN NOW() END,
+		    claimed_at = CASE WHEN $6 THEN claimed_at END
+		WHERE firestore_doc_id = $1 AND kind = $2 AND reminder_window = $3 AND due_at = $4
+	`, event.TaskDocID, event.Kind, event.Window, event.DueAt, raw, sent)
+	return err
+}
+
+// suppressReminder registra o lembrete como já enviado, sem entregá-lo, para que uma
+// janela maior não dispare depois da menor.
+func suppressReminder(ctx context.Context, db *sql.DB, event models.ReminderEvent) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO task_reminders (firestore_doc_id, workspace_id, kind, reminder_window, due_at, claimed_at, sent_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		ON CONFLICT (firestore_doc_id, kind, reminder_window, due_at) DO UPDATE SET sent_at = COALESCE(task_reminders.sent_at, NOW())
+	`, event.TaskDocID, event.WorkspaceIDPg, event.Kind, event.Window, event.DueAt)
+	return err
+}
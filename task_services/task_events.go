@@ -0,0 +1,38 @@
+package task_services
+
+import "sync"
+
+// TaskChangeListener é avisado quando o conteúdo de uma tarefa muda (criação, edição,
+// novo comentário) ou quando ela é removida. É chamado de forma síncrona por quem fez
+// a alteração, então deve apenas registrar o evento e retornar.
+type TaskChangeListener func(workspaceIDPg int64, taskDocID string, deleted bool)
+
+var (
+	taskChangeListenersMu sync.RWMutex
+	taskChangeListeners   []TaskChangeListener
+)
+
+// OnTaskChange registra um listener de alterações de tarefas (ex: o índice de busca).
+func OnTaskChange(listener TaskChangeListener) {
+	taskChangeListenersMu.Lock()
+	defer taskChangeListenersMu.Unlock()
+	taskChangeListeners = append(taskChangeListeners, listener)
+}
+
+// NotifyTaskChanged avisa os listeners de que a tarefa foi criada ou alterada.
+func NotifyTaskChanged(workspaceIDPg int64, taskDocID string) {
+	notifyTaskChange(workspaceIDPg, taskDocID, false)
+}
+
+// NotifyTaskDeleted avisa os listeners de que a tarefa foi removida.
+func NotifyTaskDeleted(workspaceIDPg int64, taskDocID string) {
+	notifyTaskChange(workspaceIDPg, taskDocID, true)
+}
+
+func notifyTaskChange(workspaceIDPg int64, taskDocID string, deleted bool) {
+	taskChangeListenersMu.RLock()
+	defer taskChangeListenersMu.RUnlock()
+	for _, listener := range taskChangeListeners {
+		listener(workspaceIDPg, taskDocID, deleted)
+	}
+}
@@ -0,0 +1,286 @@
+package task_services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"projeto-integrador/models"
+	"projeto-integrador/utilities"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const tasksSubCollectionName = "tasks" // Nome da subcoleção de tarefas no Firestore
+
+// ErrInvalidTask indica que os dados informados para a tarefa não passaram na validação.
+// Os handlers devem responder 400 quando errors.Is(err, ErrInvalidTask).
+var ErrInvalidTask = errors.New("dados da tarefa inválidos")
+
+// ErrTaskAlreadyExists indica que já existe uma tarefa com o ID de documento informado.
+var ErrTaskAlreadyExists = errors.New("tarefa já existe")
+
+// TaskRef retorna a referência do documento de uma tarefa no Firestore:
+// /workspaces/{postgres_workspace_id}/tasks/{firestore_task_doc_id}
+func TaskRef(client *firestore.Client, workspaceIDPg int64, taskDocID string) *firestore.DocumentRef {
+	return TasksCollection(client, workspaceIDPg).Doc(taskDocID)
+}
+
+// TasksCollection retorna a subcoleção de tarefas de um workspace no Firestore.
+func TasksCollection(client *firestore.Client, workspaceIDPg int64) *firestore.CollectionRef {
+	return client.Collection("workspaces").Doc(strconv.FormatInt(workspaceIDPg, 10)).Collection(tasksSubCollectionName)
+}
+
+// GetTask busca os detalhes de uma tarefa no Firestore.
+func GetTask(ctx context.Context, client *firestore.Client, workspaceIDPg int64, taskDocID string) (*models.TaskDetailsFirestore, error) {
+	docSnap, err := TaskRef(client, workspaceIDPg, taskDocID).Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar tarefa %s: %w", taskDocID, err)
+	}
+	var task models.TaskDetailsFirestore
+	if err := docSnap.DataTo(&task); err != nil {
+		return nil, fmt.Errorf("erro ao converter dados da tarefa %s: %w", taskDocID, err)
+	}
+	return &task, nil
+}
+
+// CreateTask valida o input e cria a tarefa no Firestore e o stub correspondente no
+// PostgreSQL (tabela tarefas). É o caminho único de criação usado por CreateTaskHandler
+// e por qualquer outro fluxo que gere tarefas. Não verifica membresia: isso é
+// responsabilidade do chamador.
+func CreateTask(ctx context.Context, db *sql.DB, client *firestore.Client, workspaceID int64, creatorFirebaseUID string, input models.CreateTaskInput) (string, *models.TaskDetailsFirestore, error) {
+	if strings.TrimSpace(input.Title) == "" {
+		return "", nil, fmt.Errorf("%w: task title is required", ErrInvalidTask)
+	}
+	if input.Status == "" { // Definir um status padrão se não fornecido
+		input.Status = "pending"
+	}
+
+	recurrence, err := NormalizeRecurrence(input.Recurrence, input.RRule)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidTask, err)
+	}
+
+	if input.AssigneeFirebaseUID != "" {
+		isMember, err := models.IsWorkspaceMember(db, input.AssigneeFirebaseUID, workspaceID)
+		if err != nil {
+			return "", nil, fmt.Errorf("erro ao verificar membresia do responsável: %w", err)
+		}
+		if !isMember {
+			return "", nil, fmt.Errorf("%w: assignee is not a member of the workspace", ErrInvalidTask)
+		}
+	}
+
+	// Obter o users.id (inteiro) do criador para o stub PG
+	var creatorUserIDPg int64
+	err = db.QueryRowContext(ctx, "SELECT id FROM users WHERE firebase_uid = $1", creatorFirebaseUID).Scan(&creatorUserIDPg)
+	if err != nil {
+		return "", nil, fmt.Errorf("usuário criador %s não encontrado no PG: %w", creatorFirebaseUID, err)
+	}
+
+	firestoreDocID := input.DocID
+	if firestoreDocID == "" {
+		firestoreDocID = uuid.New().String() // Gerar um ID único para o documento Firestore
+	}
+
+	now := time.Now()
+	taskDetails := models.TaskDetailsFirestore{
+		Title:               input.Title,
+		Description:         input.Description,
+		Status:              input.Status,
+		Priority:            input.Priority,
+		ExpirationDate:      input.ExpirationDate,
+		Attachment:          input.Attachment, // Assume que o cliente já fez upload e está enviando metadados
+		Recurrence:          recurrence,
+		SeriesID:            input.SeriesID,
+		OccurrenceIndex:     input.OccurrenceIndex,
+		WorkspaceIDPg:       workspaceID,
+		CreatorFirebaseUID:  creatorFirebaseUID,
+		AssigneeFirebaseUID: input.AssigneeFirebaseUID,
+		Labels:              input.Labels,
+		Checklist:           input.Checklist,
+		ImportSource:        input.ImportSource,
+		ExternalID:          input.ExternalID,
+		AIGenerated:         input.AIGenerated,
+		AIHistoryID:         input.AIHistoryID,
+		ParentTaskID:        input.ParentTaskID,
+		SubtaskOrder:        input.SubtaskOrder,
+		EstimateHours:       input.EstimateHours,
+		CreatedAt:           now,
+		LastUpdatedAt:       now,
+	}
+	if recurrence != nil && taskDetails.SeriesID == "" {
+		// A primeira ocorrência dá nome à série
+		taskDetails.SeriesID = firestoreDocID
+		taskDetails.OccurrenceIndex = 1
+		AnchorRecurrence(recurrence, input.ExpirationDate)
+	}
+
+	// 1. Criar documento no Firestore (Create falha se o ID já existir)
+	taskRef := TaskRef(client, workspaceID, firestoreDocID)
+	if _, err := taskRef.Create(ctx, taskDetails); err != nil {
+		if status.Code(err) == codes.AlreadyExists {
+			return "", nil, fmt.Errorf("%w: %s", ErrTaskAlreadyExists, firestoreDocID)
+		}
+		return "", nil, fmt.Errorf("erro ao criar tarefa no Firestore: %w", err)
+	}
+
+	// 2. Criar stub no PostgreSQL
+	_, err = db.ExecContext(ctx, `INSERT INTO tarefas (firestore_doc_id, workspace_id, criado_por) VALUES ($1, $2, $3)`,
+		firestoreDocID, workspaceID, creatorUserIDPg)
+	if err != nil {
+		// Tentar reverter a criação no Firestore (lógica de compensação)
+		if _, delErr := taskRef.Delete(ctx); delErr != nil {
+			utilities.LogError(delErr, "CreateTask: FALHA AO REVERTER criação no Firestore após erro no PG")
+		}
+		return "", nil, fmt.Errorf("erro ao criar stub da tarefa no PG: %w", err)
+	}
+
+	NotifyTaskChanged(workspaceID, firestoreDocID)
+	return firestoreDocID, &taskDetails, nil
+}
+
+// CreateNextOccurrence gera a próxima ocorrência de uma tarefa recorrente a partir da
+// ocorrência current (já concluída ou pulada) e registra o vínculo em next_occurrence_id.
+// O ID do documento é derivado da série, então chamadas repetidas para a mesma
+// ocorrência não criam duplicatas. Retorna ID vazio (sem erro) quando a série terminou.
+func CreateNextOccurrence(ctx context.Context, db *sql.DB, client *firestore.Client, currentDocID string, current *models.TaskDetailsFirestore) (string, error) {
+	if current.Recurrence == nil {
+		return "", nil
+	}
+
+	base := time.Now()
+	if current.ExpirationDate != nil {
+		base = *current.ExpirationDate
+	}
+	index := current.OccurrenceIndex
+	if index < 1 {
+		index = 1
+	}
+	seriesID := current.SeriesID
+	if seriesID == "" {
+		seriesID = currentDocID
+	}
+	nextDue, ok := NextOccurrence(current.Recurrence, base, index)
+	if !ok {
+		return "", nil
+	}
+
+	nextDocID := fmt.Sprintf("%s-%d", seriesID, index+1)
+	input := models.CreateTaskInput{
+		Title:               current.Title,
+		Description:         current.Description,
+		Status:              "pending",
+		Priority:            current.Priority,
+		ExpirationDate:      &nextDue,
+		AssigneeFirebaseUID: current.AssigneeFirebaseUID,
+		Labels:              current.Labels,
+		Recurrence:          current.Recurrence,
+		DocID:               nextDocID,
+		SeriesID:            seriesID,
+		OccurrenceIndex:     index + 1,
+	}
+	if _, _, err := CreateTask(ctx, db, client, current.WorkspaceIDPg, current.CreatorFirebaseUID, input); err != nil {
+		if _, getErr := TaskRef(client, current.WorkspaceIDPg, nextDocID).Get(ctx); getErr != nil {
+			return "", err
+		}
+		// Já gerada por uma chamada anterior; apenas garante o vínculo abaixo
+	}
+
+	_, err := TaskRef(client, current.WorkspaceIDPg, currentDocID).Update(ctx, []firestore.Update{
+		{Path: "next_occurrence_id", Value: nextDocID},
+	})
+	if err != nil {
+		utilities.LogError(err, fmt.Sprintf("CreateNextOccurrence: Erro ao vincular tarefa %s à próxima ocorrência %s", currentDocID, nextDocID))
+	}
+	return nextDocID, nil
+}
+
+// ListTasks lista todas as tarefas do workspace, em ordem de criação.
+func ListTasks(ctx context.Context, client *firestore.Client, workspaceIDPg int64) ([]string, []models.TaskDetailsFirestore, error) {
+	iter := TasksCollection(client, workspaceIDPg).OrderBy("created_at", firestore.Asc).Documents(ctx)
+	docIDs, tasks, err := collectTasks(iter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("erro ao buscar tarefas do workspace %d: %w", workspaceIDPg, err)
+	}
+	return docIDs, tasks, nil
+}
+
+// ListTasksDueSince lista as tarefas do workspace com vencimento a partir de since,
+// em ordem de vencimento. Tarefas sem expiration_date não são retornadas.
+func ListTasksDueSince(ctx context.Context, client *firestore.Client, workspaceIDPg int64, since time.Time) ([]string, []models.TaskDetailsFirestore, error) {
+	iter := TasksCollection(client, workspaceIDPg).
+		Where("expiration_date", ">=", since).
+		OrderBy("expiration_date", firestore.Asc).
+		Documents(ctx)
+	docIDs, tasks, err := collectTasks(iter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("erro ao buscar tarefas com vencimento no workspace %d: %w", workspaceIDPg, err)
+	}
+	return docIDs, tasks, nil
+}
+
+// ListSubtasks lista as subtarefas (tarefas filhas) da tarefa, na ordem da decomposição.
+func ListSubtasks(ctx context.Context, client *firestore.Client, workspaceIDPg int64, parentTaskID string) ([]string, []models.TaskDetailsFirestore, error) {
+	iter := TasksCollection(client, workspaceIDPg).Where("parent_task_id", "==", parentTaskID).Documents(ctx)
+	docIDs, tasks, err := collectTasks(iter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("erro ao buscar subtarefas da tarefa %s: %w", parentTaskID, err)
+	}
+	order := make([]int, len(tasks))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return tasks[order[i]].SubtaskOrder < tasks[order[j]].SubtaskOrder })
+	sortedIDs := make([]string, len(order))
+	sortedTasks := make([]models.TaskDetailsFirestore, len(order))
+	for i, idx := range order {
+		sortedIDs[i], sortedTasks[i] = docIDs[idx], tasks[idx]
+	}
+	return sortedIDs, sortedTasks, nil
+}
+
+// SaveTaskAISummary grava na tarefa o resumo gerado pela IA, substituindo o anterior.
+// Não altera last_updated_at: o resumo não é uma edição da tarefa.
+func SaveTaskAISummary(ctx context.Context, client *firestore.Client, workspaceIDPg int64, taskDocID string, summary models.TaskAISummary) error {
+	_, err := TaskRef(client, workspaceIDPg, taskDocID).Update(ctx, []firestore.Update{
+		{Path: "ai_summary", Value: summary},
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao salvar resumo da tarefa %s: %w", taskDocID, err)
+	}
+	return nil
+}
+
+// collectTasks consome o iterador, ignorando (com log) documentos que não convertem.
+func collectTasks(iter *firestore.DocumentIterator) ([]string, []models.TaskDetailsFirestore, error) {
+	defer iter.Stop()
+
+	var docIDs []string
+	var tasks []models.TaskDetailsFirestore
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		var task models.TaskDetailsFirestore
+		if err := doc.DataTo(&task); err != nil {
+			utilities.LogError(err, fmt.Sprintf("collectTasks: Erro ao converter tarefa %s", doc.Ref.ID))
+			continue
+		}
+		docIDs = append(docIDs, doc.Ref.ID)
+		tasks = append(tasks, task)
+	}
+	return docIDs, tasks, nil
+}
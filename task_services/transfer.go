@@ -0,0 +1,352 @@
+package task_services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"projeto-integrador/models"
+	"strings"
+	"time"
+)
+
+// MaxImportRows limita a quantidade de tarefas aceitas em uma única importação.
+const MaxImportRows = 1000
+
+// ErrInvalidImportFile indica um arquivo de importação ilegível (formato, cabeçalho etc).
+var ErrInvalidImportFile = errors.New("arquivo de importação inválido")
+
+// ExportColumns é a ordem das colunas do CSV exportado. O mesmo arquivo pode ser
+// reimportado: colunas geradas pelo servidor são ignoradas na importação.
+var ExportColumns = []string{
+	"id", "title", "description", "status", "priority", "expiration_date",
+	"assignee_firebase_uid", "rrule", "creator_firebase_uid", "created_at",
+	"last_updated_at", "completed_at",
+}
+
+// ExportedTask é o formato de cada tarefa no export JSON.
+type ExportedTask struct {
+	ID string `json:"id"`
+	models.TaskDetailsFirestore
+}
+
+// ImportRow é uma linha do arquivo já validada. Input só é usado se Errors estiver vazio.
+type ImportRow struct {
+	Line   int                    `json:"line"` // Linha do CSV (cabeçalho = 1) ou posição no array JSON (a partir de 1)
+	Title  string                 `json:"title,omitempty"`
+	Errors []string               `json:"errors,omitempty"`
+	Input  models.CreateTaskInput `json:"-"`
+}
+
+// importRecord é uma linha bruta, independente do formato de origem.
+type importRecord struct {
+	line       int
+	fields     map[string]string
+	recurrence *models.RecurrenceRule
+	errors     []string // Problemas detectados já na leitura
+}
+
+// Sinônimos aceitos nos cabeçalhos/chaves de importação.
+var importFieldAliases = map[string]string{
+	"name":       "title",
+	"summary":    "title",
+	"titulo":     "title",
+	"título":     "title",
+	"descricao":  "description",
+	"descrição":  "description",
+	"due":        "expiration_date",
+	"due_date":   "expiration_date",
+	"due_at":     "expiration_date",
+	"vencimento": "expiration_date",
+	"assignee":   "assignee_firebase_uid",
+	"prioridade": "priority",
+}
+
+var statusAliases = map[string]string{
+	"pending":      "pending",
+	"todo":         "pending",
+	"to do":        "pending",
+	"to_do":        "pending",
+	"open":         "pending",
+	"pendente":     "pending",
+	"in_progress":  "in_progress",
+	"in progress":  "in_progress",
+	"doing":        "in_progress",
+	"em andamento": "in_progress",
+	"em_andamento": "in_progress",
+	"completed":    "completed",
+	"complete":     "completed",
+	"done":         "completed",
+	"closed":       "completed",
+	"concluída":    "completed",
+	"concluida":    "completed",
+	"skipped":      "skipped", // Ocorrência pulada de uma série recorrente
+	"pulada":       "skipped",
+}
+
+var priorityAliases = map[string]string{
+	"low":    "low",
+	"baixa":  "low",
+	"medium": "medium",
+	"média":  "medium",
+	"media":  "medium",
+	"normal": "medium",
+	"high":   "high",
+	"alta":   "high",
+	"urgent": "high",
+}
+
+// NormalizeStatus converte status (inclusive sinônimos comuns) para os valores da API.
+// Vazio vira "pending".
+func NormalizeStatus(raw string) (string, error) {
+	key := strings.ToLower(strings.TrimSpace(raw))
+	if key == "" {
+		return "pending", nil
+	}
+	if status, ok := statusAliases[key]; ok {
+		return status, nil
+	}
+	return "", fmt.Errorf("invalid status %q (use pending, in_progress, completed or skipped)", raw)
+}
+
+// NormalizePriority converte prioridades (inclusive sinônimos) para low, medium ou high.
+// Vazio continua vazio.
+func NormalizePriority(raw string) (string, error) {
+	key := strings.ToLower(strings.TrimSpace(raw))
+	if key == "" {
+		return "", nil
+	}
+	if priority, ok := priorityAliases[key]; ok {
+		return priority, nil
+	}
+	return "", fmt.Errorf("invalid priority %q (use low, medium or high)", raw)
+}
+
+// ParseImportDate aceita RFC3339, ISO sem fuso (tratado como UTC) e dd/mm/aaaa.
+// Datas sem horário vencem às 23:59:59 UTC do dia.
+func ParseImportDate(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04", "02/01/2006 15:04"} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range []string{"2006-01-02", "02/01/2006"} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t.Add(24*time.Hour - time.Second), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (use RFC3339, YYYY-MM-DD or DD/MM/YYYY)", raw)
+}
+
+// WriteTasksCSV escreve as tarefas no formato de ExportColumns.
+func WriteTasksCSV(w io.Writer, docIDs []string, tasks []models.TaskDetailsFirestore) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(ExportColumns); err != nil {
+		return err
+	}
+	for i, task := range tasks {
+		rrule := ""
+		if task.Recurrence != nil {
+			rrule = RRuleString(task.Recurrence)
+		}
+		record := []string{
+			docIDs[i], task.Title, task.Description, task.Status, task.Priority,
+			formatExportTime(task.ExpirationDate), task.AssigneeFirebaseUID, rrule,
+			task.CreatorFirebaseUID, formatExportTime(&task.CreatedAt),
+			formatExportTime(&task.LastUpdatedAt), formatExportTime(task.CompletedAt),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatExportTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// ParseImportFile lê o arquivo (format "csv" ou "json") e valida cada linha.
+// members é o conjunto de Firebase UIDs do workspace, usado para validar o responsável.
+// Um erro só é retornado quando o arquivo inteiro é ilegível; problemas de linha
+// ficam em ImportRow.Errors.
+func ParseImportFile(r io.Reader, format string, members map[string]bool) ([]ImportRow, error) {
+	var records []importRecord
+	var err error
+	switch format {
+	case "csv":
+		records, err = readImportCSV(r)
+	case "json":
+		records, err = readImportJSON(r)
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q", ErrInvalidImportFile, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > MaxImportRows {
+		return nil, fmt.Errorf("%w: at most %d tasks per import", ErrInvalidImportFile, MaxImportRows)
+	}
+
+	rows := make([]ImportRow, 0, len(records))
+	for _, record := range records {
+		rows = append(rows, validateImportRecord(record, members))
+	}
+	return rows, nil
+}
+
+func validateImportRecord(record importRecord, members map[string]bool) ImportRow {
+	f := record.fields
+	row := ImportRow{Line: record.line, Title: strings.TrimSpace(f["title"]), Errors: record.errors}
+	input := models.CreateTaskInput{
+		Title:       row.Title,
+		Description: strings.TrimSpace(f["description"]),
+		Recurrence:  record.recurrence,
+	}
+
+	if input.Title == "" {
+		row.Errors = append(row.Errors, "title is required")
+	}
+	status, err := NormalizeStatus(f["status"])
+	if err != nil {
+		row.Errors = append(row.Errors, err.Error())
+	}
+	input.Status = status
+	priority, err := NormalizePriority(f["priority"])
+	if err != nil {
+		row.Errors = append(row.Errors, err.Error())
+	}
+	input.Priority = priority
+
+	if raw := strings.TrimSpace(f["expiration_date"]); raw != "" {
+		due, err := ParseImportDate(raw)
+		if err != nil {
+			row.Errors = append(row.Errors, err.Error())
+		} else {
+			input.ExpirationDate = &due
+		}
+	}
+	if assignee := strings.TrimSpace(f["assignee_firebase_uid"]); assignee != "" {
+		if !members[assignee] {
+			row.Errors = append(row.Errors, fmt.Sprintf("assignee %q is not a member of the workspace", assignee))
+		}
+		input.AssigneeFirebaseUID = assignee
+	}
+
+	rrule := strings.TrimSpace(f["rrule"])
+	if recurrence, err := NormalizeRecurrence(record.recurrence, rrule); err != nil {
+		row.Errors = append(row.Errors, err.Error())
+	} else {
+		input.Recurrence = recurrence
+		if input.Status == "skipped" && recurrence == nil {
+			// Só uma ocorrência de uma série pode ser pulada
+			row.Errors = append(row.Errors, "status skipped requires a recurrence (rrule)")
+		}
+	}
+
+	row.Input = input
+	return row
+}
+
+func canonicalImportField(name string) string {
+	name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+	if canonical, ok := importFieldAliases[name]; ok {
+		return canonical
+	}
+	return name
+}
+
+func readImportCSV(r io.Reader) ([]importRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // Linhas com colunas faltando são tratadas como vazias
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: empty file", ErrInvalidImportFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidImportFile, err)
+	}
+	columns := make([]string, len(header))
+	hasTitle := false
+	for i, name := range header {
+		columns[i] = canonicalImportField(name)
+		hasTitle = hasTitle || columns[i] == "title"
+	}
+	if !hasTitle {
+		return nil, fmt.Errorf("%w: header must contain a title column", ErrInvalidImportFile)
+	}
+
+	var records []importRecord
+	for {
+		values, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidImportFile, err)
+		}
+		line, _ := reader.FieldPos(0)
+		record := importRecord{line: line, fields: make(map[string]string, len(columns))}
+		empty := true
+		for i, value := range values {
+			if i < len(columns) {
+				record.fields[columns[i]] = value
+			}
+			empty = empty && strings.TrimSpace(value) == ""
+		}
+		if empty {
+			continue // Linhas em branco no fim do arquivo são comuns
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func readImportJSON(r io.Reader) ([]importRecord, error) {
+	var items []map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, fmt.Errorf("%w: expected a JSON array of tasks: %w", ErrInvalidImportFile, err)
+	}
+
+	records := make([]importRecord, 0, len(items))
+	for i, item := range items {
+		record := importRecord{line: i + 1, fields: make(map[string]string, len(item))}
+		for key, raw := range item {
+			key = canonicalImportField(key)
+			if key == "recurrence" {
+				if string(raw) == "null" {
+					continue
+				}
+				var rule models.RecurrenceRule
+				if err := json.Unmarshal(raw, &rule); err != nil {
+					record.errors = append(record.errors, "recurrence must be an object")
+					continue
+				}
+				record.recurrence = &rule
+				continue
+			}
+			var value string
+			if err := json.Unmarshal(raw, &value); err != nil {
+				// Números, booleanos etc. são mantidos como texto para a validação reportar
+				value = strings.Trim(string(raw), `"`)
+				if value == "null" {
+					value = ""
+				}
+			}
+			record.fields[key] = value
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}